@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -13,8 +14,10 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/aidar/avito-pr-project/internal/config"
+	"github.com/aidar/avito-pr-project/internal/domain"
 	"github.com/aidar/avito-pr-project/internal/handler"
 	"github.com/aidar/avito-pr-project/internal/middleware"
+	"github.com/aidar/avito-pr-project/internal/repository"
 	"github.com/aidar/avito-pr-project/internal/repository/postgres"
 	"github.com/aidar/avito-pr-project/internal/service"
 )
@@ -25,6 +28,11 @@ type App struct {
 	db     *pgxpool.Pool
 	server *http.Server
 	logger *slog.Logger
+
+	// Фоновые задачи запускаются при инициализации и останавливаются до закрытия БД
+	background     []func(ctx context.Context)
+	stopBackground context.CancelFunc
+	backgroundWG   sync.WaitGroup
 }
 
 // New создает новый экземпляр приложения
@@ -48,7 +56,12 @@ func (a *App) Initialize(ctx context.Context) error {
 	}
 
 	// Настраиваем HTTP сервер и роутинг
-	a.setupServer()
+	if err := a.setupServer(ctx); err != nil {
+		return fmt.Errorf("failed to setup server: %w", err)
+	}
+
+	// Запускаем фоновые задачи (доставка вебхуков)
+	a.startBackground()
 
 	a.logger.Info("Application initialized successfully")
 	return nil
@@ -81,33 +94,124 @@ func (a *App) connectDB(ctx context.Context) error {
 }
 
 // setupServer инициализирует HTTP роутер и обработчики
-func (a *App) setupServer() {
+func (a *App) setupServer(ctx context.Context) error {
 	// Инициализируем слой репозиториев (работа с БД)
 	userRepo := postgres.NewUserRepository(a.db)
 	teamRepo := postgres.NewTeamRepository(a.db)
 	prRepo := postgres.NewPullRequestRepository(a.db)
+	credentialRepo := postgres.NewCredentialRepository(a.db)
+	deliveryRepo := postgres.NewWebhookDeliveryRepository(a.db)
+	outgoingWebhookRepo := postgres.NewOutgoingWebhookRepository(a.db)
+	apiTokenRepo := postgres.NewAPITokenRepository(a.db)
+	sessionRepo := postgres.NewSessionRepository(a.db)
+	signingKeyRepo := postgres.NewSigningKeyRepository(a.db)
+	identityRepo := postgres.NewIdentityRepository(a.db)
+	absenceRepo := postgres.NewAbsenceRepository(a.db)
+	codeOwnersRepo := postgres.NewCodeOwnersRepository(a.db)
 
 	// Инициализируем слой сервисов (бизнес-логика)
-	reviewerSelector := service.NewReviewerSelector()
+	selectors := service.NewSelectorRegistry(
+		teamRepo,
+		userRepo,
+		prRepo,
+		domain.ReviewerStrategy(a.config.Reviewer.DefaultStrategy),
+	)
 	userService := service.NewUserService(userRepo)
-	teamService := service.NewTeamService(teamRepo, userRepo)
-	prService := service.NewPullRequestService(prRepo, userRepo, reviewerSelector)
+	teamService := service.NewTeamService(teamRepo, userRepo, prRepo, selectors)
+	codeOwnersService := service.NewCodeOwnersService(codeOwnersRepo, userRepo)
+	prService := service.NewPullRequestService(prRepo, userRepo, selectors, codeOwnersService)
+	signer, err := a.setupSigner(ctx, signingKeyRepo)
+	if err != nil {
+		return err
+	}
 	authService := service.NewAuthService(
 		userRepo,
-		a.config.JWT.Secret,
-		a.config.JWT.GetExpiration(),
+		credentialRepo,
+		sessionRepo,
+		service.LockoutPolicy{
+			MaxAttempts: a.config.Auth.MaxFailedAttempts,
+			Duration:    a.config.Auth.LockoutDuration,
+		},
+		signer,
+		service.TokenTTL{
+			Access:  a.config.JWT.AccessTTL,
+			Refresh: a.config.JWT.RefreshTTL,
+		},
 	)
+	accessService := service.NewAccessService(userRepo, prRepo)
+
+	// Первый администратор задается конфигурацией, а не анонимным запросом к API
+	if a.config.Auth.BootstrapAdminID != "" {
+		created, err := authService.BootstrapAdmin(ctx, a.config.Auth.BootstrapAdminID, a.config.Auth.BootstrapAdminPassword)
+		if err != nil {
+			return fmt.Errorf("failed to bootstrap admin: %w", err)
+		}
+		if created {
+			a.logger.Info("Bootstrapped first administrator", "user_id", a.config.Auth.BootstrapAdminID)
+		}
+	}
+	apiTokenService := service.NewAPITokenService(apiTokenRepo, userRepo)
 	statsService := service.NewStatsService(a.db)
+	githubService := service.NewGitHubService(prService, userRepo, deliveryRepo, a.config.GitHub.WebhookSecret)
+	webhookService := service.NewWebhookService(outgoingWebhookRepo)
+	scimService := service.NewSCIMService(userRepo, teamRepo, teamService, a.config.SCIM.DefaultTeam)
+	absenceService := service.NewAbsenceService(absenceRepo)
+
+	// Доставка исходящих вебхуков из outbox работает в фоне
+	dispatcher := service.NewWebhookDispatcher(
+		outgoingWebhookRepo,
+		service.WebhookRetryPolicy{
+			BaseDelay:   a.config.Webhook.RetryBaseDelay,
+			MaxDelay:    a.config.Webhook.RetryMaxDelay,
+			MaxAttempts: a.config.Webhook.MaxAttempts,
+		},
+		a.config.Webhook.PollInterval,
+		a.config.Webhook.Timeout,
+		a.logger,
+	)
+	a.background = append(a.background, dispatcher.Run)
+
+	// Ревью пользователей, ушедших в длительное отсутствие, передаются другим в фоне
+	absenceReassigner := service.NewAbsenceReassigner(
+		absenceRepo,
+		teamService,
+		a.config.Absence.ReassignMinDays,
+		a.config.Absence.PollInterval,
+		a.logger,
+	)
+	a.background = append(a.background, absenceReassigner.Run)
 
 	// Инициализируем HTTP обработчики
-	authHandler := handler.NewAuthHandler(authService)
-	teamHandler := handler.NewTeamHandler(teamService)
-	userHandler := handler.NewUserHandler(userService, prService)
-	prHandler := handler.NewPullRequestHandler(prService)
+	authHandler := handler.NewAuthHandler(authService, accessService)
+	teamHandler := handler.NewTeamHandler(teamService, accessService)
+	userHandler := handler.NewUserHandler(userService, prService, accessService)
+	prHandler := handler.NewPullRequestHandler(prService, accessService)
 	statsHandler := handler.NewStatsHandler(statsService)
+	webhookHandler := handler.NewWebhookHandler(githubService, webhookService)
+	tokenHandler := handler.NewTokenHandler(apiTokenService)
+	scimHandler := handler.NewSCIMHandler(scimService)
+	absenceHandler := handler.NewAbsenceHandler(absenceService, accessService)
+	codeOwnersHandler := handler.NewCodeOwnersHandler(codeOwnersService, accessService)
+
+	// Вход через OIDC включается только при заданном провайдере
+	var oidcHandler *handler.OIDCHandler
+	if a.config.OIDC.Enabled() {
+		oidcService := service.NewOIDCService(identityRepo, userRepo, teamRepo, authService, service.OIDCSettings{
+			IssuerURL:       a.config.OIDC.IssuerURL,
+			ClientID:        a.config.OIDC.ClientID,
+			ClientSecret:    a.config.OIDC.ClientSecret,
+			RedirectURL:     a.config.OIDC.RedirectURL,
+			Scopes:          a.config.OIDC.Scopes,
+			GroupsClaim:     a.config.OIDC.GroupsClaim,
+			GroupTeamPrefix: a.config.OIDC.GroupTeamPrefix,
+			AutoProvision:   a.config.OIDC.AutoProvision,
+			StateTTL:        a.config.OIDC.StateTTL,
+		})
+		oidcHandler = handler.NewOIDCHandler(oidcService)
+	}
 
-	// Инициализируем middleware для JWT авторизации
-	authMiddleware := middleware.AuthMiddleware(authService)
+	// Инициализируем middleware для авторизации по JWT и API токенам
+	authMiddleware := middleware.AuthMiddleware(authService, apiTokenService)
 
 	// Настраиваем роутер
 	r := chi.NewRouter()
@@ -121,9 +225,24 @@ func (a *App) setupServer() {
 
 	// Публичные эндпоинты (без авторизации)
 	r.Route("/auth", func(r chi.Router) {
-		r.Post("/login", authHandler.Login)
+		// При входе только через OIDC пароли не принимаются
+		if !a.config.OIDC.DisablePasswordLogin {
+			r.Post("/login", authHandler.Login)
+			// Пароль задает только вошедший пользователь (свой) или администратор и лид (первый пароль участника)
+			r.With(authMiddleware, middleware.DenyAPITokens()).Post("/setPassword", authHandler.SetPassword)
+		}
+		r.Post("/refresh", authHandler.Refresh)
+		r.Post("/logout", authHandler.Logout)
+
+		if oidcHandler != nil {
+			r.Get("/oidc/login", oidcHandler.Login)
+			r.Get("/oidc/callback", oidcHandler.Callback)
+		}
 	})
 
+	// Публичные ключи для проверки токенов другими сервисами
+	r.Get("/.well-known/jwks.json", authHandler.JWKS)
+
 	// Health check для мониторинга
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -132,29 +251,123 @@ func (a *App) setupServer() {
 		}
 	})
 
-	// Создание команды доступно без токена (для начальной настройки)
-	// В production рекомендуется защитить или использовать seed-скрипт
-	r.Post("/team/add", teamHandler.AddTeam)
+	// Вебхуки аутентифицируются подписью запроса, а не JWT токеном
+	r.Post("/webhooks/github", webhookHandler.GitHub)
 
-	// Защищенные эндпоинты (требуют JWT токен в заголовке Authorization)
+	// Защищенные эндпоинты (требуют JWT или API токен в заголовке Authorization).
+	// API токены допускаются только в группах со своей областью действия.
 	r.Group(func(r chi.Router) {
 		r.Use(authMiddleware)
 
-		// Эндпоинты команд
-		r.Get("/team/get", teamHandler.GetTeam)
-
-		// Эндпоинты пользователей
-		r.Post("/users/setIsActive", userHandler.SetIsActive)
-		r.Get("/users/getReview", userHandler.GetReview)
-
-		// Эндпоинты Pull Request'ов
-		r.Post("/pullRequest/create", prHandler.CreatePR)
-		r.Post("/pullRequest/merge", prHandler.MergePR)
-		r.Post("/pullRequest/reassign", prHandler.Reassign)
-
-		// Эндпоинты статистики (дополнительное задание)
-		r.Get("/stats", statsHandler.GetStats)
-		r.Get("/stats/user", statsHandler.GetUserStats)
+		// Чтение команд (team:read)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(domain.ScopeTeamRead))
+
+			r.Get("/team/get", teamHandler.GetTeam)
+			r.Get("/team/getSettings", teamHandler.GetSettings)
+			r.Get("/codeowners/get", codeOwnersHandler.GetCodeOwners)
+		})
+
+		// Эндпоинты Pull Request'ов (pr:write)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(domain.ScopePRWrite))
+
+			r.Get("/users/getReview", userHandler.GetReview)
+			r.Get("/pullRequest/get", prHandler.GetPR)
+			r.Get("/pullRequest/timeline", prHandler.GetTimeline)
+			r.Post("/pullRequest/create", prHandler.CreatePR)
+			r.Post("/pullRequest/merge", prHandler.MergePR)
+			r.Post("/pullRequest/reassign", prHandler.Reassign)
+			r.Post("/pullRequest/addReviewer", prHandler.AddReviewer)
+			r.Post("/pullRequest/removeReviewer", prHandler.RemoveReviewer)
+			r.Post("/pullRequest/ready", prHandler.MarkReady)
+			r.Post("/pullRequest/close", prHandler.ClosePR)
+			r.Post("/pullRequest/reopen", prHandler.ReopenPR)
+			r.Post("/pullRequest/review", prHandler.SubmitReview)
+		})
+
+		// Эндпоинты статистики (stats:read)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(domain.ScopeStatsRead))
+
+			r.Get("/stats", statsHandler.GetStats)
+			r.Get("/stats/user", statsHandler.GetUserStats)
+		})
+
+		// Синхронизация пользователей и команд из внешнего каталога по SCIM 2.0 (scim:write, только администраторы)
+		r.Route("/scim/v2", func(r chi.Router) {
+			r.Use(middleware.RequireScope(domain.ScopeSCIM))
+			r.Use(middleware.RequireRole(domain.RoleAdmin))
+
+			r.Get("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+
+			r.Get("/Users", scimHandler.ListUsers)
+			r.Post("/Users", scimHandler.CreateUser)
+			r.Get("/Users/{id}", scimHandler.GetUser)
+			r.Put("/Users/{id}", scimHandler.ReplaceUser)
+			r.Patch("/Users/{id}", scimHandler.PatchUser)
+			r.Delete("/Users/{id}", scimHandler.DeleteUser)
+
+			r.Get("/Groups", scimHandler.ListGroups)
+			r.Post("/Groups", scimHandler.CreateGroup)
+			r.Get("/Groups/{id}", scimHandler.GetGroup)
+			r.Put("/Groups/{id}", scimHandler.ReplaceGroup)
+			r.Patch("/Groups/{id}", scimHandler.PatchGroup)
+			r.Delete("/Groups/{id}", scimHandler.DeleteGroup)
+		})
+
+		// Управление командами, пользователями, вебхуками и токенами - только с JWT токеном
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.DenyAPITokens())
+
+			// Эндпоинты команд
+			r.With(middleware.RequireRole(domain.RoleAdmin)).Post("/team/add", teamHandler.AddTeam)
+			r.Post("/team/deactivateUsers", teamHandler.DeactivateUsers)
+			r.Post("/team/addMembers", teamHandler.AddMembers)
+			r.Post("/team/removeMembers", teamHandler.RemoveMembers)
+			r.Post("/team/moveMember", teamHandler.MoveMember)
+			r.Get("/team/removalPreview", teamHandler.PreviewRemoval)
+			r.With(middleware.RequireRole(domain.RoleAdmin)).Post("/team/archive", teamHandler.ArchiveTeam)
+			r.With(middleware.RequireRole(domain.RoleAdmin)).Post("/team/delete", teamHandler.DeleteTeam)
+			r.Post("/team/setReviewerStrategy", teamHandler.SetReviewerStrategy)
+			r.Post("/team/setReviewerLimits", teamHandler.SetReviewerLimits)
+			r.Post("/team/setMergeRule", teamHandler.SetMergeRule)
+			r.Post("/team/setMaxOpenReviews", teamHandler.SetMaxOpenReviews)
+			r.Post("/team/setFallbackTeams", teamHandler.SetFallbackTeams)
+
+			// Эндпоинты CODEOWNERS (CODEOWNERS репозиториев - только администраторы)
+			r.Post("/codeowners/set", codeOwnersHandler.SetCodeOwners)
+			r.Post("/codeowners/delete", codeOwnersHandler.DeleteCodeOwners)
+
+			// Эндпоинты пользователей
+			r.Post("/users/setIsActive", userHandler.SetIsActive)
+			r.Post("/users/setReviewWeight", userHandler.SetReviewWeight)
+			r.Post("/users/setMaxOpenReviews", userHandler.SetMaxOpenReviews)
+			r.Post("/users/setGithubLogin", userHandler.SetGitHubLogin)
+			r.Post("/users/setEmail", userHandler.SetEmail)
+			r.Post("/users/setPrimaryTeam", userHandler.SetPrimaryTeam)
+			r.With(middleware.RequireRole(domain.RoleAdmin)).Post("/users/setRole", userHandler.SetRole)
+
+			// Эндпоинты периодов отсутствия
+			r.Post("/users/absences/add", absenceHandler.AddAbsence)
+			r.Get("/users/absences/list", absenceHandler.ListAbsences)
+			r.Post("/users/absences/delete", absenceHandler.DeleteAbsence)
+
+			// Эндпоинты API токенов
+			r.Post("/tokens/create", tokenHandler.CreateToken)
+			r.Get("/tokens/list", tokenHandler.ListTokens)
+			r.Post("/tokens/revoke", tokenHandler.RevokeToken)
+
+			// Эндпоинты подписок на исходящие вебхуки (только для администраторов)
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireRole(domain.RoleAdmin))
+
+				r.Post("/webhooks/subscriptions/add", webhookHandler.Subscribe)
+				r.Get("/webhooks/subscriptions/list", webhookHandler.ListSubscriptions)
+				r.Post("/webhooks/subscriptions/delete", webhookHandler.Unsubscribe)
+				r.Get("/webhooks/subscriptions/deliveries", webhookHandler.GetDeliveries)
+			})
+		})
 	})
 
 	// Создаем HTTP сервер с настройками таймаутов
@@ -168,6 +381,33 @@ func (a *App) setupServer() {
 	}
 
 	a.logger.Info("HTTP server configured", "addr", addr)
+	return nil
+}
+
+// setupSigner выбирает подпись токенов: общий секрет для HS256 или ротируемые ключи для RS256/EdDSA.
+// Ключ подписи загружается (или создается) до запуска сервера, ротация выполняется в фоне
+func (a *App) setupSigner(ctx context.Context, keyRepo repository.SigningKeyRepository) (service.TokenSigner, error) {
+	algorithm := domain.SigningAlgorithm(a.config.JWT.SigningAlgorithm)
+	if !algorithm.IsAsymmetric() {
+		return service.NewHMACSigner(a.config.JWT.Secret), nil
+	}
+
+	keyManager := service.NewKeyManager(
+		keyRepo,
+		algorithm,
+		service.KeyRotationPolicy{
+			Interval: a.config.JWT.KeyRotationInterval,
+			Overlap:  a.config.JWT.KeyOverlap,
+		},
+		a.config.JWT.KeyPollInterval,
+		a.logger,
+	)
+	if err := keyManager.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	a.background = append(a.background, keyManager.Run)
+
+	return keyManager, nil
 }
 
 // Run запускает HTTP сервер
@@ -176,6 +416,20 @@ func (a *App) Run() error {
 	return a.server.ListenAndServe()
 }
 
+// startBackground запускает фоновые задачи в отдельных горутинах
+func (a *App) startBackground() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopBackground = cancel
+
+	for _, task := range a.background {
+		a.backgroundWG.Add(1)
+		go func() {
+			defer a.backgroundWG.Done()
+			task(ctx)
+		}()
+	}
+}
+
 // Shutdown корректно останавливает приложение
 func (a *App) Shutdown(ctx context.Context) error {
 	a.logger.Info("Shutting down application")
@@ -185,6 +439,12 @@ func (a *App) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("failed to shutdown server: %w", err)
 	}
 
+	// Останавливаем фоновые задачи до закрытия подключений к БД
+	if a.stopBackground != nil {
+		a.stopBackground()
+		a.backgroundWG.Wait()
+	}
+
 	// Закрываем подключения к базе данных
 	if a.db != nil {
 		a.db.Close()
@@ -2,9 +2,12 @@ package config
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
 )
 
 // Config содержит всю конфигурацию приложения
@@ -12,6 +15,13 @@ type Config struct {
 	Server   ServerConfig   // Настройки HTTP сервера
 	Database DatabaseConfig // Настройки подключения к БД
 	JWT      JWTConfig      // Настройки JWT авторизации
+	Auth     AuthConfig     // Настройки входа по паролю
+	OIDC     OIDCConfig     // Настройки входа через OIDC провайдера
+	SCIM     SCIMConfig     // Настройки синхронизации пользователей и команд по SCIM
+	Reviewer ReviewerConfig // Настройки назначения ревьюверов
+	GitHub   GitHubConfig   // Настройки интеграции с GitHub
+	Webhook  WebhookConfig  // Настройки исходящих вебхуков
+	Absence  AbsenceConfig  // Настройки периодов отсутствия пользователей
 }
 
 // ServerConfig содержит настройки HTTP сервера
@@ -34,13 +44,144 @@ type DatabaseConfig struct {
 
 // JWTConfig содержит настройки JWT авторизации
 type JWTConfig struct {
-	Secret          string `envconfig:"JWT_SECRET" required:"true"`
-	ExpirationHours int    `envconfig:"JWT_EXPIRATION_HOURS" default:"24"`
+	// SigningAlgorithm - HS256 с общим секретом или RS256/EdDSA с ротируемыми ключами и JWKS
+	SigningAlgorithm string `envconfig:"JWT_SIGNING_ALG" default:"HS256"`
+	// Secret нужен только для HS256
+	Secret string `envconfig:"JWT_SECRET"`
+	// AccessTTL - время жизни access токена; отзыв сессии действует сразу, а смена роли - после обновления
+	AccessTTL time.Duration `envconfig:"JWT_ACCESS_TTL" default:"15m"`
+	// RefreshTTL - время жизни сессии с момента входа, после него нужен повторный вход
+	RefreshTTL time.Duration `envconfig:"JWT_REFRESH_TTL" default:"720h"`
+	// KeyRotationInterval - возраст ключа RS256/EdDSA, после которого выпускается новый
+	KeyRotationInterval time.Duration `envconfig:"JWT_KEY_ROTATION_INTERVAL" default:"720h"`
+	// KeyOverlap - сколько замененный ключ еще проверяет токены; не меньше JWT_ACCESS_TTL
+	KeyOverlap time.Duration `envconfig:"JWT_KEY_OVERLAP" default:"24h"`
+	// KeyPollInterval - как часто проверяется срок ротации и подхватываются ключи других экземпляров
+	KeyPollInterval time.Duration `envconfig:"JWT_KEY_POLL_INTERVAL" default:"1m"`
+}
+
+// AuthConfig содержит настройки входа по паролю
+type AuthConfig struct {
+	MaxFailedAttempts int           `envconfig:"AUTH_MAX_FAILED_ATTEMPTS" default:"5"`
+	LockoutDuration   time.Duration `envconfig:"AUTH_LOCKOUT_DURATION" default:"15m"`
+	// BootstrapAdminID - пользователь, который при старте становится администратором, пока в системе
+	// нет ни одного (создается без команды, если его нет)
+	BootstrapAdminID string `envconfig:"AUTH_BOOTSTRAP_ADMIN_ID"`
+	// BootstrapAdminPassword задается этому пользователю, если у него еще нет пароля
+	BootstrapAdminPassword string `envconfig:"AUTH_BOOTSTRAP_ADMIN_PASSWORD"`
+}
+
+// OIDCConfig содержит настройки входа через OIDC провайдера; без IssuerURL вход через OIDC выключен
+type OIDCConfig struct {
+	IssuerURL    string   `envconfig:"OIDC_ISSUER_URL"`
+	ClientID     string   `envconfig:"OIDC_CLIENT_ID"`
+	ClientSecret string   `envconfig:"OIDC_CLIENT_SECRET"`
+	RedirectURL  string   `envconfig:"OIDC_REDIRECT_URL"`
+	Scopes       []string `envconfig:"OIDC_SCOPES" default:"openid,email,profile"`
+	GroupsClaim  string   `envconfig:"OIDC_GROUPS_CLAIM" default:"groups"`
+	// GroupTeamPrefix отбирает группы, задающие команду: группа "<префикс><команда>"
+	GroupTeamPrefix string        `envconfig:"OIDC_GROUP_TEAM_PREFIX"`
+	AutoProvision   bool          `envconfig:"OIDC_AUTO_PROVISION" default:"false"`
+	StateTTL        time.Duration `envconfig:"OIDC_STATE_TTL" default:"10m"`
+	// DisablePasswordLogin отключает /auth/login и /auth/setPassword, оставляя только вход через OIDC
+	DisablePasswordLogin bool `envconfig:"OIDC_DISABLE_PASSWORD_LOGIN" default:"false"`
+}
+
+// Enabled возвращает true, если вход через OIDC настроен
+func (o OIDCConfig) Enabled() bool {
+	return o.IssuerURL != ""
+}
+
+// SCIMConfig содержит настройки синхронизации пользователей и команд по SCIM
+type SCIMConfig struct {
+	// DefaultTeam - команда для пользователей, созданных без команды или исключенных из своей команды
+	DefaultTeam string `envconfig:"SCIM_DEFAULT_TEAM"`
+}
+
+// ReviewerConfig содержит настройки назначения ревьюверов
+type ReviewerConfig struct {
+	// DefaultStrategy используется для команд без собственной настройки
+	DefaultStrategy string `envconfig:"REVIEWER_STRATEGY" default:"random"`
+}
+
+// GitHubConfig содержит настройки интеграции с GitHub
+type GitHubConfig struct {
+	// WebhookSecret проверяет подпись X-Hub-Signature-256; без него вебхуки отклоняются
+	WebhookSecret string `envconfig:"GITHUB_WEBHOOK_SECRET"`
+}
+
+// WebhookConfig содержит настройки доставки исходящих вебхуков
+type WebhookConfig struct {
+	PollInterval   time.Duration `envconfig:"WEBHOOK_POLL_INTERVAL" default:"1s"`
+	Timeout        time.Duration `envconfig:"WEBHOOK_TIMEOUT" default:"10s"`
+	RetryBaseDelay time.Duration `envconfig:"WEBHOOK_RETRY_BASE_DELAY" default:"10s"`
+	RetryMaxDelay  time.Duration `envconfig:"WEBHOOK_RETRY_MAX_DELAY" default:"1h"`
+	MaxAttempts    int           `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"10"`
+}
+
+// AbsenceConfig содержит настройки передачи ревью отсутствующих пользователей
+type AbsenceConfig struct {
+	// PollInterval - как часто проверяются начавшиеся периоды отсутствия
+	PollInterval time.Duration `envconfig:"ABSENCE_POLL_INTERVAL" default:"1m"`
+	// ReassignMinDays - длительность периода в днях, начиная с которой открытые ревью пользователя переназначаются
+	ReassignMinDays int `envconfig:"ABSENCE_REASSIGN_MIN_DAYS" default:"3"`
+}
+
+// validateSigning проверяет настройки подписи токенов
+func (j JWTConfig) validateSigning() error {
+	alg := domain.SigningAlgorithm(j.SigningAlgorithm)
+	if !alg.IsValid() {
+		return fmt.Errorf("unknown JWT signing algorithm: %q", j.SigningAlgorithm)
+	}
+	if !alg.IsAsymmetric() {
+		if j.Secret == "" {
+			return fmt.Errorf("JWT_SECRET is required for %s", alg)
+		}
+		return nil
+	}
+	if j.KeyRotationInterval <= 0 || j.KeyPollInterval <= 0 {
+		return fmt.Errorf("JWT_KEY_ROTATION_INTERVAL and JWT_KEY_POLL_INTERVAL must be positive")
+	}
+	// Токены, подписанные замененным ключом, должны проверяться до своего истечения
+	if j.KeyOverlap < j.AccessTTL {
+		return fmt.Errorf("JWT_KEY_OVERLAP must not be shorter than JWT_ACCESS_TTL")
+	}
+	return nil
+}
+
+// validateBootstrap проверяет настройки первого администратора
+func (a AuthConfig) validateBootstrap() error {
+	if a.BootstrapAdminPassword == "" {
+		return nil
+	}
+	if a.BootstrapAdminID == "" {
+		return fmt.Errorf("AUTH_BOOTSTRAP_ADMIN_PASSWORD requires AUTH_BOOTSTRAP_ADMIN_ID")
+	}
+	// Ограничения те же, что и для паролей, задаваемых через API (bcrypt учитывает только 72 байта)
+	if len(a.BootstrapAdminPassword) < 8 || len(a.BootstrapAdminPassword) > 72 {
+		return fmt.Errorf("AUTH_BOOTSTRAP_ADMIN_PASSWORD must be 8 to 72 bytes long")
+	}
+	return nil
 }
 
-// GetExpiration возвращает срок действия токена как time.Duration
-func (j JWTConfig) GetExpiration() time.Duration {
-	return time.Duration(j.ExpirationHours) * time.Hour
+// validate проверяет настройки входа через OIDC
+func (o OIDCConfig) validate() error {
+	if !o.Enabled() {
+		if o.DisablePasswordLogin {
+			return fmt.Errorf("OIDC_DISABLE_PASSWORD_LOGIN requires OIDC_ISSUER_URL")
+		}
+		return nil
+	}
+	if o.ClientID == "" || o.RedirectURL == "" {
+		return fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required for OIDC login")
+	}
+	if !slices.Contains(o.Scopes, "openid") {
+		return fmt.Errorf("OIDC_SCOPES must include openid")
+	}
+	if o.StateTTL <= 0 {
+		return fmt.Errorf("OIDC_STATE_TTL must be positive")
+	}
+	return nil
 }
 
 // DSN возвращает строку подключения к PostgreSQL
@@ -57,5 +198,29 @@ func Load() (*Config, error) {
 	if err := envconfig.Process("", &cfg); err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
+	if !domain.ReviewerStrategy(cfg.Reviewer.DefaultStrategy).IsValid() {
+		return nil, fmt.Errorf("unknown reviewer strategy: %q", cfg.Reviewer.DefaultStrategy)
+	}
+	if cfg.JWT.AccessTTL <= 0 || cfg.JWT.RefreshTTL < cfg.JWT.AccessTTL {
+		return nil, fmt.Errorf("JWT_ACCESS_TTL must be positive and not exceed JWT_REFRESH_TTL")
+	}
+	if err := cfg.JWT.validateSigning(); err != nil {
+		return nil, err
+	}
+	if err := cfg.OIDC.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.MaxFailedAttempts < 1 {
+		return nil, fmt.Errorf("AUTH_MAX_FAILED_ATTEMPTS must be positive")
+	}
+	if err := cfg.Auth.validateBootstrap(); err != nil {
+		return nil, err
+	}
+	if cfg.Webhook.PollInterval <= 0 || cfg.Webhook.Timeout <= 0 || cfg.Webhook.MaxAttempts < 1 {
+		return nil, fmt.Errorf("invalid webhook delivery settings")
+	}
+	if cfg.Absence.PollInterval <= 0 || cfg.Absence.ReassignMinDays < 1 {
+		return nil, fmt.Errorf("ABSENCE_POLL_INTERVAL and ABSENCE_REASSIGN_MIN_DAYS must be positive")
+	}
 	return &cfg, nil
 }
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// Absence представляет период отсутствия пользователя (отпуск, больничный).
+// Даты включительные и не содержат времени; в течение периода пользователь не выбирается ревьювером
+type Absence struct {
+	AbsenceID           int64
+	UserID              string
+	StartDate           time.Time
+	EndDate             time.Time
+	Reason              string
+	CreatedBy           string
+	CreatedAt           time.Time
+	ReviewsReassignedAt *time.Time // Когда открытые ревью пользователя были переназначены; nil - не переназначались
+	HandoverAttempts    int        // Число неудачных попыток переназначить ревью
+}
+
+// Days возвращает длительность периода в днях
+func (a *Absence) Days() int {
+	return int(a.EndDate.Sub(a.StartDate).Hours()/24) + 1
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// TokenScope ограничивает операции, доступные по API токену
+type TokenScope string
+
+// Области действия API токенов
+const (
+	ScopePRWrite   TokenScope = "pr:write"   // Чтение и изменение PR, включая решения ревьюверов
+	ScopeTeamRead  TokenScope = "team:read"  // Чтение команд и их настроек
+	ScopeStatsRead TokenScope = "stats:read" // Чтение статистики
+	ScopeSCIM      TokenScope = "scim:write" // Синхронизация пользователей и команд по SCIM
+)
+
+// IsValid проверяет, что область действия поддерживается
+func (s TokenScope) IsValid() bool {
+	switch s {
+	case ScopePRWrite, ScopeTeamRead, ScopeStatsRead, ScopeSCIM:
+		return true
+	default:
+		return false
+	}
+}
+
+// APITokenKind определяет, от чьего имени действует API токен
+type APITokenKind string
+
+// Виды API токенов
+const (
+	TokenPersonal APITokenKind = "personal" // Действует от имени владельца с его ролью
+	TokenService  APITokenKind = "service"  // Не привязан к пользователю, действует с правами администратора
+)
+
+// IsValid проверяет, что вид токена поддерживается
+func (k APITokenKind) IsValid() bool {
+	return k == TokenPersonal || k == TokenService
+}
+
+// APIToken представляет долгоживущий API токен. Сам токен не хранится, только его хеш.
+type APIToken struct {
+	TokenID    int64        `json:"token_id"`
+	Name       string       `json:"name"`
+	Kind       APITokenKind `json:"kind"`
+	UserID     string       `json:"user_id,omitempty"` // Владелец персонального токена
+	Scopes     []TokenScope `json:"scopes"`
+	Prefix     string       `json:"prefix"` // Начало токена для его опознания в списке
+	CreatedBy  string       `json:"created_by"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	ExpiresAt  *time.Time   `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time   `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time   `json:"revokedAt,omitempty"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// CodeOwners представляет файл CODEOWNERS, загруженный для команды или для репозитория.
+// Задано ровно одно из полей TeamName и Repository
+type CodeOwners struct {
+	TeamName   string    `json:"team_name,omitempty"`
+	Repository string    `json:"repository,omitempty"`
+	Content    string    `json:"content"`
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// CodeOwnersRule представляет строку CODEOWNERS: шаблон пути и его владельцев
+// (@user_id или @login GitHub - пользователь, @org/team - команда team).
+// Правило без владельцев снимает владельцев, заданных для пути правилами выше
+type CodeOwnersRule struct {
+	Line    int
+	Pattern string
+	Owners  []string
+}
+
+// MaxChangedFiles ограничивает число измененных файлов в запросе на создание PR
+const MaxChangedFiles = 3000
@@ -0,0 +1,17 @@
+package domain
+
+// DirectoryUser представляет пользователя вместе с атрибутами, которые синхронизирует внешний каталог (SCIM)
+type DirectoryUser struct {
+	User
+	Email      string // Пустой - email не задан
+	ExternalID string // ID пользователя в каталоге клиента
+}
+
+// DirectoryFilter задает отбор и страницу пользователей каталога; пустые поля не ограничивают выборку
+type DirectoryFilter struct {
+	UserID     string
+	Email      string // Без учета регистра
+	ExternalID string
+	Offset     int
+	Limit      int
+}
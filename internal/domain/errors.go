@@ -1,24 +1,81 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Доменные ошибки согласно OpenAPI спецификации
 var (
 	// ErrTeamExists возвращается при попытке создать уже существующую команду
 	ErrTeamExists = errors.New("team already exists")
 
+	// ErrTeamArchived возвращается при попытке изменить состав архивной команды или архивировать ее повторно
+	ErrTeamArchived = errors.New("team is archived")
+
+	// ErrUserExists возвращается при попытке создать уже существующего пользователя
+	ErrUserExists = errors.New("user already exists")
+
+	// ErrNotTeamMember возвращается, когда пользователь не состоит в указанной команде
+	ErrNotTeamMember = errors.New("user is not a member of the team")
+
+	// ErrTeamRequired возвращается, когда операция оставила бы пользователя без команды
+	ErrTeamRequired = errors.New("user must belong to a team")
+
+	// ErrAbsenceOverlap возвращается при попытке добавить период отсутствия, пересекающийся с другим периодом пользователя
+	ErrAbsenceOverlap = errors.New("absence overlaps another absence of the user")
+
 	// ErrPRExists возвращается при попытке создать уже существующий PR
 	ErrPRExists = errors.New("pull request already exists")
 
 	// ErrPRMerged возвращается при попытке изменить смерженный PR
 	ErrPRMerged = errors.New("cannot modify merged pull request")
 
+	// ErrInvalidStatus возвращается при операции, недопустимой в текущем статусе PR (DRAFT, CLOSED)
+	ErrInvalidStatus = errors.New("operation is not allowed in current pull request status")
+
 	// ErrNotAssigned возвращается при попытке переназначить неназначенного ревьювера
 	ErrNotAssigned = errors.New("reviewer is not assigned to this PR")
 
-	// ErrNoCandidate возвращается когда нет доступных ревьюверов для назначения
+	// ErrAlreadyAssigned возвращается при попытке назначить ревьювера, уже назначенного на PR
+	ErrAlreadyAssigned = errors.New("reviewer is already assigned to this PR")
+
+	// ErrReviewerInactive возвращается при попытке явно назначить ревьювером неактивного пользователя
+	ErrReviewerInactive = errors.New("reviewer is not active")
+
+	// ErrReviewerUnavailable возвращается при попытке явно назначить ревьювером отсутствующего сегодня пользователя
+	// или пользователя, не состоящего ни в одной неархивной команде
+	ErrReviewerUnavailable = errors.New("reviewer is absent or not in any active team")
+
+	// ErrAuthorReviewer возвращается при попытке назначить автора ревьювером его PR
+	ErrAuthorReviewer = errors.New("author cannot review their own pull request")
+
+	// ErrTooManyReviewers возвращается, когда у PR уже max_reviewers ревьюверов команды PR
+	ErrTooManyReviewers = errors.New("pull request already has the maximum number of reviewers")
+
+	// ErrTooFewReviewers возвращается, когда после снятия ревьювера у PR осталось бы меньше min_reviewers
+	ErrTooFewReviewers = errors.New("pull request would have fewer than the minimum number of reviewers")
+
+	// ErrNoCandidate возвращается когда нет доступных ревьюверов для назначения: в команде нет активных кандидатов
 	ErrNoCandidate = errors.New("no active replacement candidate in team")
 
+	// ErrCandidatesAtCapacity возвращается вместо ErrNoCandidate (и совпадает с ним по errors.Is),
+	// когда активные кандидаты есть, но у всех исчерпан лимит открытых ревью
+	ErrCandidatesAtCapacity = fmt.Errorf("%w: all candidates are at review capacity", ErrNoCandidate)
+
+	// ErrNotApproved возвращается при попытке смержить PR, не выполнивший правило одобрений команды
+	ErrNotApproved = errors.New("pull request does not have required approvals")
+
+	// ErrGitHubLoginTaken возвращается при попытке привязать логин GitHub, уже привязанный к другому пользователю
+	ErrGitHubLoginTaken = errors.New("github login is already linked to another user")
+
+	// ErrEmailTaken возвращается при попытке задать email, уже заданный другому пользователю
+	ErrEmailTaken = errors.New("email is already used by another user")
+
+	// ErrIdentityNotLinked возвращается при входе через OIDC, если учетная запись провайдера
+	// не сопоставлена ни с одним пользователем и не может быть создана автоматически
+	ErrIdentityNotLinked = errors.New("identity is not linked to any user")
+
 	// ErrNotFound возвращается когда ресурс не найден
 	ErrNotFound = errors.New("resource not found")
 
@@ -31,9 +88,27 @@ var (
 	// ErrPRNotFound возвращается когда PR не найден
 	ErrPRNotFound = errors.New("pull request not found")
 
+	// ErrTokenNotFound возвращается когда API токен не найден
+	ErrTokenNotFound = errors.New("api token not found")
+
+	// ErrAbsenceNotFound возвращается когда период отсутствия не найден
+	ErrAbsenceNotFound = errors.New("absence not found")
+
+	// ErrCodeOwnersNotFound возвращается когда для команды или репозитория не загружен CODEOWNERS
+	ErrCodeOwnersNotFound = errors.New("code owners not found")
+
+	// ErrSubscriptionNotFound возвращается когда подписка на вебхуки не найдена
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
 	// ErrUnauthorized возвращается при неудачной аутентификации
 	ErrUnauthorized = errors.New("unauthorized")
 
+	// ErrForbidden возвращается, когда роли пользователя недостаточно для операции
+	ErrForbidden = errors.New("access denied")
+
+	// ErrAccountLocked возвращается при попытке входа, пока вход заблокирован после неудачных попыток
+	ErrAccountLocked = errors.New("account is temporarily locked")
+
 	// ErrInvalidToken возвращается когда JWT токен невалиден
 	ErrInvalidToken = errors.New("invalid token")
 )
@@ -43,12 +118,23 @@ type ErrorCode string
 
 // Коды ошибок согласно OpenAPI спецификации
 const (
-	CodeTeamExists  ErrorCode = "TEAM_EXISTS"  // Команда уже существует
-	CodePRExists    ErrorCode = "PR_EXISTS"    // Pull request уже существует
-	CodePRMerged    ErrorCode = "PR_MERGED"    // Нельзя изменить смерженный PR
-	CodeNotAssigned ErrorCode = "NOT_ASSIGNED" // Ревьювер не назначен
-	CodeNoCandidate ErrorCode = "NO_CANDIDATE" // Нет активных кандидатов для замены
-	CodeNotFound    ErrorCode = "NOT_FOUND"    // Ресурс не найден
+	CodeTeamExists      ErrorCode = "TEAM_EXISTS"      // Команда уже существует
+	CodeTeamArchived    ErrorCode = "TEAM_ARCHIVED"    // Команда в архиве
+	CodeUserExists      ErrorCode = "USER_EXISTS"      // Пользователь уже существует
+	CodeNotMember       ErrorCode = "NOT_MEMBER"       // Пользователь не состоит в команде
+	CodeAbsenceOverlap  ErrorCode = "ABSENCE_OVERLAP"  // Период отсутствия пересекается с другим
+	CodePRExists        ErrorCode = "PR_EXISTS"        // Pull request уже существует
+	CodePRMerged        ErrorCode = "PR_MERGED"        // Нельзя изменить смерженный PR
+	CodeInvalidStatus   ErrorCode = "INVALID_STATUS"   // Операция недопустима в текущем статусе PR
+	CodeNotAssigned     ErrorCode = "NOT_ASSIGNED"     // Ревьювер не назначен
+	CodeAlreadyAssigned ErrorCode = "ALREADY_ASSIGNED" // Ревьювер уже назначен
+	CodeInvalidReviewer ErrorCode = "INVALID_REVIEWER" // Пользователь не может быть ревьювером PR
+	CodeReviewerLimit   ErrorCode = "REVIEWER_LIMIT"   // Нарушены лимиты числа ревьюверов команды
+	CodeNoCandidate     ErrorCode = "NO_CANDIDATE"     // Нет активных кандидатов для замены
+	CodeNotApproved     ErrorCode = "NOT_APPROVED"     // Не выполнено правило одобрений для merge
+	CodeLoginTaken      ErrorCode = "LOGIN_TAKEN"      // Логин GitHub уже привязан к другому пользователю
+	CodeEmailTaken      ErrorCode = "EMAIL_TAKEN"      // Email уже задан другому пользователю
+	CodeNotFound        ErrorCode = "NOT_FOUND"        // Ресурс не найден
 )
 
 // MapErrorToCode преобразует доменные ошибки в коды ошибок API
@@ -56,16 +142,39 @@ func MapErrorToCode(err error) ErrorCode {
 	switch {
 	case errors.Is(err, ErrTeamExists):
 		return CodeTeamExists
+	case errors.Is(err, ErrTeamArchived):
+		return CodeTeamArchived
+	case errors.Is(err, ErrUserExists):
+		return CodeUserExists
+	case errors.Is(err, ErrNotTeamMember):
+		return CodeNotMember
+	case errors.Is(err, ErrAbsenceOverlap):
+		return CodeAbsenceOverlap
 	case errors.Is(err, ErrPRExists):
 		return CodePRExists
 	case errors.Is(err, ErrPRMerged):
 		return CodePRMerged
+	case errors.Is(err, ErrInvalidStatus):
+		return CodeInvalidStatus
 	case errors.Is(err, ErrNotAssigned):
 		return CodeNotAssigned
+	case errors.Is(err, ErrAlreadyAssigned):
+		return CodeAlreadyAssigned
+	case errors.Is(err, ErrReviewerInactive), errors.Is(err, ErrReviewerUnavailable), errors.Is(err, ErrAuthorReviewer):
+		return CodeInvalidReviewer
+	case errors.Is(err, ErrTooManyReviewers), errors.Is(err, ErrTooFewReviewers):
+		return CodeReviewerLimit
 	case errors.Is(err, ErrNoCandidate):
 		return CodeNoCandidate
+	case errors.Is(err, ErrNotApproved):
+		return CodeNotApproved
+	case errors.Is(err, ErrGitHubLoginTaken):
+		return CodeLoginTaken
+	case errors.Is(err, ErrEmailTaken):
+		return CodeEmailTaken
 	case errors.Is(err, ErrNotFound), errors.Is(err, ErrUserNotFound),
-		errors.Is(err, ErrTeamNotFound), errors.Is(err, ErrPRNotFound):
+		errors.Is(err, ErrTeamNotFound), errors.Is(err, ErrPRNotFound), errors.Is(err, ErrSubscriptionNotFound), errors.Is(err, ErrTokenNotFound),
+		errors.Is(err, ErrAbsenceNotFound), errors.Is(err, ErrCodeOwnersNotFound):
 		return CodeNotFound
 	default:
 		return CodeNotFound
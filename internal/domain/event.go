@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// PREventType представляет тип события в истории PR
+type PREventType string
+
+// Возможные типы событий в истории PR
+const (
+	EventCreated                 PREventType = "created"                   // PR создан
+	EventReadyForReview          PREventType = "ready_for_review"          // Черновик переведен в OPEN
+	EventReviewerAssigned        PREventType = "reviewer_assigned"         // Ревьювер назначен
+	EventReviewerReassigned      PREventType = "reviewer_reassigned"       // Ревьювер заменен другим
+	EventReviewerUnassigned      PREventType = "reviewer_unassigned"       // Ревьювер снят без замены
+	EventReviewerActivityChanged PREventType = "reviewer_activity_changed" // Ревьювер активирован или деактивирован
+	EventMerged                  PREventType = "merged"                    // PR смержен
+	EventClosed                  PREventType = "closed"                    // PR закрыт без merge
+	EventReopened                PREventType = "reopened"                  // Закрытый PR переоткрыт
+)
+
+// PREventReason объясняет, почему произошло событие
+type PREventReason string
+
+// Возможные причины событий
+const (
+	ReasonManual          PREventReason = "manual"           // Явный запрос через API
+	ReasonUserDeactivated PREventReason = "user_deactivated" // Пользователь деактивирован
+	ReasonUserActivated   PREventReason = "user_activated"   // Пользователь снова активен
+	ReasonPRClosed        PREventReason = "pr_closed"        // PR закрыт без merge
+	ReasonTeamChanged     PREventReason = "team_changed"     // Ревьювер покинул команду
+	ReasonUserAbsent      PREventReason = "user_absent"      // Началось долгое отсутствие ревьювера
+)
+
+// PREvent представляет запись в истории PR (только добавление, записи не изменяются)
+type PREvent struct {
+	EventID       int64         `json:"event_id"`
+	PullRequestID string        `json:"pull_request_id"`
+	Type          PREventType   `json:"type"`
+	ActorID       string        `json:"actor_id,omitempty"`    // Кто выполнил действие
+	UserID        string        `json:"user_id,omitempty"`     // Ревьювер, которого касается событие
+	OldUserID     string        `json:"old_user_id,omitempty"` // Замененный ревьювер
+	NewUserID     string        `json:"new_user_id,omitempty"` // Новый ревьювер
+	Reason        PREventReason `json:"reason,omitempty"`
+	CreatedAt     time.Time     `json:"createdAt"`
+}
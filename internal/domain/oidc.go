@@ -0,0 +1,18 @@
+package domain
+
+// OIDCLoginState представляет незавершенный вход через OIDC
+type OIDCLoginState struct {
+	State        string // Передается провайдеру и возвращается в callback
+	Nonce        string // Должен совпасть с claim nonce в ID токене
+	CodeVerifier string // PKCE: провайдер проверяет его при обмене кода на токены
+}
+
+// OIDCIdentity представляет учетную запись пользователя у провайдера OIDC по данным ID токена
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
@@ -7,19 +7,61 @@ type PullRequestStatus string
 
 // Возможные статусы pull request'а
 const (
+	StatusDraft  PullRequestStatus = "DRAFT"  // Черновик, ревьюверы не назначаются до готовности
 	StatusOpen   PullRequestStatus = "OPEN"   // PR открыт и может быть изменен
 	StatusMerged PullRequestStatus = "MERGED" // PR смержен и не может быть изменен
+	StatusClosed PullRequestStatus = "CLOSED" // PR закрыт без merge, ревьюверы освобождены
 )
 
+// ReviewVerdict представляет решение ревьювера по PR
+type ReviewVerdict string
+
+// Возможные решения ревьювера
+const (
+	VerdictApproved         ReviewVerdict = "APPROVED"          // PR одобрен
+	VerdictChangesRequested ReviewVerdict = "CHANGES_REQUESTED" // Требуются изменения
+	VerdictCommented        ReviewVerdict = "COMMENTED"         // Оставлены комментарии без решения
+)
+
+// IsValid проверяет, что решение поддерживается
+func (v ReviewVerdict) IsValid() bool {
+	switch v {
+	case VerdictApproved, VerdictChangesRequested, VerdictCommented:
+		return true
+	default:
+		return false
+	}
+}
+
+// Review представляет последнее решение ревьювера по PR
+type Review struct {
+	UserID      string        `json:"user_id"`
+	Verdict     ReviewVerdict `json:"verdict"`
+	SubmittedAt *time.Time    `json:"submittedAt,omitempty"`
+}
+
+// FallbackReviewer описывает ревьювера, назначенного из запасной команды
+type FallbackReviewer struct {
+	UserID   string `json:"user_id"`
+	TeamName string `json:"team_name"`
+}
+
 // PullRequest представляет pull request с назначенными ревьюверами
 type PullRequest struct {
-	PullRequestID     string            `json:"pull_request_id"`
-	PullRequestName   string            `json:"pull_request_name"`
-	AuthorID          string            `json:"author_id"`
-	Status            PullRequestStatus `json:"status"`
-	AssignedReviewers []string          `json:"assigned_reviewers"` // До 2 ревьюверов
-	CreatedAt         *time.Time        `json:"createdAt,omitempty"`
-	MergedAt          *time.Time        `json:"mergedAt,omitempty"`
+	PullRequestID     string             `json:"pull_request_id"`
+	PullRequestName   string             `json:"pull_request_name"`
+	AuthorID          string             `json:"author_id"`
+	TeamName          string             `json:"team_name,omitempty"`     // Команда, из которой назначаются ревьюверы
+	Repository        string             `json:"repository,omitempty"`    // Репозиторий, чей CODEOWNERS применяется вместо CODEOWNERS команды
+	ChangedFiles      []string           `json:"changed_files,omitempty"` // Измененные файлы для выбора владельцев кода
+	Status            PullRequestStatus  `json:"status"`
+	AssignedReviewers []string           `json:"assigned_reviewers"` // Не больше max_reviewers команды PR
+	CreatedAt         *time.Time         `json:"createdAt,omitempty"`
+	MergedAt          *time.Time         `json:"mergedAt,omitempty"`
+	ClosedAt          *time.Time         `json:"closedAt,omitempty"`
+	MissingReviewers  int                `json:"missing_reviewers,omitempty"`  // Сколько ревьюверов не хватает до min_reviewers команды
+	Reviews           []Review           `json:"reviews,omitempty"`            // Последние решения назначенных ревьюверов
+	FallbackReviewers []FallbackReviewer `json:"fallback_reviewers,omitempty"` // Ревьюверы, добранные из запасных команд
 }
 
 // PullRequestShort представляет сокращенную информацию о PR (используется в списках)
@@ -30,11 +72,35 @@ type PullRequestShort struct {
 	Status          PullRequestStatus `json:"status"`
 }
 
+// ReviewerReassignment описывает замену ревьювера на открытом PR
+type ReviewerReassignment struct {
+	PullRequestID string `json:"pull_request_id"`
+	OldUserID     string `json:"old_user_id"`
+	ReplacedBy    string `json:"replaced_by,omitempty"` // Пусто, если замены не нашлось и ревьювер снят с PR
+}
+
 // IsMerged возвращает true если PR находится в статусе MERGED
 func (pr *PullRequest) IsMerged() bool {
 	return pr.Status == StatusMerged
 }
 
+// IsOpen возвращает true если PR находится в статусе OPEN
+func (pr *PullRequest) IsOpen() bool {
+	return pr.Status == StatusOpen
+}
+
+// CheckOpen возвращает ошибку, если ревьюверов и решения по PR нельзя менять в текущем статусе
+func (pr *PullRequest) CheckOpen() error {
+	switch pr.Status {
+	case StatusOpen:
+		return nil
+	case StatusMerged:
+		return ErrPRMerged
+	default:
+		return ErrInvalidStatus
+	}
+}
+
 // IsReviewerAssigned проверяет, назначен ли пользователь ревьювером этого PR
 func (pr *PullRequest) IsReviewerAssigned(userID string) bool {
 	for _, reviewer := range pr.AssignedReviewers {
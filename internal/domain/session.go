@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// Session представляет сессию входа, продлеваемую refresh токеном
+type Session struct {
+	SessionID int64
+	UserID    string
+	ExpiresAt time.Time // Сессия не продлевается после этого времени, нужен повторный вход
+}
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// SigningAlgorithm определяет алгоритм подписи JWT
+type SigningAlgorithm string
+
+// Поддерживаемые алгоритмы подписи JWT
+const (
+	SigningHS256 SigningAlgorithm = "HS256" // HMAC с общим секретом JWT_SECRET
+	SigningRS256 SigningAlgorithm = "RS256" // RSA 2048, ключи ротируются и публикуются в JWKS
+	SigningEdDSA SigningAlgorithm = "EdDSA" // Ed25519, ключи ротируются и публикуются в JWKS
+)
+
+// IsValid проверяет, что алгоритм поддерживается
+func (a SigningAlgorithm) IsValid() bool {
+	switch a {
+	case SigningHS256, SigningRS256, SigningEdDSA:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsAsymmetric возвращает true для алгоритмов с публичным ключом проверки
+func (a SigningAlgorithm) IsAsymmetric() bool {
+	return a == SigningRS256 || a == SigningEdDSA
+}
+
+// SigningKey представляет ключ асимметричной подписи JWT
+type SigningKey struct {
+	KeyID      string
+	Algorithm  SigningAlgorithm
+	PrivateKey []byte // PKCS #8 DER
+	PublicKey  []byte // PKIX DER
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time // nil у текущего ключа подписи
+}
+
+// JWK представляет публичный ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // Модуль RSA
+	E         string `json:"e,omitempty"`   // Экспонента RSA
+	Curve     string `json:"crv,omitempty"` // Кривая EC или OKP
+	X         string `json:"x,omitempty"`   // Публичный ключ OKP или координата X точки EC
+	Y         string `json:"y,omitempty"`   // Координата Y точки EC
+}
+
+// JWKS представляет набор публичных ключей для проверки JWT сторонними сервисами
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -1,7 +1,125 @@
 package domain
 
+import "time"
+
 // Team представляет группу пользователей (команду)
 type Team struct {
-	TeamName string       `json:"team_name"`
-	Members  []TeamMember `json:"members"`
+	TeamName   string       `json:"team_name"`
+	Members    []TeamMember `json:"members"`
+	ArchivedAt *time.Time   `json:"archived_at,omitempty"` // Архивная команда не участвует в выборе ревьюверов
+}
+
+// MemberAction определяет, что происходит с участниками архивируемой или удаляемой команды
+type MemberAction string
+
+// Действия с участниками при архивации и удалении команды
+const (
+	MemberActionKeep       MemberAction = "keep"       // Остаются в архивной команде; при удалении - остаются без команды
+	MemberActionMove       MemberAction = "move"       // Переводятся в другую команду вместе со своими ревью
+	MemberActionDeactivate MemberAction = "deactivate" // Деактивируются и снимаются с открытых ревью; участники других команд только исключаются
+)
+
+// IsValid проверяет, что действие поддерживается
+func (a MemberAction) IsValid() bool {
+	switch a {
+	case MemberActionKeep, MemberActionMove, MemberActionDeactivate:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReviewAssignment представляет назначение пользователя ревьювером PR
+type ReviewAssignment struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+}
+
+// TeamImpact описывает, кого и что затронет архивация или удаление команды
+type TeamImpact struct {
+	TeamName         string              `json:"team_name"`
+	Archived         bool                `json:"archived"`
+	Members          []TeamMember        `json:"members"`
+	OpenPullRequests []*PullRequestShort `json:"open_pull_requests"` // Открытые PR, авторы которых - участники команды
+	OpenReviews      []ReviewAssignment  `json:"open_reviews"`       // Назначения участников на открытые PR
+}
+
+// TeamRemoval описывает изменения участников, которые применяются в одной транзакции с архивацией или удалением команды
+type TeamRemoval struct {
+	TeamName      string
+	MoveTo        string                  // Команда, в которую переводятся участники Moved
+	Moved         []string                // Участники, переводимые в MoveTo вместе со своими ревью
+	Deactivated   []string                // Участники только этой команды, которые деактивируются
+	Detached      []string                // Участники нескольких команд, которые только исключаются из этой
+	Reassignments []*ReviewerReassignment // Замены деактивируемых и исключаемых участников на открытых PR
+	ActorID       string
+}
+
+// ReviewPolicy определяет, что происходит с открытыми ревью пользователя, покидающего команду
+type ReviewPolicy string
+
+// Политики открытых ревью при смене команды
+const (
+	ReviewPolicyKeep     ReviewPolicy = "keep"     // Пользователь остается ревьювером своих открытых PR
+	ReviewPolicyReassign ReviewPolicy = "reassign" // Ревью переназначаются на активных участников старой команды
+)
+
+// IsValid проверяет, что политика поддерживается
+func (p ReviewPolicy) IsValid() bool {
+	switch p {
+	case ReviewPolicyKeep, ReviewPolicyReassign:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReviewerStrategy определяет алгоритм выбора ревьюверов
+type ReviewerStrategy string
+
+// Доступные стратегии выбора ревьюверов
+const (
+	StrategyRandom      ReviewerStrategy = "random"       // Равновероятный случайный выбор
+	StrategyRoundRobin  ReviewerStrategy = "round_robin"  // По кругу внутри команды
+	StrategyLeastLoaded ReviewerStrategy = "least_loaded" // Наименьшее число открытых ревью
+	StrategyWeighted    ReviewerStrategy = "weighted"     // Случайный выбор с учетом веса пользователя
+)
+
+// IsValid проверяет, что стратегия поддерживается
+func (s ReviewerStrategy) IsValid() bool {
+	switch s {
+	case StrategyRandom, StrategyRoundRobin, StrategyLeastLoaded, StrategyWeighted:
+		return true
+	default:
+		return false
+	}
+}
+
+// Лимиты числа ревьюверов на PR
+const (
+	DefaultMinReviewers = 0  // По умолчанию PR может остаться без ревьюверов
+	DefaultMaxReviewers = 2  // По умолчанию назначается до 2 ревьюверов
+	MaxReviewersLimit   = 10 // Верхняя граница max_reviewers для любой команды
+	MaxFallbackTeams    = 10 // Сколько запасных команд может быть у команды
+)
+
+// TeamSettings представляет настройки назначения ревьюверов для команды
+type TeamSettings struct {
+	TeamName         string           `json:"team_name"`
+	ReviewerStrategy ReviewerStrategy `json:"reviewer_strategy"`
+	MinReviewers     int              `json:"min_reviewers"`
+	MaxReviewers     int              `json:"max_reviewers"`
+	// RequiredApprovals - правило merge: nil - выключено, иначе нужно столько одобрений
+	// и ни одного CHANGES_REQUESTED от назначенных ревьюверов
+	RequiredApprovals *int `json:"required_approvals"`
+	// MaxOpenReviews - сколько открытых ревью может быть у участника без собственного лимита; nil - без ограничения
+	MaxOpenReviews *int `json:"max_open_reviews"`
+	// FallbackTeams - запасные команды по порядку, из которых добираются ревьюверы до max_reviewers
+	FallbackTeams []string `json:"fallback_teams"`
+}
+
+// ValidReviewerLimits проверяет, что лимиты ревьюверов допустимы
+func ValidReviewerLimits(minReviewers, maxReviewers int) bool {
+	return minReviewers >= 0 && maxReviewers >= 1 &&
+		minReviewers <= maxReviewers && maxReviewers <= MaxReviewersLimit
 }
@@ -1,11 +1,36 @@
 package domain
 
+import "slices"
+
+// Role определяет права пользователя
+type Role string
+
+// Роли пользователей
+const (
+	RoleAdmin    Role = "admin"     // Создает команды и управляет всеми пользователями и PR
+	RoleTeamLead Role = "team_lead" // Управляет пользователями, настройками и PR команд, где отмечен лидом
+	RoleMember   Role = "member"    // Действует только в PR, где он автор или ревьювер
+)
+
+// IsValid проверяет, что роль поддерживается
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleAdmin, RoleTeamLead, RoleMember:
+		return true
+	default:
+		return false
+	}
+}
+
 // User представляет участника команды
 type User struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	TeamName string `json:"team_name"`
-	IsActive bool   `json:"is_active"`
+	UserID    string   `json:"user_id"`
+	Username  string   `json:"username"`
+	TeamName  string   `json:"team_name"`            // Основная команда; пусто, если пользователь не состоит в командах
+	Teams     []string `json:"teams"`                // Все команды пользователя
+	LeadTeams []string `json:"lead_teams,omitempty"` // Команды, где пользователь отмечен лидом
+	IsActive  bool     `json:"is_active"`
+	Role      Role     `json:"role,omitempty"`
 }
 
 // TeamMember представляет пользователя в составе команды (используется в Team.Members)
@@ -13,4 +38,40 @@ type TeamMember struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	IsActive bool   `json:"is_active"`
+	Role     Role   `json:"role,omitempty"` // Роль в команде: team_lead только у лида этой команды; по умолчанию member
+}
+
+// Principal представляет аутентифицированного пользователя, выполняющего запрос
+type Principal struct {
+	UserID    string
+	TeamName  string   // Основная команда
+	Teams     []string // Все команды пользователя
+	LeadTeams []string // Команды, где пользователь отмечен лидом
+	Role      Role
+	TokenID   int64        // ID API токена; 0 - запрос с JWT токеном
+	Scopes    []TokenScope // Области действия API токена
+}
+
+// HasScope проверяет, что запрос допускает операции в области scope. JWT токен допускает все.
+func (p Principal) HasScope(scope TokenScope) bool {
+	return p.TokenID == 0 || slices.Contains(p.Scopes, scope)
+}
+
+// IsAdmin проверяет, что пользователь - администратор
+func (p Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+// LeadsTeam проверяет, что пользователь - лид указанной команды: роль team_lead дает права
+// только в командах, где он отмечен лидом, а не во всех командах, в которых он состоит
+func (p Principal) LeadsTeam(teamName string) bool {
+	return p.Role == RoleTeamLead && teamName != "" && slices.Contains(p.LeadTeams, teamName)
+}
+
+// Credentials представляет учетные данные пользователя для входа по паролю
+type Credentials struct {
+	UserID         string
+	PasswordHash   string
+	FailedAttempts int
+	Locked         bool // Вход заблокирован после серии неудачных попыток
 }
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// IsWebhookEvent проверяет, что о событии уведомляются подписчики исходящих вебхуков
+func (t PREventType) IsWebhookEvent() bool {
+	switch t {
+	case EventReviewerAssigned, EventReviewerReassigned, EventMerged:
+		return true
+	default:
+		return false
+	}
+}
+
+// WebhookSubscription представляет подписчика исходящих вебхуков
+type WebhookSubscription struct {
+	SubscriptionID int64         `json:"subscription_id"`
+	URL            string        `json:"url"`
+	Secret         string        `json:"-"` // Секрет подписи не возвращается через API
+	EventTypes     []PREventType `json:"event_types"`
+	CreatedAt      time.Time     `json:"createdAt"`
+}
+
+// DeliveryStatus представляет статус доставки вебхука подписчику
+type DeliveryStatus string
+
+// Возможные статусы доставки
+const (
+	DeliveryPending   DeliveryStatus = "PENDING"   // Ожидает отправки или повторной попытки
+	DeliveryDelivered DeliveryStatus = "DELIVERED" // Подписчик ответил 2xx
+	DeliveryFailed    DeliveryStatus = "FAILED"    // Попытки исчерпаны
+)
+
+// WebhookDelivery представляет запись журнала доставки события подписчику
+type WebhookDelivery struct {
+	DeliveryID     int64          `json:"delivery_id"`
+	SubscriptionID int64          `json:"subscription_id"`
+	EventID        int64          `json:"event_id"`
+	EventType      PREventType    `json:"event_type"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	LastStatusCode *int           `json:"last_status_code,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time     `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    *time.Time     `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+}
+
+// PendingDelivery содержит все необходимое для отправки доставки подписчику
+type PendingDelivery struct {
+	DeliveryID int64
+	Attempts   int // Число уже сделанных попыток
+	URL        string
+	Secret     string
+	Event      PREvent
+}
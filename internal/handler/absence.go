@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/middleware"
+	"github.com/aidar/avito-pr-project/internal/service"
+)
+
+// AbsenceHandler обрабатывает эндпоинты периодов отсутствия пользователей
+type AbsenceHandler struct {
+	absenceService *service.AbsenceService
+	accessService  *service.AccessService
+}
+
+// NewAbsenceHandler создает новый AbsenceHandler
+func NewAbsenceHandler(absenceService *service.AbsenceService, accessService *service.AccessService) *AbsenceHandler {
+	return &AbsenceHandler{
+		absenceService: absenceService,
+		accessService:  accessService,
+	}
+}
+
+// AbsenceView представляет период отсутствия в ответах API; даты в формате YYYY-MM-DD
+type AbsenceView struct {
+	AbsenceID           int64      `json:"absence_id"`
+	UserID              string     `json:"user_id"`
+	StartDate           string     `json:"start_date"`
+	EndDate             string     `json:"end_date"`
+	Days                int        `json:"days"`
+	Reason              string     `json:"reason,omitempty"`
+	CreatedBy           string     `json:"created_by,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	ReviewsReassignedAt *time.Time `json:"reviewsReassignedAt,omitempty"`
+}
+
+// newAbsenceView преобразует период отсутствия в представление для API
+func newAbsenceView(a *domain.Absence) *AbsenceView {
+	return &AbsenceView{
+		AbsenceID:           a.AbsenceID,
+		UserID:              a.UserID,
+		StartDate:           a.StartDate.Format(time.DateOnly),
+		EndDate:             a.EndDate.Format(time.DateOnly),
+		Days:                a.Days(),
+		Reason:              a.Reason,
+		CreatedBy:           a.CreatedBy,
+		CreatedAt:           a.CreatedAt,
+		ReviewsReassignedAt: a.ReviewsReassignedAt,
+	}
+}
+
+// AddAbsenceRequest представляет тело запроса на добавление периода отсутствия
+type AddAbsenceRequest struct {
+	UserID    string `json:"user_id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Reason    string `json:"reason"`
+}
+
+// AbsenceResponse представляет ответ с периодом отсутствия
+type AbsenceResponse struct {
+	Absence *AbsenceView `json:"absence"`
+}
+
+// AddAbsence обрабатывает POST /users/absences/add
+func (h *AbsenceHandler) AddAbsence(w http.ResponseWriter, r *http.Request) {
+	var req AddAbsenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.UserID == "" || req.StartDate == "" || req.EndDate == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id, start_date and end_date are required")
+		return
+	}
+
+	startDate, errStart := time.Parse(time.DateOnly, req.StartDate)
+	endDate, errEnd := time.Parse(time.DateOnly, req.EndDate)
+	if errStart != nil || errEnd != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "start_date and end_date must be in YYYY-MM-DD format")
+		return
+	}
+
+	if endDate.Before(startDate) {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "end_date must not be before start_date")
+		return
+	}
+
+	if err := h.accessService.CheckAbsence(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.UserID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	absence, err := h.absenceService.Create(r.Context(), &domain.Absence{
+		UserID:    req.UserID,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Reason:    req.Reason,
+		CreatedBy: middleware.GetUserIDFromContext(r.Context()),
+	})
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusCreated, AbsenceResponse{Absence: newAbsenceView(absence)})
+}
+
+// ListAbsencesResponse представляет ответ со списком периодов отсутствия пользователя
+type ListAbsencesResponse struct {
+	UserID   string         `json:"user_id"`
+	Absences []*AbsenceView `json:"absences"`
+}
+
+// ListAbsences обрабатывает GET /users/absences/list?user_id=...&past=true
+func (h *AbsenceHandler) ListAbsences(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id query parameter is required")
+		return
+	}
+
+	// Необязательный флаг: включить закончившиеся периоды
+	past := false
+	if raw := r.URL.Query().Get("past"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "past must be a boolean")
+			return
+		}
+		past = parsed
+	}
+
+	if err := h.accessService.CheckAbsence(r.Context(), middleware.GetPrincipalFromContext(r.Context()), userID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	absences, err := h.absenceService.List(r.Context(), userID, past)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	views := make([]*AbsenceView, 0, len(absences))
+	for _, absence := range absences {
+		views = append(views, newAbsenceView(absence))
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, ListAbsencesResponse{
+		UserID:   userID,
+		Absences: views,
+	})
+}
+
+// DeleteAbsenceRequest представляет тело запроса на удаление периода отсутствия
+type DeleteAbsenceRequest struct {
+	AbsenceID int64 `json:"absence_id"`
+}
+
+// DeleteAbsenceResponse представляет ответ на удаление периода отсутствия
+type DeleteAbsenceResponse struct {
+	AbsenceID int64 `json:"absence_id"`
+}
+
+// DeleteAbsence обрабатывает POST /users/absences/delete
+func (h *AbsenceHandler) DeleteAbsence(w http.ResponseWriter, r *http.Request) {
+	var req DeleteAbsenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.AbsenceID <= 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "absence_id is required")
+		return
+	}
+
+	absence, err := h.absenceService.Get(r.Context(), req.AbsenceID)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	if err := h.accessService.CheckAbsence(r.Context(), middleware.GetPrincipalFromContext(r.Context()), absence.UserID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	if err := h.absenceService.Delete(r.Context(), req.AbsenceID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, DeleteAbsenceResponse{AbsenceID: req.AbsenceID})
+}
@@ -4,29 +4,50 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/aidar/avito-pr-project/internal/middleware"
 	"github.com/aidar/avito-pr-project/internal/service"
 )
 
+// Ограничения длины пароля; bcrypt учитывает только первые 72 байта
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
 // AuthHandler обрабатывает эндпоинты аутентификации
 type AuthHandler struct {
-	authService *service.AuthService
+	authService   *service.AuthService
+	accessService *service.AccessService
 }
 
 // NewAuthHandler создает новый AuthHandler
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, accessService *service.AccessService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:   authService,
+		accessService: accessService,
 	}
 }
 
 // LoginRequest представляет тело запроса на логин
 type LoginRequest struct {
-	UserID string `json:"user_id"`
+	UserID   string `json:"user_id"`
+	Password string `json:"password"`
 }
 
-// LoginResponse представляет тело ответа на логин
+// LoginResponse представляет тело ответа на логин и обновление токена
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // Время жизни token в секундах
+}
+
+// newLoginResponse собирает ответ из пары токенов
+func newLoginResponse(pair *service.TokenPair) LoginResponse {
+	return LoginResponse{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    int64(pair.ExpiresIn.Seconds()),
+	}
 }
 
 // Login обрабатывает POST /auth/login
@@ -37,16 +58,133 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.UserID == "" {
-		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+	if req.UserID == "" || req.Password == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id and password are required")
+		return
+	}
+
+	pair, err := h.authService.Login(r.Context(), req.UserID, req.Password)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, newLoginResponse(pair))
+}
+
+// RefreshRequest представляет тело запроса на обновление токена
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh обрабатывает POST /auth/refresh
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
 		return
 	}
 
-	token, err := h.authService.Login(r.Context(), req.UserID)
+	if req.RefreshToken == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "refresh_token is required")
+		return
+	}
+
+	pair, err := h.authService.Refresh(r.Context(), req.RefreshToken)
 	if err != nil {
 		HandleError(w, r, err)
 		return
 	}
 
-	RespondWithJSON(w, r, http.StatusOK, LoginResponse{Token: token})
+	RespondWithJSON(w, r, http.StatusOK, newLoginResponse(pair))
+}
+
+// LogoutRequest представляет тело запроса на выход
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"` // Завершить все сессии пользователя
+}
+
+// LogoutResponse представляет ответ на выход
+type LogoutResponse struct {
+	Status string `json:"status"`
+}
+
+// Logout обрабатывает POST /auth/logout
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.RefreshToken == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "refresh_token is required")
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), req.RefreshToken, req.All); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, LogoutResponse{Status: "ok"})
+}
+
+// SetPasswordRequest представляет тело запроса на установку пароля
+type SetPasswordRequest struct {
+	UserID          string `json:"user_id"`
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// SetPasswordResponse представляет ответ на установку пароля
+type SetPasswordResponse struct {
+	UserID string `json:"user_id"`
+}
+
+// SetPassword обрабатывает POST /auth/setPassword
+func (h *AuthHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	var req SetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.UserID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+
+	if len(req.NewPassword) < minPasswordLength || len(req.NewPassword) > maxPasswordLength {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "new_password must be 8 to 72 bytes long")
+		return
+	}
+
+	// Свой пароль пользователь меняет сам; первый пароль другого пользователя задает администратор или его лид
+	p := middleware.GetPrincipalFromContext(r.Context())
+	if req.UserID == p.UserID {
+		if err := h.authService.SetPassword(r.Context(), req.UserID, req.CurrentPassword, req.NewPassword); err != nil {
+			HandleError(w, r, err)
+			return
+		}
+	} else {
+		if err := h.accessService.CheckUser(r.Context(), p, req.UserID); err != nil {
+			HandleError(w, r, err)
+			return
+		}
+		if err := h.authService.SetInitialPassword(r.Context(), req.UserID, req.NewPassword); err != nil {
+			HandleError(w, r, err)
+			return
+		}
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, SetPasswordResponse{UserID: req.UserID})
+}
+
+// JWKS обрабатывает GET /.well-known/jwks.json
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Ключи меняются редко, а неизвестный kid - сигнал клиенту перезапросить набор
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	RespondWithJSON(w, r, http.StatusOK, h.authService.JWKS())
 }
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/middleware"
+	"github.com/aidar/avito-pr-project/internal/service"
+)
+
+// CodeOwnersHandler обрабатывает эндпоинты CODEOWNERS команд и репозиториев
+type CodeOwnersHandler struct {
+	codeOwnersService *service.CodeOwnersService
+	accessService     *service.AccessService
+}
+
+// NewCodeOwnersHandler создает новый CodeOwnersHandler
+func NewCodeOwnersHandler(codeOwnersService *service.CodeOwnersService, accessService *service.AccessService) *CodeOwnersHandler {
+	return &CodeOwnersHandler{
+		codeOwnersService: codeOwnersService,
+		accessService:     accessService,
+	}
+}
+
+// CodeOwnersScope определяет, к чему относится CODEOWNERS: к команде или к репозиторию (ровно одно из полей)
+type CodeOwnersScope struct {
+	TeamName   string `json:"team_name"`
+	Repository string `json:"repository"`
+}
+
+// valid проверяет, что задано ровно одно из полей
+func (s CodeOwnersScope) valid() bool {
+	return (s.TeamName == "") != (s.Repository == "")
+}
+
+// SetCodeOwnersRequest представляет тело запроса на загрузку CODEOWNERS
+type SetCodeOwnersRequest struct {
+	CodeOwnersScope
+	Content string `json:"content"`
+}
+
+// CodeOwnersResponse представляет ответ с CODEOWNERS
+type CodeOwnersResponse struct {
+	CodeOwners *domain.CodeOwners      `json:"code_owners"`
+	Rules      []domain.CodeOwnersRule `json:"rules"`
+}
+
+// newCodeOwnersResponse добавляет к CODEOWNERS разобранные правила
+func newCodeOwnersResponse(codeOwners *domain.CodeOwners) (*CodeOwnersResponse, error) {
+	rules, err := service.ParseCodeOwners(codeOwners.Content)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []domain.CodeOwnersRule{}
+	}
+	return &CodeOwnersResponse{CodeOwners: codeOwners, Rules: rules}, nil
+}
+
+// SetCodeOwners обрабатывает POST /codeowners/set
+func (h *CodeOwnersHandler) SetCodeOwners(w http.ResponseWriter, r *http.Request) {
+	var req SetCodeOwnersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if !req.valid() {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "exactly one of team_name and repository is required")
+		return
+	}
+	if _, err := service.ParseCodeOwners(req.Content); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid CODEOWNERS: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckCodeOwners(middleware.GetPrincipalFromContext(r.Context()), req.TeamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	codeOwners, err := h.codeOwnersService.Set(r.Context(), &domain.CodeOwners{
+		TeamName:   req.TeamName,
+		Repository: req.Repository,
+		Content:    req.Content,
+		UpdatedBy:  middleware.GetUserIDFromContext(r.Context()),
+	})
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	resp, err := newCodeOwnersResponse(codeOwners)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+	RespondWithJSON(w, r, http.StatusOK, resp)
+}
+
+// GetCodeOwners обрабатывает GET /codeowners/get?team_name=... или ?repository=...
+func (h *CodeOwnersHandler) GetCodeOwners(w http.ResponseWriter, r *http.Request) {
+	scope := CodeOwnersScope{
+		TeamName:   r.URL.Query().Get("team_name"),
+		Repository: r.URL.Query().Get("repository"),
+	}
+	if !scope.valid() {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "exactly one of team_name and repository query parameters is required")
+		return
+	}
+
+	codeOwners, err := h.codeOwnersService.Get(r.Context(), scope.TeamName, scope.Repository)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	resp, err := newCodeOwnersResponse(codeOwners)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+	RespondWithJSON(w, r, http.StatusOK, resp)
+}
+
+// DeleteCodeOwners обрабатывает POST /codeowners/delete
+func (h *CodeOwnersHandler) DeleteCodeOwners(w http.ResponseWriter, r *http.Request) {
+	var req CodeOwnersScope
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if !req.valid() {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "exactly one of team_name and repository is required")
+		return
+	}
+
+	if err := h.accessService.CheckCodeOwners(middleware.GetPrincipalFromContext(r.Context()), req.TeamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	if err := h.codeOwnersService.Delete(r.Context(), req.TeamName, req.Repository); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, req)
+}
@@ -35,18 +35,56 @@ func HandleError(w http.ResponseWriter, r *http.Request, err error) {
 	switch {
 	case err == domain.ErrTeamExists:
 		RespondWithError(w, r, http.StatusBadRequest, string(domain.CodeTeamExists), "team already exists")
+	case err == domain.ErrTeamArchived:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeTeamArchived), "team is archived")
+	case err == domain.ErrUserExists:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeUserExists), "user already exists")
+	case err == domain.ErrNotTeamMember:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeNotMember), "user is not a member of the team")
+	case err == domain.ErrAbsenceOverlap:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeAbsenceOverlap), "absence overlaps another absence of the user")
 	case err == domain.ErrPRExists:
 		RespondWithError(w, r, http.StatusConflict, string(domain.CodePRExists), "pull request already exists")
 	case err == domain.ErrPRMerged:
 		RespondWithError(w, r, http.StatusConflict, string(domain.CodePRMerged), "cannot modify merged pull request")
+	case err == domain.ErrInvalidStatus:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeInvalidStatus), "operation is not allowed in current pull request status")
 	case err == domain.ErrNotAssigned:
 		RespondWithError(w, r, http.StatusConflict, string(domain.CodeNotAssigned), "reviewer is not assigned to this PR")
+	case err == domain.ErrAlreadyAssigned:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeAlreadyAssigned), "reviewer is already assigned to this PR")
+	case err == domain.ErrReviewerInactive:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeInvalidReviewer), "reviewer is not active")
+	case err == domain.ErrReviewerUnavailable:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeInvalidReviewer), "reviewer is absent or not in any active team")
+	case err == domain.ErrAuthorReviewer:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeInvalidReviewer), "author cannot review their own pull request")
+	case err == domain.ErrTooManyReviewers:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeReviewerLimit), "pull request already has the maximum number of reviewers")
+	case err == domain.ErrTooFewReviewers:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeReviewerLimit), "pull request would have fewer than the minimum number of reviewers")
+	case err == domain.ErrCandidatesAtCapacity:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeNoCandidate), "all active candidates are at review capacity")
 	case err == domain.ErrNoCandidate:
 		RespondWithError(w, r, http.StatusConflict, string(domain.CodeNoCandidate), "no active replacement candidate in team")
-	case err == domain.ErrUserNotFound, err == domain.ErrTeamNotFound, err == domain.ErrPRNotFound, err == domain.ErrNotFound:
+	case err == domain.ErrNotApproved:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeNotApproved), "pull request does not have required approvals")
+	case err == domain.ErrGitHubLoginTaken:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeLoginTaken), "github login is already linked to another user")
+	case err == domain.ErrEmailTaken:
+		RespondWithError(w, r, http.StatusConflict, string(domain.CodeEmailTaken), "email is already used by another user")
+	case err == domain.ErrUserNotFound, err == domain.ErrTeamNotFound, err == domain.ErrPRNotFound,
+		err == domain.ErrSubscriptionNotFound, err == domain.ErrTokenNotFound, err == domain.ErrAbsenceNotFound,
+		err == domain.ErrCodeOwnersNotFound, err == domain.ErrNotFound:
 		RespondWithError(w, r, http.StatusNotFound, string(domain.CodeNotFound), "resource not found")
 	case err == domain.ErrUnauthorized, err == domain.ErrInvalidToken:
 		RespondWithError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	case err == domain.ErrForbidden:
+		RespondWithError(w, r, http.StatusForbidden, "FORBIDDEN", "access denied")
+	case err == domain.ErrIdentityNotLinked:
+		RespondWithError(w, r, http.StatusForbidden, "IDENTITY_NOT_LINKED", "identity is not linked to any user")
+	case err == domain.ErrAccountLocked:
+		RespondWithError(w, r, http.StatusLocked, "ACCOUNT_LOCKED", "account is temporarily locked")
 	default:
 		RespondWithError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "internal server error")
 	}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/aidar/avito-pr-project/internal/service"
+)
+
+// OIDCHandler обрабатывает вход через OIDC провайдера
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+// NewOIDCHandler создает новый OIDCHandler
+func NewOIDCHandler(oidcService *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+	}
+}
+
+// Login обрабатывает GET /auth/oidc/login: перенаправляет пользователя на страницу входа провайдера
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, err := h.oidcService.AuthCodeURL(r.Context())
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback обрабатывает GET /auth/oidc/callback: завершает вход и выдает пару токенов
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Провайдер сообщает об отказе пользователя или своей ошибке параметром error
+	if providerErr := query.Get("error"); providerErr != "" {
+		RespondWithError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "identity provider error: "+providerErr)
+		return
+	}
+
+	code, state := query.Get("code"), query.Get("state")
+	if code == "" || state == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "code and state are required")
+		return
+	}
+
+	pair, err := h.oidcService.Callback(r.Context(), code, state)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, newLoginResponse(pair))
+}
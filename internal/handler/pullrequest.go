@@ -1,30 +1,40 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/middleware"
 	"github.com/aidar/avito-pr-project/internal/service"
 )
 
 // PullRequestHandler обрабатывает эндпоинты pull request'ов
 type PullRequestHandler struct {
-	prService *service.PullRequestService
+	prService     *service.PullRequestService
+	accessService *service.AccessService
 }
 
 // NewPullRequestHandler создает новый PullRequestHandler
-func NewPullRequestHandler(prService *service.PullRequestService) *PullRequestHandler {
+func NewPullRequestHandler(prService *service.PullRequestService, accessService *service.AccessService) *PullRequestHandler {
 	return &PullRequestHandler{
-		prService: prService,
+		prService:     prService,
+		accessService: accessService,
 	}
 }
 
 // CreatePRRequest представляет тело запроса для создания PR
 type CreatePRRequest struct {
-	PullRequestID   string `json:"pull_request_id"`
-	PullRequestName string `json:"pull_request_name"`
-	AuthorID        string `json:"author_id"`
+	PullRequestID   string   `json:"pull_request_id"`
+	PullRequestName string   `json:"pull_request_name"`
+	AuthorID        string   `json:"author_id"`
+	TeamName        string   `json:"team_name"`     // Команда, из которой назначаются ревьюверы; по умолчанию основная команда автора
+	Repository      string   `json:"repository"`    // Репозиторий, чей CODEOWNERS важнее командного
+	ChangedFiles    []string `json:"changed_files"` // Измененные файлы для назначения владельцев кода
+	Draft           bool     `json:"draft"`
 }
 
 // CreatePRResponse представляет ответ на создание PR
@@ -45,9 +55,30 @@ func (h *PullRequestHandler) CreatePR(w http.ResponseWriter, r *http.Request) {
 		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id, pull_request_name, and author_id are required")
 		return
 	}
+	if len(req.ChangedFiles) > domain.MaxChangedFiles {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", fmt.Sprintf("changed_files must contain at most %d paths", domain.MaxChangedFiles))
+		return
+	}
+	if slices.Contains(req.ChangedFiles, "") {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "changed_files must not contain empty paths")
+		return
+	}
 
-	// Создаем PR (автоматически назначаются ревьюверы)
-	pr, err := h.prService.CreatePR(r.Context(), req.PullRequestID, req.PullRequestName, req.AuthorID)
+	principal := middleware.GetPrincipalFromContext(r.Context())
+	if err := h.accessService.CheckCreatePR(r.Context(), principal, req.AuthorID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	// Создаем PR (ревьюверы назначаются автоматически, если это не черновик)
+	pr, err := h.prService.CreatePR(r.Context(), &domain.PullRequest{
+		PullRequestID:   req.PullRequestID,
+		PullRequestName: req.PullRequestName,
+		AuthorID:        req.AuthorID,
+		TeamName:        req.TeamName,
+		Repository:      req.Repository,
+		ChangedFiles:    req.ChangedFiles,
+	}, req.Draft, middleware.GetUserIDFromContext(r.Context()))
 	if err != nil {
 		HandleError(w, r, err)
 		return
@@ -56,6 +87,54 @@ func (h *PullRequestHandler) CreatePR(w http.ResponseWriter, r *http.Request) {
 	RespondWithJSON(w, r, http.StatusCreated, CreatePRResponse{PR: pr})
 }
 
+// GetPRResponse представляет ответ с информацией о PR
+type GetPRResponse struct {
+	PR *domain.PullRequest `json:"pr"`
+}
+
+// GetPR обрабатывает GET /pullRequest/get?pull_request_id=...
+func (h *PullRequestHandler) GetPR(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id query parameter is required")
+		return
+	}
+
+	pr, err := h.prService.GetByID(r.Context(), prID)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, GetPRResponse{PR: pr})
+}
+
+// GetTimelineResponse представляет ответ с историей событий PR
+type GetTimelineResponse struct {
+	PullRequestID string            `json:"pull_request_id"`
+	Events        []*domain.PREvent `json:"events"`
+}
+
+// GetTimeline обрабатывает GET /pullRequest/timeline?pull_request_id=...
+func (h *PullRequestHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id query parameter is required")
+		return
+	}
+
+	events, err := h.prService.GetTimeline(r.Context(), prID)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, GetTimelineResponse{
+		PullRequestID: prID,
+		Events:        events,
+	})
+}
+
 // MergePRRequest представляет тело запроса для merge PR
 type MergePRRequest struct {
 	PullRequestID string `json:"pull_request_id"`
@@ -79,8 +158,13 @@ func (h *PullRequestHandler) MergePR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.accessService.CheckPR(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.PullRequestID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
 	// Мержим PR (идемпотентная операция)
-	pr, err := h.prService.MergePR(r.Context(), req.PullRequestID)
+	pr, err := h.prService.MergePR(r.Context(), req.PullRequestID, middleware.GetUserIDFromContext(r.Context()))
 	if err != nil {
 		HandleError(w, r, err)
 		return
@@ -89,10 +173,67 @@ func (h *PullRequestHandler) MergePR(w http.ResponseWriter, r *http.Request) {
 	RespondWithJSON(w, r, http.StatusOK, MergePRResponse{PR: pr})
 }
 
+// ChangeStatusRequest представляет тело запроса для смены статуса PR
+type ChangeStatusRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+}
+
+// ChangeStatusResponse представляет ответ на смену статуса PR
+type ChangeStatusResponse struct {
+	PR *domain.PullRequest `json:"pr"`
+}
+
+// MarkReady обрабатывает POST /pullRequest/ready (DRAFT -> OPEN, идемпотентная операция)
+func (h *PullRequestHandler) MarkReady(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.prService.MarkReady)
+}
+
+// ClosePR обрабатывает POST /pullRequest/close (OPEN/DRAFT -> CLOSED, идемпотентная операция)
+func (h *PullRequestHandler) ClosePR(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.prService.ClosePR)
+}
+
+// ReopenPR обрабатывает POST /pullRequest/reopen (CLOSED -> OPEN, идемпотентная операция)
+func (h *PullRequestHandler) ReopenPR(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.prService.ReopenPR)
+}
+
+// changeStatus разбирает запрос смены статуса и вызывает соответствующую операцию сервиса
+func (h *PullRequestHandler) changeStatus(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(ctx context.Context, prID, actorID string) (*domain.PullRequest, error),
+) {
+	var req ChangeStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.PullRequestID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id is required")
+		return
+	}
+
+	if err := h.accessService.CheckPR(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.PullRequestID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	pr, err := change(r.Context(), req.PullRequestID, middleware.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, ChangeStatusResponse{PR: pr})
+}
+
 // ReassignRequest представляет тело запроса для переназначения ревьювера
 type ReassignRequest struct {
 	PullRequestID string `json:"pull_request_id"`
 	OldUserID     string `json:"old_user_id"`
+	NewUserID     string `json:"new_user_id"` // Необязательно: явно выбранный ревьювер вместо выбора стратегией команды
 }
 
 // ReassignResponse представляет ответ на переназначение ревьювера
@@ -114,8 +255,24 @@ func (h *PullRequestHandler) Reassign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	principal := middleware.GetPrincipalFromContext(r.Context())
+	if err := h.accessService.CheckPR(r.Context(), principal, req.PullRequestID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	// Явно выбрать нового ревьювера может только лид команды PR или администратор
+	if req.NewUserID != "" {
+		if err := h.accessService.CheckReviewers(r.Context(), principal, req.PullRequestID); err != nil {
+			HandleError(w, r, err)
+			return
+		}
+	}
+
 	// Переназначаем ревьювера
-	pr, newReviewerID, err := h.prService.ReassignReviewer(r.Context(), req.PullRequestID, req.OldUserID)
+	pr, newReviewerID, err := h.prService.ReassignReviewer(
+		r.Context(), req.PullRequestID, req.OldUserID, req.NewUserID, middleware.GetUserIDFromContext(r.Context()),
+	)
 	if err != nil {
 		HandleError(w, r, err)
 		return
@@ -126,3 +283,102 @@ func (h *PullRequestHandler) Reassign(w http.ResponseWriter, r *http.Request) {
 		ReplacedBy: newReviewerID,
 	})
 }
+
+// ReviewerRequest представляет тело запроса на ручное назначение или снятие ревьювера
+type ReviewerRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+}
+
+// ReviewerResponse представляет ответ на ручное назначение или снятие ревьювера
+type ReviewerResponse struct {
+	PR *domain.PullRequest `json:"pr"`
+}
+
+// AddReviewer обрабатывает POST /pullRequest/addReviewer
+func (h *PullRequestHandler) AddReviewer(w http.ResponseWriter, r *http.Request) {
+	h.changeReviewer(w, r, h.prService.AddReviewer)
+}
+
+// RemoveReviewer обрабатывает POST /pullRequest/removeReviewer
+func (h *PullRequestHandler) RemoveReviewer(w http.ResponseWriter, r *http.Request) {
+	h.changeReviewer(w, r, h.prService.RemoveReviewer)
+}
+
+// changeReviewer разбирает ReviewerRequest, проверяет, что вызывающий — лид команды PR или администратор,
+// и применяет операцию с ревьювером
+func (h *PullRequestHandler) changeReviewer(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(ctx context.Context, prID, reviewerID, actorID string) (*domain.PullRequest, error),
+) {
+	var req ReviewerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.PullRequestID == "" || req.UserID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id and user_id are required")
+		return
+	}
+
+	if err := h.accessService.CheckReviewers(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.PullRequestID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	pr, err := change(r.Context(), req.PullRequestID, req.UserID, middleware.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, ReviewerResponse{PR: pr})
+}
+
+// SubmitReviewRequest представляет тело запроса на отправку решения ревьювера
+type SubmitReviewRequest struct {
+	PullRequestID string               `json:"pull_request_id"`
+	UserID        string               `json:"user_id"`
+	Verdict       domain.ReviewVerdict `json:"verdict"`
+}
+
+// SubmitReviewResponse представляет ответ на отправку решения ревьювера
+type SubmitReviewResponse struct {
+	PR *domain.PullRequest `json:"pr"`
+}
+
+// SubmitReview обрабатывает POST /pullRequest/review
+func (h *PullRequestHandler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	var req SubmitReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.PullRequestID == "" || req.UserID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id and user_id are required")
+		return
+	}
+
+	if !req.Verdict.IsValid() {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST",
+			"verdict must be one of: APPROVED, CHANGES_REQUESTED, COMMENTED")
+		return
+	}
+
+	// Решение отправляет только сам ревьювер
+	if err := h.accessService.CheckReview(middleware.GetPrincipalFromContext(r.Context()), req.UserID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	pr, err := h.prService.SubmitReview(r.Context(), req.PullRequestID, req.UserID, req.Verdict)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, SubmitReviewResponse{PR: pr})
+}
@@ -0,0 +1,897 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/middleware"
+	"github.com/aidar/avito-pr-project/internal/service"
+)
+
+// Схемы и тип содержимого SCIM 2.0 (RFC 7643, RFC 7644)
+const (
+	scimContentType                 = "application/scim+json"
+	scimSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaEnterpriseUser        = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	scimSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// scimMaxResults - наибольший размер страницы списка
+const scimMaxResults = 100
+
+// scimFilterPattern разбирает единственный поддерживаемый вид фильтра: attribute eq "value"
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([a-z0-9_.:]+)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// SCIMHandler обрабатывает эндпоинты SCIM 2.0 для синхронизации пользователей и команд из внешнего каталога.
+// Пользователь SCIM - пользователь сервиса (userName = user_id), группа SCIM - команда (displayName = team_name)
+type SCIMHandler struct {
+	scimService *service.SCIMService
+}
+
+// NewSCIMHandler создает новый SCIMHandler
+func NewSCIMHandler(scimService *service.SCIMService) *SCIMHandler {
+	return &SCIMHandler{
+		scimService: scimService,
+	}
+}
+
+// SCIMUser представляет ресурс User
+type SCIMUser struct {
+	Schemas     []string            `json:"schemas"`
+	ID          string              `json:"id,omitempty"`
+	ExternalID  string              `json:"externalId,omitempty"`
+	UserName    string              `json:"userName"`
+	Name        *SCIMName           `json:"name,omitempty"`
+	DisplayName string              `json:"displayName,omitempty"`
+	Active      *bool               `json:"active,omitempty"`
+	Emails      []SCIMEmail         `json:"emails,omitempty"`
+	Groups      []SCIMReference     `json:"groups,omitempty"`
+	Enterprise  *SCIMEnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta        *SCIMMeta           `json:"meta,omitempty"`
+}
+
+// SCIMName представляет имя пользователя; сервис хранит только полное имя
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMEmail представляет email пользователя
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMEnterpriseUser представляет расширение enterprise; department задает команду пользователя
+type SCIMEnterpriseUser struct {
+	Department string `json:"department,omitempty"`
+}
+
+// SCIMGroup представляет ресурс Group
+type SCIMGroup struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []SCIMReference `json:"members"`
+	Meta        *SCIMMeta       `json:"meta,omitempty"`
+}
+
+// SCIMReference представляет ссылку на пользователя (участник группы) или группу (группы пользователя)
+type SCIMReference struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// SCIMMeta содержит метаданные ресурса
+type SCIMMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+// SCIMListResponse представляет страницу списка ресурсов
+type SCIMListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// SCIMPatchRequest представляет тело запроса PATCH
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation представляет одну операцию PATCH (add, replace или remove)
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// SCIMErrorResponse представляет ошибку в формате SCIM
+type SCIMErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// scimError - ошибка запроса, которая возвращается клиенту SCIM как есть
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimError) Error() string {
+	return e.detail
+}
+
+// newSCIMError создает ошибку запроса SCIM
+func newSCIMError(status int, scimType, detail string) *scimError {
+	return &scimError{status: status, scimType: scimType, detail: detail}
+}
+
+// ServiceProviderConfig обрабатывает GET /scim/v2/ServiceProviderConfig
+func (h *SCIMHandler) ServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	respondSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{scimSchemaServiceProviderConfig},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": map[string]bool{"supported": false},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "API token with the scim:write scope",
+			"primary":     true,
+		}},
+	})
+}
+
+// ListUsers обрабатывает GET /scim/v2/Users (фильтры userName, externalId и emails по eq)
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	startIndex, count, err := parseSCIMPage(r)
+	if err != nil {
+		handleSCIMError(w, err)
+		return
+	}
+
+	filter := domain.DirectoryFilter{Offset: startIndex - 1, Limit: count}
+	if raw := r.URL.Query().Get("filter"); raw != "" {
+		attribute, value, err := parseSCIMFilter(raw)
+		if err != nil {
+			handleSCIMError(w, err)
+			return
+		}
+
+		switch attribute {
+		case "username", "id":
+			filter.UserID = value
+		case "externalid":
+			filter.ExternalID = value
+		case "emails", "emails.value":
+			filter.Email = value
+		default:
+			handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidFilter", "unsupported filter attribute"))
+			return
+		}
+
+		// Пустое значение в фильтре ничему не соответствует, а не снимает отбор
+		if value == "" {
+			respondSCIMList(w, startIndex, 0, []interface{}{})
+			return
+		}
+	}
+
+	users, total, err := h.scimService.ListUsers(r.Context(), filter)
+	if err != nil {
+		handleSCIMError(w, err)
+		return
+	}
+
+	resources := make([]interface{}, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, newSCIMUser(r, user))
+	}
+
+	respondSCIMList(w, startIndex, total, resources)
+}
+
+// GetUser обрабатывает GET /scim/v2/Users/{id}
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.scimService.GetUser(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		handleSCIMError(w, err)
+		return
+	}
+
+	respondSCIM(w, http.StatusOK, newSCIMUser(r, user))
+}
+
+// CreateUser обрабатывает POST /scim/v2/Users
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid request body"))
+		return
+	}
+
+	if req.UserName == "" {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidValue", "userName is required"))
+		return
+	}
+
+	user, err := h.scimService.CreateUser(r.Context(), directoryUserFromSCIM(req.UserName, &req))
+	if err != nil {
+		handleSCIMError(w, userTeamError(err))
+		return
+	}
+
+	resource := newSCIMUser(r, user)
+	w.Header().Set("Location", resource.Meta.Location)
+	respondSCIM(w, http.StatusCreated, resource)
+}
+
+// ReplaceUser обрабатывает PUT /scim/v2/Users/{id}
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	var req SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid request body"))
+		return
+	}
+
+	if req.UserName != "" && req.UserName != userID {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "mutability", "userName cannot be changed"))
+		return
+	}
+
+	user, err := h.scimService.ReplaceUser(r.Context(), directoryUserFromSCIM(userID, &req), scimActorID(r))
+	if err != nil {
+		handleSCIMError(w, userTeamError(err))
+		return
+	}
+
+	respondSCIM(w, http.StatusOK, newSCIMUser(r, user))
+}
+
+// PatchUser обрабатывает PATCH /scim/v2/Users/{id}. Деактивация (active = false) отзывает сессии
+// пользователя и переназначает его открытые ревью
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	var req SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Operations) == 0 {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid patch request"))
+		return
+	}
+
+	user, err := h.scimService.GetUser(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		handleSCIMError(w, err)
+		return
+	}
+
+	for _, op := range req.Operations {
+		if err := applyUserPatch(user, op); err != nil {
+			handleSCIMError(w, err)
+			return
+		}
+	}
+
+	user, err = h.scimService.ReplaceUser(r.Context(), user, scimActorID(r))
+	if err != nil {
+		handleSCIMError(w, userTeamError(err))
+		return
+	}
+
+	respondSCIM(w, http.StatusOK, newSCIMUser(r, user))
+}
+
+// DeleteUser обрабатывает DELETE /scim/v2/Users/{id}. Пользователь не удаляется (на него ссылаются PR),
+// а деактивируется с переназначением открытых ревью
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.scimService.DeprovisionUser(r.Context(), chi.URLParam(r, "id"), scimActorID(r)); err != nil {
+		handleSCIMError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListGroups обрабатывает GET /scim/v2/Groups (фильтр displayName по eq)
+func (h *SCIMHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	startIndex, count, err := parseSCIMPage(r)
+	if err != nil {
+		handleSCIMError(w, err)
+		return
+	}
+
+	teamName := ""
+	if raw := r.URL.Query().Get("filter"); raw != "" {
+		attribute, value, err := parseSCIMFilter(raw)
+		if err != nil {
+			handleSCIMError(w, err)
+			return
+		}
+		if attribute != "displayname" && attribute != "id" {
+			handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidFilter", "unsupported filter attribute"))
+			return
+		}
+		if value == "" {
+			respondSCIMList(w, startIndex, 0, []interface{}{})
+			return
+		}
+		teamName = value
+	}
+
+	teams, total, err := h.scimService.ListGroups(r.Context(), teamName, startIndex-1, count)
+	if err != nil {
+		handleSCIMError(w, err)
+		return
+	}
+
+	resources := make([]interface{}, 0, len(teams))
+	for _, team := range teams {
+		resources = append(resources, newSCIMGroup(r, team))
+	}
+
+	respondSCIMList(w, startIndex, total, resources)
+}
+
+// GetGroup обрабатывает GET /scim/v2/Groups/{id}
+func (h *SCIMHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	team, err := h.scimService.GetGroup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		handleSCIMError(w, err)
+		return
+	}
+
+	respondSCIM(w, http.StatusOK, newSCIMGroup(r, team))
+}
+
+// CreateGroup обрабатывает POST /scim/v2/Groups
+func (h *SCIMHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req SCIMGroup
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid request body"))
+		return
+	}
+
+	if req.DisplayName == "" {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidValue", "displayName is required"))
+		return
+	}
+
+	team, err := h.scimService.CreateGroup(r.Context(), req.DisplayName, referenceValues(req.Members))
+	if err != nil {
+		handleSCIMError(w, memberError(err))
+		return
+	}
+
+	resource := newSCIMGroup(r, team)
+	w.Header().Set("Location", resource.Meta.Location)
+	respondSCIM(w, http.StatusCreated, resource)
+}
+
+// ReplaceGroup обрабатывает PUT /scim/v2/Groups/{id}: заменяет состав команды
+func (h *SCIMHandler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	teamName := chi.URLParam(r, "id")
+
+	var req SCIMGroup
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid request body"))
+		return
+	}
+
+	if req.DisplayName != "" && req.DisplayName != teamName {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "mutability", "displayName cannot be changed"))
+		return
+	}
+
+	if err := h.scimService.ReplaceGroupMembers(r.Context(), teamName, referenceValues(req.Members)); err != nil {
+		handleSCIMError(w, memberError(err))
+		return
+	}
+
+	h.GetGroup(w, r)
+}
+
+// PatchGroup обрабатывает PATCH /scim/v2/Groups/{id}: добавляет, заменяет и исключает участников.
+// Пользователь состоит ровно в одной команде, поэтому добавление переводит его из прежней команды,
+// а исключение - в команду по умолчанию (SCIM_DEFAULT_TEAM)
+func (h *SCIMHandler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	teamName := chi.URLParam(r, "id")
+
+	var req SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Operations) == 0 {
+		handleSCIMError(w, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid patch request"))
+		return
+	}
+
+	if _, err := h.scimService.GetGroup(r.Context(), teamName); err != nil {
+		handleSCIMError(w, err)
+		return
+	}
+
+	for _, op := range req.Operations {
+		if err := h.applyGroupPatch(r, teamName, op); err != nil {
+			handleSCIMError(w, memberError(err))
+			return
+		}
+	}
+
+	h.GetGroup(w, r)
+}
+
+// DeleteGroup обрабатывает DELETE /scim/v2/Groups/{id}; команды через SCIM не удаляются
+func (h *SCIMHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	handleSCIMError(w, newSCIMError(http.StatusNotImplemented, "", "groups cannot be deleted"))
+}
+
+// applyGroupPatch применяет одну операцию PATCH к составу команды
+func (h *SCIMHandler) applyGroupPatch(r *http.Request, teamName string, op SCIMPatchOperation) error {
+	ctx := r.Context()
+	operation := strings.ToLower(op.Op)
+	path := strings.TrimSpace(op.Path)
+
+	// Без path значение - объект с изменяемыми атрибутами
+	if path == "" {
+		if operation == "remove" {
+			return newSCIMError(http.StatusBadRequest, "noTarget", "remove requires a path")
+		}
+
+		var value struct {
+			DisplayName string          `json:"displayName"`
+			Members     []SCIMReference `json:"members"`
+		}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", "invalid operation value")
+		}
+		if value.DisplayName != "" && value.DisplayName != teamName {
+			return newSCIMError(http.StatusBadRequest, "mutability", "displayName cannot be changed")
+		}
+		if value.Members == nil {
+			return nil
+		}
+		if operation == "replace" {
+			return h.scimService.ReplaceGroupMembers(ctx, teamName, referenceValues(value.Members))
+		}
+		return h.scimService.AddGroupMembers(ctx, teamName, referenceValues(value.Members))
+	}
+
+	lowerPath := strings.ToLower(path)
+	switch {
+	case lowerPath == "displayname":
+		var displayName string
+		if err := json.Unmarshal(op.Value, &displayName); err != nil || displayName != teamName {
+			return newSCIMError(http.StatusBadRequest, "mutability", "displayName cannot be changed")
+		}
+		return nil
+
+	case lowerPath == "members":
+		var members []SCIMReference
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &members); err != nil {
+				return newSCIMError(http.StatusBadRequest, "invalidValue", "members must be a list")
+			}
+		}
+
+		switch operation {
+		case "add":
+			return h.scimService.AddGroupMembers(ctx, teamName, referenceValues(members))
+		case "replace":
+			return h.scimService.ReplaceGroupMembers(ctx, teamName, referenceValues(members))
+		case "remove":
+			// remove без значения исключает всех участников
+			if len(op.Value) == 0 {
+				return h.scimService.ReplaceGroupMembers(ctx, teamName, nil)
+			}
+			return h.scimService.RemoveGroupMembers(ctx, teamName, referenceValues(members))
+		}
+
+	case strings.HasPrefix(lowerPath, "members[") && strings.HasSuffix(lowerPath, "]") && operation == "remove":
+		// members[value eq "user-id"]
+		attribute, value, err := parseSCIMFilter(path[len("members[") : len(path)-1])
+		if err != nil || attribute != "value" {
+			return newSCIMError(http.StatusBadRequest, "invalidPath", "unsupported members filter")
+		}
+		return h.scimService.RemoveGroupMembers(ctx, teamName, []string{value})
+
+	default:
+		return newSCIMError(http.StatusBadRequest, "invalidPath", fmt.Sprintf("unsupported path %q", path))
+	}
+
+	return newSCIMError(http.StatusBadRequest, "invalidSyntax", fmt.Sprintf("unsupported operation %q", op.Op))
+}
+
+// applyUserPatch применяет одну операцию PATCH к пользователю. Атрибуты, которые сервис не хранит
+// (например, name.givenName или title), пропускаются, чтобы не прерывать синхронизацию каталога
+func applyUserPatch(user *domain.DirectoryUser, op SCIMPatchOperation) error {
+	operation := strings.ToLower(op.Op)
+	if operation != "add" && operation != "replace" && operation != "remove" {
+		return newSCIMError(http.StatusBadRequest, "invalidSyntax", fmt.Sprintf("unsupported operation %q", op.Op))
+	}
+
+	if op.Path == "" {
+		if operation == "remove" {
+			return newSCIMError(http.StatusBadRequest, "noTarget", "remove requires a path")
+		}
+
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attributes); err != nil {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", "invalid operation value")
+		}
+		for name, value := range attributes {
+			if err := setUserAttribute(user, name, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if operation == "remove" {
+		return removeUserAttribute(user, op.Path)
+	}
+	return setUserAttribute(user, op.Path, op.Value)
+}
+
+// setUserAttribute задает атрибут пользователя по пути SCIM
+func setUserAttribute(user *domain.DirectoryUser, path string, value json.RawMessage) error {
+	invalid := newSCIMError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("invalid value for %q", path))
+	attribute := strings.ToLower(strings.TrimSpace(path))
+
+	switch {
+	case attribute == "active":
+		active, ok := parseSCIMBool(value)
+		if !ok {
+			return invalid
+		}
+		user.IsActive = active
+
+	case attribute == "displayname", attribute == "name.formatted":
+		var name string
+		if err := json.Unmarshal(value, &name); err != nil {
+			return invalid
+		}
+		if name != "" {
+			user.Username = name
+		}
+
+	case attribute == "name":
+		var name SCIMName
+		if err := json.Unmarshal(value, &name); err != nil {
+			return invalid
+		}
+		if formatted := scimFormattedName(&name); formatted != "" {
+			user.Username = formatted
+		}
+
+	case attribute == "externalid":
+		if err := json.Unmarshal(value, &user.ExternalID); err != nil {
+			return invalid
+		}
+
+	case attribute == "username":
+		var userName string
+		if err := json.Unmarshal(value, &userName); err != nil || userName != user.UserID {
+			return newSCIMError(http.StatusBadRequest, "mutability", "userName cannot be changed")
+		}
+
+	case attribute == "emails":
+		var emails []SCIMEmail
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return invalid
+		}
+		user.Email = primaryEmail(emails)
+
+	case strings.HasPrefix(attribute, "emails") && strings.HasSuffix(attribute, ".value"):
+		// emails.value или emails[type eq "work"].value
+		if err := json.Unmarshal(value, &user.Email); err != nil {
+			return invalid
+		}
+
+	case attribute == strings.ToLower(scimSchemaEnterpriseUser):
+		var enterprise SCIMEnterpriseUser
+		if err := json.Unmarshal(value, &enterprise); err != nil {
+			return invalid
+		}
+		if enterprise.Department != "" {
+			user.TeamName = enterprise.Department
+		}
+
+	case attribute == strings.ToLower(scimSchemaEnterpriseUser+":department"):
+		if err := json.Unmarshal(value, &user.TeamName); err != nil {
+			return invalid
+		}
+	}
+
+	return nil
+}
+
+// removeUserAttribute очищает атрибут пользователя по пути SCIM
+func removeUserAttribute(user *domain.DirectoryUser, path string) error {
+	attribute := strings.ToLower(strings.TrimSpace(path))
+
+	switch {
+	case attribute == "externalid":
+		user.ExternalID = ""
+	case strings.HasPrefix(attribute, "emails"):
+		user.Email = ""
+	case attribute == "username", attribute == "active":
+		return newSCIMError(http.StatusBadRequest, "mutability", fmt.Sprintf("%q cannot be removed", path))
+	}
+
+	return nil
+}
+
+// directoryUserFromSCIM собирает пользователя каталога из ресурса SCIM
+func directoryUserFromSCIM(userID string, req *SCIMUser) *domain.DirectoryUser {
+	user := &domain.DirectoryUser{
+		User: domain.User{
+			UserID:   userID,
+			Username: req.DisplayName,
+			IsActive: req.Active == nil || *req.Active,
+		},
+		Email:      primaryEmail(req.Emails),
+		ExternalID: req.ExternalID,
+	}
+
+	if user.Username == "" {
+		user.Username = scimFormattedName(req.Name)
+	}
+	if user.Username == "" {
+		user.Username = userID
+	}
+
+	if req.Enterprise != nil {
+		user.TeamName = req.Enterprise.Department
+	}
+
+	return user
+}
+
+// newSCIMUser собирает ресурс User из пользователя каталога
+func newSCIMUser(r *http.Request, user *domain.DirectoryUser) SCIMUser {
+	active := user.IsActive
+	resource := SCIMUser{
+		Schemas:     []string{scimSchemaUser, scimSchemaEnterpriseUser},
+		ID:          user.UserID,
+		ExternalID:  user.ExternalID,
+		UserName:    user.UserID,
+		Name:        &SCIMName{Formatted: user.Username},
+		DisplayName: user.Username,
+		Active:      &active,
+		Enterprise:  &SCIMEnterpriseUser{Department: user.TeamName},
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Location:     scimLocation(r, "Users", user.UserID),
+		},
+	}
+
+	// Группы - все команды пользователя; department - основная команда
+	for _, teamName := range user.Teams {
+		resource.Groups = append(resource.Groups, SCIMReference{
+			Value:   teamName,
+			Display: teamName,
+			Ref:     scimLocation(r, "Groups", teamName),
+		})
+	}
+
+	if user.Email != "" {
+		resource.Emails = []SCIMEmail{{Value: user.Email, Type: "work", Primary: true}}
+	}
+
+	return resource
+}
+
+// newSCIMGroup собирает ресурс Group из команды
+func newSCIMGroup(r *http.Request, team *domain.Team) SCIMGroup {
+	members := make([]SCIMReference, 0, len(team.Members))
+	for _, member := range team.Members {
+		members = append(members, SCIMReference{
+			Value:   member.UserID,
+			Display: member.Username,
+			Ref:     scimLocation(r, "Users", member.UserID),
+		})
+	}
+
+	return SCIMGroup{
+		Schemas:     []string{scimSchemaGroup},
+		ID:          team.TeamName,
+		DisplayName: team.TeamName,
+		Members:     members,
+		Meta: &SCIMMeta{
+			ResourceType: "Group",
+			Location:     scimLocation(r, "Groups", team.TeamName),
+		},
+	}
+}
+
+// scimFormattedName возвращает полное имя: formatted или имя и фамилию
+func scimFormattedName(name *SCIMName) string {
+	if name == nil {
+		return ""
+	}
+	if name.Formatted != "" {
+		return name.Formatted
+	}
+	return strings.TrimSpace(name.GivenName + " " + name.FamilyName)
+}
+
+// primaryEmail возвращает основной email, а без него - первый из списка
+func primaryEmail(emails []SCIMEmail) string {
+	for _, email := range emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+// parseSCIMBool разбирает логическое значение; некоторые клиенты передают его строкой ("False")
+func parseSCIMBool(value json.RawMessage) (bool, bool) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, true
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(strings.ToLower(s)); err == nil {
+			return b, true
+		}
+	}
+
+	return false, false
+}
+
+// referenceValues возвращает ID из списка ссылок
+func referenceValues(references []SCIMReference) []string {
+	values := make([]string, 0, len(references))
+	for _, reference := range references {
+		values = append(values, reference.Value)
+	}
+	return values
+}
+
+// parseSCIMFilter разбирает фильтр вида attribute eq "value"; имя атрибута приводится к нижнему регистру
+func parseSCIMFilter(filter string) (string, string, error) {
+	match := scimFilterPattern.FindStringSubmatch(filter)
+	if match == nil {
+		return "", "", newSCIMError(http.StatusBadRequest, "invalidFilter", `only filters of the form attribute eq "value" are supported`)
+	}
+
+	value, err := strconv.Unquote(match[2])
+	if err != nil {
+		return "", "", newSCIMError(http.StatusBadRequest, "invalidFilter", "invalid filter value")
+	}
+
+	return strings.ToLower(match[1]), value, nil
+}
+
+// parseSCIMPage разбирает параметры страницы startIndex (с 1) и count
+func parseSCIMPage(r *http.Request) (int, int, error) {
+	startIndex, count := 1, scimMaxResults
+
+	if raw := r.URL.Query().Get("startIndex"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, 0, newSCIMError(http.StatusBadRequest, "invalidValue", "startIndex must be an integer")
+		}
+		startIndex = max(value, 1)
+	}
+
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, 0, newSCIMError(http.StatusBadRequest, "invalidValue", "count must be an integer")
+		}
+		count = min(max(value, 0), scimMaxResults)
+	}
+
+	return startIndex, count, nil
+}
+
+// scimLocation возвращает URL ресурса
+func scimLocation(r *http.Request, resourceType, id string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/scim/v2/" + resourceType + "/" + url.PathEscape(id)
+}
+
+// scimActorID возвращает ID пользователя, от имени которого выполняется синхронизация (пустой для сервисного токена)
+func scimActorID(r *http.Request) string {
+	return middleware.GetPrincipalFromContext(r.Context()).UserID
+}
+
+// userTeamError сообщает о несуществующей команде пользователя как об ошибке значения, а не о ненайденном ресурсе
+func userTeamError(err error) error {
+	if err == domain.ErrTeamNotFound {
+		return newSCIMError(http.StatusBadRequest, "invalidValue", "team (enterprise department) does not exist")
+	}
+	return err
+}
+
+// memberError сообщает о несуществующем участнике группы как об ошибке значения, а не о ненайденном ресурсе
+func memberError(err error) error {
+	if err == domain.ErrUserNotFound {
+		return newSCIMError(http.StatusBadRequest, "invalidValue", "member does not exist")
+	}
+	return err
+}
+
+// respondSCIM отправляет ответ SCIM
+func respondSCIM(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// respondSCIMList отправляет страницу списка ресурсов
+func respondSCIMList(w http.ResponseWriter, startIndex, total int, resources []interface{}) {
+	respondSCIM(w, http.StatusOK, SCIMListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// handleSCIMError преобразует ошибки в ответы SCIM
+func handleSCIMError(w http.ResponseWriter, err error) {
+	var scimErr *scimError
+	switch {
+	case errors.As(err, &scimErr):
+		respondSCIMError(w, scimErr.status, scimErr.scimType, scimErr.detail)
+	case err == domain.ErrUserNotFound:
+		respondSCIMError(w, http.StatusNotFound, "", "user not found")
+	case err == domain.ErrTeamNotFound:
+		respondSCIMError(w, http.StatusNotFound, "", "group not found")
+	case err == domain.ErrUserExists:
+		respondSCIMError(w, http.StatusConflict, "uniqueness", "user already exists")
+	case err == domain.ErrTeamExists:
+		respondSCIMError(w, http.StatusConflict, "uniqueness", "group already exists")
+	case err == domain.ErrEmailTaken:
+		respondSCIMError(w, http.StatusConflict, "uniqueness", "email is already used by another user")
+	case err == domain.ErrTeamRequired:
+		respondSCIMError(w, http.StatusBadRequest, "invalidValue", "user must belong to a team; set enterprise department or SCIM_DEFAULT_TEAM")
+	default:
+		respondSCIMError(w, http.StatusInternalServerError, "", "internal server error")
+	}
+}
+
+// respondSCIMError отправляет ошибку в формате SCIM
+func respondSCIMError(w http.ResponseWriter, statusCode int, scimType, detail string) {
+	respondSCIM(w, statusCode, SCIMErrorResponse{
+		Schemas:  []string{scimSchemaError},
+		Status:   strconv.Itoa(statusCode),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
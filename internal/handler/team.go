@@ -2,21 +2,26 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/middleware"
 	"github.com/aidar/avito-pr-project/internal/service"
 )
 
 // TeamHandler обрабатывает эндпоинты команд
 type TeamHandler struct {
-	teamService *service.TeamService
+	teamService   *service.TeamService
+	accessService *service.AccessService
 }
 
 // NewTeamHandler создает новый TeamHandler
-func NewTeamHandler(teamService *service.TeamService) *TeamHandler {
+func NewTeamHandler(teamService *service.TeamService, accessService *service.AccessService) *TeamHandler {
 	return &TeamHandler{
-		teamService: teamService,
+		teamService:   teamService,
+		accessService: accessService,
 	}
 }
 
@@ -39,6 +44,13 @@ func (h *TeamHandler) AddTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for _, member := range team.Members {
+		if member.Role != "" && !member.Role.IsValid() {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "role must be one of: admin, team_lead, member")
+			return
+		}
+	}
+
 	// Создаем команду
 	createdTeam, err := h.teamService.AddTeam(r.Context(), &team)
 	if err != nil {
@@ -65,3 +77,548 @@ func (h *TeamHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
 
 	RespondWithJSON(w, r, http.StatusOK, team)
 }
+
+// DeactivateUsersRequest представляет тело запроса на массовую деактивацию
+type DeactivateUsersRequest struct {
+	TeamName string   `json:"team_name"`
+	UserIDs  []string `json:"user_ids"` // Если пусто - деактивируется вся команда
+}
+
+// DeactivateUsersResponse представляет ответ на массовую деактивацию
+type DeactivateUsersResponse struct {
+	TeamName         string                         `json:"team_name"`
+	DeactivatedUsers []string                       `json:"deactivated_users"`
+	Reassignments    []*domain.ReviewerReassignment `json:"reassignments"`
+}
+
+// DeactivateUsers обрабатывает POST /team/deactivateUsers
+func (h *TeamHandler) DeactivateUsers(w http.ResponseWriter, r *http.Request) {
+	var req DeactivateUsersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return
+	}
+
+	if err := h.accessService.CheckTeam(middleware.GetPrincipalFromContext(r.Context()), req.TeamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	// Деактивируем пользователей и переназначаем их открытые ревью
+	deactivated, reassignments, err := h.teamService.DeactivateUsers(
+		r.Context(), req.TeamName, req.UserIDs, middleware.GetUserIDFromContext(r.Context()),
+	)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, DeactivateUsersResponse{
+		TeamName:         req.TeamName,
+		DeactivatedUsers: deactivated,
+		Reassignments:    reassignments,
+	})
+}
+
+// AddMembersRequest представляет тело запроса на добавление участников в команду
+type AddMembersRequest struct {
+	TeamName string              `json:"team_name"`
+	Members  []domain.TeamMember `json:"members"`
+}
+
+// AddMembers обрабатывает POST /team/addMembers
+func (h *TeamHandler) AddMembers(w http.ResponseWriter, r *http.Request) {
+	var req AddMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" || len(req.Members) == 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name and members are required")
+		return
+	}
+
+	for _, member := range req.Members {
+		if member.UserID == "" {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id is required for every member")
+			return
+		}
+		if member.Role != "" && !member.Role.IsValid() {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "role must be one of: admin, team_lead, member")
+			return
+		}
+	}
+
+	principal := middleware.GetPrincipalFromContext(r.Context())
+	if err := h.accessService.CheckAddMembers(r.Context(), principal, req.TeamName, req.Members); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	team, err := h.teamService.AddMembers(r.Context(), req.TeamName, req.Members)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, AddTeamResponse{Team: team})
+}
+
+// RemoveMembersRequest представляет тело запроса на удаление участников из команды
+type RemoveMembersRequest struct {
+	TeamName     string              `json:"team_name"`
+	UserIDs      []string            `json:"user_ids"`
+	ReviewPolicy domain.ReviewPolicy `json:"review_policy"` // По умолчанию reassign
+}
+
+// RemoveMembersResponse представляет ответ на удаление участников из команды
+type RemoveMembersResponse struct {
+	TeamName      string                         `json:"team_name"`
+	RemovedUsers  []string                       `json:"removed_users"`
+	Reassignments []*domain.ReviewerReassignment `json:"reassignments"`
+}
+
+// RemoveMembers обрабатывает POST /team/removeMembers
+func (h *TeamHandler) RemoveMembers(w http.ResponseWriter, r *http.Request) {
+	var req RemoveMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" || len(req.UserIDs) == 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name and user_ids are required")
+		return
+	}
+
+	policy, ok := parseReviewPolicy(w, r, req.ReviewPolicy)
+	if !ok {
+		return
+	}
+
+	if err := h.accessService.CheckTeam(middleware.GetPrincipalFromContext(r.Context()), req.TeamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	// Исключаем пользователей из команды и, по политике, переназначаем их открытые ревью
+	removed, reassignments, err := h.teamService.RemoveMembers(
+		r.Context(), req.TeamName, req.UserIDs, policy, middleware.GetUserIDFromContext(r.Context()),
+	)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, RemoveMembersResponse{
+		TeamName:      req.TeamName,
+		RemovedUsers:  removed,
+		Reassignments: reassignments,
+	})
+}
+
+// MoveMemberRequest представляет тело запроса на перевод пользователя в другую команду
+type MoveMemberRequest struct {
+	UserID       string              `json:"user_id"`
+	FromTeam     string              `json:"from_team"` // По умолчанию основная команда пользователя
+	TeamName     string              `json:"team_name"`
+	ReviewPolicy domain.ReviewPolicy `json:"review_policy"` // По умолчанию reassign
+}
+
+// MoveMemberResponse представляет ответ на перевод пользователя в другую команду
+type MoveMemberResponse struct {
+	User          *domain.User                   `json:"user"`
+	FromTeam      string                         `json:"from_team"`
+	Reassignments []*domain.ReviewerReassignment `json:"reassignments"`
+}
+
+// MoveMember обрабатывает POST /team/moveMember
+func (h *TeamHandler) MoveMember(w http.ResponseWriter, r *http.Request) {
+	var req MoveMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.UserID == "" || req.TeamName == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id and team_name are required")
+		return
+	}
+
+	policy, ok := parseReviewPolicy(w, r, req.ReviewPolicy)
+	if !ok {
+		return
+	}
+
+	// Переводить может тот, кто управляет пользователем, его прежней командой и командой назначения
+	if err := h.accessService.CheckMoveMember(
+		r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.UserID, req.FromTeam, req.TeamName,
+	); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	user, fromTeam, reassignments, err := h.teamService.MoveMember(
+		r.Context(), req.UserID, req.FromTeam, req.TeamName, policy, middleware.GetUserIDFromContext(r.Context()),
+	)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, MoveMemberResponse{
+		User:          user,
+		FromTeam:      fromTeam,
+		Reassignments: reassignments,
+	})
+}
+
+// PreviewRemoval обрабатывает GET /team/removalPreview?team_name=...
+func (h *TeamHandler) PreviewRemoval(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name query parameter is required")
+		return
+	}
+
+	if err := h.accessService.CheckTeam(middleware.GetPrincipalFromContext(r.Context()), teamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	impact, err := h.teamService.PreviewRemoval(r.Context(), teamName)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, impact)
+}
+
+// RemoveTeamRequest представляет тело запроса на архивацию или удаление команды
+type RemoveTeamRequest struct {
+	TeamName   string              `json:"team_name"`
+	Members    domain.MemberAction `json:"members"`     // По умолчанию keep
+	TargetTeam string              `json:"target_team"` // Обязательна для members = move
+}
+
+// RemoveTeamResponse представляет ответ на архивацию или удаление команды
+type RemoveTeamResponse struct {
+	Members    domain.MemberAction `json:"members"`
+	TargetTeam string              `json:"target_team,omitempty"`
+	Impact     *domain.TeamImpact  `json:"impact"` // Состояние команды до операции
+}
+
+// ArchiveTeam обрабатывает POST /team/archive (только для администраторов)
+func (h *TeamHandler) ArchiveTeam(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRemoveTeamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	impact, err := h.teamService.Archive(
+		r.Context(), req.TeamName, req.Members, req.TargetTeam, middleware.GetUserIDFromContext(r.Context()),
+	)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, RemoveTeamResponse{
+		Members:    req.Members,
+		TargetTeam: req.TargetTeam,
+		Impact:     impact,
+	})
+}
+
+// DeleteTeam обрабатывает POST /team/delete (только для администраторов)
+func (h *TeamHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRemoveTeamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	impact, err := h.teamService.Delete(
+		r.Context(), req.TeamName, req.Members, req.TargetTeam, middleware.GetUserIDFromContext(r.Context()),
+	)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, RemoveTeamResponse{
+		Members:    req.Members,
+		TargetTeam: req.TargetTeam,
+		Impact:     impact,
+	})
+}
+
+// decodeRemoveTeamRequest разбирает и проверяет запрос на архивацию или удаление команды; при ошибке отвечает 400
+func decodeRemoveTeamRequest(w http.ResponseWriter, r *http.Request) (*RemoveTeamRequest, bool) {
+	var req RemoveTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return nil, false
+	}
+
+	if req.TeamName == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return nil, false
+	}
+
+	if req.Members == "" {
+		req.Members = domain.MemberActionKeep
+	}
+	if !req.Members.IsValid() {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "members must be one of: keep, move, deactivate")
+		return nil, false
+	}
+
+	if req.Members == domain.MemberActionMove {
+		if req.TargetTeam == "" || req.TargetTeam == req.TeamName {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "target_team must name another team when members = move")
+			return nil, false
+		}
+	} else {
+		req.TargetTeam = ""
+	}
+
+	return &req, true
+}
+
+// parseReviewPolicy проверяет политику открытых ревью (пустая - reassign); при ошибке отвечает 400
+func parseReviewPolicy(w http.ResponseWriter, r *http.Request, policy domain.ReviewPolicy) (domain.ReviewPolicy, bool) {
+	if policy == "" {
+		return domain.ReviewPolicyReassign, true
+	}
+	if !policy.IsValid() {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "review_policy must be one of: keep, reassign")
+		return "", false
+	}
+	return policy, true
+}
+
+// GetSettings обрабатывает GET /team/getSettings?team_name=...
+func (h *TeamHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name query parameter is required")
+		return
+	}
+
+	settings, err := h.teamService.GetSettings(r.Context(), teamName)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, settings)
+}
+
+// SetReviewerStrategyRequest представляет тело запроса на смену стратегии выбора ревьюверов
+type SetReviewerStrategyRequest struct {
+	TeamName string                  `json:"team_name"`
+	Strategy domain.ReviewerStrategy `json:"reviewer_strategy"`
+}
+
+// SetReviewerStrategy обрабатывает POST /team/setReviewerStrategy
+func (h *TeamHandler) SetReviewerStrategy(w http.ResponseWriter, r *http.Request) {
+	var req SetReviewerStrategyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return
+	}
+
+	if !req.Strategy.IsValid() {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST",
+			"reviewer_strategy must be one of: random, round_robin, least_loaded, weighted")
+		return
+	}
+
+	if err := h.accessService.CheckTeam(middleware.GetPrincipalFromContext(r.Context()), req.TeamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	settings, err := h.teamService.SetReviewerStrategy(r.Context(), req.TeamName, req.Strategy)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, settings)
+}
+
+// SetReviewerLimitsRequest представляет тело запроса на изменение лимитов ревьюверов
+type SetReviewerLimitsRequest struct {
+	TeamName     string `json:"team_name"`
+	MinReviewers *int   `json:"min_reviewers"`
+	MaxReviewers *int   `json:"max_reviewers"`
+}
+
+// SetReviewerLimits обрабатывает POST /team/setReviewerLimits
+func (h *TeamHandler) SetReviewerLimits(w http.ResponseWriter, r *http.Request) {
+	var req SetReviewerLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" || req.MinReviewers == nil || req.MaxReviewers == nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name, min_reviewers, and max_reviewers are required")
+		return
+	}
+
+	if !domain.ValidReviewerLimits(*req.MinReviewers, *req.MaxReviewers) {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST",
+			fmt.Sprintf("limits must satisfy 0 <= min_reviewers <= max_reviewers, 1 <= max_reviewers <= %d", domain.MaxReviewersLimit))
+		return
+	}
+
+	if err := h.accessService.CheckTeam(middleware.GetPrincipalFromContext(r.Context()), req.TeamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	settings, err := h.teamService.SetReviewerLimits(r.Context(), req.TeamName, *req.MinReviewers, *req.MaxReviewers)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, settings)
+}
+
+// SetMergeRuleRequest представляет тело запроса на изменение правила merge
+type SetMergeRuleRequest struct {
+	TeamName          string `json:"team_name"`
+	RequiredApprovals *int   `json:"required_approvals"` // null - правило выключено
+}
+
+// SetMergeRule обрабатывает POST /team/setMergeRule
+func (h *TeamHandler) SetMergeRule(w http.ResponseWriter, r *http.Request) {
+	var req SetMergeRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return
+	}
+
+	if req.RequiredApprovals != nil && (*req.RequiredApprovals < 0 || *req.RequiredApprovals > domain.MaxReviewersLimit) {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST",
+			fmt.Sprintf("required_approvals must be between 0 and %d or null", domain.MaxReviewersLimit))
+		return
+	}
+
+	if err := h.accessService.CheckTeam(middleware.GetPrincipalFromContext(r.Context()), req.TeamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	settings, err := h.teamService.SetMergeRule(r.Context(), req.TeamName, req.RequiredApprovals)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, settings)
+}
+
+// SetTeamMaxOpenReviewsRequest представляет тело запроса на изменение лимита открытых ревью участника команды
+type SetTeamMaxOpenReviewsRequest struct {
+	TeamName       string `json:"team_name"`
+	MaxOpenReviews *int   `json:"max_open_reviews"` // null - без ограничения
+}
+
+// SetMaxOpenReviews обрабатывает POST /team/setMaxOpenReviews
+func (h *TeamHandler) SetMaxOpenReviews(w http.ResponseWriter, r *http.Request) {
+	var req SetTeamMaxOpenReviewsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return
+	}
+
+	if req.MaxOpenReviews != nil && *req.MaxOpenReviews < 1 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "max_open_reviews must be positive or null")
+		return
+	}
+
+	if err := h.accessService.CheckTeam(middleware.GetPrincipalFromContext(r.Context()), req.TeamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	settings, err := h.teamService.SetMaxOpenReviews(r.Context(), req.TeamName, req.MaxOpenReviews)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, settings)
+}
+
+// SetFallbackTeamsRequest представляет тело запроса на изменение запасных команд
+type SetFallbackTeamsRequest struct {
+	TeamName      string   `json:"team_name"`
+	FallbackTeams []string `json:"fallback_teams"` // По порядку обращения; пустой список отключает запасные команды
+}
+
+// SetFallbackTeams обрабатывает POST /team/setFallbackTeams
+func (h *TeamHandler) SetFallbackTeams(w http.ResponseWriter, r *http.Request) {
+	var req SetFallbackTeamsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" || req.FallbackTeams == nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "team_name and fallback_teams are required")
+		return
+	}
+
+	if len(req.FallbackTeams) > domain.MaxFallbackTeams {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST",
+			fmt.Sprintf("fallback_teams must contain at most %d teams", domain.MaxFallbackTeams))
+		return
+	}
+	for i, fallback := range req.FallbackTeams {
+		if fallback == "" || fallback == req.TeamName || slices.Contains(req.FallbackTeams[:i], fallback) {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST",
+				"fallback_teams must be distinct non-empty names of other teams")
+			return
+		}
+	}
+
+	if err := h.accessService.CheckTeam(middleware.GetPrincipalFromContext(r.Context()), req.TeamName); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	settings, err := h.teamService.SetFallbackTeams(r.Context(), req.TeamName, req.FallbackTeams)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, settings)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/middleware"
+	"github.com/aidar/avito-pr-project/internal/service"
+)
+
+// TokenHandler обрабатывает эндпоинты API токенов
+type TokenHandler struct {
+	tokenService *service.APITokenService
+}
+
+// NewTokenHandler создает новый TokenHandler
+func NewTokenHandler(tokenService *service.APITokenService) *TokenHandler {
+	return &TokenHandler{
+		tokenService: tokenService,
+	}
+}
+
+// CreateTokenRequest представляет тело запроса на создание API токена
+type CreateTokenRequest struct {
+	Name          string              `json:"name"`
+	Kind          domain.APITokenKind `json:"kind"` // По умолчанию personal
+	Scopes        []domain.TokenScope `json:"scopes"`
+	ExpiresInDays *int                `json:"expires_in_days"` // null - бессрочный токен
+}
+
+// CreateTokenResponse представляет ответ на создание API токена; сам токен показывается только один раз
+type CreateTokenResponse struct {
+	Token    string           `json:"token"`
+	APIToken *domain.APIToken `json:"api_token"`
+}
+
+// CreateToken обрабатывает POST /tokens/create
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.Name == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "name is required")
+		return
+	}
+
+	if req.Kind == "" {
+		req.Kind = domain.TokenPersonal
+	}
+	if !req.Kind.IsValid() {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "kind must be one of: personal, service")
+		return
+	}
+
+	if len(req.Scopes) == 0 || slices.ContainsFunc(req.Scopes, func(s domain.TokenScope) bool { return !s.IsValid() }) {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST",
+			"scopes must be a non-empty list of: pr:write, team:read, stats:read, scim:write")
+		return
+	}
+	slices.Sort(req.Scopes)
+	req.Scopes = slices.Compact(req.Scopes)
+
+	var ttl time.Duration
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays < 1 {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "expires_in_days must be positive or null")
+			return
+		}
+		ttl = time.Duration(*req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	token, plaintext, err := h.tokenService.Create(
+		r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.Name, req.Kind, req.Scopes, ttl,
+	)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusCreated, CreateTokenResponse{Token: plaintext, APIToken: token})
+}
+
+// ListTokensResponse представляет список API токенов
+type ListTokensResponse struct {
+	Tokens []*domain.APIToken `json:"tokens"`
+}
+
+// ListTokens обрабатывает GET /tokens/list (администратор видит все токены, остальные - свои)
+func (h *TokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.tokenService.List(r.Context(), middleware.GetPrincipalFromContext(r.Context()))
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, ListTokensResponse{Tokens: tokens})
+}
+
+// RevokeTokenRequest представляет тело запроса на отзыв API токена
+type RevokeTokenRequest struct {
+	TokenID int64 `json:"token_id"`
+}
+
+// RevokeTokenResponse представляет ответ на отзыв API токена
+type RevokeTokenResponse struct {
+	APIToken *domain.APIToken `json:"api_token"`
+}
+
+// RevokeToken обрабатывает POST /tokens/revoke (идемпотентная операция)
+func (h *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var req RevokeTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.TokenID == 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "token_id is required")
+		return
+	}
+
+	token, err := h.tokenService.Revoke(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.TokenID)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, RevokeTokenResponse{APIToken: token})
+}
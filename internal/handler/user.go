@@ -3,22 +3,31 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"net/mail"
+	"strconv"
 
 	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/middleware"
 	"github.com/aidar/avito-pr-project/internal/service"
 )
 
 // UserHandler обрабатывает эндпоинты пользователей
 type UserHandler struct {
-	userService *service.UserService
-	prService   *service.PullRequestService
+	userService   *service.UserService
+	prService     *service.PullRequestService
+	accessService *service.AccessService
 }
 
 // NewUserHandler создает новый UserHandler
-func NewUserHandler(userService *service.UserService, prService *service.PullRequestService) *UserHandler {
+func NewUserHandler(
+	userService *service.UserService,
+	prService *service.PullRequestService,
+	accessService *service.AccessService,
+) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		prService:   prService,
+		userService:   userService,
+		prService:     prService,
+		accessService: accessService,
 	}
 }
 
@@ -46,7 +55,14 @@ func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.SetIsActive(r.Context(), req.UserID, req.IsActive)
+	if err := h.accessService.CheckUser(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.UserID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	user, err := h.userService.SetIsActive(
+		r.Context(), req.UserID, req.IsActive, middleware.GetUserIDFromContext(r.Context()),
+	)
 	if err != nil {
 		HandleError(w, r, err)
 		return
@@ -55,13 +71,269 @@ func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 	RespondWithJSON(w, r, http.StatusOK, SetIsActiveResponse{User: user})
 }
 
+// SetRoleRequest представляет тело запроса на смену роли пользователя
+type SetRoleRequest struct {
+	UserID string      `json:"user_id"`
+	Role   domain.Role `json:"role"`
+}
+
+// SetRoleResponse представляет ответ на смену роли пользователя
+type SetRoleResponse struct {
+	User *domain.User `json:"user"`
+}
+
+// SetRole обрабатывает POST /users/setRole (только для администраторов)
+func (h *UserHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.UserID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+
+	if !req.Role.IsValid() {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "role must be one of: admin, team_lead, member")
+		return
+	}
+
+	user, err := h.userService.SetRole(r.Context(), req.UserID, req.Role)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, SetRoleResponse{User: user})
+}
+
+// SetPrimaryTeamRequest представляет тело запроса на смену основной команды пользователя
+type SetPrimaryTeamRequest struct {
+	UserID   string `json:"user_id"`
+	TeamName string `json:"team_name"`
+}
+
+// SetPrimaryTeamResponse представляет ответ на смену основной команды пользователя
+type SetPrimaryTeamResponse struct {
+	User *domain.User `json:"user"`
+}
+
+// SetPrimaryTeam обрабатывает POST /users/setPrimaryTeam
+func (h *UserHandler) SetPrimaryTeam(w http.ResponseWriter, r *http.Request) {
+	var req SetPrimaryTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.UserID == "" || req.TeamName == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id and team_name are required")
+		return
+	}
+
+	if err := h.accessService.CheckUser(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.UserID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	user, err := h.userService.SetPrimaryTeam(r.Context(), req.UserID, req.TeamName)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, SetPrimaryTeamResponse{User: user})
+}
+
+// SetReviewWeightRequest представляет тело запроса для установки веса ревьювера
+type SetReviewWeightRequest struct {
+	UserID string `json:"user_id"`
+	Weight *int   `json:"review_weight"`
+}
+
+// SetReviewWeightResponse представляет ответ на установку веса ревьювера
+type SetReviewWeightResponse struct {
+	UserID string `json:"user_id"`
+	Weight int    `json:"review_weight"`
+}
+
+// SetReviewWeight обрабатывает POST /users/setReviewWeight
+func (h *UserHandler) SetReviewWeight(w http.ResponseWriter, r *http.Request) {
+	var req SetReviewWeightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.UserID == "" || req.Weight == nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id and review_weight are required")
+		return
+	}
+
+	if *req.Weight < 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "review_weight must not be negative")
+		return
+	}
+
+	if err := h.accessService.CheckUser(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.UserID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	if err := h.userService.SetReviewWeight(r.Context(), req.UserID, *req.Weight); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, SetReviewWeightResponse{
+		UserID: req.UserID,
+		Weight: *req.Weight,
+	})
+}
+
+// SetUserMaxOpenReviewsRequest представляет тело запроса на изменение лимита открытых ревью пользователя
+type SetUserMaxOpenReviewsRequest struct {
+	UserID         string `json:"user_id"`
+	MaxOpenReviews *int   `json:"max_open_reviews"` // null - лимит команды
+}
+
+// SetUserMaxOpenReviewsResponse представляет ответ на изменение лимита открытых ревью пользователя
+type SetUserMaxOpenReviewsResponse struct {
+	UserID         string `json:"user_id"`
+	MaxOpenReviews *int   `json:"max_open_reviews"`
+}
+
+// SetMaxOpenReviews обрабатывает POST /users/setMaxOpenReviews
+func (h *UserHandler) SetMaxOpenReviews(w http.ResponseWriter, r *http.Request) {
+	var req SetUserMaxOpenReviewsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.UserID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+
+	if req.MaxOpenReviews != nil && *req.MaxOpenReviews < 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "max_open_reviews must not be negative")
+		return
+	}
+
+	if err := h.accessService.CheckUser(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.UserID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	if err := h.userService.SetMaxOpenReviews(r.Context(), req.UserID, req.MaxOpenReviews); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, SetUserMaxOpenReviewsResponse{
+		UserID:         req.UserID,
+		MaxOpenReviews: req.MaxOpenReviews,
+	})
+}
+
+// SetGitHubLoginRequest представляет тело запроса для привязки логина GitHub
+type SetGitHubLoginRequest struct {
+	UserID      string `json:"user_id"`
+	GitHubLogin string `json:"github_login"`
+}
+
+// SetGitHubLoginResponse представляет ответ на привязку логина GitHub
+type SetGitHubLoginResponse struct {
+	UserID      string `json:"user_id"`
+	GitHubLogin string `json:"github_login"`
+}
+
+// SetGitHubLogin обрабатывает POST /users/setGithubLogin
+func (h *UserHandler) SetGitHubLogin(w http.ResponseWriter, r *http.Request) {
+	var req SetGitHubLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.UserID == "" || req.GitHubLogin == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id and github_login are required")
+		return
+	}
+
+	if err := h.accessService.CheckUser(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.UserID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	if err := h.userService.SetGitHubLogin(r.Context(), req.UserID, req.GitHubLogin); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, SetGitHubLoginResponse{
+		UserID:      req.UserID,
+		GitHubLogin: req.GitHubLogin,
+	})
+}
+
+// SetEmailRequest представляет тело запроса для задания email
+type SetEmailRequest struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// SetEmailResponse представляет ответ на задание email
+type SetEmailResponse struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// SetEmail обрабатывает POST /users/setEmail
+func (h *UserHandler) SetEmail(w http.ResponseWriter, r *http.Request) {
+	var req SetEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.UserID == "" || req.Email == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user_id and email are required")
+		return
+	}
+
+	// Принимается только голый адрес, без имени и угловых скобок
+	if address, err := mail.ParseAddress(req.Email); err != nil || address.Address != req.Email {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "email is invalid")
+		return
+	}
+
+	if err := h.accessService.CheckUser(r.Context(), middleware.GetPrincipalFromContext(r.Context()), req.UserID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	if err := h.userService.SetEmail(r.Context(), req.UserID, req.Email); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, SetEmailResponse{
+		UserID: req.UserID,
+		Email:  req.Email,
+	})
+}
+
 // GetReviewResponse представляет ответ со списком PR пользователя
 type GetReviewResponse struct {
 	UserID       string                     `json:"user_id"`
 	PullRequests []*domain.PullRequestShort `json:"pull_requests"`
 }
 
-// GetReview обрабатывает GET /users/getReview?user_id=...
+// GetReview обрабатывает GET /users/getReview?user_id=...&exclude_approved=true
 func (h *UserHandler) GetReview(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
@@ -69,7 +341,18 @@ func (h *UserHandler) GetReview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prs, err := h.prService.GetPRsByReviewer(r.Context(), userID)
+	// Необязательный фильтр: скрыть PR, которые пользователь уже одобрил
+	excludeApproved := false
+	if raw := r.URL.Query().Get("exclude_approved"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "exclude_approved must be a boolean")
+			return
+		}
+		excludeApproved = parsed
+	}
+
+	prs, err := h.prService.GetPRsByReviewer(r.Context(), userID, excludeApproved)
 	if err != nil {
 		HandleError(w, r, err)
 		return
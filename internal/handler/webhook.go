@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/service"
+)
+
+// maxWebhookPayloadSize ограничивает размер тела вебхука (GitHub отправляет не более 25 МБ)
+const maxWebhookPayloadSize = 25 << 20
+
+// maxDeliveriesLimit ограничивает число записей журнала доставок в одном ответе
+const maxDeliveriesLimit = 100
+
+// WebhookHandler обрабатывает входящие вебхуки внешних систем и подписки на исходящие вебхуки
+type WebhookHandler struct {
+	githubService  *service.GitHubService
+	webhookService *service.WebhookService
+}
+
+// NewWebhookHandler создает новый WebhookHandler
+func NewWebhookHandler(githubService *service.GitHubService, webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		githubService:  githubService,
+		webhookService: webhookService,
+	}
+}
+
+// WebhookResponse представляет ответ на доставку вебхука
+type WebhookResponse struct {
+	Status string `json:"status"`
+}
+
+// GitHub обрабатывает POST /webhooks/github
+func (h *WebhookHandler) GitHub(w http.ResponseWriter, r *http.Request) {
+	// Подпись считается по исходному телу, поэтому читаем его целиком до разбора
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if err := h.githubService.VerifySignature(payload, r.Header.Get("X-Hub-Signature-256")); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	switch r.Header.Get("X-GitHub-Event") {
+	case "ping":
+		RespondWithJSON(w, r, http.StatusOK, WebhookResponse{Status: "pong"})
+		return
+	case "pull_request":
+	default:
+		RespondWithJSON(w, r, http.StatusOK, WebhookResponse{Status: string(service.WebhookIgnored)})
+		return
+	}
+
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
+	if deliveryID == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "X-GitHub-Delivery header is required")
+		return
+	}
+
+	var event service.GitHubPullRequestEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid pull_request payload")
+		return
+	}
+
+	result, err := h.githubService.HandlePullRequestEvent(r.Context(), deliveryID, &event)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, WebhookResponse{Status: string(result)})
+}
+
+// SubscribeRequest представляет тело запроса на подписку на исходящие вебхуки
+type SubscribeRequest struct {
+	URL        string               `json:"url"`
+	Secret     string               `json:"secret"`
+	EventTypes []domain.PREventType `json:"event_types"`
+}
+
+// SubscriptionResponse представляет ответ с подпиской
+type SubscriptionResponse struct {
+	Subscription *domain.WebhookSubscription `json:"subscription"`
+}
+
+// Subscribe обрабатывает POST /webhooks/subscriptions/add
+func (h *WebhookHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	var req SubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.URL == "" || req.Secret == "" || len(req.EventTypes) == 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "url, secret and event_types are required")
+		return
+	}
+
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "url must be an absolute http(s) URL")
+		return
+	}
+
+	for _, eventType := range req.EventTypes {
+		if !eventType.IsWebhookEvent() {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST",
+				fmt.Sprintf("unsupported event type %q, expected one of: reviewer_assigned, reviewer_reassigned, merged", eventType))
+			return
+		}
+	}
+
+	sub, err := h.webhookService.Subscribe(r.Context(), req.URL, req.Secret, req.EventTypes)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusCreated, SubscriptionResponse{Subscription: sub})
+}
+
+// ListSubscriptionsResponse представляет ответ со списком подписок
+type ListSubscriptionsResponse struct {
+	Subscriptions []*domain.WebhookSubscription `json:"subscriptions"`
+}
+
+// ListSubscriptions обрабатывает GET /webhooks/subscriptions/list
+func (h *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, ListSubscriptionsResponse{Subscriptions: subs})
+}
+
+// UnsubscribeRequest представляет тело запроса на удаление подписки
+type UnsubscribeRequest struct {
+	SubscriptionID int64 `json:"subscription_id"`
+}
+
+// UnsubscribeResponse представляет ответ на удаление подписки
+type UnsubscribeResponse struct {
+	SubscriptionID int64 `json:"subscription_id"`
+}
+
+// Unsubscribe обрабатывает POST /webhooks/subscriptions/delete
+func (h *WebhookHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	var req UnsubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+
+	if req.SubscriptionID <= 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "subscription_id is required")
+		return
+	}
+
+	if err := h.webhookService.Unsubscribe(r.Context(), req.SubscriptionID); err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, UnsubscribeResponse{SubscriptionID: req.SubscriptionID})
+}
+
+// GetDeliveriesResponse представляет ответ с журналом доставок подписки
+type GetDeliveriesResponse struct {
+	SubscriptionID int64                     `json:"subscription_id"`
+	Deliveries     []*domain.WebhookDelivery `json:"deliveries"`
+}
+
+// GetDeliveries обрабатывает GET /webhooks/subscriptions/deliveries?subscription_id=...&limit=...
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	subscriptionID, err := strconv.ParseInt(r.URL.Query().Get("subscription_id"), 10, 64)
+	if err != nil || subscriptionID <= 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST", "subscription_id query parameter is required")
+		return
+	}
+
+	limit := maxDeliveriesLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxDeliveriesLimit {
+			RespondWithError(w, r, http.StatusBadRequest, "BAD_REQUEST",
+				fmt.Sprintf("limit must be between 1 and %d", maxDeliveriesLimit))
+			return
+		}
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(r.Context(), subscriptionID, limit)
+	if err != nil {
+		HandleError(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, r, http.StatusOK, GetDeliveriesResponse{
+		SubscriptionID: subscriptionID,
+		Deliveries:     deliveries,
+	})
+}
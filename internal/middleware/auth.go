@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/aidar/avito-pr-project/internal/domain"
 	"github.com/aidar/avito-pr-project/internal/service"
 )
 
@@ -16,10 +17,20 @@ const (
 	UserIDKey ContextKey = "user_id"
 	// TeamNameKey ключ контекста для названия команды
 	TeamNameKey ContextKey = "team_name"
+	// TeamsKey ключ контекста для всех команд пользователя
+	TeamsKey ContextKey = "teams"
+	// LeadTeamsKey ключ контекста для команд, где пользователь - лид
+	LeadTeamsKey ContextKey = "lead_teams"
+	// RoleKey ключ контекста для роли пользователя
+	RoleKey ContextKey = "role"
+	// TokenIDKey ключ контекста для ID API токена
+	TokenIDKey ContextKey = "token_id"
+	// ScopesKey ключ контекста для областей действия API токена
+	ScopesKey ContextKey = "scopes"
 )
 
-// AuthMiddleware создает middleware для валидации JWT токенов
-func AuthMiddleware(authService *service.AuthService) func(http.Handler) http.Handler {
+// AuthMiddleware создает middleware для валидации JWT и API токенов
+func AuthMiddleware(authService *service.AuthService, tokenService *service.APITokenService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Получаем токен из заголовка Authorization
@@ -38,16 +49,41 @@ func AuthMiddleware(authService *service.AuthService) func(http.Handler) http.Ha
 
 			token := parts[1]
 
-			// Валидируем токен
-			claims, err := authService.ValidateToken(token)
+			// API токены отличаются от JWT префиксом
+			if service.IsAPIToken(token) {
+				principal, err := tokenService.Authenticate(r.Context(), token)
+				if err == domain.ErrInvalidToken {
+					http.Error(w, `{"error":{"code":"UNAUTHORIZED","message":"invalid, expired or revoked api token"}}`, http.StatusUnauthorized)
+					return
+				}
+				if err != nil {
+					http.Error(w, `{"error":{"code":"INTERNAL_ERROR","message":"internal server error"}}`, http.StatusInternalServerError)
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+				return
+			}
+
+			// Валидируем токен и проверяем, что его сессия не отозвана
+			claims, err := authService.ValidateToken(r.Context(), token)
+			if err == domain.ErrInvalidToken {
+				http.Error(w, `{"error":{"code":"UNAUTHORIZED","message":"invalid, expired or revoked token"}}`, http.StatusUnauthorized)
+				return
+			}
 			if err != nil {
-				http.Error(w, `{"error":{"code":"UNAUTHORIZED","message":"invalid or expired token"}}`, http.StatusUnauthorized)
+				http.Error(w, `{"error":{"code":"INTERNAL_ERROR","message":"internal server error"}}`, http.StatusInternalServerError)
 				return
 			}
 
 			// Добавляем claims в контекст
-			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-			ctx = context.WithValue(ctx, TeamNameKey, claims.TeamName)
+			ctx := withPrincipal(r.Context(), &domain.Principal{
+				UserID:    claims.UserID,
+				TeamName:  claims.TeamName,
+				Teams:     claims.Teams,
+				LeadTeams: claims.LeadTeams,
+				Role:      claims.Role,
+			})
 
 			// Вызываем следующий обработчик
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -55,6 +91,20 @@ func AuthMiddleware(authService *service.AuthService) func(http.Handler) http.Ha
 	}
 }
 
+// withPrincipal добавляет данные аутентифицированного пользователя в контекст
+func withPrincipal(ctx context.Context, p *domain.Principal) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, p.UserID)
+	ctx = context.WithValue(ctx, TeamNameKey, p.TeamName)
+	ctx = context.WithValue(ctx, TeamsKey, p.Teams)
+	ctx = context.WithValue(ctx, LeadTeamsKey, p.LeadTeams)
+	ctx = context.WithValue(ctx, RoleKey, p.Role)
+	if p.TokenID != 0 {
+		ctx = context.WithValue(ctx, TokenIDKey, p.TokenID)
+		ctx = context.WithValue(ctx, ScopesKey, p.Scopes)
+	}
+	return ctx
+}
+
 // GetUserIDFromContext извлекает ID пользователя из контекста
 func GetUserIDFromContext(ctx context.Context) string {
 	userID, ok := ctx.Value(UserIDKey).(string)
@@ -72,3 +122,46 @@ func GetTeamNameFromContext(ctx context.Context) string {
 	}
 	return teamName
 }
+
+// GetTeamsFromContext извлекает все команды пользователя из контекста
+func GetTeamsFromContext(ctx context.Context) []string {
+	teams, ok := ctx.Value(TeamsKey).([]string)
+	if !ok {
+		return nil
+	}
+	return teams
+}
+
+// GetLeadTeamsFromContext извлекает команды, где пользователь - лид, из контекста
+func GetLeadTeamsFromContext(ctx context.Context) []string {
+	teams, ok := ctx.Value(LeadTeamsKey).([]string)
+	if !ok {
+		return nil
+	}
+	return teams
+}
+
+// GetRoleFromContext извлекает роль пользователя из контекста
+func GetRoleFromContext(ctx context.Context) domain.Role {
+	role, ok := ctx.Value(RoleKey).(domain.Role)
+	if !ok {
+		return ""
+	}
+	return role
+}
+
+// GetPrincipalFromContext возвращает пользователя, выполняющего запрос; пустой, если запрос без токена
+func GetPrincipalFromContext(ctx context.Context) domain.Principal {
+	tokenID, _ := ctx.Value(TokenIDKey).(int64)
+	scopes, _ := ctx.Value(ScopesKey).([]domain.TokenScope)
+
+	return domain.Principal{
+		UserID:    GetUserIDFromContext(ctx),
+		TeamName:  GetTeamNameFromContext(ctx),
+		Teams:     GetTeamsFromContext(ctx),
+		LeadTeams: GetLeadTeamsFromContext(ctx),
+		Role:      GetRoleFromContext(ctx),
+		TokenID:   tokenID,
+		Scopes:    scopes,
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// RequireRole создает middleware, пропускающий только пользователей с одной из указанных ролей.
+// Должен подключаться после AuthMiddleware.
+func RequireRole(roles ...domain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(roles, GetRoleFromContext(r.Context())) {
+				http.Error(w, `{"error":{"code":"FORBIDDEN","message":"access denied"}}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope создает middleware, пропускающий API токены только с областью действия scope.
+// Запросы с JWT токеном пропускаются. Должен подключаться после AuthMiddleware.
+func RequireScope(scope domain.TokenScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !GetPrincipalFromContext(r.Context()).HasScope(scope) {
+				http.Error(w, `{"error":{"code":"FORBIDDEN","message":"api token scope does not allow this operation"}}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DenyAPITokens создает middleware, пропускающий только запросы с JWT токеном.
+// Используется для управления командами, пользователями и токенами, которые не входят ни в одну область действия.
+func DenyAPITokens() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetPrincipalFromContext(r.Context()).TokenID != 0 {
+				http.Error(w, `{"error":{"code":"FORBIDDEN","message":"operation is not available with api tokens"}}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
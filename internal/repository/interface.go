@@ -2,26 +2,216 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/aidar/avito-pr-project/internal/domain"
 )
 
 // UserRepository определяет методы для работы с данными пользователей
 type UserRepository interface {
-	// CreateOrUpdate создает нового пользователя или обновляет существующего
+	// CreateOrUpdate создает нового пользователя или обновляет существующего и добавляет его в команду user.TeamName
+	// (основной она становится, только если основной команды не было); с ролью team_lead - лидом этой команды
 	CreateOrUpdate(ctx context.Context, user *domain.User) error
 
 	// GetByID получает пользователя по ID
 	GetByID(ctx context.Context, userID string) (*domain.User, error)
 
-	// SetIsActive обновляет статус активности пользователя
-	SetIsActive(ctx context.Context, userID string, isActive bool) error
+	// SetIsActive обновляет статус активности пользователя и отмечает изменение в истории его открытых ревью;
+	// при деактивации отзывает сессии пользователя
+	SetIsActive(ctx context.Context, userID string, isActive bool, actorID string) error
 
 	// GetActiveTeamMembers возвращает всех активных пользователей команды, исключая указанного
+	// и отсутствующих сегодня (пустой список для архивной команды)
 	GetActiveTeamMembers(ctx context.Context, teamName, excludeUserID string) ([]*domain.User, error)
 
 	// GetTeamMembers возвращает всех пользователей команды
 	GetTeamMembers(ctx context.Context, teamName string) ([]*domain.User, error)
+
+	// DeactivateWithReassignments в одной транзакции деактивирует пользователей, отзывает их сессии
+	// и применяет замены ревьюверов на открытых PR, записывая их в историю PR. Замены на PR, которые уже
+	// не открыты, и замены уже снятых ревьюверов пропускаются; возвращаются примененные замены
+	DeactivateWithReassignments(
+		ctx context.Context,
+		userIDs []string,
+		reassignments []*domain.ReviewerReassignment,
+		actorID string,
+	) ([]*domain.ReviewerReassignment, error)
+
+	// ChangeMembership в одной транзакции исключает пользователей из команды fromTeam и добавляет в команду toTeam
+	// (пустое название - без исключения или без добавления), применяет замены ревьюверов на открытых PR
+	// и записывает их в историю PR; ErrUserNotFound, если кто-то из них не состоит в fromTeam.
+	// Устаревшие замены пропускаются, как в DeactivateWithReassignments; возвращаются примененные замены
+	ChangeMembership(
+		ctx context.Context,
+		userIDs []string,
+		fromTeam, toTeam string,
+		reassignments []*domain.ReviewerReassignment,
+		actorID string,
+	) ([]*domain.ReviewerReassignment, error)
+
+	// AddTeamMembers в одной транзакции создает новых пользователей в команде и добавляет в нее
+	// существующих пользователей, не меняя их остальные команды; пользователи с ролью team_lead становятся лидами команды
+	AddTeamMembers(ctx context.Context, teamName string, users []*domain.User) error
+
+	// RemoveTeamMembers в одной транзакции исключает пользователей из команды (не состоящие в ней пропускаются);
+	// исключенные из последней команды переходят в defaultTeam, а если ее нет - ErrTeamRequired
+	RemoveTeamMembers(ctx context.Context, teamName string, userIDs []string, defaultTeam string) error
+
+	// ReplaceTeamMembers в одной транзакции делает пользователей единственными участниками команды; остальные
+	// участники исключаются из нее так же, как в RemoveTeamMembers
+	ReplaceTeamMembers(ctx context.Context, teamName string, userIDs []string, defaultTeam string) error
+
+	// SetReviewWeight обновляет вес пользователя для взвешенного выбора ревьюверов
+	SetReviewWeight(ctx context.Context, userID string, weight int) error
+
+	// GetReviewWeights возвращает веса указанных пользователей
+	GetReviewWeights(ctx context.Context, userIDs []string) (map[string]int, error)
+
+	// IsAvailable проверяет, что пользователь может получить ревью: активен, не отсутствует сегодня
+	// и состоит хотя бы в одной неархивной команде
+	IsAvailable(ctx context.Context, userID string) (bool, error)
+
+	// GetAvailableByHandles возвращает активных и не отсутствующих сегодня пользователей, у которых ID
+	// или логин GitHub (без учета регистра) совпадает с одним из handles; ключ результата - handle
+	GetAvailableByHandles(ctx context.Context, handles []string) (map[string]*domain.User, error)
+
+	// SetMaxOpenReviews задает собственный лимит открытых ревью пользователя (nil - лимит команды)
+	SetMaxOpenReviews(ctx context.Context, userID string, limit *int) error
+
+	// GetMaxOpenReviews возвращает собственные лимиты открытых ревью указанных пользователей;
+	// пользователи без собственного лимита в результат не попадают
+	GetMaxOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error)
+
+	// SetGitHubLogin привязывает логин GitHub к пользователю
+	SetGitHubLogin(ctx context.Context, userID, login string) error
+
+	// GetByGitHubLogin получает пользователя по логину GitHub (без учета регистра)
+	GetByGitHubLogin(ctx context.Context, login string) (*domain.User, error)
+
+	// SetEmail задает email пользователя для входа через OIDC
+	SetEmail(ctx context.Context, userID, email string) error
+
+	// GetByEmail получает пользователя по email (без учета регистра)
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+
+	// SetRole обновляет роль пользователя; новый лид без команд под управлением становится лидом основной команды
+	SetRole(ctx context.Context, userID string, role domain.Role) error
+
+	// HasAdmin проверяет, есть ли в системе хотя бы один администратор
+	HasAdmin(ctx context.Context) (bool, error)
+
+	// ListDirectory возвращает страницу пользователей каталога по фильтру и общее число подходящих
+	ListDirectory(ctx context.Context, filter domain.DirectoryFilter) ([]*domain.DirectoryUser, int, error)
+
+	// GetDirectoryUser получает пользователя вместе с атрибутами каталога
+	GetDirectoryUser(ctx context.Context, userID string) (*domain.DirectoryUser, error)
+
+	// CreateDirectoryUser создает пользователя из каталога; ErrUserExists, если пользователь уже есть
+	CreateDirectoryUser(ctx context.Context, user *domain.DirectoryUser) error
+
+	// UpdateDirectoryUser в одной транзакции обновляет имя, команду, email, externalId и активность пользователя
+	// (роль не меняется); при деактивации отзывает сессии и применяет замены ревьюверов на открытых PR
+	UpdateDirectoryUser(
+		ctx context.Context,
+		user *domain.DirectoryUser,
+		reassignments []*domain.ReviewerReassignment,
+		actorID string,
+	) error
+
+	// SetPrimaryTeam делает команду основной для пользователя; ErrNotTeamMember, если он в ней не состоит
+	SetPrimaryTeam(ctx context.Context, userID, teamName string) error
+}
+
+// CredentialRepository определяет методы для работы с учетными данными пользователей
+type CredentialRepository interface {
+	// Get получает учетные данные пользователя; ErrNotFound, если пароль не задан
+	Get(ctx context.Context, userID string) (*domain.Credentials, error)
+
+	// Create задает первый пароль пользователя; возвращает false, если пароль уже задан
+	Create(ctx context.Context, userID, passwordHash string) (bool, error)
+
+	// UpdatePassword заменяет хеш пароля и снимает блокировку входа
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+
+	// RegisterFailedAttempt учитывает неудачную попытку входа. После maxAttempts попыток подряд
+	// вход блокируется на lockout, а счетчик сбрасывается. Возвращает true, если вход заблокирован.
+	RegisterFailedAttempt(ctx context.Context, userID string, maxAttempts int, lockout time.Duration) (bool, error)
+
+	// ResetFailedAttempts сбрасывает счетчик неудачных попыток после успешного входа
+	ResetFailedAttempts(ctx context.Context, userID string) error
+}
+
+// APITokenRepository определяет методы для работы с API токенами
+type APITokenRepository interface {
+	// Create сохраняет токен по хешу и заполняет его ID и время создания
+	Create(ctx context.Context, token *domain.APIToken, tokenHash string) error
+
+	// GetByID получает токен по ID
+	GetByID(ctx context.Context, tokenID int64) (*domain.APIToken, error)
+
+	// GetActiveByHash получает неотозванный токен с неистекшим сроком действия по хешу
+	GetActiveByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error)
+
+	// List возвращает токены пользователя; при пустом userID - все токены
+	List(ctx context.Context, userID string) ([]*domain.APIToken, error)
+
+	// Revoke отзывает токен; повторный отзыв не меняет время отзыва
+	Revoke(ctx context.Context, tokenID int64) error
+
+	// TouchLastUsed обновляет время последнего использования не чаще раза в минуту
+	TouchLastUsed(ctx context.Context, tokenID int64) error
+}
+
+// SessionRepository определяет методы для работы с сессиями входа и их refresh токенами
+type SessionRepository interface {
+	// Create открывает сессию пользователя с refresh токеном, действующую ttl
+	Create(ctx context.Context, userID, refreshTokenHash string, ttl time.Duration) (*domain.Session, error)
+
+	// Rotate заменяет refresh токен активной сессии новым. Предъявление уже замененного токена
+	// отзывает сессию. ErrTokenNotFound, если токен не относится к активной сессии
+	Rotate(ctx context.Context, refreshTokenHash, newTokenHash string) (*domain.Session, error)
+
+	// RevokeByRefreshToken отзывает активную сессию по ее refresh токену
+	RevokeByRefreshToken(ctx context.Context, refreshTokenHash string) (*domain.Session, error)
+
+	// RevokeAllForUser отзывает все активные сессии пользователя
+	RevokeAllForUser(ctx context.Context, userID string) error
+
+	// IsActive проверяет, что сессия не отозвана и не истекла
+	IsActive(ctx context.Context, sessionID int64) (bool, error)
+}
+
+// IdentityRepository определяет методы для входа через OIDC: незавершенные входы
+// и привязка учетных записей провайдера к пользователям
+type IdentityRepository interface {
+	// SaveLoginState сохраняет незавершенный вход, действующий ttl, и удаляет истекшие
+	SaveLoginState(ctx context.Context, state *domain.OIDCLoginState, ttl time.Duration) error
+
+	// ConsumeLoginState получает и удаляет незавершенный вход; ErrNotFound, если он не найден или истек
+	ConsumeLoginState(ctx context.Context, state string) (*domain.OIDCLoginState, error)
+
+	// GetUser получает пользователя, к которому привязана учетная запись провайдера
+	GetUser(ctx context.Context, issuer, subject string) (*domain.User, error)
+
+	// Link привязывает учетную запись провайдера к пользователю
+	Link(ctx context.Context, issuer, subject, userID string) error
+
+	// Provision в одной транзакции создает пользователя и привязывает к нему учетную запись провайдера.
+	// ErrIdentityNotLinked, если пользователь с таким ID уже есть
+	Provision(ctx context.Context, user *domain.User, email, issuer, subject string) error
+}
+
+// SigningKeyRepository определяет методы для работы с ключами подписи JWT
+type SigningKeyRepository interface {
+	// ListValid возвращает ключи, пригодные для проверки токенов, начиная с самого нового
+	ListValid(ctx context.Context) ([]*domain.SigningKey, error)
+
+	// RotationDue проверяет, что текущего ключа алгоритма нет или он старше interval
+	RotationDue(ctx context.Context, algorithm domain.SigningAlgorithm, interval time.Duration) (bool, error)
+
+	// Rotate сохраняет новый ключ подписи, если за последние interval не появилось ключа того же алгоритма.
+	// Прежние ключи остаются действительными еще overlap. Возвращает false, если ключ уже ротирован
+	Rotate(ctx context.Context, key *domain.SigningKey, interval, overlap time.Duration) (bool, error)
 }
 
 // TeamRepository определяет методы для работы с данными команд
@@ -34,25 +224,174 @@ type TeamRepository interface {
 
 	// Exists проверяет существование команды
 	Exists(ctx context.Context, teamName string) (bool, error)
+
+	// ListNames возвращает страницу названий команд по алфавиту и общее число команд;
+	// непустой teamName оставляет только эту команду
+	ListNames(ctx context.Context, teamName string, offset, limit int) ([]string, int, error)
+
+	// GetSettings возвращает настройки команды (значения по умолчанию для незаданных параметров)
+	GetSettings(ctx context.Context, teamName string) (*domain.TeamSettings, error)
+
+	// SetReviewerStrategy сохраняет стратегию выбора ревьюверов для команды
+	SetReviewerStrategy(ctx context.Context, teamName string, strategy domain.ReviewerStrategy) error
+
+	// SetReviewerLimits сохраняет минимальное и максимальное число ревьюверов для команды
+	SetReviewerLimits(ctx context.Context, teamName string, minReviewers, maxReviewers int) error
+
+	// SetRequiredApprovals сохраняет правило merge для команды (nil - правило выключено)
+	SetRequiredApprovals(ctx context.Context, teamName string, requiredApprovals *int) error
+
+	// SetMaxOpenReviews сохраняет лимит открытых ревью участника команды по умолчанию (nil - без ограничения)
+	SetMaxOpenReviews(ctx context.Context, teamName string, limit *int) error
+
+	// SetFallbackTeams заменяет список запасных команд; ErrTeamNotFound, если какой-то из команд нет
+	SetFallbackTeams(ctx context.Context, teamName string, fallbackTeams []string) error
+
+	// Archive в одной транзакции применяет изменения участников и помечает команду архивной
+	Archive(ctx context.Context, removal *domain.TeamRemoval) error
+
+	// Delete в одной транзакции применяет изменения участников и удаляет команду вместе с ее настройками;
+	// оставшиеся участники остаются без команды
+	Delete(ctx context.Context, removal *domain.TeamRemoval) error
 }
 
 // PullRequestRepository определяет методы для работы с данными pull request'ов
 type PullRequestRepository interface {
-	// Create создает новый pull request с назначенными ревьюверами
-	Create(ctx context.Context, pr *domain.PullRequest) error
+	// Create создает pull request черновиком; при ready в той же транзакции переводит его
+	// в OPEN и назначает ревьюверов
+	Create(ctx context.Context, pr *domain.PullRequest, ready bool, actorID string) error
 
 	// GetByID получает pull request по ID
 	GetByID(ctx context.Context, prID string) (*domain.PullRequest, error)
 
-	// Merge помечает pull request как смерженный (идемпотентная операция)
-	Merge(ctx context.Context, prID string) (*domain.PullRequest, error)
+	// Merge помечает открытый pull request как смерженный (идемпотентная операция); ErrInvalidStatus для черновика
+	// или закрытого PR, ErrNotApproved, если при requiredApprovals != nil PR не удовлетворяет правилу merge
+	Merge(ctx context.Context, prID string, requiredApprovals *int, actorID string) (*domain.PullRequest, error)
+
+	// OpenWithReviewers переводит PR из статуса from (DRAFT или CLOSED) в OPEN и назначает ревьюверов
+	// (fallbackReviewers - те из них, кто добран из запасных команд)
+	OpenWithReviewers(
+		ctx context.Context,
+		prID string,
+		from domain.PullRequestStatus,
+		reviewers []string,
+		fallbackReviewers []domain.FallbackReviewer,
+		actorID string,
+	) error
+
+	// Close закрывает открытый PR или черновик без merge и освобождает ревьюверов
+	Close(ctx context.Context, prID, actorID string) error
+
+	// UpdateReviewers заменяет старого ревьювера на нового под блокировкой PR; ErrAlreadyAssigned, если новый
+	// уже назначен, ErrNotAssigned, если старый уже снят, ErrPRMerged/ErrInvalidStatus, если PR больше не открыт
+	UpdateReviewers(ctx context.Context, prID, oldReviewerID, newReviewerID, actorID string) error
+
+	// AddReviewer назначает ревьювера на PR вручную; ErrAlreadyAssigned, если он уже назначен,
+	// ErrTooManyReviewers, если у PR уже maxReviewers ревьюверов, ErrPRMerged/ErrInvalidStatus, если PR не открыт
+	AddReviewer(ctx context.Context, prID, reviewerID string, maxReviewers int, actorID string) error
+
+	// RemoveReviewer снимает ревьювера с PR без замены; ErrNotAssigned, если он не назначен,
+	// ErrTooFewReviewers, если у PR останется меньше minReviewers ревьюверов, ErrPRMerged/ErrInvalidStatus, если PR не открыт
+	RemoveReviewer(ctx context.Context, prID, reviewerID string, minReviewers int, actorID string) error
 
-	// UpdateReviewers заменяет старого ревьювера на нового
-	UpdateReviewers(ctx context.Context, prID, oldReviewerID, newReviewerID string) error
+	// GetByReviewer возвращает все PR где пользователь назначен ревьювером,
+	// при excludeApproved - кроме уже одобренных им
+	GetByReviewer(ctx context.Context, userID string, excludeApproved bool) ([]*domain.PullRequestShort, error)
 
-	// GetByReviewer возвращает все PR где пользователь назначен ревьювером
-	GetByReviewer(ctx context.Context, userID string) ([]*domain.PullRequestShort, error)
+	// AddReview сохраняет решение ревьювера по PR
+	AddReview(ctx context.Context, prID string, review *domain.Review) error
+
+	// GetOpenByReviewers возвращает открытые PR, где ревьювером назначен хотя бы один из пользователей
+	GetOpenByReviewers(ctx context.Context, userIDs []string) ([]*domain.PullRequest, error)
+
+	// GetOpenByAuthors возвращает открытые PR указанных авторов
+	GetOpenByAuthors(ctx context.Context, userIDs []string) ([]*domain.PullRequestShort, error)
+
+	// CountOpenReviews возвращает число открытых PR на ревью у каждого из пользователей
+	CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error)
 
 	// Exists проверяет существование PR
 	Exists(ctx context.Context, prID string) (bool, error)
+
+	// GetTimeline возвращает историю событий PR в порядке их добавления
+	GetTimeline(ctx context.Context, prID string) ([]*domain.PREvent, error)
+}
+
+// WebhookDeliveryRepository определяет методы для учета полученных доставок вебхуков
+type WebhookDeliveryRepository interface {
+	// Register сохраняет доставку; возвращает false, если доставка с таким ID уже была получена
+	Register(ctx context.Context, deliveryID, event string) (bool, error)
+
+	// Forget удаляет доставку, чтобы ее повторная отправка была обработана заново
+	Forget(ctx context.Context, deliveryID string) error
+}
+
+// OutgoingWebhookRepository определяет методы для работы с подписчиками исходящих вебхуков,
+// outbox событий и журналом доставок
+type OutgoingWebhookRepository interface {
+	// CreateSubscription сохраняет нового подписчика
+	CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error
+
+	// ListSubscriptions возвращает всех подписчиков
+	ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error)
+
+	// DeleteSubscription удаляет подписчика вместе с журналом его доставок
+	DeleteSubscription(ctx context.Context, subscriptionID int64) error
+
+	// GetDeliveries возвращает журнал доставок подписчика, начиная с последних
+	GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]*domain.WebhookDelivery, error)
+
+	// FanOutOutbox создает доставки для подписчиков по необработанным событиям outbox
+	FanOutOutbox(ctx context.Context, limit int) (int, error)
+
+	// ClaimDueDeliveries выбирает доставки, которые пора отправить, и откладывает их на время lease
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.PendingDelivery, error)
+
+	// MarkDelivered отмечает успешную попытку доставки
+	MarkDelivered(ctx context.Context, deliveryID int64, statusCode int) error
+
+	// MarkFailed отмечает неудачную попытку доставки (retryAfter = nil - попытки исчерпаны)
+	MarkFailed(ctx context.Context, deliveryID int64, statusCode *int, lastError string, retryAfter *time.Duration) error
+}
+
+// AbsenceRepository определяет методы для работы с периодами отсутствия пользователей
+type AbsenceRepository interface {
+	// Create сохраняет период отсутствия и заполняет его ID и время создания;
+	// ErrUserNotFound, если пользователя нет, ErrAbsenceOverlap, если период пересекается с другим
+	Create(ctx context.Context, absence *domain.Absence) error
+
+	// GetByID получает период отсутствия по ID
+	GetByID(ctx context.Context, absenceID int64) (*domain.Absence, error)
+
+	// ListByUser возвращает периоды отсутствия пользователя по дате начала; без past - только незакончившиеся
+	ListByUser(ctx context.Context, userID string, past bool) ([]*domain.Absence, error)
+
+	// Delete удаляет период отсутствия
+	Delete(ctx context.Context, absenceID int64) error
+
+	// ListDueForReassignment возвращает начавшиеся и незакончившиеся периоды не короче minDays дней,
+	// ревью пользователей которых еще не переназначались; отложенные после неудачи периоды пропускаются
+	// до времени следующей попытки, а затем идут после остальных
+	ListDueForReassignment(ctx context.Context, minDays, limit int) ([]*domain.Absence, error)
+
+	// MarkReassignmentFailed отмечает неудачную попытку переназначить ревью и откладывает следующую на retryAfter
+	MarkReassignmentFailed(ctx context.Context, absenceID int64, retryAfter time.Duration) error
+
+	// ReassignReviews в одной транзакции отмечает, что ревью отсутствующего пользователя переназначены,
+	// применяет замены на открытых PR и записывает их в историю PR. ErrAbsenceNotFound, если период удален
+	// или его ревью уже переназначены (например, другим экземпляром сервиса)
+	ReassignReviews(ctx context.Context, absenceID int64, reassignments []*domain.ReviewerReassignment) error
+}
+
+// CodeOwnersRepository определяет методы для работы с файлами CODEOWNERS команд и репозиториев.
+// Владелец файла задается ровно одним из teamName и repository
+type CodeOwnersRepository interface {
+	// Set сохраняет или заменяет CODEOWNERS и заполняет время изменения; ErrTeamNotFound, если команды нет
+	Set(ctx context.Context, codeOwners *domain.CodeOwners) error
+
+	// Get получает CODEOWNERS команды или репозитория
+	Get(ctx context.Context, teamName, repository string) (*domain.CodeOwners, error)
+
+	// Delete удаляет CODEOWNERS команды или репозитория
+	Delete(ctx context.Context, teamName, repository string) error
 }
@@ -0,0 +1,238 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// absenceColumns перечисляет колонки периода отсутствия в порядке сканирования scanAbsence
+const absenceColumns = `
+	absence_id, user_id, start_date, end_date, COALESCE(reason, ''), COALESCE(created_by, ''),
+	created_at, reviews_reassigned_at, handover_attempts
+`
+
+// AbsenceRepository реализует repository.AbsenceRepository для PostgreSQL
+type AbsenceRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAbsenceRepository создает новый экземпляр AbsenceRepository
+func NewAbsenceRepository(db *pgxpool.Pool) *AbsenceRepository {
+	return &AbsenceRepository{db: db}
+}
+
+// Create сохраняет период отсутствия и заполняет его ID и время создания;
+// ErrUserNotFound, если пользователя нет, ErrAbsenceOverlap, если период пересекается с другим
+func (r *AbsenceRepository) Create(ctx context.Context, absence *domain.Absence) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	// Блокировка пользователя не дает параллельным запросам добавить пересекающиеся периоды
+	var userID string
+	err = tx.QueryRow(ctx, `SELECT user_id FROM users WHERE user_id = $1 FOR UPDATE`, absence.UserID).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		return err
+	}
+
+	var overlaps bool
+	overlapQuery := `
+		SELECT EXISTS(
+			SELECT 1 FROM user_absences
+			WHERE user_id = $1 AND start_date <= $3 AND end_date >= $2
+		)
+	`
+	if err := tx.QueryRow(ctx, overlapQuery, absence.UserID, absence.StartDate, absence.EndDate).Scan(&overlaps); err != nil {
+		return err
+	}
+	if overlaps {
+		return domain.ErrAbsenceOverlap
+	}
+
+	query := `
+		INSERT INTO user_absences (user_id, start_date, end_date, reason, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+		RETURNING absence_id, created_at
+	`
+
+	err = tx.QueryRow(ctx, query,
+		absence.UserID,
+		absence.StartDate,
+		absence.EndDate,
+		absence.Reason,
+		absence.CreatedBy,
+	).Scan(&absence.AbsenceID, &absence.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetByID получает период отсутствия по ID
+func (r *AbsenceRepository) GetByID(ctx context.Context, absenceID int64) (*domain.Absence, error) {
+	query := `SELECT ` + absenceColumns + ` FROM user_absences WHERE absence_id = $1`
+
+	return scanAbsence(r.db.QueryRow(ctx, query, absenceID))
+}
+
+// ListByUser возвращает периоды отсутствия пользователя по дате начала; без past - только незакончившиеся
+func (r *AbsenceRepository) ListByUser(ctx context.Context, userID string, past bool) ([]*domain.Absence, error) {
+	query := `
+		SELECT ` + absenceColumns + `
+		FROM user_absences
+		WHERE user_id = $1 AND ($2 OR end_date >= CURRENT_DATE)
+		ORDER BY start_date
+	`
+
+	return queryAbsences(ctx, r.db, query, userID, past)
+}
+
+// Delete удаляет период отсутствия
+func (r *AbsenceRepository) Delete(ctx context.Context, absenceID int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM user_absences WHERE absence_id = $1`, absenceID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrAbsenceNotFound
+	}
+
+	return nil
+}
+
+// ListDueForReassignment возвращает начавшиеся и незакончившиеся периоды не короче minDays дней,
+// ревью пользователей которых еще не переназначались. Периоды после неудачной попытки пропускаются
+// до времени следующей попытки, а затем идут после периодов с меньшим числом неудач
+func (r *AbsenceRepository) ListDueForReassignment(ctx context.Context, minDays, limit int) ([]*domain.Absence, error) {
+	query := `
+		SELECT ` + absenceColumns + `
+		FROM user_absences
+		WHERE reviews_reassigned_at IS NULL
+		  AND CURRENT_DATE BETWEEN start_date AND end_date
+		  AND end_date - start_date + 1 >= $1
+		  AND (next_handover_at IS NULL OR next_handover_at <= NOW())
+		ORDER BY handover_attempts, start_date, absence_id
+		LIMIT $2
+	`
+
+	return queryAbsences(ctx, r.db, query, minDays, limit)
+}
+
+// MarkReassignmentFailed отмечает неудачную попытку переназначить ревью; следующая попытка будет через retryAfter
+func (r *AbsenceRepository) MarkReassignmentFailed(ctx context.Context, absenceID int64, retryAfter time.Duration) error {
+	query := `
+		UPDATE user_absences
+		SET handover_attempts = handover_attempts + 1,
+		    next_handover_at = NOW() + $2::float8 * INTERVAL '1 second'
+		WHERE absence_id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, absenceID, retryAfter.Seconds())
+	return err
+}
+
+// ReassignReviews в одной транзакции отмечает, что ревью отсутствующего пользователя переназначены,
+// применяет замены на открытых PR и записывает их в историю PR
+func (r *AbsenceRepository) ReassignReviews(
+	ctx context.Context,
+	absenceID int64,
+	reassignments []*domain.ReviewerReassignment,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	// Отметка блокирует период, поэтому его ревью переназначаются только один раз
+	markQuery := `
+		UPDATE user_absences
+		SET reviews_reassigned_at = NOW()
+		WHERE absence_id = $1 AND reviews_reassigned_at IS NULL
+	`
+
+	result, err := tx.Exec(ctx, markQuery, absenceID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrAbsenceNotFound
+	}
+
+	applied, err := applyReassignments(ctx, tx, reassignments)
+	if err != nil {
+		return err
+	}
+
+	// Замены выполняет сервис, а не пользователь, поэтому у событий нет автора
+	events := make([]*domain.PREvent, 0, len(applied))
+	for _, ra := range applied {
+		events = append(events, reassignmentEvent(ra, "", domain.ReasonUserAbsent))
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// queryAbsences выполняет запрос, возвращающий колонки absenceColumns
+func queryAbsences(ctx context.Context, db *pgxpool.Pool, query string, args ...any) ([]*domain.Absence, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	absences := []*domain.Absence{}
+	for rows.Next() {
+		absence, err := scanAbsence(rows)
+		if err != nil {
+			return nil, err
+		}
+		absences = append(absences, absence)
+	}
+
+	return absences, rows.Err()
+}
+
+// scanAbsence читает период отсутствия из строки с колонками absenceColumns
+func scanAbsence(row pgx.Row) (*domain.Absence, error) {
+	var absence domain.Absence
+	err := row.Scan(
+		&absence.AbsenceID,
+		&absence.UserID,
+		&absence.StartDate,
+		&absence.EndDate,
+		&absence.Reason,
+		&absence.CreatedBy,
+		&absence.CreatedAt,
+		&absence.ReviewsReassignedAt,
+		&absence.HandoverAttempts,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrAbsenceNotFound
+		}
+		return nil, err
+	}
+
+	return &absence, nil
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// apiTokenColumns перечисляет колонки токена в порядке сканирования scanAPIToken
+const apiTokenColumns = `
+	token_id, name, kind, COALESCE(user_id, ''), scopes, token_prefix, created_by,
+	created_at, expires_at, last_used_at, revoked_at
+`
+
+// APITokenRepository реализует repository.APITokenRepository для PostgreSQL
+type APITokenRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAPITokenRepository создает новый экземпляр APITokenRepository
+func NewAPITokenRepository(db *pgxpool.Pool) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+// Create сохраняет токен по хешу и заполняет его ID и время создания
+func (r *APITokenRepository) Create(ctx context.Context, token *domain.APIToken, tokenHash string) error {
+	query := `
+		INSERT INTO api_tokens (name, kind, user_id, scopes, token_hash, token_prefix, created_by, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8)
+		RETURNING token_id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query,
+		token.Name,
+		token.Kind,
+		token.UserID,
+		token.Scopes,
+		tokenHash,
+		token.Prefix,
+		token.CreatedBy,
+		token.ExpiresAt,
+	).Scan(&token.TokenID, &token.CreatedAt)
+}
+
+// GetByID получает токен по ID
+func (r *APITokenRepository) GetByID(ctx context.Context, tokenID int64) (*domain.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE token_id = $1`
+
+	return scanAPIToken(r.db.QueryRow(ctx, query, tokenID))
+}
+
+// GetActiveByHash получает неотозванный токен с неистекшим сроком действия по хешу
+func (r *APITokenRepository) GetActiveByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error) {
+	query := `
+		SELECT ` + apiTokenColumns + `
+		FROM api_tokens
+		WHERE token_hash = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	return scanAPIToken(r.db.QueryRow(ctx, query, tokenHash))
+}
+
+// List возвращает токены пользователя; при пустом userID - все токены
+func (r *APITokenRepository) List(ctx context.Context, userID string) ([]*domain.APIToken, error) {
+	query := `
+		SELECT ` + apiTokenColumns + `
+		FROM api_tokens
+		WHERE $1 = '' OR user_id = $1
+		ORDER BY token_id
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*domain.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Revoke отзывает токен; повторный отзыв не меняет время отзыва
+func (r *APITokenRepository) Revoke(ctx context.Context, tokenID int64) error {
+	query := `
+		UPDATE api_tokens
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE token_id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query, tokenID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrTokenNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed обновляет время последнего использования не чаще раза в минуту,
+// чтобы не записывать строку на каждый запрос
+func (r *APITokenRepository) TouchLastUsed(ctx context.Context, tokenID int64) error {
+	query := `
+		UPDATE api_tokens
+		SET last_used_at = NOW()
+		WHERE token_id = $1
+		  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+
+	_, err := r.db.Exec(ctx, query, tokenID)
+	return err
+}
+
+// scanAPIToken читает токен из строки с колонками apiTokenColumns
+func scanAPIToken(row pgx.Row) (*domain.APIToken, error) {
+	var token domain.APIToken
+	err := row.Scan(
+		&token.TokenID,
+		&token.Name,
+		&token.Kind,
+		&token.UserID,
+		&token.Scopes,
+		&token.Prefix,
+		&token.CreatedBy,
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// CodeOwnersRepository реализует repository.CodeOwnersRepository для PostgreSQL
+type CodeOwnersRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewCodeOwnersRepository создает новый экземпляр CodeOwnersRepository
+func NewCodeOwnersRepository(db *pgxpool.Pool) *CodeOwnersRepository {
+	return &CodeOwnersRepository{db: db}
+}
+
+// codeOwnersScope возвращает колонку и значение, которыми задан владелец CODEOWNERS
+func codeOwnersScope(teamName, repository string) (string, string) {
+	if teamName != "" {
+		return "team_name", teamName
+	}
+	return "repository", repository
+}
+
+// Set сохраняет или заменяет CODEOWNERS и заполняет время изменения
+func (r *CodeOwnersRepository) Set(ctx context.Context, codeOwners *domain.CodeOwners) error {
+	column, value := codeOwnersScope(codeOwners.TeamName, codeOwners.Repository)
+
+	// Для каждого владельца уникален свой частичный индекс, поэтому конфликт указывается по его колонке
+	query := fmt.Sprintf(`
+		INSERT INTO code_owners (%[1]s, content, updated_by)
+		VALUES ($1, $2, NULLIF($3, ''))
+		ON CONFLICT (%[1]s) WHERE %[1]s IS NOT NULL DO UPDATE
+		SET content = EXCLUDED.content,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+		RETURNING updated_at
+	`, column)
+
+	err := r.db.QueryRow(ctx, query, value, codeOwners.Content, codeOwners.UpdatedBy).Scan(&codeOwners.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return domain.ErrTeamNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Get получает CODEOWNERS команды или репозитория
+func (r *CodeOwnersRepository) Get(ctx context.Context, teamName, repository string) (*domain.CodeOwners, error) {
+	column, value := codeOwnersScope(teamName, repository)
+
+	query := fmt.Sprintf(`
+		SELECT COALESCE(team_name, ''), COALESCE(repository, ''), content, COALESCE(updated_by, ''), updated_at
+		FROM code_owners
+		WHERE %s = $1
+	`, column)
+
+	var codeOwners domain.CodeOwners
+	err := r.db.QueryRow(ctx, query, value).Scan(
+		&codeOwners.TeamName,
+		&codeOwners.Repository,
+		&codeOwners.Content,
+		&codeOwners.UpdatedBy,
+		&codeOwners.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrCodeOwnersNotFound
+		}
+		return nil, err
+	}
+
+	return &codeOwners, nil
+}
+
+// Delete удаляет CODEOWNERS команды или репозитория
+func (r *CodeOwnersRepository) Delete(ctx context.Context, teamName, repository string) error {
+	column, value := codeOwnersScope(teamName, repository)
+
+	result, err := r.db.Exec(ctx, fmt.Sprintf(`DELETE FROM code_owners WHERE %s = $1`, column), value)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrCodeOwnersNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// CredentialRepository реализует repository.CredentialRepository для PostgreSQL
+type CredentialRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewCredentialRepository создает новый экземпляр CredentialRepository
+func NewCredentialRepository(db *pgxpool.Pool) *CredentialRepository {
+	return &CredentialRepository{db: db}
+}
+
+// Get получает учетные данные пользователя; ErrNotFound, если пароль не задан
+func (r *CredentialRepository) Get(ctx context.Context, userID string) (*domain.Credentials, error) {
+	// Блокировка проверяется по часам БД, как и выставляется
+	query := `
+		SELECT user_id, password_hash, failed_attempts, COALESCE(locked_until > NOW(), false)
+		FROM user_credentials
+		WHERE user_id = $1
+	`
+
+	var cred domain.Credentials
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&cred.UserID,
+		&cred.PasswordHash,
+		&cred.FailedAttempts,
+		&cred.Locked,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &cred, nil
+}
+
+// Create задает первый пароль пользователя; возвращает false, если пароль уже задан
+func (r *CredentialRepository) Create(ctx context.Context, userID, passwordHash string) (bool, error) {
+	query := `
+		INSERT INTO user_credentials (user_id, password_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO NOTHING
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, passwordHash)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return false, domain.ErrUserNotFound
+		}
+		return false, err
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// UpdatePassword заменяет хеш пароля и снимает блокировку входа
+func (r *CredentialRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	query := `
+		UPDATE user_credentials
+		SET password_hash = $1, failed_attempts = 0, locked_until = NULL, password_changed_at = NOW()
+		WHERE user_id = $2
+	`
+
+	result, err := r.db.Exec(ctx, query, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// RegisterFailedAttempt учитывает неудачную попытку входа и блокирует вход после maxAttempts попыток подряд
+func (r *CredentialRepository) RegisterFailedAttempt(
+	ctx context.Context,
+	userID string,
+	maxAttempts int,
+	lockout time.Duration,
+) (bool, error) {
+	// Счетчик обновляется одним запросом, чтобы параллельные попытки не терялись
+	query := `
+		UPDATE user_credentials
+		SET failed_attempts = CASE WHEN failed_attempts + 1 >= $1 THEN 0 ELSE failed_attempts + 1 END,
+		    locked_until = CASE
+		        WHEN failed_attempts + 1 >= $1 THEN NOW() + $2::float8 * INTERVAL '1 second'
+		        ELSE locked_until
+		    END
+		WHERE user_id = $3
+		RETURNING COALESCE(locked_until > NOW(), false)
+	`
+
+	var locked bool
+	err := r.db.QueryRow(ctx, query, maxAttempts, lockout.Seconds(), userID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, domain.ErrNotFound
+		}
+		return false, err
+	}
+
+	return locked, nil
+}
+
+// ResetFailedAttempts сбрасывает счетчик неудачных попыток после успешного входа
+func (r *CredentialRepository) ResetFailedAttempts(ctx context.Context, userID string) error {
+	_, err := r.db.Exec(ctx, `UPDATE user_credentials SET failed_attempts = 0 WHERE user_id = $1`, userID)
+	return err
+}
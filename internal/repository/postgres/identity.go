@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// IdentityRepository реализует repository.IdentityRepository для PostgreSQL
+type IdentityRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewIdentityRepository создает новый экземпляр IdentityRepository
+func NewIdentityRepository(db *pgxpool.Pool) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// SaveLoginState сохраняет незавершенный вход, действующий ttl, и удаляет истекшие
+func (r *IdentityRepository) SaveLoginState(ctx context.Context, state *domain.OIDCLoginState, ttl time.Duration) error {
+	// Брошенные входы никто не завершит, поэтому истекшие удаляются при создании новых
+	if _, err := r.db.Exec(ctx, `DELETE FROM oidc_login_states WHERE expires_at <= NOW()`); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO oidc_login_states (state, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4::float8 * INTERVAL '1 second')
+	`
+
+	_, err := r.db.Exec(ctx, query, state.State, state.Nonce, state.CodeVerifier, ttl.Seconds())
+	return err
+}
+
+// ConsumeLoginState получает и удаляет незавершенный вход, чтобы callback нельзя было повторить
+func (r *IdentityRepository) ConsumeLoginState(ctx context.Context, state string) (*domain.OIDCLoginState, error) {
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state = $1
+		RETURNING state, nonce, code_verifier, expires_at > NOW()
+	`
+
+	var loginState domain.OIDCLoginState
+	var valid bool
+	err := r.db.QueryRow(ctx, query, state).Scan(&loginState.State, &loginState.Nonce, &loginState.CodeVerifier, &valid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	if !valid {
+		return nil, domain.ErrNotFound
+	}
+
+	return &loginState, nil
+}
+
+// GetUser получает пользователя, к которому привязана учетная запись провайдера
+func (r *IdentityRepository) GetUser(ctx context.Context, issuer, subject string) (*domain.User, error) {
+	query := `
+		SELECT u.user_id, u.username, COALESCE(u.team_name, ''),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = u.user_id ORDER BY tm.team_name),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = u.user_id AND tm.is_lead ORDER BY tm.team_name),
+		       u.is_active, u.role
+		FROM user_identities ui
+		INNER JOIN users u ON u.user_id = ui.user_id
+		WHERE ui.issuer = $1 AND ui.subject = $2
+	`
+
+	var user domain.User
+	err := r.db.QueryRow(ctx, query, issuer, subject).Scan(
+		&user.UserID,
+		&user.Username,
+		&user.TeamName,
+		&user.Teams,
+		&user.LeadTeams,
+		&user.IsActive,
+		&user.Role,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// Link привязывает учетную запись провайдера к пользователю; повторная привязка ничего не меняет
+func (r *IdentityRepository) Link(ctx context.Context, issuer, subject, userID string) error {
+	query := `
+		INSERT INTO user_identities (issuer, subject, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (issuer, subject) DO NOTHING
+	`
+
+	_, err := r.db.Exec(ctx, query, issuer, subject, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return domain.ErrUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Provision в одной транзакции создает пользователя в его команде и привязывает к нему учетную запись провайдера.
+// Существующий пользователь не изменяется: совпадение ID не дает права войти под ним
+func (r *IdentityRepository) Provision(ctx context.Context, user *domain.User, email, issuer, subject string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	userQuery := `
+		INSERT INTO users (user_id, username, team_name, is_active, role, email)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		ON CONFLICT (user_id) DO NOTHING
+	`
+
+	result, err := tx.Exec(ctx, userQuery, user.UserID, user.Username, user.TeamName, user.IsActive, user.Role, email)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23503": // foreign_key_violation
+				return domain.ErrTeamNotFound
+			case "23505": // unique_violation (email)
+				return domain.ErrEmailTaken
+			}
+		}
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrIdentityNotLinked
+	}
+
+	if err := joinTeam(ctx, tx, user.TeamName, []string{user.UserID}); err != nil {
+		return err
+	}
+
+	identityQuery := `
+		INSERT INTO user_identities (issuer, subject, user_id)
+		VALUES ($1, $2, $3)
+	`
+
+	if _, err := tx.Exec(ctx, identityQuery, issuer, subject, user.UserID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,286 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// OutgoingWebhookRepository реализует repository.OutgoingWebhookRepository для PostgreSQL
+type OutgoingWebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewOutgoingWebhookRepository создает новый экземпляр OutgoingWebhookRepository
+func NewOutgoingWebhookRepository(db *pgxpool.Pool) *OutgoingWebhookRepository {
+	return &OutgoingWebhookRepository{db: db}
+}
+
+// CreateSubscription сохраняет нового подписчика
+func (r *OutgoingWebhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, event_types)
+		VALUES ($1, $2, $3)
+		RETURNING subscription_id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, sub.URL, sub.Secret, sub.EventTypes).Scan(&sub.SubscriptionID, &sub.CreatedAt)
+}
+
+// ListSubscriptions возвращает всех подписчиков
+func (r *OutgoingWebhookRepository) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	query := `
+		SELECT subscription_id, url, event_types, created_at
+		FROM webhook_subscriptions
+		ORDER BY subscription_id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []*domain.WebhookSubscription{}
+	for rows.Next() {
+		var sub domain.WebhookSubscription
+		if err := rows.Scan(&sub.SubscriptionID, &sub.URL, &sub.EventTypes, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, &sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// DeleteSubscription удаляет подписчика вместе с журналом его доставок
+func (r *OutgoingWebhookRepository) DeleteSubscription(ctx context.Context, subscriptionID int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE subscription_id = $1`, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+// GetDeliveries возвращает журнал доставок подписчика, начиная с последних
+func (r *OutgoingWebhookRepository) GetDeliveries(
+	ctx context.Context,
+	subscriptionID int64,
+	limit int,
+) ([]*domain.WebhookDelivery, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM webhook_subscriptions WHERE subscription_id = $1)`, subscriptionID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+
+	query := `
+		SELECT d.delivery_id, d.subscription_id, d.event_id, e.event_type, d.status, d.attempts,
+		       d.last_status_code, COALESCE(d.last_error, ''), d.next_attempt_at, d.delivered_at, d.created_at
+		FROM outgoing_webhook_deliveries d
+		INNER JOIN pr_events e ON e.event_id = d.event_id
+		WHERE d.subscription_id = $1
+		ORDER BY d.delivery_id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		if err := rows.Scan(
+			&d.DeliveryID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.EventType,
+			&d.Status,
+			&d.Attempts,
+			&d.LastStatusCode,
+			&d.LastError,
+			&d.NextAttemptAt,
+			&d.DeliveredAt,
+			&d.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		// Время следующей попытки имеет смысл только для ожидающих доставок
+		if d.Status != domain.DeliveryPending {
+			d.NextAttemptAt = nil
+		}
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// FanOutOutbox создает доставки для подписчиков по необработанным событиям outbox
+// и отмечает события обработанными. Возвращает число обработанных событий.
+func (r *OutgoingWebhookRepository) FanOutOutbox(ctx context.Context, limit int) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	// SKIP LOCKED позволяет нескольким экземплярам сервиса разбирать outbox параллельно
+	rows, err := tx.Query(ctx, `
+		SELECT outbox_id
+		FROM webhook_outbox
+		WHERE processed_at IS NULL
+		ORDER BY outbox_id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+	outboxIDs, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, err
+	}
+	if len(outboxIDs) == 0 {
+		return 0, nil
+	}
+
+	fanOutQuery := `
+		INSERT INTO outgoing_webhook_deliveries (subscription_id, event_id)
+		SELECT s.subscription_id, o.event_id
+		FROM webhook_outbox o
+		INNER JOIN pr_events e ON e.event_id = o.event_id
+		INNER JOIN webhook_subscriptions s ON e.event_type = ANY(s.event_types)
+		WHERE o.outbox_id = ANY($1)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, fanOutQuery, outboxIDs); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE webhook_outbox SET processed_at = NOW() WHERE outbox_id = ANY($1)`, outboxIDs); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return len(outboxIDs), nil
+}
+
+// ClaimDueDeliveries выбирает ожидающие доставки, которые пора отправить, и откладывает
+// их на время lease, чтобы другие экземпляры сервиса не отправили их повторно
+func (r *OutgoingWebhookRepository) ClaimDueDeliveries(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*domain.PendingDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT delivery_id
+			FROM outgoing_webhook_deliveries
+			WHERE status = $1 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outgoing_webhook_deliveries d
+		SET next_attempt_at = NOW() + $3::float8 * INTERVAL '1 second'
+		FROM due, webhook_subscriptions s, pr_events e
+		WHERE d.delivery_id = due.delivery_id
+		  AND s.subscription_id = d.subscription_id
+		  AND e.event_id = d.event_id
+		RETURNING d.delivery_id, d.attempts, s.url, s.secret,
+		          e.event_id, e.pull_request_id, e.event_type, COALESCE(e.actor_id, ''), COALESCE(e.user_id, ''),
+		          COALESCE(e.old_user_id, ''), COALESCE(e.new_user_id, ''), COALESCE(e.reason, ''), e.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, domain.DeliveryPending, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*domain.PendingDelivery
+	for rows.Next() {
+		var d domain.PendingDelivery
+		if err := rows.Scan(
+			&d.DeliveryID,
+			&d.Attempts,
+			&d.URL,
+			&d.Secret,
+			&d.Event.EventID,
+			&d.Event.PullRequestID,
+			&d.Event.Type,
+			&d.Event.ActorID,
+			&d.Event.UserID,
+			&d.Event.OldUserID,
+			&d.Event.NewUserID,
+			&d.Event.Reason,
+			&d.Event.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// MarkDelivered отмечает успешную попытку доставки
+func (r *OutgoingWebhookRepository) MarkDelivered(ctx context.Context, deliveryID int64, statusCode int) error {
+	query := `
+		UPDATE outgoing_webhook_deliveries
+		SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = NULL, delivered_at = NOW()
+		WHERE delivery_id = $3
+	`
+
+	_, err := r.db.Exec(ctx, query, domain.DeliveryDelivered, statusCode, deliveryID)
+	return err
+}
+
+// MarkFailed отмечает неудачную попытку доставки. При retryAfter = nil попытки
+// считаются исчерпанными, иначе следующая попытка будет через retryAfter.
+func (r *OutgoingWebhookRepository) MarkFailed(
+	ctx context.Context,
+	deliveryID int64,
+	statusCode *int,
+	lastError string,
+	retryAfter *time.Duration,
+) error {
+	status := domain.DeliveryFailed
+	var retrySeconds float64
+	if retryAfter != nil {
+		status = domain.DeliveryPending
+		retrySeconds = retryAfter.Seconds()
+	}
+
+	query := `
+		UPDATE outgoing_webhook_deliveries
+		SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = $3,
+		    next_attempt_at = NOW() + $4::float8 * INTERVAL '1 second'
+		WHERE delivery_id = $5
+	`
+
+	_, err := r.db.Exec(ctx, query, status, statusCode, lastError, retrySeconds, deliveryID)
+	return err
+}
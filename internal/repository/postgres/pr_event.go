@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// insertEventQuery добавляет событие в историю PR; пустые строки сохраняются как NULL
+const insertEventQuery = `
+	INSERT INTO pr_events (pull_request_id, event_type, actor_id, user_id, old_user_id, new_user_id, reason)
+	VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''))
+`
+
+// insertWebhookEventQuery добавляет событие в историю PR и в outbox для рассылки подписчикам
+const insertWebhookEventQuery = `
+	WITH event AS (
+		INSERT INTO pr_events (pull_request_id, event_type, actor_id, user_id, old_user_id, new_user_id, reason)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''))
+		RETURNING event_id
+	)
+	INSERT INTO webhook_outbox (event_id)
+	SELECT event_id FROM event
+`
+
+// insertEvents добавляет события в историю PR в той же транзакции, что и само изменение.
+// События для исходящих вебхуков попадают в outbox в той же транзакции и не теряются при сбое.
+func insertEvents(ctx context.Context, tx pgx.Tx, events []*domain.PREvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, e := range events {
+		query := insertEventQuery
+		if e.Type.IsWebhookEvent() {
+			query = insertWebhookEventQuery
+		}
+		batch.Queue(query, e.PullRequestID, e.Type, e.ActorID, e.UserID, e.OldUserID, e.NewUserID, e.Reason)
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// assignedEvents возвращает события назначения ревьюверов на PR
+func assignedEvents(prID, actorID string, reviewers []string) []*domain.PREvent {
+	events := make([]*domain.PREvent, 0, len(reviewers))
+	for _, reviewerID := range reviewers {
+		events = append(events, &domain.PREvent{
+			PullRequestID: prID,
+			Type:          domain.EventReviewerAssigned,
+			ActorID:       actorID,
+			UserID:        reviewerID,
+		})
+	}
+	return events
+}
+
+// GetTimeline возвращает историю событий PR в порядке их добавления
+func (r *PullRequestRepository) GetTimeline(ctx context.Context, prID string) ([]*domain.PREvent, error) {
+	exists, err := r.Exists(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrPRNotFound
+	}
+
+	query := `
+		SELECT event_id, pull_request_id, event_type, COALESCE(actor_id, ''), COALESCE(user_id, ''),
+		       COALESCE(old_user_id, ''), COALESCE(new_user_id, ''), COALESCE(reason, ''), created_at
+		FROM pr_events
+		WHERE pull_request_id = $1
+		ORDER BY event_id
+	`
+
+	rows, err := r.db.Query(ctx, query, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*domain.PREvent{}
+	for rows.Next() {
+		var e domain.PREvent
+		if err := rows.Scan(
+			&e.EventID,
+			&e.PullRequestID,
+			&e.Type,
+			&e.ActorID,
+			&e.UserID,
+			&e.OldUserID,
+			&e.NewUserID,
+			&e.Reason,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+
+	return events, rows.Err()
+}
@@ -22,8 +22,9 @@ func NewPullRequestRepository(db *pgxpool.Pool) *PullRequestRepository {
 	return &PullRequestRepository{db: db}
 }
 
-// Create создает новый pull request с назначенными ревьюверами
-func (r *PullRequestRepository) Create(ctx context.Context, pr *domain.PullRequest) error {
+// Create создает pull request черновиком; при ready в той же транзакции переводит его
+// в OPEN и назначает ревьюверов
+func (r *PullRequestRepository) Create(ctx context.Context, pr *domain.PullRequest, ready bool, actorID string) error {
 	// Start transaction
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
@@ -35,12 +36,14 @@ func (r *PullRequestRepository) Create(ctx context.Context, pr *domain.PullReque
 
 	// Insert PR
 	query := `
-		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_name, repository, status, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
 	`
 
 	createdAt := time.Now()
-	_, err = tx.Exec(ctx, query, pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, createdAt)
+	_, err = tx.Exec(ctx, query,
+		pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.TeamName, pr.Repository, domain.StatusDraft, createdAt,
+	)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -48,23 +51,42 @@ func (r *PullRequestRepository) Create(ctx context.Context, pr *domain.PullReque
 				return domain.ErrPRExists
 			}
 			if pgErr.Code == "23503" { // foreign_key_violation
+				if pgErr.ConstraintName == "pull_requests_team_name_fkey" {
+					return domain.ErrTeamNotFound
+				}
 				return domain.ErrUserNotFound
 			}
 		}
 		return err
 	}
 
-	// Insert reviewers
-	if len(pr.AssignedReviewers) > 0 {
-		reviewerQuery := `
-			INSERT INTO pr_reviewers (pull_request_id, user_id)
-			VALUES ($1, $2)
+	// Измененные файлы сохраняются, чтобы заново выбрать владельцев кода при открытии черновика или закрытого PR
+	if len(pr.ChangedFiles) > 0 {
+		filesQuery := `
+			INSERT INTO pr_changed_files (pull_request_id, path)
+			SELECT $1, UNNEST($2::text[])
+			ON CONFLICT DO NOTHING
 		`
-		for _, reviewerID := range pr.AssignedReviewers {
-			_, err = tx.Exec(ctx, reviewerQuery, pr.PullRequestID, reviewerID)
-			if err != nil {
-				return err
-			}
+		if _, err := tx.Exec(ctx, filesQuery, pr.PullRequestID, pr.ChangedFiles); err != nil {
+			return err
+		}
+	}
+
+	// Record history
+	events := []*domain.PREvent{{
+		PullRequestID: pr.PullRequestID,
+		Type:          domain.EventCreated,
+		ActorID:       actorID,
+	}}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	// Ревьюверы назначаются только при переходе DRAFT -> OPEN
+	if ready {
+		err := openWithReviewers(ctx, tx, pr.PullRequestID, domain.StatusDraft, pr.AssignedReviewers, pr.FallbackReviewers, actorID)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -81,7 +103,9 @@ func (r *PullRequestRepository) Create(ctx context.Context, pr *domain.PullReque
 func (r *PullRequestRepository) GetByID(ctx context.Context, prID string) (*domain.PullRequest, error) {
 	// Get PR basic info
 	query := `
-		SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+		SELECT pull_request_id, pull_request_name, author_id, COALESCE(team_name, ''), COALESCE(repository, ''), status,
+		       created_at, merged_at, closed_at,
+		       ARRAY(SELECT f.path FROM pr_changed_files f WHERE f.pull_request_id = pull_requests.pull_request_id ORDER BY f.path)
 		FROM pull_requests
 		WHERE pull_request_id = $1
 	`
@@ -91,9 +115,13 @@ func (r *PullRequestRepository) GetByID(ctx context.Context, prID string) (*doma
 		&pr.PullRequestID,
 		&pr.PullRequestName,
 		&pr.AuthorID,
+		&pr.TeamName,
+		&pr.Repository,
 		&pr.Status,
 		&pr.CreatedAt,
 		&pr.MergedAt,
+		&pr.ClosedAt,
+		&pr.ChangedFiles,
 	)
 
 	if err != nil {
@@ -103,51 +131,70 @@ func (r *PullRequestRepository) GetByID(ctx context.Context, prID string) (*doma
 		return nil, err
 	}
 
-	// Get assigned reviewers
-	reviewersQuery := `
-		SELECT user_id
-		FROM pr_reviewers
-		WHERE pull_request_id = $1
-		ORDER BY assigned_at
-	`
+	// Get assigned reviewers and their verdicts
+	if err := r.loadReviewers(ctx, &pr); err != nil {
+		return nil, err
+	}
 
-	rows, err := r.db.Query(ctx, reviewersQuery, prID)
+	return &pr, nil
+}
+
+// Merge помечает открытый pull request как смерженный (идемпотентная операция).
+// Статус и правило merge проверяются под блокировкой строки PR: черновик или закрытый PR - ErrInvalidStatus,
+// при requiredApprovals != nil не хватает одобрений или есть запрос изменений - ErrNotApproved
+func (r *PullRequestRepository) Merge(
+	ctx context.Context,
+	prID string,
+	requiredApprovals *int,
+	actorID string,
+) (*domain.PullRequest, error) {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
 
-	var reviewers []string
-	for rows.Next() {
-		var reviewerID string
-		if err := rows.Scan(&reviewerID); err != nil {
+	// Блокируем PR, чтобы параллельные закрытие, возврат в черновик и ревью не обошли проверки,
+	// а событие merge записалось только при первом вызове
+	prevStatus, err := lockPR(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch prevStatus {
+	case domain.StatusMerged:
+		// Повторный merge возвращает PR без изменений
+	case domain.StatusOpen:
+		if err := checkMergeRule(ctx, tx, prID, requiredApprovals); err != nil {
 			return nil, err
 		}
-		reviewers = append(reviewers, reviewerID)
+	default:
+		return nil, domain.ErrInvalidStatus
 	}
 
-	pr.AssignedReviewers = reviewers
-
-	return &pr, rows.Err()
-}
-
-// Merge помечает pull request как смерженный (идемпотентная операция)
-func (r *PullRequestRepository) Merge(ctx context.Context, prID string) (*domain.PullRequest, error) {
 	query := `
 		UPDATE pull_requests
 		SET status = $1, merged_at = COALESCE(merged_at, NOW())
 		WHERE pull_request_id = $2
-		RETURNING pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+		RETURNING pull_request_id, pull_request_name, author_id, COALESCE(team_name, ''), COALESCE(repository, ''), status,
+		          created_at, merged_at, closed_at,
+		          ARRAY(SELECT f.path FROM pr_changed_files f WHERE f.pull_request_id = pull_requests.pull_request_id ORDER BY f.path)
 	`
 
 	var pr domain.PullRequest
-	err := r.db.QueryRow(ctx, query, domain.StatusMerged, prID).Scan(
+	err = tx.QueryRow(ctx, query, domain.StatusMerged, prID).Scan(
 		&pr.PullRequestID,
 		&pr.PullRequestName,
 		&pr.AuthorID,
+		&pr.TeamName,
+		&pr.Repository,
 		&pr.Status,
 		&pr.CreatedAt,
 		&pr.MergedAt,
+		&pr.ClosedAt,
+		&pr.ChangedFiles,
 	)
 
 	if err != nil {
@@ -157,44 +204,322 @@ func (r *PullRequestRepository) Merge(ctx context.Context, prID string) (*domain
 		return nil, err
 	}
 
-	// Get assigned reviewers
+	if prevStatus != domain.StatusMerged {
+		event := &domain.PREvent{PullRequestID: prID, Type: domain.EventMerged, ActorID: actorID}
+		if err := insertEvents(ctx, tx, []*domain.PREvent{event}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	// Get assigned reviewers and their verdicts
+	if err := r.loadReviewers(ctx, &pr); err != nil {
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// lockPR блокирует строку PR до конца транзакции и возвращает его статус
+func lockPR(ctx context.Context, tx pgx.Tx, prID string) (domain.PullRequestStatus, error) {
+	var status domain.PullRequestStatus
+	err := tx.QueryRow(ctx, `SELECT status FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE`, prID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", domain.ErrPRNotFound
+		}
+		return "", err
+	}
+	return status, nil
+}
+
+// checkMergeRule возвращает ErrNotApproved, если среди последних решений назначенных ревьюверов меньше
+// requiredApprovals одобрений или есть запрос изменений; как и в loadReviewers, решения до последнего
+// назначения не учитываются. При requiredApprovals == nil правило не задано
+func checkMergeRule(ctx context.Context, tx pgx.Tx, prID string, requiredApprovals *int) error {
+	if requiredApprovals == nil {
+		return nil
+	}
+
+	query := `
+		SELECT COUNT(*) FILTER (WHERE v.verdict = $2), COUNT(*) FILTER (WHERE v.verdict = $3)
+		FROM (
+			SELECT DISTINCT ON (rv.user_id) rv.verdict
+			FROM pr_reviews rv
+			INNER JOIN pr_reviewers prr ON prr.pull_request_id = rv.pull_request_id AND prr.user_id = rv.user_id
+			WHERE rv.pull_request_id = $1 AND rv.submitted_at >= prr.assigned_at
+			ORDER BY rv.user_id, rv.review_id DESC
+		) v
+	`
+
+	var approvals, changesRequested int
+	err := tx.QueryRow(ctx, query, prID, domain.VerdictApproved, domain.VerdictChangesRequested).
+		Scan(&approvals, &changesRequested)
+	if err != nil {
+		return err
+	}
+
+	if approvals < *requiredApprovals || changesRequested > 0 {
+		return domain.ErrNotApproved
+	}
+	return nil
+}
+
+// loadReviewers заполняет назначенных ревьюверов PR и их последние решения
+func (r *PullRequestRepository) loadReviewers(ctx context.Context, pr *domain.PullRequest) error {
 	reviewersQuery := `
-		SELECT user_id
+		SELECT user_id, COALESCE(fallback_team, '')
 		FROM pr_reviewers
 		WHERE pull_request_id = $1
 		ORDER BY assigned_at
 	`
 
-	rows, err := r.db.Query(ctx, reviewersQuery, prID)
+	rows, err := r.db.Query(ctx, reviewersQuery, pr.PullRequestID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
 	var reviewers []string
+	var fallbackReviewers []domain.FallbackReviewer
 	for rows.Next() {
-		var reviewerID string
-		if err := rows.Scan(&reviewerID); err != nil {
-			return nil, err
+		var reviewerID, fallbackTeam string
+		if err := rows.Scan(&reviewerID, &fallbackTeam); err != nil {
+			return err
 		}
 		reviewers = append(reviewers, reviewerID)
+		if fallbackTeam != "" {
+			fallbackReviewers = append(fallbackReviewers, domain.FallbackReviewer{UserID: reviewerID, TeamName: fallbackTeam})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
 	pr.AssignedReviewers = reviewers
+	pr.FallbackReviewers = fallbackReviewers
+
+	// Решения снятых с PR ревьюверов остаются в истории, но не показываются.
+	// При повторном назначении учитываются только решения после последнего назначения
+	reviewsQuery := `
+		SELECT DISTINCT ON (rv.user_id) rv.user_id, rv.verdict, rv.submitted_at
+		FROM pr_reviews rv
+		INNER JOIN pr_reviewers prr ON prr.pull_request_id = rv.pull_request_id AND prr.user_id = rv.user_id
+		WHERE rv.pull_request_id = $1 AND rv.submitted_at >= prr.assigned_at
+		ORDER BY rv.user_id, rv.review_id DESC
+	`
+
+	reviewRows, err := r.db.Query(ctx, reviewsQuery, pr.PullRequestID)
+	if err != nil {
+		return err
+	}
+	defer reviewRows.Close()
+
+	var reviews []domain.Review
+	for reviewRows.Next() {
+		var review domain.Review
+		if err := reviewRows.Scan(&review.UserID, &review.Verdict, &review.SubmittedAt); err != nil {
+			return err
+		}
+		reviews = append(reviews, review)
+	}
+
+	pr.Reviews = reviews
+
+	return reviewRows.Err()
+}
+
+// AddReview сохраняет решение ревьювера по PR
+func (r *PullRequestRepository) AddReview(ctx context.Context, prID string, review *domain.Review) error {
+	query := `
+		INSERT INTO pr_reviews (pull_request_id, user_id, verdict)
+		VALUES ($1, $2, $3)
+		RETURNING submitted_at
+	`
+
+	err := r.db.QueryRow(ctx, query, prID, review.UserID, review.Verdict).Scan(&review.SubmittedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return domain.ErrPRNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// OpenWithReviewers переводит PR из статуса from в OPEN и назначает ревьюверов
+func (r *PullRequestRepository) OpenWithReviewers(
+	ctx context.Context,
+	prID string,
+	from domain.PullRequestStatus,
+	reviewers []string,
+	fallbackReviewers []domain.FallbackReviewer,
+	actorID string,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	if err := openWithReviewers(ctx, tx, prID, from, reviewers, fallbackReviewers, actorID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// openWithReviewers переводит PR в OPEN и назначает ревьюверов в рамках транзакции
+func openWithReviewers(
+	ctx context.Context,
+	tx pgx.Tx,
+	prID string,
+	from domain.PullRequestStatus,
+	reviewers []string,
+	fallbackReviewers []domain.FallbackReviewer,
+	actorID string,
+) error {
+	// Условие на статус защищает от параллельной смены статуса
+	query := `
+		UPDATE pull_requests
+		SET status = $1, closed_at = NULL
+		WHERE pull_request_id = $2 AND status = $3
+	`
+
+	result, err := tx.Exec(ctx, query, domain.StatusOpen, prID, from)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrInvalidStatus
+	}
+
+	if err := insertReviewers(ctx, tx, prID, reviewers, fallbackReviewers); err != nil {
+		return err
+	}
+
+	eventType := domain.EventReopened
+	if from == domain.StatusDraft {
+		eventType = domain.EventReadyForReview
+	}
+	events := append([]*domain.PREvent{{
+		PullRequestID: prID,
+		Type:          eventType,
+		ActorID:       actorID,
+	}}, assignedEvents(prID, actorID, reviewers)...)
+	return insertEvents(ctx, tx, events)
+}
+
+// insertReviewers назначает ревьюверов PR, отмечая добранных из запасных команд
+func insertReviewers(
+	ctx context.Context,
+	tx pgx.Tx,
+	prID string,
+	reviewers []string,
+	fallbackReviewers []domain.FallbackReviewer,
+) error {
+	fallbackTeams := make(map[string]string, len(fallbackReviewers))
+	for _, fr := range fallbackReviewers {
+		fallbackTeams[fr.UserID] = fr.TeamName
+	}
+
+	query := `
+		INSERT INTO pr_reviewers (pull_request_id, user_id, fallback_team)
+		VALUES ($1, $2, NULLIF($3, ''))
+	`
+	for _, reviewerID := range reviewers {
+		if _, err := tx.Exec(ctx, query, prID, reviewerID, fallbackTeams[reviewerID]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close закрывает открытый PR или черновик без merge и освобождает ревьюверов
+func (r *PullRequestRepository) Close(ctx context.Context, prID, actorID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	query := `
+		UPDATE pull_requests
+		SET status = $1, closed_at = NOW()
+		WHERE pull_request_id = $2 AND status IN ($3, $4)
+	`
+
+	result, err := tx.Exec(ctx, query, domain.StatusClosed, prID, domain.StatusOpen, domain.StatusDraft)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrInvalidStatus
+	}
+
+	rows, err := tx.Query(ctx, `DELETE FROM pr_reviewers WHERE pull_request_id = $1 RETURNING user_id`, prID)
+	if err != nil {
+		return err
+	}
+	removed, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
 
-	return &pr, rows.Err()
+	events := []*domain.PREvent{{PullRequestID: prID, Type: domain.EventClosed, ActorID: actorID}}
+	for _, reviewerID := range removed {
+		events = append(events, &domain.PREvent{
+			PullRequestID: prID,
+			Type:          domain.EventReviewerUnassigned,
+			ActorID:       actorID,
+			UserID:        reviewerID,
+			Reason:        domain.ReasonPRClosed,
+		})
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
-// UpdateReviewers заменяет старого ревьювера на нового
-func (r *PullRequestRepository) UpdateReviewers(ctx context.Context, prID, oldReviewerID, newReviewerID string) error {
+// UpdateReviewers заменяет старого ревьювера на нового; PR блокируется и должен оставаться открытым
+func (r *PullRequestRepository) UpdateReviewers(ctx context.Context, prID, oldReviewerID, newReviewerID, actorID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	// Под блокировкой старый ревьювер не может быть снят или заменен параллельно
+	if err := lockOpenPR(ctx, tx, prID); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE pr_reviewers
-		SET user_id = $1, assigned_at = NOW()
+		SET user_id = $1, assigned_at = NOW(), fallback_team = NULL
 		WHERE pull_request_id = $2 AND user_id = $3
 	`
 
-	result, err := r.db.Exec(ctx, query, newReviewerID, prID, oldReviewerID)
+	result, err := tx.Exec(ctx, query, newReviewerID, prID, oldReviewerID)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return domain.ErrAlreadyAssigned
+		}
 		return err
 	}
 
@@ -202,20 +527,171 @@ func (r *PullRequestRepository) UpdateReviewers(ctx context.Context, prID, oldRe
 		return domain.ErrNotAssigned
 	}
 
-	return nil
+	event := &domain.PREvent{
+		PullRequestID: prID,
+		Type:          domain.EventReviewerReassigned,
+		ActorID:       actorID,
+		OldUserID:     oldReviewerID,
+		NewUserID:     newReviewerID,
+		Reason:        domain.ReasonManual,
+	}
+	if err := insertEvents(ctx, tx, []*domain.PREvent{event}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// AddReviewer назначает ревьювера на PR вручную
+func (r *PullRequestRepository) AddReviewer(
+	ctx context.Context,
+	prID, reviewerID string,
+	maxReviewers int,
+	actorID string,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	count, err := lockReviewerCount(ctx, tx, prID)
+	if err != nil {
+		return err
+	}
+	if count >= maxReviewers {
+		return domain.ErrTooManyReviewers
+	}
+
+	query := `
+		INSERT INTO pr_reviewers (pull_request_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	result, err := tx.Exec(ctx, query, prID, reviewerID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrAlreadyAssigned
+	}
+
+	event := &domain.PREvent{
+		PullRequestID: prID,
+		Type:          domain.EventReviewerAssigned,
+		ActorID:       actorID,
+		UserID:        reviewerID,
+		Reason:        domain.ReasonManual,
+	}
+	if err := insertEvents(ctx, tx, []*domain.PREvent{event}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RemoveReviewer снимает ревьювера с PR без замены; его решения остаются в истории
+func (r *PullRequestRepository) RemoveReviewer(
+	ctx context.Context,
+	prID, reviewerID string,
+	minReviewers int,
+	actorID string,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	count, err := lockReviewerCount(ctx, tx, prID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND user_id = $2`, prID, reviewerID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotAssigned
+	}
+	if count-1 < minReviewers {
+		return domain.ErrTooFewReviewers
+	}
+
+	event := &domain.PREvent{
+		PullRequestID: prID,
+		Type:          domain.EventReviewerUnassigned,
+		ActorID:       actorID,
+		UserID:        reviewerID,
+		Reason:        domain.ReasonManual,
+	}
+	if err := insertEvents(ctx, tx, []*domain.PREvent{event}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockOpenPR блокирует строку PR до конца транзакции и проверяет, что PR открыт:
+// ErrPRMerged для смерженного, ErrInvalidStatus для черновика или закрытого PR
+func lockOpenPR(ctx context.Context, tx pgx.Tx, prID string) error {
+	status, err := lockPR(ctx, tx, prID)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case domain.StatusOpen:
+		return nil
+	case domain.StatusMerged:
+		return domain.ErrPRMerged
+	default:
+		return domain.ErrInvalidStatus
+	}
+}
+
+// lockReviewerCount блокирует открытый PR до конца транзакции и возвращает число его ревьюверов,
+// чтобы параллельные ручные назначения, снятия и смена статуса не обошли проверки
+func lockReviewerCount(ctx context.Context, tx pgx.Tx, prID string) (int, error) {
+	if err := lockOpenPR(ctx, tx, prID); err != nil {
+		return 0, err
+	}
+
+	var count int
+	err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM pr_reviewers WHERE pull_request_id = $1`, prID).Scan(&count)
+	return count, err
 }
 
-// GetByReviewer возвращает все PR где пользователь назначен ревьювером
-func (r *PullRequestRepository) GetByReviewer(ctx context.Context, userID string) ([]*domain.PullRequestShort, error) {
+// GetByReviewer возвращает все PR где пользователь назначен ревьювером.
+// При excludeApproved пропускаются PR, последнее решение пользователя по которым - APPROVED
+func (r *PullRequestRepository) GetByReviewer(
+	ctx context.Context,
+	userID string,
+	excludeApproved bool,
+) ([]*domain.PullRequestShort, error) {
 	query := `
 		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status
 		FROM pull_requests pr
 		INNER JOIN pr_reviewers prr ON pr.pull_request_id = prr.pull_request_id
 		WHERE prr.user_id = $1
+		  AND (NOT $2 OR COALESCE((
+		      SELECT rv.verdict
+		      FROM pr_reviews rv
+		      WHERE rv.pull_request_id = pr.pull_request_id AND rv.user_id = $1
+		        AND rv.submitted_at >= prr.assigned_at
+		      ORDER BY rv.review_id DESC
+		      LIMIT 1
+		  ), '') <> $3)
 		ORDER BY pr.created_at DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, userID)
+	rows, err := r.db.Query(ctx, query, userID, excludeApproved, domain.VerdictApproved)
 	if err != nil {
 		return nil, err
 	}
@@ -253,3 +729,105 @@ func (r *PullRequestRepository) Exists(ctx context.Context, prID string) (bool,
 
 	return exists, nil
 }
+
+// GetOpenByReviewers возвращает открытые PR, где ревьювером назначен хотя бы один из пользователей
+func (r *PullRequestRepository) GetOpenByReviewers(ctx context.Context, userIDs []string) ([]*domain.PullRequest, error) {
+	query := `
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, COALESCE(pr.team_name, ''), pr.status,
+		       pr.created_at, pr.merged_at, array_agg(prr.user_id ORDER BY prr.assigned_at)
+		FROM pull_requests pr
+		INNER JOIN pr_reviewers prr ON pr.pull_request_id = prr.pull_request_id
+		WHERE pr.status = $1
+		  AND pr.pull_request_id IN (
+		      SELECT pull_request_id FROM pr_reviewers WHERE user_id = ANY($2)
+		  )
+		GROUP BY pr.pull_request_id
+		ORDER BY pr.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, domain.StatusOpen, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prs []*domain.PullRequest
+	for rows.Next() {
+		var pr domain.PullRequest
+		if err := rows.Scan(
+			&pr.PullRequestID,
+			&pr.PullRequestName,
+			&pr.AuthorID,
+			&pr.TeamName,
+			&pr.Status,
+			&pr.CreatedAt,
+			&pr.MergedAt,
+			&pr.AssignedReviewers,
+		); err != nil {
+			return nil, err
+		}
+		prs = append(prs, &pr)
+	}
+
+	return prs, rows.Err()
+}
+
+// GetOpenByAuthors возвращает открытые PR указанных авторов
+func (r *PullRequestRepository) GetOpenByAuthors(ctx context.Context, userIDs []string) ([]*domain.PullRequestShort, error) {
+	query := `
+		SELECT pull_request_id, pull_request_name, author_id, status
+		FROM pull_requests
+		WHERE status = $1 AND author_id = ANY($2)
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, domain.StatusOpen, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prs := []*domain.PullRequestShort{}
+	for rows.Next() {
+		var pr domain.PullRequestShort
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status); err != nil {
+			return nil, err
+		}
+		prs = append(prs, &pr)
+	}
+
+	return prs, rows.Err()
+}
+
+// CountOpenReviews возвращает число открытых PR на ревью у каждого из пользователей
+func (r *PullRequestRepository) CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error) {
+	query := `
+		SELECT prr.user_id, COUNT(*)
+		FROM pr_reviewers prr
+		INNER JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id
+		WHERE pr.status = $1 AND prr.user_id = ANY($2)
+		GROUP BY prr.user_id
+	`
+
+	rows, err := r.db.Query(ctx, query, domain.StatusOpen, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Пользователи без открытых ревью не попадают в выборку и получают 0
+	counts := make(map[string]int, len(userIDs))
+	for _, userID := range userIDs {
+		counts[userID] = 0
+	}
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		counts[userID] = count
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// SessionRepository реализует repository.SessionRepository для PostgreSQL
+type SessionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSessionRepository создает новый экземпляр SessionRepository
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create открывает сессию пользователя с refresh токеном, действующую ttl
+func (r *SessionRepository) Create(
+	ctx context.Context,
+	userID, refreshTokenHash string,
+	ttl time.Duration,
+) (*domain.Session, error) {
+	query := `
+		INSERT INTO auth_sessions (user_id, refresh_token_hash, expires_at)
+		VALUES ($1, $2, NOW() + $3::float8 * INTERVAL '1 second')
+		RETURNING session_id, user_id, expires_at
+	`
+
+	session, err := scanSession(r.db.QueryRow(ctx, query, userID, refreshTokenHash, ttl.Seconds()))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Rotate заменяет refresh токен активной сессии новым. Предъявление уже замененного токена
+// означает, что он мог быть украден, поэтому сессия отзывается
+func (r *SessionRepository) Rotate(ctx context.Context, refreshTokenHash, newTokenHash string) (*domain.Session, error) {
+	query := `
+		UPDATE auth_sessions
+		SET previous_token_hash = refresh_token_hash,
+		    refresh_token_hash = $2,
+		    refreshed_at = NOW()
+		WHERE refresh_token_hash = $1
+		  AND revoked_at IS NULL
+		  AND expires_at > NOW()
+		RETURNING session_id, user_id, expires_at
+	`
+
+	session, err := scanSession(r.db.QueryRow(ctx, query, refreshTokenHash, newTokenHash))
+	if err != domain.ErrTokenNotFound {
+		return session, err
+	}
+
+	reuseQuery := `
+		UPDATE auth_sessions
+		SET revoked_at = NOW()
+		WHERE previous_token_hash = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.Exec(ctx, reuseQuery, refreshTokenHash); err != nil {
+		return nil, err
+	}
+
+	return nil, domain.ErrTokenNotFound
+}
+
+// RevokeByRefreshToken отзывает активную сессию по ее refresh токену
+func (r *SessionRepository) RevokeByRefreshToken(ctx context.Context, refreshTokenHash string) (*domain.Session, error) {
+	query := `
+		UPDATE auth_sessions
+		SET revoked_at = NOW()
+		WHERE refresh_token_hash = $1
+		  AND revoked_at IS NULL
+		  AND expires_at > NOW()
+		RETURNING session_id, user_id, expires_at
+	`
+
+	return scanSession(r.db.QueryRow(ctx, query, refreshTokenHash))
+}
+
+// RevokeAllForUser отзывает все активные сессии пользователя
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	return revokeSessions(ctx, r.db, []string{userID})
+}
+
+// IsActive проверяет, что сессия не отозвана и не истекла
+func (r *SessionRepository) IsActive(ctx context.Context, sessionID int64) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM auth_sessions
+			WHERE session_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`
+
+	var active bool
+	err := r.db.QueryRow(ctx, query, sessionID).Scan(&active)
+	return active, err
+}
+
+// sessionExecutor выполняет запрос как в пуле, так и в транзакции
+type sessionExecutor interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// revokeSessions отзывает активные сессии пользователей. Вызывается и в транзакции деактивации,
+// чтобы деактивированный пользователь терял доступ вместе со сменой статуса
+func revokeSessions(ctx context.Context, db sessionExecutor, userIDs []string) error {
+	query := `
+		UPDATE auth_sessions
+		SET revoked_at = NOW()
+		WHERE user_id = ANY($1) AND revoked_at IS NULL
+	`
+
+	_, err := db.Exec(ctx, query, userIDs)
+	return err
+}
+
+// scanSession читает сессию из строки session_id, user_id, expires_at
+func scanSession(row pgx.Row) (*domain.Session, error) {
+	var session domain.Session
+	if err := row.Scan(&session.SessionID, &session.UserID, &session.ExpiresAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+)
+
+// SigningKeyRepository реализует repository.SigningKeyRepository для PostgreSQL
+type SigningKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSigningKeyRepository создает новый экземпляр SigningKeyRepository
+func NewSigningKeyRepository(db *pgxpool.Pool) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+// ListValid возвращает ключи, пригодные для проверки токенов, начиная с самого нового
+func (r *SigningKeyRepository) ListValid(ctx context.Context) ([]*domain.SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, public_key, created_at, expires_at
+		FROM jwt_signing_keys
+		WHERE expires_at IS NULL OR expires_at > NOW()
+		ORDER BY created_at DESC, kid
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.SigningKey, error) {
+		var key domain.SigningKey
+		err := row.Scan(&key.KeyID, &key.Algorithm, &key.PrivateKey, &key.PublicKey, &key.CreatedAt, &key.ExpiresAt)
+		return &key, err
+	})
+}
+
+// RotationDue проверяет, что текущего ключа алгоритма нет или он старше interval
+func (r *SigningKeyRepository) RotationDue(
+	ctx context.Context,
+	algorithm domain.SigningAlgorithm,
+	interval time.Duration,
+) (bool, error) {
+	return rotationDue(ctx, r.db, algorithm, interval)
+}
+
+// Rotate сохраняет новый ключ подписи, если за последние interval не появилось ключа того же алгоритма.
+// Таблица блокируется, чтобы несколько экземпляров сервиса не ротировали ключ одновременно
+func (r *SigningKeyRepository) Rotate(
+	ctx context.Context,
+	key *domain.SigningKey,
+	interval, overlap time.Duration,
+) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	if _, err := tx.Exec(ctx, `LOCK TABLE jwt_signing_keys IN EXCLUSIVE MODE`); err != nil {
+		return false, err
+	}
+
+	due, err := rotationDue(ctx, tx, key.Algorithm, interval)
+	if err != nil || !due {
+		return false, err
+	}
+
+	// Прежние ключи проверяют уже выданные токены еще overlap, истекшие удаляются
+	retireQuery := `
+		UPDATE jwt_signing_keys
+		SET expires_at = NOW() + $1::float8 * INTERVAL '1 second'
+		WHERE expires_at IS NULL
+	`
+	if _, err := tx.Exec(ctx, retireQuery, overlap.Seconds()); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM jwt_signing_keys WHERE expires_at <= NOW()`); err != nil {
+		return false, err
+	}
+
+	insertQuery := `
+		INSERT INTO jwt_signing_keys (kid, algorithm, private_key, public_key)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+	err = tx.QueryRow(ctx, insertQuery, key.KeyID, key.Algorithm, key.PrivateKey, key.PublicKey).Scan(&key.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// rowQuerier выполняет запрос как в пуле, так и в транзакции
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// rotationDue проверяет срок ротации по часам БД, в которых записано created_at
+func rotationDue(
+	ctx context.Context,
+	db rowQuerier,
+	algorithm domain.SigningAlgorithm,
+	interval time.Duration,
+) (bool, error) {
+	query := `
+		SELECT NOT EXISTS(
+			SELECT 1 FROM jwt_signing_keys
+			WHERE algorithm = $1
+			  AND expires_at IS NULL
+			  AND created_at > NOW() - $2::float8 * INTERVAL '1 second'
+		)
+	`
+
+	var due bool
+	err := db.QueryRow(ctx, query, algorithm, interval.Seconds()).Scan(&due)
+	return due, err
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"errors"
+	"slices"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -41,20 +43,23 @@ func (r *TeamRepository) Create(ctx context.Context, teamName string) error {
 // GetByName получает команду со всеми участниками
 func (r *TeamRepository) GetByName(ctx context.Context, teamName string) (*domain.Team, error) {
 	// First check if team exists
-	exists, err := r.Exists(ctx, teamName)
+	var archivedAt *time.Time
+	err := r.db.QueryRow(ctx, `SELECT archived_at FROM teams WHERE team_name = $1`, teamName).Scan(&archivedAt)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrTeamNotFound
+		}
 		return nil, err
 	}
-	if !exists {
-		return nil, domain.ErrTeamNotFound
-	}
 
-	// Get all team members
+	// Роль участника - его роль в этой команде: лид другой команды здесь обычный участник
 	query := `
-		SELECT user_id, username, is_active
-		FROM users
-		WHERE team_name = $1
-		ORDER BY user_id
+		SELECT u.user_id, u.username, u.is_active,
+		       CASE WHEN u.role = 'team_lead' AND NOT tm.is_lead THEN 'member' ELSE u.role END
+		FROM team_members tm
+		INNER JOIN users u ON u.user_id = tm.user_id
+		WHERE tm.team_name = $1
+		ORDER BY u.user_id
 	`
 
 	rows, err := r.db.Query(ctx, query, teamName)
@@ -66,7 +71,7 @@ func (r *TeamRepository) GetByName(ctx context.Context, teamName string) (*domai
 	var members []domain.TeamMember
 	for rows.Next() {
 		var member domain.TeamMember
-		if err := rows.Scan(&member.UserID, &member.Username, &member.IsActive); err != nil {
+		if err := rows.Scan(&member.UserID, &member.Username, &member.IsActive, &member.Role); err != nil {
 			return nil, err
 		}
 		members = append(members, member)
@@ -77,8 +82,9 @@ func (r *TeamRepository) GetByName(ctx context.Context, teamName string) (*domai
 	}
 
 	team := &domain.Team{
-		TeamName: teamName,
-		Members:  members,
+		TeamName:   teamName,
+		Members:    members,
+		ArchivedAt: archivedAt,
 	}
 
 	return team, nil
@@ -99,3 +105,325 @@ func (r *TeamRepository) Exists(ctx context.Context, teamName string) (bool, err
 
 	return exists, nil
 }
+
+// GetSettings возвращает настройки команды (значения по умолчанию для незаданных параметров).
+// Стратегия по умолчанию задается конфигурацией, поэтому для нее возвращается пустое значение
+func (r *TeamRepository) GetSettings(ctx context.Context, teamName string) (*domain.TeamSettings, error) {
+	query := `
+		SELECT t.team_name, COALESCE(ts.reviewer_strategy, ''),
+		       COALESCE(ts.min_reviewers, $2), COALESCE(ts.max_reviewers, $3),
+		       ts.required_approvals, ts.max_open_reviews,
+		       ARRAY(SELECT f.fallback_team FROM team_fallback_teams f WHERE f.team_name = t.team_name ORDER BY f.position)
+		FROM teams t
+		LEFT JOIN team_settings ts ON ts.team_name = t.team_name
+		WHERE t.team_name = $1
+	`
+
+	var settings domain.TeamSettings
+	err := r.db.QueryRow(ctx, query, teamName, domain.DefaultMinReviewers, domain.DefaultMaxReviewers).Scan(
+		&settings.TeamName,
+		&settings.ReviewerStrategy,
+		&settings.MinReviewers,
+		&settings.MaxReviewers,
+		&settings.RequiredApprovals,
+		&settings.MaxOpenReviews,
+		&settings.FallbackTeams,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrTeamNotFound
+		}
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// SetReviewerStrategy сохраняет стратегию выбора ревьюверов для команды
+func (r *TeamRepository) SetReviewerStrategy(ctx context.Context, teamName string, strategy domain.ReviewerStrategy) error {
+	query := `
+		INSERT INTO team_settings (team_name, reviewer_strategy)
+		VALUES ($1, $2)
+		ON CONFLICT (team_name) DO UPDATE
+		SET reviewer_strategy = EXCLUDED.reviewer_strategy,
+		    updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(ctx, query, teamName, strategy)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return domain.ErrTeamNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SetReviewerLimits сохраняет минимальное и максимальное число ревьюверов для команды
+func (r *TeamRepository) SetReviewerLimits(ctx context.Context, teamName string, minReviewers, maxReviewers int) error {
+	query := `
+		INSERT INTO team_settings (team_name, min_reviewers, max_reviewers)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (team_name) DO UPDATE
+		SET min_reviewers = EXCLUDED.min_reviewers,
+		    max_reviewers = EXCLUDED.max_reviewers,
+		    updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(ctx, query, teamName, minReviewers, maxReviewers)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return domain.ErrTeamNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SetRequiredApprovals сохраняет правило merge для команды (nil - правило выключено)
+func (r *TeamRepository) SetRequiredApprovals(ctx context.Context, teamName string, requiredApprovals *int) error {
+	query := `
+		INSERT INTO team_settings (team_name, required_approvals)
+		VALUES ($1, $2)
+		ON CONFLICT (team_name) DO UPDATE
+		SET required_approvals = EXCLUDED.required_approvals,
+		    updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(ctx, query, teamName, requiredApprovals)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return domain.ErrTeamNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SetMaxOpenReviews сохраняет лимит открытых ревью участника команды по умолчанию (nil - без ограничения)
+func (r *TeamRepository) SetMaxOpenReviews(ctx context.Context, teamName string, limit *int) error {
+	query := `
+		INSERT INTO team_settings (team_name, max_open_reviews)
+		VALUES ($1, $2)
+		ON CONFLICT (team_name) DO UPDATE
+		SET max_open_reviews = EXCLUDED.max_open_reviews,
+		    updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(ctx, query, teamName, limit)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return domain.ErrTeamNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SetFallbackTeams заменяет список запасных команд; порядок в списке - порядок обращения к ним
+func (r *TeamRepository) SetFallbackTeams(ctx context.Context, teamName string, fallbackTeams []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	// Блокируем команду, чтобы параллельные замены списка не смешались
+	var locked string
+	err = tx.QueryRow(ctx, `SELECT team_name FROM teams WHERE team_name = $1 FOR UPDATE`, teamName).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrTeamNotFound
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM team_fallback_teams WHERE team_name = $1`, teamName); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO team_fallback_teams (team_name, fallback_team, position)
+		SELECT $1, f.team, f.position
+		FROM UNNEST($2::text[]) WITH ORDINALITY AS f(team, position)
+	`
+	if _, err := tx.Exec(ctx, query, teamName, fallbackTeams); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return domain.ErrTeamNotFound
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListNames возвращает страницу названий команд по алфавиту и общее число команд;
+// непустой teamName оставляет только эту команду
+func (r *TeamRepository) ListNames(ctx context.Context, teamName string, offset, limit int) ([]string, int, error) {
+	var total int
+	err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM teams WHERE $1 = '' OR team_name = $1`, teamName,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT team_name
+		FROM teams
+		WHERE $1 = '' OR team_name = $1
+		ORDER BY team_name
+		OFFSET $2 LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, teamName, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, 0, err
+		}
+		names = append(names, name)
+	}
+
+	return names, total, rows.Err()
+}
+
+// Archive в одной транзакции применяет изменения участников и помечает команду архивной
+func (r *TeamRepository) Archive(ctx context.Context, removal *domain.TeamRemoval) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	query := `
+		UPDATE teams
+		SET archived_at = NOW()
+		WHERE team_name = $1 AND archived_at IS NULL
+	`
+
+	result, err := tx.Exec(ctx, query, removal.TeamName)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrTeamNotFound
+	}
+
+	if err := applyTeamRemoval(ctx, tx, removal); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete в одной транзакции применяет изменения участников и удаляет команду; настройки и членство удаляются
+// каскадно. Участники, для которых команда была основной, получают основной другую свою команду,
+// а без других команд остаются без команды (ON DELETE SET NULL)
+func (r *TeamRepository) Delete(ctx context.Context, removal *domain.TeamRemoval) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	if err := applyTeamRemoval(ctx, tx, removal); err != nil {
+		return err
+	}
+
+	// Участники запоминаются до удаления: после него их основная команда уже сброшена в NULL
+	var memberIDs []string
+	err = tx.QueryRow(ctx,
+		`SELECT ARRAY(SELECT user_id FROM users WHERE team_name = $1)`, removal.TeamName,
+	).Scan(&memberIDs)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM teams WHERE team_name = $1`, removal.TeamName)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrTeamNotFound
+	}
+
+	primaryQuery := `
+		UPDATE users u
+		SET team_name = (SELECT MIN(tm.team_name) FROM team_members tm WHERE tm.user_id = u.user_id),
+		    updated_at = NOW()
+		WHERE u.user_id = ANY($1)
+	`
+
+	if _, err := tx.Exec(ctx, primaryQuery, memberIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// applyTeamRemoval переводит, деактивирует и исключает участников команды, применяет замены ревьюверов
+// и записывает их в историю PR
+func applyTeamRemoval(ctx context.Context, tx pgx.Tx, removal *domain.TeamRemoval) error {
+	if len(removal.Moved) > 0 {
+		if err := joinTeam(ctx, tx, removal.MoveTo, removal.Moved); err != nil {
+			return err
+		}
+		if err := leaveTeam(ctx, tx, removal.TeamName, removal.MoveTo, removal.Moved); err != nil {
+			return err
+		}
+	}
+
+	if len(removal.Detached) > 0 {
+		if err := leaveTeam(ctx, tx, removal.TeamName, "", removal.Detached); err != nil {
+			return err
+		}
+	}
+
+	if len(removal.Deactivated) > 0 {
+		if err := deactivateUsers(ctx, tx, removal.Deactivated); err != nil {
+			return err
+		}
+	}
+
+	applied, err := applyReassignments(ctx, tx, removal.Reassignments)
+	if err != nil {
+		return err
+	}
+
+	var deactivated, detached []*domain.ReviewerReassignment
+	for _, ra := range applied {
+		if slices.Contains(removal.Deactivated, ra.OldUserID) {
+			deactivated = append(deactivated, ra)
+		} else {
+			detached = append(detached, ra)
+		}
+	}
+
+	events := deactivationEvents(deactivated, removal.ActorID)
+	for _, ra := range detached {
+		events = append(events, reassignmentEvent(ra, removal.ActorID, domain.ReasonTeamChanged))
+	}
+	return insertEvents(ctx, tx, events)
+}
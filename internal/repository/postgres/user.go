@@ -3,8 +3,11 @@ package postgres
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/aidar/avito-pr-project/internal/domain"
@@ -20,26 +23,51 @@ func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-// CreateOrUpdate создает нового пользователя или обновляет существующего
+// CreateOrUpdate создает нового пользователя или обновляет существующего и добавляет его в команду user.TeamName.
+// Остальные команды пользователя сохраняются, основная команда меняется, только если ее не было.
+// С ролью team_lead пользователь становится лидом команды user.TeamName
 func (r *UserRepository) CreateOrUpdate(ctx context.Context, user *domain.User) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
 	query := `
-		INSERT INTO users (user_id, username, team_name, is_active)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO users (user_id, username, team_name, is_active, role)
+		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (user_id) DO UPDATE
 		SET username = EXCLUDED.username,
-		    team_name = EXCLUDED.team_name,
+		    team_name = COALESCE(users.team_name, EXCLUDED.team_name),
 		    is_active = EXCLUDED.is_active,
+		    role = EXCLUDED.role,
 		    updated_at = NOW()
 	`
 
-	_, err := r.db.Exec(ctx, query, user.UserID, user.Username, user.TeamName, user.IsActive)
-	return err
+	if _, err := tx.Exec(ctx, query, user.UserID, user.Username, user.TeamName, user.IsActive, user.Role); err != nil {
+		return err
+	}
+
+	if err := joinTeam(ctx, tx, user.TeamName, []string{user.UserID}); err != nil {
+		return err
+	}
+
+	if err := setTeamLead(ctx, tx, user.TeamName, []string{user.UserID}, user.Role == domain.RoleTeamLead); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 // GetByID получает пользователя по ID
 func (r *UserRepository) GetByID(ctx context.Context, userID string) (*domain.User, error) {
 	query := `
-		SELECT user_id, username, team_name, is_active
+		SELECT user_id, username, COALESCE(team_name, ''),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = users.user_id ORDER BY tm.team_name),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = users.user_id AND tm.is_lead ORDER BY tm.team_name),
+		       is_active, role
 		FROM users
 		WHERE user_id = $1
 	`
@@ -49,7 +77,10 @@ func (r *UserRepository) GetByID(ctx context.Context, userID string) (*domain.Us
 		&user.UserID,
 		&user.Username,
 		&user.TeamName,
+		&user.Teams,
+		&user.LeadTeams,
 		&user.IsActive,
+		&user.Role,
 	)
 
 	if err != nil {
@@ -62,33 +93,86 @@ func (r *UserRepository) GetByID(ctx context.Context, userID string) (*domain.Us
 	return &user, nil
 }
 
-// SetIsActive обновляет статус активности пользователя
-func (r *UserRepository) SetIsActive(ctx context.Context, userID string, isActive bool) error {
+// SetIsActive обновляет статус активности пользователя и отмечает изменение
+// в истории открытых PR, где пользователь назначен ревьювером. При деактивации отзывает его сессии
+func (r *UserRepository) SetIsActive(ctx context.Context, userID string, isActive bool, actorID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	var wasActive bool
+	err = tx.QueryRow(ctx, `SELECT is_active FROM users WHERE user_id = $1 FOR UPDATE`, userID).Scan(&wasActive)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		return err
+	}
+
 	query := `
 		UPDATE users
 		SET is_active = $1, updated_at = NOW()
 		WHERE user_id = $2
 	`
 
-	result, err := r.db.Exec(ctx, query, isActive, userID)
-	if err != nil {
+	if _, err := tx.Exec(ctx, query, isActive, userID); err != nil {
 		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		return domain.ErrUserNotFound
+	// Повторная установка того же значения в историю не попадает
+	if wasActive != isActive {
+		if err := insertActivityEvents(ctx, tx, userID, isActive, actorID); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	// Деактивированный пользователь сразу теряет доступ: его сессии отзываются
+	if !isActive {
+		if err := revokeSessions(ctx, tx, []string{userID}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
-// GetActiveTeamMembers возвращает всех активных пользователей команды, исключая указанного
+// insertActivityEvents отмечает изменение активности пользователя в истории открытых PR, где он назначен ревьювером
+func insertActivityEvents(ctx context.Context, tx pgx.Tx, userID string, isActive bool, actorID string) error {
+	reason := domain.ReasonUserDeactivated
+	if isActive {
+		reason = domain.ReasonUserActivated
+	}
+
+	query := `
+		INSERT INTO pr_events (pull_request_id, event_type, actor_id, user_id, reason)
+		SELECT prr.pull_request_id, $1, NULLIF($2, ''), prr.user_id, $3
+		FROM pr_reviewers prr
+		INNER JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id
+		WHERE prr.user_id = $4 AND pr.status = $5
+	`
+
+	_, err := tx.Exec(ctx, query, domain.EventReviewerActivityChanged, actorID, reason, userID, domain.StatusOpen)
+	return err
+}
+
+// GetActiveTeamMembers возвращает всех активных пользователей команды, исключая указанного и отсутствующих сегодня.
+// Участники архивной команды ревьюверами не назначаются, поэтому для нее список пуст
 func (r *UserRepository) GetActiveTeamMembers(ctx context.Context, teamName, excludeUserID string) ([]*domain.User, error) {
 	query := `
-		SELECT user_id, username, team_name, is_active
-		FROM users
-		WHERE team_name = $1 AND is_active = true AND user_id != $2
-		ORDER BY user_id
+		SELECT u.user_id, u.username, tm.team_name, u.is_active
+		FROM team_members tm
+		INNER JOIN users u ON u.user_id = tm.user_id
+		INNER JOIN teams t ON t.team_name = tm.team_name
+		WHERE tm.team_name = $1 AND u.is_active = true AND u.user_id != $2 AND t.archived_at IS NULL
+		  AND NOT EXISTS (
+		      SELECT 1 FROM user_absences a
+		      WHERE a.user_id = u.user_id AND CURRENT_DATE BETWEEN a.start_date AND a.end_date
+		  )
+		ORDER BY u.user_id
 	`
 
 	rows, err := r.db.Query(ctx, query, teamName, excludeUserID)
@@ -112,10 +196,11 @@ func (r *UserRepository) GetActiveTeamMembers(ctx context.Context, teamName, exc
 // GetTeamMembers возвращает всех пользователей команды
 func (r *UserRepository) GetTeamMembers(ctx context.Context, teamName string) ([]*domain.User, error) {
 	query := `
-		SELECT user_id, username, team_name, is_active
-		FROM users
-		WHERE team_name = $1
-		ORDER BY user_id
+		SELECT u.user_id, u.username, tm.team_name, u.is_active
+		FROM team_members tm
+		INNER JOIN users u ON u.user_id = tm.user_id
+		WHERE tm.team_name = $1
+		ORDER BY u.user_id
 	`
 
 	rows, err := r.db.Query(ctx, query, teamName)
@@ -135,3 +220,1036 @@ func (r *UserRepository) GetTeamMembers(ctx context.Context, teamName string) ([
 
 	return users, rows.Err()
 }
+
+// DeactivateWithReassignments в одной транзакции деактивирует пользователей, отзывает их сессии,
+// применяет замены ревьюверов на открытых PR и записывает их в историю PR
+func (r *UserRepository) DeactivateWithReassignments(
+	ctx context.Context,
+	userIDs []string,
+	reassignments []*domain.ReviewerReassignment,
+	actorID string,
+) ([]*domain.ReviewerReassignment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	if err := deactivateUsers(ctx, tx, userIDs); err != nil {
+		return nil, err
+	}
+
+	applied, err := applyReassignments(ctx, tx, reassignments)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertEvents(ctx, tx, deactivationEvents(applied, actorID)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// deactivateUsers деактивирует пользователей и отзывает их сессии
+func deactivateUsers(ctx context.Context, tx pgx.Tx, userIDs []string) error {
+	query := `
+		UPDATE users
+		SET is_active = false, updated_at = NOW()
+		WHERE user_id = ANY($1)
+	`
+
+	result, err := tx.Exec(ctx, query, userIDs)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() != int64(len(userIDs)) {
+		return domain.ErrUserNotFound
+	}
+
+	return revokeSessions(ctx, tx, userIDs)
+}
+
+// ChangeMembership в одной транзакции исключает пользователей из команды fromTeam и добавляет в команду toTeam
+// (пустое название - без исключения или без добавления), применяет замены ревьюверов на открытых PR
+// и записывает их в историю PR
+func (r *UserRepository) ChangeMembership(
+	ctx context.Context,
+	userIDs []string,
+	fromTeam, toTeam string,
+	reassignments []*domain.ReviewerReassignment,
+	actorID string,
+) ([]*domain.ReviewerReassignment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	if toTeam != "" {
+		if err := joinTeam(ctx, tx, toTeam, userIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if fromTeam != "" {
+		if err := leaveTeam(ctx, tx, fromTeam, toTeam, userIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	applied, err := applyReassignments(ctx, tx, reassignments)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*domain.PREvent, 0, len(applied))
+	for _, ra := range applied {
+		events = append(events, reassignmentEvent(ra, actorID, domain.ReasonTeamChanged))
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// RemoveTeamMembers в одной транзакции исключает пользователей из команды; не состоящие в ней пропускаются.
+// Исключенные из последней команды переходят в defaultTeam
+func (r *UserRepository) RemoveTeamMembers(ctx context.Context, teamName string, userIDs []string, defaultTeam string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	if err := lockTeam(ctx, tx, teamName); err != nil {
+		return err
+	}
+
+	if err := removeFromTeam(ctx, tx, teamName, userIDs, defaultTeam); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReplaceTeamMembers в одной транзакции добавляет пользователей в команду и исключает из нее остальных участников.
+// Исключенные из последней команды переходят в defaultTeam
+func (r *UserRepository) ReplaceTeamMembers(ctx context.Context, teamName string, userIDs []string, defaultTeam string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	if err := lockTeam(ctx, tx, teamName); err != nil {
+		return err
+	}
+
+	if len(userIDs) > 0 {
+		if err := joinTeam(ctx, tx, teamName, userIDs); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.Query(ctx,
+		`SELECT user_id FROM team_members WHERE team_name = $1 AND user_id <> ALL(COALESCE($2::varchar[], '{}')) ORDER BY user_id`,
+		teamName, userIDs)
+	if err != nil {
+		return err
+	}
+	removing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	if err := removeFromTeam(ctx, tx, teamName, removing, defaultTeam); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockTeam блокирует команду до конца транзакции, чтобы параллельные изменения ее состава не смешались
+func lockTeam(ctx context.Context, tx pgx.Tx, teamName string) error {
+	var locked string
+	err := tx.QueryRow(ctx, `SELECT team_name FROM teams WHERE team_name = $1 FOR UPDATE`, teamName).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrTeamNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// removeFromTeam исключает из команды тех пользователей, кто в ней состоит. Для кого она последняя,
+// тот переходит в defaultTeam; если defaultTeam пуста или совпадает с командой - ErrTeamRequired.
+// Строки пользователей блокируются, чтобы последняя команда определялась по актуальному составу
+func removeFromTeam(ctx context.Context, tx pgx.Tx, teamName string, userIDs []string, defaultTeam string) error {
+	query := `
+		SELECT u.user_id,
+		       NOT EXISTS (
+		           SELECT 1 FROM team_members o WHERE o.user_id = u.user_id AND o.team_name <> $1
+		       )
+		FROM users u
+		INNER JOIN team_members tm ON tm.user_id = u.user_id AND tm.team_name = $1
+		WHERE u.user_id = ANY($2)
+		ORDER BY u.user_id
+		FOR UPDATE OF u
+	`
+
+	rows, err := tx.Query(ctx, query, teamName, userIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var removing, lastTeam []string
+	for rows.Next() {
+		var userID string
+		var last bool
+		if err := rows.Scan(&userID, &last); err != nil {
+			return err
+		}
+		removing = append(removing, userID)
+		if last {
+			lastTeam = append(lastTeam, userID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(removing) == 0 {
+		return nil
+	}
+
+	if len(lastTeam) > 0 {
+		if defaultTeam == "" || defaultTeam == teamName {
+			return domain.ErrTeamRequired
+		}
+		if err := joinTeam(ctx, tx, defaultTeam, lastTeam); err != nil {
+			return err
+		}
+	}
+
+	return leaveTeam(ctx, tx, teamName, "", removing)
+}
+
+// AddTeamMembers в одной транзакции добавляет пользователей в команду: новые пользователи создаются,
+// существующие становятся участниками команды без изменения остальных атрибутов и других команд.
+// Пользователи с ролью team_lead становятся лидами команды; существующий member при этом получает роль team_lead
+func (r *UserRepository) AddTeamMembers(ctx context.Context, teamName string, users []*domain.User) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	query := `
+		INSERT INTO users (user_id, username, team_name, is_active, role)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO NOTHING
+	`
+
+	userIDs := make([]string, 0, len(users))
+	var leadIDs []string
+	for _, user := range users {
+		if _, err := tx.Exec(ctx, query, user.UserID, user.Username, teamName, user.IsActive, user.Role); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+				return domain.ErrTeamNotFound
+			}
+			return err
+		}
+		userIDs = append(userIDs, user.UserID)
+		if user.Role == domain.RoleTeamLead {
+			leadIDs = append(leadIDs, user.UserID)
+		}
+	}
+
+	if err := joinTeam(ctx, tx, teamName, userIDs); err != nil {
+		return err
+	}
+
+	if len(leadIDs) > 0 {
+		if err := setTeamLead(ctx, tx, teamName, leadIDs, true); err != nil {
+			return err
+		}
+
+		promoteQuery := `
+			UPDATE users
+			SET role = 'team_lead', updated_at = NOW()
+			WHERE user_id = ANY($1) AND role = 'member'
+		`
+		if _, err := tx.Exec(ctx, promoteQuery, leadIDs); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// joinTeam добавляет пользователей в команду; у кого нет основной команды, для того она становится основной
+func joinTeam(ctx context.Context, tx pgx.Tx, teamName string, userIDs []string) error {
+	query := `
+		INSERT INTO team_members (team_name, user_id)
+		SELECT $1, unnest($2::varchar[])
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := tx.Exec(ctx, query, teamName, userIDs); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			if pgErr.ConstraintName == "team_members_user_id_fkey" {
+				return domain.ErrUserNotFound
+			}
+			return domain.ErrTeamNotFound
+		}
+		return err
+	}
+
+	primaryQuery := `
+		UPDATE users
+		SET team_name = $1, updated_at = NOW()
+		WHERE user_id = ANY($2) AND team_name IS NULL
+	`
+
+	_, err := tx.Exec(ctx, primaryQuery, teamName, userIDs)
+	return err
+}
+
+// setTeamLead отмечает пользователей лидами команды или снимает отметку
+func setTeamLead(ctx context.Context, tx pgx.Tx, teamName string, userIDs []string, isLead bool) error {
+	query := `
+		UPDATE team_members
+		SET is_lead = $3
+		WHERE team_name = $1 AND user_id = ANY($2)
+	`
+
+	_, err := tx.Exec(ctx, query, teamName, userIDs, isLead)
+	return err
+}
+
+// leaveTeam исключает пользователей из команды; если кого-то из них нет в команде, никто не исключается.
+// Основной командой тех, для кого она была основной, становится replacement, иначе другая их команда (если есть)
+func leaveTeam(ctx context.Context, tx pgx.Tx, teamName, replacement string, userIDs []string) error {
+	result, err := tx.Exec(ctx, `DELETE FROM team_members WHERE team_name = $1 AND user_id = ANY($2)`, teamName, userIDs)
+	if err != nil {
+		return err
+	}
+
+	unique := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		unique[id] = true
+	}
+	if result.RowsAffected() != int64(len(unique)) {
+		return domain.ErrUserNotFound
+	}
+
+	primaryQuery := `
+		UPDATE users u
+		SET team_name = COALESCE(NULLIF($3, ''), (
+		        SELECT MIN(tm.team_name) FROM team_members tm WHERE tm.user_id = u.user_id
+		    )),
+		    updated_at = NOW()
+		WHERE u.user_id = ANY($2) AND u.team_name = $1
+	`
+
+	_, err = tx.Exec(ctx, primaryQuery, teamName, userIDs, replacement)
+	return err
+}
+
+// applyReassignments применяет замены ревьюверов одним батчем, чтобы уложиться в один round-trip.
+// План составляется до транзакции, поэтому проверяется заново: замены на PR, которые уже не открыты,
+// и замены ревьюверов, снятых или замененных параллельным запросом, пропускаются. Возвращает примененные замены
+func applyReassignments(
+	ctx context.Context,
+	tx pgx.Tx,
+	reassignments []*domain.ReviewerReassignment,
+) ([]*domain.ReviewerReassignment, error) {
+	applied := make([]*domain.ReviewerReassignment, 0, len(reassignments))
+	if len(reassignments) == 0 {
+		return applied, nil
+	}
+
+	prIDs := make([]string, 0, len(reassignments))
+	for _, ra := range reassignments {
+		prIDs = append(prIDs, ra.PullRequestID)
+	}
+
+	// Открытые PR блокируются, чтобы их не смержили и не закрыли до конца транзакции
+	openQuery := `
+		SELECT pull_request_id
+		FROM pull_requests
+		WHERE pull_request_id = ANY($1) AND status = $2
+		ORDER BY pull_request_id
+		FOR UPDATE
+	`
+	rows, err := tx.Query(ctx, openQuery, prIDs, domain.StatusOpen)
+	if err != nil {
+		return nil, err
+	}
+	openIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	replaceQuery := `
+		UPDATE pr_reviewers
+		SET user_id = $1, assigned_at = NOW(), fallback_team = NULL
+		WHERE pull_request_id = $2 AND user_id = $3
+	`
+	removeQuery := `
+		DELETE FROM pr_reviewers
+		WHERE pull_request_id = $1 AND user_id = $2
+	`
+
+	batch := &pgx.Batch{}
+	var pending []*domain.ReviewerReassignment
+	for _, ra := range reassignments {
+		if !slices.Contains(openIDs, ra.PullRequestID) {
+			continue
+		}
+		pending = append(pending, ra)
+		if ra.ReplacedBy == "" {
+			batch.Queue(removeQuery, ra.PullRequestID, ra.OldUserID)
+		} else {
+			batch.Queue(replaceQuery, ra.ReplacedBy, ra.PullRequestID, ra.OldUserID)
+		}
+	}
+	if len(pending) == 0 {
+		return applied, nil
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	for _, ra := range pending {
+		tag, err := results.Exec()
+		if err != nil {
+			_ = results.Close()
+			return nil, err
+		}
+		if tag.RowsAffected() > 0 {
+			applied = append(applied, ra)
+		}
+	}
+	return applied, results.Close()
+}
+
+// deactivationEvents возвращает события деактивации ревьюверов и их замены на PR
+func deactivationEvents(reassignments []*domain.ReviewerReassignment, actorID string) []*domain.PREvent {
+	events := make([]*domain.PREvent, 0, 2*len(reassignments))
+	for _, ra := range reassignments {
+		events = append(events, &domain.PREvent{
+			PullRequestID: ra.PullRequestID,
+			Type:          domain.EventReviewerActivityChanged,
+			ActorID:       actorID,
+			UserID:        ra.OldUserID,
+			Reason:        domain.ReasonUserDeactivated,
+		})
+		events = append(events, reassignmentEvent(ra, actorID, domain.ReasonUserDeactivated))
+	}
+	return events
+}
+
+// reassignmentEvent возвращает событие замены ревьювера или его снятия, если замены нет
+func reassignmentEvent(ra *domain.ReviewerReassignment, actorID string, reason domain.PREventReason) *domain.PREvent {
+	if ra.ReplacedBy == "" {
+		return &domain.PREvent{
+			PullRequestID: ra.PullRequestID,
+			Type:          domain.EventReviewerUnassigned,
+			ActorID:       actorID,
+			UserID:        ra.OldUserID,
+			Reason:        reason,
+		}
+	}
+
+	return &domain.PREvent{
+		PullRequestID: ra.PullRequestID,
+		Type:          domain.EventReviewerReassigned,
+		ActorID:       actorID,
+		OldUserID:     ra.OldUserID,
+		NewUserID:     ra.ReplacedBy,
+		Reason:        reason,
+	}
+}
+
+// SetReviewWeight обновляет вес пользователя для взвешенного выбора ревьюверов
+func (r *UserRepository) SetReviewWeight(ctx context.Context, userID string, weight int) error {
+	query := `
+		UPDATE users
+		SET review_weight = $1, updated_at = NOW()
+		WHERE user_id = $2
+	`
+
+	result, err := r.db.Exec(ctx, query, weight, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetReviewWeights возвращает веса указанных пользователей
+func (r *UserRepository) GetReviewWeights(ctx context.Context, userIDs []string) (map[string]int, error) {
+	query := `
+		SELECT user_id, review_weight
+		FROM users
+		WHERE user_id = ANY($1)
+	`
+
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weights := make(map[string]int, len(userIDs))
+	for rows.Next() {
+		var userID string
+		var weight int
+		if err := rows.Scan(&userID, &weight); err != nil {
+			return nil, err
+		}
+		weights[userID] = weight
+	}
+
+	return weights, rows.Err()
+}
+
+// IsAvailable проверяет, что пользователь может получить ревью: по тому же правилу, что и GetActiveTeamMembers,
+// он активен, не отсутствует сегодня и состоит хотя бы в одной неархивной команде
+func (r *UserRepository) IsAvailable(ctx context.Context, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM team_members tm
+			INNER JOIN users u ON u.user_id = tm.user_id
+			INNER JOIN teams t ON t.team_name = tm.team_name
+			WHERE tm.user_id = $1 AND u.is_active = true AND t.archived_at IS NULL
+			  AND NOT EXISTS (
+			      SELECT 1 FROM user_absences a
+			      WHERE a.user_id = u.user_id AND CURRENT_DATE BETWEEN a.start_date AND a.end_date
+			  )
+		)
+	`
+
+	var available bool
+	err := r.db.QueryRow(ctx, query, userID).Scan(&available)
+	return available, err
+}
+
+// GetAvailableByHandles возвращает активных и не отсутствующих сегодня пользователей по ID или логину GitHub.
+// Совпадение по ID важнее совпадения по логину
+func (r *UserRepository) GetAvailableByHandles(ctx context.Context, handles []string) (map[string]*domain.User, error) {
+	lowered := make([]string, len(handles))
+	for i, handle := range handles {
+		lowered[i] = strings.ToLower(handle)
+	}
+
+	query := `
+		SELECT user_id, username, COALESCE(team_name, ''), is_active, COALESCE(LOWER(github_login), '')
+		FROM users u
+		WHERE (user_id = ANY($1) OR LOWER(github_login) = ANY($2)) AND is_active = true
+		  AND NOT EXISTS (
+		      SELECT 1 FROM user_absences a
+		      WHERE a.user_id = u.user_id AND CURRENT_DATE BETWEEN a.start_date AND a.end_date
+		  )
+	`
+
+	rows, err := r.db.Query(ctx, query, handles, lowered)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*domain.User)
+	byLogin := make(map[string]*domain.User)
+	for rows.Next() {
+		var user domain.User
+		var login string
+		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive, &login); err != nil {
+			return nil, err
+		}
+		byID[user.UserID] = &user
+		if login != "" {
+			byLogin[login] = &user
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]*domain.User, len(handles))
+	for i, handle := range handles {
+		if user, ok := byID[handle]; ok {
+			users[handle] = user
+		} else if user, ok := byLogin[lowered[i]]; ok {
+			users[handle] = user
+		}
+	}
+
+	return users, nil
+}
+
+// SetMaxOpenReviews задает собственный лимит открытых ревью пользователя (nil - лимит команды)
+func (r *UserRepository) SetMaxOpenReviews(ctx context.Context, userID string, limit *int) error {
+	query := `
+		UPDATE users
+		SET max_open_reviews = $1, updated_at = NOW()
+		WHERE user_id = $2
+	`
+
+	result, err := r.db.Exec(ctx, query, limit, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetMaxOpenReviews возвращает собственные лимиты открытых ревью указанных пользователей
+func (r *UserRepository) GetMaxOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error) {
+	query := `
+		SELECT user_id, max_open_reviews
+		FROM users
+		WHERE user_id = ANY($1) AND max_open_reviews IS NOT NULL
+	`
+
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make(map[string]int)
+	for rows.Next() {
+		var userID string
+		var limit int
+		if err := rows.Scan(&userID, &limit); err != nil {
+			return nil, err
+		}
+		limits[userID] = limit
+	}
+
+	return limits, rows.Err()
+}
+
+// SetGitHubLogin привязывает логин GitHub к пользователю
+func (r *UserRepository) SetGitHubLogin(ctx context.Context, userID, login string) error {
+	query := `
+		UPDATE users
+		SET github_login = $1, updated_at = NOW()
+		WHERE user_id = $2
+	`
+
+	result, err := r.db.Exec(ctx, query, login, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return domain.ErrGitHubLoginTaken
+		}
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetByGitHubLogin получает пользователя по логину GitHub (без учета регистра)
+func (r *UserRepository) GetByGitHubLogin(ctx context.Context, login string) (*domain.User, error) {
+	query := `
+		SELECT user_id, username, COALESCE(team_name, ''),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = users.user_id ORDER BY tm.team_name),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = users.user_id AND tm.is_lead ORDER BY tm.team_name),
+		       is_active, role
+		FROM users
+		WHERE LOWER(github_login) = LOWER($1)
+	`
+
+	var user domain.User
+	err := r.db.QueryRow(ctx, query, login).Scan(
+		&user.UserID,
+		&user.Username,
+		&user.TeamName,
+		&user.Teams,
+		&user.LeadTeams,
+		&user.IsActive,
+		&user.Role,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// SetRole обновляет роль пользователя. Новый лид, который еще не ведет ни одной команды,
+// становится лидом своей основной команды
+func (r *UserRepository) SetRole(ctx context.Context, userID string, role domain.Role) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	query := `
+		UPDATE users
+		SET role = $1, updated_at = NOW()
+		WHERE user_id = $2
+	`
+
+	result, err := tx.Exec(ctx, query, role, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	if role == domain.RoleTeamLead {
+		leadQuery := `
+			UPDATE team_members tm
+			SET is_lead = true
+			FROM users u
+			WHERE u.user_id = tm.user_id AND tm.user_id = $1 AND tm.team_name = u.team_name
+			  AND NOT EXISTS (SELECT 1 FROM team_members WHERE user_id = $1 AND is_lead)
+		`
+		if _, err := tx.Exec(ctx, leadQuery, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetPrimaryTeam делает команду основной для пользователя; ErrNotTeamMember, если он в ней не состоит
+func (r *UserRepository) SetPrimaryTeam(ctx context.Context, userID, teamName string) error {
+	query := `
+		UPDATE users
+		SET team_name = $2, updated_at = NOW()
+		WHERE user_id = $1
+		  AND EXISTS(SELECT 1 FROM team_members WHERE user_id = $1 AND team_name = $2)
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, teamName)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		if _, err := r.GetByID(ctx, userID); err != nil {
+			return err
+		}
+		return domain.ErrNotTeamMember
+	}
+
+	return nil
+}
+
+// HasAdmin проверяет, есть ли в системе хотя бы один администратор
+func (r *UserRepository) HasAdmin(ctx context.Context) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE role = $1)`, domain.RoleAdmin).Scan(&exists)
+	return exists, err
+}
+
+// SetEmail задает email пользователя для входа через OIDC
+func (r *UserRepository) SetEmail(ctx context.Context, userID, email string) error {
+	query := `
+		UPDATE users
+		SET email = $1, updated_at = NOW()
+		WHERE user_id = $2
+	`
+
+	result, err := r.db.Exec(ctx, query, email, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return domain.ErrEmailTaken
+		}
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetByEmail получает пользователя по email (без учета регистра)
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	query := `
+		SELECT user_id, username, COALESCE(team_name, ''),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = users.user_id ORDER BY tm.team_name),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = users.user_id AND tm.is_lead ORDER BY tm.team_name),
+		       is_active, role
+		FROM users
+		WHERE LOWER(email) = LOWER($1)
+	`
+
+	var user domain.User
+	err := r.db.QueryRow(ctx, query, email).Scan(
+		&user.UserID,
+		&user.Username,
+		&user.TeamName,
+		&user.Teams,
+		&user.LeadTeams,
+		&user.IsActive,
+		&user.Role,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ListDirectory возвращает страницу пользователей каталога по фильтру и общее число подходящих
+func (r *UserRepository) ListDirectory(ctx context.Context, filter domain.DirectoryFilter) ([]*domain.DirectoryUser, int, error) {
+	where := `
+		WHERE ($1 = '' OR user_id = $1)
+		  AND ($2 = '' OR LOWER(email) = LOWER($2))
+		  AND ($3 = '' OR external_id = $3)
+	`
+
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users`+where,
+		filter.UserID, filter.Email, filter.ExternalID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT user_id, username, COALESCE(team_name, ''),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = users.user_id ORDER BY tm.team_name),
+		       is_active, role, COALESCE(email, ''), COALESCE(external_id, '')
+		FROM users
+	` + where + `
+		ORDER BY user_id
+		OFFSET $4 LIMIT $5
+	`
+
+	rows, err := r.db.Query(ctx, query, filter.UserID, filter.Email, filter.ExternalID, filter.Offset, filter.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []*domain.DirectoryUser{}
+	for rows.Next() {
+		user, err := scanDirectoryUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	return users, total, rows.Err()
+}
+
+// GetDirectoryUser получает пользователя вместе с атрибутами каталога
+func (r *UserRepository) GetDirectoryUser(ctx context.Context, userID string) (*domain.DirectoryUser, error) {
+	query := `
+		SELECT user_id, username, COALESCE(team_name, ''),
+		       ARRAY(SELECT tm.team_name FROM team_members tm WHERE tm.user_id = users.user_id ORDER BY tm.team_name),
+		       is_active, role, COALESCE(email, ''), COALESCE(external_id, '')
+		FROM users
+		WHERE user_id = $1
+	`
+
+	return scanDirectoryUser(r.db.QueryRow(ctx, query, userID))
+}
+
+// CreateDirectoryUser создает пользователя из каталога участником его основной команды
+func (r *UserRepository) CreateDirectoryUser(ctx context.Context, user *domain.DirectoryUser) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	query := `
+		INSERT INTO users (user_id, username, team_name, is_active, role, email, external_id)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+	`
+
+	_, err = tx.Exec(ctx, query,
+		user.UserID, user.Username, user.TeamName, user.IsActive, user.Role, user.Email, user.ExternalID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch {
+			case pgErr.Code == "23505" && pgErr.ConstraintName == "idx_users_email": // unique_violation (email)
+				return domain.ErrEmailTaken
+			case pgErr.Code == "23505": // unique_violation (user_id)
+				return domain.ErrUserExists
+			case pgErr.Code == "23503": // foreign_key_violation
+				return domain.ErrTeamNotFound
+			}
+		}
+		return err
+	}
+
+	if user.TeamName != "" {
+		if err := joinTeam(ctx, tx, user.TeamName, []string{user.UserID}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UpdateDirectoryUser в одной транзакции обновляет имя, основную команду, email, externalId и активность пользователя.
+// Новая основная команда добавляется к командам пользователя, прежние команды сохраняются. При деактивации
+// отзываются сессии и применяются замены ревьюверов, при активации изменение отмечается в истории открытых PR
+func (r *UserRepository) UpdateDirectoryUser(
+	ctx context.Context,
+	user *domain.DirectoryUser,
+	reassignments []*domain.ReviewerReassignment,
+	actorID string,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // Ignore error as it will fail if transaction was committed
+	}()
+
+	var wasActive bool
+	err = tx.QueryRow(ctx, `SELECT is_active FROM users WHERE user_id = $1 FOR UPDATE`, user.UserID).Scan(&wasActive)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		return err
+	}
+
+	query := `
+		UPDATE users
+		SET username = $1,
+		    team_name = NULLIF($2, ''),
+		    email = NULLIF($3, ''),
+		    external_id = NULLIF($4, ''),
+		    updated_at = NOW()
+		WHERE user_id = $5
+	`
+
+	_, err = tx.Exec(ctx, query, user.Username, user.TeamName, user.Email, user.ExternalID, user.UserID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505": // unique_violation (email)
+				return domain.ErrEmailTaken
+			case "23503": // foreign_key_violation
+				return domain.ErrTeamNotFound
+			}
+		}
+		return err
+	}
+
+	if user.TeamName != "" {
+		if err := joinTeam(ctx, tx, user.TeamName, []string{user.UserID}); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case wasActive && !user.IsActive:
+		if err := deactivateUsers(ctx, tx, []string{user.UserID}); err != nil {
+			return err
+		}
+		applied, err := applyReassignments(ctx, tx, reassignments)
+		if err != nil {
+			return err
+		}
+		if err := insertEvents(ctx, tx, deactivationEvents(applied, actorID)); err != nil {
+			return err
+		}
+	case !wasActive && user.IsActive:
+		activateQuery := `
+			UPDATE users
+			SET is_active = true, updated_at = NOW()
+			WHERE user_id = $1
+		`
+		if _, err := tx.Exec(ctx, activateQuery, user.UserID); err != nil {
+			return err
+		}
+		if err := insertActivityEvents(ctx, tx, user.UserID, true, actorID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// scanDirectoryUser читает пользователя с атрибутами каталога из строки результата
+func scanDirectoryUser(row pgx.Row) (*domain.DirectoryUser, error) {
+	var user domain.DirectoryUser
+	err := row.Scan(
+		&user.UserID,
+		&user.Username,
+		&user.TeamName,
+		&user.Teams,
+		&user.IsActive,
+		&user.Role,
+		&user.Email,
+		&user.ExternalID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookDeliveryRepository реализует repository.WebhookDeliveryRepository для PostgreSQL
+type WebhookDeliveryRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewWebhookDeliveryRepository создает новый экземпляр WebhookDeliveryRepository
+func NewWebhookDeliveryRepository(db *pgxpool.Pool) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db}
+}
+
+// Register сохраняет доставку; возвращает false, если доставка с таким ID уже была получена
+func (r *WebhookDeliveryRepository) Register(ctx context.Context, deliveryID, event string) (bool, error) {
+	query := `
+		INSERT INTO webhook_deliveries (delivery_id, event)
+		VALUES ($1, $2)
+		ON CONFLICT (delivery_id) DO NOTHING
+	`
+
+	result, err := r.db.Exec(ctx, query, deliveryID, event)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// Forget удаляет доставку, чтобы ее повторная отправка была обработана заново
+func (r *WebhookDeliveryRepository) Forget(ctx context.Context, deliveryID string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM webhook_deliveries WHERE delivery_id = $1`, deliveryID)
+	return err
+}
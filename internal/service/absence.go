@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+const (
+	// absenceBatchSize limits how many absences are handed over per poll
+	absenceBatchSize = 50
+
+	// absenceMaxRetryDelay caps the backoff of an absence whose handover keeps failing
+	absenceMaxRetryDelay = time.Hour
+)
+
+// AbsenceService manages out-of-office periods of users
+type AbsenceService struct {
+	absenceRepo repository.AbsenceRepository
+}
+
+// NewAbsenceService creates a new AbsenceService
+func NewAbsenceService(absenceRepo repository.AbsenceRepository) *AbsenceService {
+	return &AbsenceService{
+		absenceRepo: absenceRepo,
+	}
+}
+
+// Create records an absence of the user; it must not overlap their other absences
+func (s *AbsenceService) Create(ctx context.Context, absence *domain.Absence) (*domain.Absence, error) {
+	if err := s.absenceRepo.Create(ctx, absence); err != nil {
+		return nil, err
+	}
+	return absence, nil
+}
+
+// Get retrieves an absence by ID
+func (s *AbsenceService) Get(ctx context.Context, absenceID int64) (*domain.Absence, error) {
+	return s.absenceRepo.GetByID(ctx, absenceID)
+}
+
+// List returns the user's absences that have not ended yet, or all of them with past
+func (s *AbsenceService) List(ctx context.Context, userID string, past bool) ([]*domain.Absence, error) {
+	return s.absenceRepo.ListByUser(ctx, userID, past)
+}
+
+// Delete removes an absence; reviews already handed over are not returned to the user
+func (s *AbsenceService) Delete(ctx context.Context, absenceID int64) error {
+	return s.absenceRepo.Delete(ctx, absenceID)
+}
+
+// AbsenceReassigner hands over the open reviews of users at the start of a long absence in the background.
+// Short absences only keep the user from being picked as a reviewer.
+type AbsenceReassigner struct {
+	absenceRepo  repository.AbsenceRepository
+	teamService  *TeamService
+	minDays      int
+	pollInterval time.Duration
+	logger       *slog.Logger
+}
+
+// NewAbsenceReassigner creates a new AbsenceReassigner handing over reviews for absences of at least minDays days
+func NewAbsenceReassigner(
+	absenceRepo repository.AbsenceRepository,
+	teamService *TeamService,
+	minDays int,
+	pollInterval time.Duration,
+	logger *slog.Logger,
+) *AbsenceReassigner {
+	return &AbsenceReassigner{
+		absenceRepo:  absenceRepo,
+		teamService:  teamService,
+		minDays:      minDays,
+		pollInterval: pollInterval,
+		logger:       logger,
+	}
+}
+
+// Run polls for absences that have started and hands over their reviews until ctx is canceled
+func (r *AbsenceReassigner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reassign(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Error("Failed to reassign reviews of absent users", "error", err)
+			}
+		}
+	}
+}
+
+// reassign hands over the reviews of every absence that is due. An absence that fails is logged and retried
+// with exponential backoff, so it does not hold up the others.
+func (r *AbsenceReassigner) reassign(ctx context.Context) error {
+	absences, err := r.absenceRepo.ListDueForReassignment(ctx, r.minDays, absenceBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, absence := range absences {
+		if err := r.handOver(ctx, absence); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			attempts := absence.HandoverAttempts + 1
+			r.logger.Error("Failed to reassign reviews of absent user",
+				"absence_id", absence.AbsenceID, "user_id", absence.UserID, "attempt", attempts, "error", err)
+
+			if err := r.absenceRepo.MarkReassignmentFailed(ctx, absence.AbsenceID, r.retryDelay(attempts)); err != nil {
+				r.logger.Error("Failed to postpone reassignment of absent user",
+					"absence_id", absence.AbsenceID, "error", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// retryDelay returns the delay before the next handover after the given number of failed attempts:
+// the poll interval doubled per attempt, capped at absenceMaxRetryDelay
+func (r *AbsenceReassigner) retryDelay(attempts int) time.Duration {
+	delay := r.pollInterval
+	for i := 1; i < attempts && delay < absenceMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, absenceMaxRetryDelay)
+}
+
+// handOver reassigns the open reviews of the absent user
+func (r *AbsenceReassigner) handOver(ctx context.Context, absence *domain.Absence) error {
+	reassignments, err := r.teamService.PlanHandover(ctx, absence.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Another instance may have handled or deleted the absence in the meantime
+	err = r.absenceRepo.ReassignReviews(ctx, absence.AbsenceID, reassignments)
+	if err != nil && !errors.Is(err, domain.ErrAbsenceNotFound) {
+		return err
+	}
+	return nil
+}
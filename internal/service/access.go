@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+// AccessService decides whether the principal may perform an operation on a team, user or PR.
+// Admins may do everything, team leads manage the teams they are marked lead of, members act on their own PRs.
+type AccessService struct {
+	userRepo repository.UserRepository
+	prRepo   repository.PullRequestRepository
+}
+
+// NewAccessService creates a new AccessService
+func NewAccessService(userRepo repository.UserRepository, prRepo repository.PullRequestRepository) *AccessService {
+	return &AccessService{
+		userRepo: userRepo,
+		prRepo:   prRepo,
+	}
+}
+
+// CheckTeam allows admins and the lead of the team to manage the team
+func (s *AccessService) CheckTeam(p domain.Principal, teamName string) error {
+	if p.IsAdmin() || p.LeadsTeam(teamName) {
+		return nil
+	}
+	return domain.ErrForbidden
+}
+
+// CheckCodeOwners allows admins and the team lead to manage the team's CODEOWNERS;
+// CODEOWNERS of a repository span teams and are managed by admins only
+func (s *AccessService) CheckCodeOwners(p domain.Principal, teamName string) error {
+	if teamName == "" {
+		if p.IsAdmin() {
+			return nil
+		}
+		return domain.ErrForbidden
+	}
+	return s.CheckTeam(p, teamName)
+}
+
+// CheckAddMembers allows admins and the lead of the team to add members. Only admins may appoint admins
+// and leads or add existing admins; other existing users may be added by the lead of one of their current teams.
+func (s *AccessService) CheckAddMembers(
+	ctx context.Context,
+	p domain.Principal,
+	teamName string,
+	members []domain.TeamMember,
+) error {
+	if err := s.CheckTeam(p, teamName); err != nil {
+		return err
+	}
+	if p.IsAdmin() {
+		return nil
+	}
+
+	for _, member := range members {
+		if member.Role == domain.RoleAdmin || member.Role == domain.RoleTeamLead {
+			return domain.ErrForbidden
+		}
+
+		user, err := s.userRepo.GetByID(ctx, member.UserID)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if user.Role == domain.RoleAdmin || !s.leadsUser(p, user) {
+			return domain.ErrForbidden
+		}
+	}
+	return nil
+}
+
+// CheckMoveMember requires the principal to manage the user, the team being left (if given) and the destination team
+func (s *AccessService) CheckMoveMember(ctx context.Context, p domain.Principal, userID, fromTeam, teamName string) error {
+	if err := s.CheckUser(ctx, p, userID); err != nil {
+		return err
+	}
+	if fromTeam != "" {
+		if err := s.CheckTeam(p, fromTeam); err != nil {
+			return err
+		}
+	}
+	return s.CheckTeam(p, teamName)
+}
+
+// CheckUser allows admins and the lead of any of the user's teams to manage the user
+func (s *AccessService) CheckUser(ctx context.Context, p domain.Principal, userID string) error {
+	if p.IsAdmin() {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !s.leadsUser(p, user) {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+// leadsUser reports whether the principal leads any of the user's teams
+func (s *AccessService) leadsUser(p domain.Principal, user *domain.User) bool {
+	for _, teamName := range user.Teams {
+		if p.LeadsTeam(teamName) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCreatePR allows users to open PRs as themselves; admins and the author's team lead may open them for others
+func (s *AccessService) CheckCreatePR(ctx context.Context, p domain.Principal, authorID string) error {
+	if p.UserID == authorID {
+		return nil
+	}
+	return s.CheckUser(ctx, p, authorID)
+}
+
+// CheckAbsence allows users to manage their own absences; admins and the user's team lead may manage anyone's
+func (s *AccessService) CheckAbsence(ctx context.Context, p domain.Principal, userID string) error {
+	if p.UserID == userID {
+		return nil
+	}
+	return s.CheckUser(ctx, p, userID)
+}
+
+// CheckPR allows the author and assigned reviewers to act on the PR,
+// as well as admins and the lead of the author's team
+func (s *AccessService) CheckPR(ctx context.Context, p domain.Principal, prID string) error {
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return err
+	}
+
+	if pr.AuthorID == p.UserID || slices.Contains(pr.AssignedReviewers, p.UserID) {
+		return nil
+	}
+
+	return s.CheckUser(ctx, p, pr.AuthorID)
+}
+
+// CheckReviewers allows only admins and the lead of the PR's reviewing team to pick reviewers by hand.
+// PRs without a reviewing team fall back to the lead of any of the author's teams.
+func (s *AccessService) CheckReviewers(ctx context.Context, p domain.Principal, prID string) error {
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return err
+	}
+
+	if pr.TeamName != "" {
+		return s.CheckTeam(p, pr.TeamName)
+	}
+	return s.CheckUser(ctx, p, pr.AuthorID)
+}
+
+// CheckReview allows reviewers to submit only their own verdicts
+func (s *AccessService) CheckReview(p domain.Principal, reviewerID string) error {
+	if p.UserID != reviewerID {
+		return domain.ErrForbidden
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/aidar/avito-pr-project/internal/domain"
 	"github.com/aidar/avito-pr-project/internal/repository"
@@ -13,75 +14,344 @@ import (
 
 // Claims represents JWT claims
 type Claims struct {
-	UserID   string `json:"user_id"`
-	TeamName string `json:"team_name"`
+	UserID    string      `json:"user_id"`
+	TeamName  string      `json:"team_name"`            // Primary team
+	Teams     []string    `json:"teams"`                // All team memberships
+	LeadTeams []string    `json:"lead_teams,omitempty"` // Teams the user leads
+	Role      domain.Role `json:"role"`
+	SessionID int64       `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// AuthService handles authentication and JWT operations
+// TokenPair is a short-lived access token together with the refresh token of its session
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration // Lifetime of the access token
+}
+
+// TokenTTL defines lifetimes of access tokens and login sessions
+type TokenTTL struct {
+	Access  time.Duration // Lifetime of an access token
+	Refresh time.Duration // Lifetime of a session; refreshing does not extend it
+}
+
+// LockoutPolicy defines when password login is locked after failed attempts
+type LockoutPolicy struct {
+	MaxAttempts int           // Consecutive failed attempts that lock the login
+	Duration    time.Duration // How long the login stays locked
+}
+
+// dummyPasswordHash is compared against when a user has no password,
+// so that unknown users take as long to reject as wrong passwords
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// AuthService handles authentication, login sessions and JWT operations
 type AuthService struct {
-	userRepo  repository.UserRepository
-	jwtSecret string
-	jwtExpiry time.Duration
+	userRepo       repository.UserRepository
+	credentialRepo repository.CredentialRepository
+	sessionRepo    repository.SessionRepository
+	lockout        LockoutPolicy
+	signer         TokenSigner
+	ttl            TokenTTL
 }
 
 // NewAuthService creates a new AuthService
-func NewAuthService(userRepo repository.UserRepository, jwtSecret string, jwtExpiry time.Duration) *AuthService {
+func NewAuthService(
+	userRepo repository.UserRepository,
+	credentialRepo repository.CredentialRepository,
+	sessionRepo repository.SessionRepository,
+	lockout LockoutPolicy,
+	signer TokenSigner,
+	ttl TokenTTL,
+) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
-		jwtExpiry: jwtExpiry,
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+		sessionRepo:    sessionRepo,
+		lockout:        lockout,
+		signer:         signer,
+		ttl:            ttl,
 	}
 }
 
-// Login generates a JWT token for a user
-func (s *AuthService) Login(ctx context.Context, userID string) (string, error) {
-	// Get user to verify existence and get team info
+// Login checks the user's password and opens a new session.
+// Unknown users, users without a password, wrong passwords and deactivated users
+// are all rejected with ErrUnauthorized.
+func (s *AuthService) Login(ctx context.Context, userID, password string) (*TokenPair, error) {
+	if err := s.checkPassword(ctx, userID, password); err != nil {
+		return nil, err
+	}
+
+	// Get user to get team info
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return "", err
+		if err == domain.ErrUserNotFound {
+			return nil, domain.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	return s.StartSession(ctx, user)
+}
+
+// StartSession opens a session for a user who has already been authenticated, by password or single sign-on.
+// Deactivated users are rejected with ErrUnauthorized.
+func (s *AuthService) StartSession(ctx context.Context, user *domain.User) (*TokenPair, error) {
+	if !user.IsActive {
+		return nil, domain.ErrUnauthorized
+	}
+
+	refreshToken, err := generateToken("")
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.Create(ctx, user.UserID, hashToken(refreshToken), s.ttl.Refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokenPair(user, session, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair. The refresh token is rotated:
+// the presented one stops working, and presenting it again revokes the whole session.
+// The access token reflects the user's current role and team. A deactivated user gets ErrUnauthorized
+// and loses all sessions, even if the deactivation path did not revoke them.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	newRefreshToken, err := generateToken("")
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.Rotate(ctx, hashToken(refreshToken), hashToken(newRefreshToken))
+	if err == domain.ErrTokenNotFound {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		if err := s.sessionRepo.RevokeAllForUser(ctx, user.UserID); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrUnauthorized
+	}
+
+	return s.issueTokenPair(user, session, newRefreshToken)
+}
+
+// Logout revokes the session of the refresh token, or all sessions of its user when all is set.
+// Access tokens of revoked sessions are rejected by ValidateToken.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string, all bool) error {
+	session, err := s.sessionRepo.RevokeByRefreshToken(ctx, hashToken(refreshToken))
+	if err == domain.ErrTokenNotFound {
+		return domain.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if all {
+		return s.sessionRepo.RevokeAllForUser(ctx, session.UserID)
+	}
+	return nil
+}
+
+// SetPassword sets the signed-in user's own password. A user without a password (e.g. signed in through
+// OIDC) sets the first one without currentPassword; changing an existing password requires the current one
+// and counts failures towards the lockout.
+func (s *AuthService) SetPassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	_, err = s.credentialRepo.Get(ctx, userID)
+	if err == domain.ErrNotFound {
+		created, err := s.credentialRepo.Create(ctx, userID, string(hash))
+		if err == domain.ErrUserNotFound {
+			return domain.ErrUnauthorized
+		}
+		if err != nil {
+			return err
+		}
+		// Another request has set the first password concurrently
+		if !created {
+			return domain.ErrUnauthorized
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkPassword(ctx, userID, currentPassword); err != nil {
+		return err
+	}
+
+	return s.credentialRepo.UpdatePassword(ctx, userID, string(hash))
+}
+
+// SetInitialPassword sets the first password of a user who has none yet, on behalf of their admin or team lead.
+// An existing password is changed only by the user themselves, so ErrForbidden is returned for it.
+func (s *AuthService) SetInitialPassword(ctx context.Context, userID, newPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	created, err := s.credentialRepo.Create(ctx, userID, string(hash))
+	if err != nil {
+		return err
+	}
+	if !created {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+// BootstrapAdmin gives a fresh or upgraded installation its first administrator. While no admin exists,
+// the user is created without a team if missing and made an admin; the password is set only if the user
+// has none yet. Once any admin exists it does nothing and returns false.
+func (s *AuthService) BootstrapAdmin(ctx context.Context, userID, password string) (bool, error) {
+	hasAdmin, err := s.userRepo.HasAdmin(ctx)
+	if err != nil || hasAdmin {
+		return false, err
+	}
+
+	// Several instances may start at once: the user is created by one of them and promoted by all
+	err = s.userRepo.CreateDirectoryUser(ctx, &domain.DirectoryUser{User: domain.User{
+		UserID:   userID,
+		Username: userID,
+		IsActive: true,
+		Role:     domain.RoleAdmin,
+	}})
+	if err != nil && err != domain.ErrUserExists {
+		return false, err
+	}
+	if err := s.userRepo.SetRole(ctx, userID, domain.RoleAdmin); err != nil {
+		return false, err
+	}
+
+	if password == "" {
+		return true, nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash password: %w", err)
+	}
+	if _, err := s.credentialRepo.Create(ctx, userID, string(hash)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkPassword verifies the password against the stored hash and applies the lockout policy
+func (s *AuthService) checkPassword(ctx context.Context, userID, password string) error {
+	cred, err := s.credentialRepo.Get(ctx, userID)
+	if err == domain.ErrNotFound {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return domain.ErrUnauthorized
+	}
+	if err != nil {
+		return err
+	}
+
+	if cred.Locked {
+		return domain.ErrAccountLocked
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(cred.PasswordHash), []byte(password)) != nil {
+		if _, err := s.credentialRepo.RegisterFailedAttempt(
+			ctx, userID, s.lockout.MaxAttempts, s.lockout.Duration,
+		); err != nil {
+			return err
+		}
+		return domain.ErrUnauthorized
+	}
+
+	if cred.FailedAttempts > 0 {
+		if err := s.credentialRepo.ResetFailedAttempts(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// issueTokenPair generates a signed access token bound to the session
+func (s *AuthService) issueTokenPair(user *domain.User, session *domain.Session, refreshToken string) (*TokenPair, error) {
+	// Access tokens never outlive their session
+	expiresAt := time.Now().Add(s.ttl.Access)
+	if session.ExpiresAt.Before(expiresAt) {
+		expiresAt = session.ExpiresAt
 	}
 
 	// Create claims
 	claims := &Claims{
-		UserID:   user.UserID,
-		TeamName: user.TeamName,
+		UserID:    user.UserID,
+		TeamName:  user.TeamName,
+		Teams:     user.Teams,
+		LeadTeams: user.LeadTeams,
+		Role:      user.Role,
+		SessionID: session.SessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.jwtExpiry)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
 	// Sign token
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	tokenString, err := s.signer.Sign(claims)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
+		return nil, fmt.Errorf("failed to sign token: %w", err)
 	}
 
-	return tokenString, nil
+	return &TokenPair{
+		AccessToken:  tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    time.Until(expiresAt),
+	}, nil
 }
 
-// ValidateToken validates a JWT token and returns claims
-func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.jwtSecret), nil
-	})
+// ValidateToken validates a JWT token, checks that its session has not been revoked and returns claims
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
+	// Only the signer's algorithms are accepted, which rules out "none" and algorithm confusion
+	token, err := jwt.ParseWithClaims(
+		tokenString, &Claims{}, s.signer.Keyfunc, jwt.WithValidMethods(s.signer.ValidMethods()),
+	)
 
 	if err != nil {
 		return nil, domain.ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
+	if !ok || !token.Valid || claims.SessionID == 0 {
+		return nil, domain.ErrInvalidToken
+	}
+
+	// Logout and deactivation revoke the session before the access token expires
+	active, err := s.sessionRepo.IsActive(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
 		return nil, domain.ErrInvalidToken
 	}
 
 	return claims, nil
 }
+
+// JWKS returns the public keys that verify access tokens
+func (s *AuthService) JWKS() domain.JWKS {
+	return s.signer.JWKS()
+}
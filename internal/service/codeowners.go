@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+// CodeOwnersService manages CODEOWNERS files of teams and repositories and picks code owners of changed files
+type CodeOwnersService struct {
+	codeOwnersRepo repository.CodeOwnersRepository
+	userRepo       repository.UserRepository
+}
+
+// NewCodeOwnersService creates a new CodeOwnersService
+func NewCodeOwnersService(
+	codeOwnersRepo repository.CodeOwnersRepository,
+	userRepo repository.UserRepository,
+) *CodeOwnersService {
+	return &CodeOwnersService{
+		codeOwnersRepo: codeOwnersRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// Set validates and stores the CODEOWNERS of a team or a repository, replacing the previous one
+func (s *CodeOwnersService) Set(ctx context.Context, codeOwners *domain.CodeOwners) (*domain.CodeOwners, error) {
+	if _, err := ParseCodeOwners(codeOwners.Content); err != nil {
+		return nil, err
+	}
+
+	if err := s.codeOwnersRepo.Set(ctx, codeOwners); err != nil {
+		return nil, err
+	}
+	return codeOwners, nil
+}
+
+// Get retrieves the CODEOWNERS of a team or a repository
+func (s *CodeOwnersService) Get(ctx context.Context, teamName, repository string) (*domain.CodeOwners, error) {
+	return s.codeOwnersRepo.Get(ctx, teamName, repository)
+}
+
+// Delete removes the CODEOWNERS of a team or a repository
+func (s *CodeOwnersService) Delete(ctx context.Context, teamName, repository string) error {
+	return s.codeOwnersRepo.Delete(ctx, teamName, repository)
+}
+
+// ParseCodeOwners parses CODEOWNERS content: every line is a path pattern followed by owners.
+// Blank lines and # comments are skipped. Owners are @user (user ID or GitHub login) and @org/team.
+func ParseCodeOwners(content string) ([]domain.CodeOwnersRule, error) {
+	compiled, err := compileCodeOwners(content)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]domain.CodeOwnersRule, len(compiled))
+	for i, c := range compiled {
+		rules[i] = c.rule
+	}
+	return rules, nil
+}
+
+// compiledRule is a CODEOWNERS rule with its pattern translated to a regular expression
+type compiledRule struct {
+	rule    domain.CodeOwnersRule
+	pattern *regexp.Regexp
+}
+
+// compileCodeOwners parses CODEOWNERS content and compiles the patterns
+func compileCodeOwners(content string) ([]compiledRule, error) {
+	var rules []compiledRule
+	for i, line := range strings.Split(content, "\n") {
+		// Comments may follow the owners on the same line
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		pattern, err := compilePattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		for _, owner := range fields[1:] {
+			if !validOwner(owner) {
+				return nil, fmt.Errorf("line %d: owner %q must be @user or @org/team", i+1, owner)
+			}
+		}
+
+		rules = append(rules, compiledRule{
+			rule:    domain.CodeOwnersRule{Line: i + 1, Pattern: fields[0], Owners: fields[1:]},
+			pattern: pattern,
+		})
+	}
+
+	return rules, nil
+}
+
+// validOwner checks that the owner is @user or @org/team
+func validOwner(owner string) bool {
+	name, ok := strings.CutPrefix(owner, "@")
+	if !ok || name == "" {
+		return false
+	}
+	org, team, isTeam := strings.Cut(name, "/")
+	return !isTeam || (org != "" && team != "" && !strings.Contains(team, "/"))
+}
+
+// compilePattern translates a CODEOWNERS pattern to a regular expression over slash-separated paths.
+// As in gitignore, a pattern without a slash matches at any depth, a leading or inner slash anchors it
+// to the root and a trailing slash matches everything in the directory; * and ? never match a slash,
+// ** matches any number of directories. dir/* matches files directly in dir only.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if strings.ContainsAny(pattern, "![]\\") {
+		return nil, fmt.Errorf("pattern %q uses unsupported syntax", pattern)
+	}
+
+	trimmed := strings.Trim(pattern, "/")
+	if trimmed == "" {
+		return nil, fmt.Errorf("pattern %q matches no path", pattern)
+	}
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(trimmed, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+
+	segments := strings.Split(trimmed, "/")
+	last := segments[len(segments)-1]
+	for i, segment := range segments {
+		switch {
+		case segment == "**" && i == len(segments)-1:
+			expr.WriteString(".*")
+			continue
+		case segment == "**":
+			expr.WriteString("(?:.*/)?")
+			continue
+		}
+
+		for _, ch := range segment {
+			switch ch {
+			case '*':
+				expr.WriteString("[^/]*")
+			case '?':
+				expr.WriteString("[^/]")
+			default:
+				expr.WriteString(regexp.QuoteMeta(string(ch)))
+			}
+		}
+		if i < len(segments)-1 {
+			expr.WriteString("/")
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(pattern, "/"):
+		expr.WriteString("/.*")
+	case last != "*" && last != "**":
+		// A pattern naming a directory covers everything in it
+		expr.WriteString("(?:/.*)?")
+	}
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// ownersOf returns the owners of the path according to the last matching rule, or nil if no rule matches
+func ownersOf(rules []compiledRule, path string) (*domain.CodeOwnersRule, bool) {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(path) {
+			return &rules[i].rule, true
+		}
+	}
+	return nil, false
+}
+
+// codeOwnersFor returns the rules that apply to the PR: the repository's CODEOWNERS if it was uploaded,
+// otherwise the CODEOWNERS of the reviewing team
+func (s *CodeOwnersService) codeOwnersFor(ctx context.Context, pr *domain.PullRequest, teamName string) ([]compiledRule, error) {
+	var scopes [][2]string
+	if pr.Repository != "" {
+		scopes = append(scopes, [2]string{"", pr.Repository})
+	}
+	if teamName != "" {
+		scopes = append(scopes, [2]string{teamName, ""})
+	}
+
+	for _, scope := range scopes {
+		codeOwners, err := s.codeOwnersRepo.Get(ctx, scope[0], scope[1])
+		if errors.Is(err, domain.ErrCodeOwnersNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return compileCodeOwners(codeOwners.Content)
+	}
+
+	return nil, nil
+}
+
+// ownerGroups returns, for every rule owning some of the PR's changed files, the owners available
+// for review: active, not absent users other than the author. Unknown owners are ignored.
+func (s *CodeOwnersService) ownerGroups(ctx context.Context, pr *domain.PullRequest, teamName string) ([][]*domain.User, error) {
+	if len(pr.ChangedFiles) == 0 {
+		return nil, nil
+	}
+
+	rules, err := s.codeOwnersFor(ctx, pr, teamName)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+
+	// Files owned by the same rule need one reviewer between them
+	var owned []*domain.CodeOwnersRule
+	for _, path := range pr.ChangedFiles {
+		rule, ok := ownersOf(rules, path)
+		if ok && len(rule.Owners) > 0 && !slices.Contains(owned, rule) {
+			owned = append(owned, rule)
+		}
+	}
+	if len(owned) == 0 {
+		return nil, nil
+	}
+
+	var handles []string
+	for _, rule := range owned {
+		for _, owner := range rule.Owners {
+			if name := strings.TrimPrefix(owner, "@"); !strings.Contains(name, "/") {
+				handles = append(handles, name)
+			}
+		}
+	}
+	users, err := s.userRepo.GetAvailableByHandles(ctx, handles)
+	if err != nil {
+		return nil, err
+	}
+
+	teamMembers := make(map[string][]*domain.User)
+	groups := make([][]*domain.User, 0, len(owned))
+	for _, rule := range owned {
+		var group []*domain.User
+		for _, owner := range rule.Owners {
+			name := strings.TrimPrefix(owner, "@")
+			_, team, isTeam := strings.Cut(name, "/")
+			if !isTeam {
+				if user, ok := users[name]; ok && user.UserID != pr.AuthorID {
+					group = appendUser(group, user)
+				}
+				continue
+			}
+
+			members, ok := teamMembers[team]
+			if !ok {
+				if members, err = s.userRepo.GetActiveTeamMembers(ctx, team, pr.AuthorID); err != nil {
+					return nil, err
+				}
+				teamMembers[team] = members
+			}
+			for _, member := range members {
+				group = appendUser(group, member)
+			}
+		}
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, nil
+}
+
+// appendUser adds the user to users unless a user with the same ID is already there
+func appendUser(users []*domain.User, user *domain.User) []*domain.User {
+	for _, u := range users {
+		if u.UserID == user.UserID {
+			return users
+		}
+	}
+	return append(users, user)
+}
+
+// selectOwners picks up to maxReviewers code owners so that every owner group gets a reviewer.
+// Owners covering more of the remaining groups go first; among equal ones the team's selector decides,
+// so owners at their review limit are skipped.
+func (s *CodeOwnersService) selectOwners(
+	ctx context.Context,
+	pr *domain.PullRequest,
+	teamName string,
+	selector ReviewerSelector,
+	maxReviewers int,
+) ([]string, error) {
+	groups, err := s.ownerGroups(ctx, pr, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewers := []string{}
+	skipped := make(map[string]bool)
+	for len(reviewers) < maxReviewers {
+		coverage := make(map[string]int)
+		byID := make(map[string]*domain.User)
+		for _, group := range groups {
+			if slices.ContainsFunc(group, func(u *domain.User) bool { return slices.Contains(reviewers, u.UserID) }) {
+				continue
+			}
+			for _, u := range group {
+				if !skipped[u.UserID] {
+					coverage[u.UserID]++
+					byID[u.UserID] = u
+				}
+			}
+		}
+		if len(coverage) == 0 {
+			break
+		}
+
+		best := 0
+		for _, count := range coverage {
+			best = max(best, count)
+		}
+		var tied []*domain.User
+		for id, count := range coverage {
+			if count == best {
+				tied = append(tied, byID[id])
+			}
+		}
+		slices.SortFunc(tied, func(a, b *domain.User) int { return strings.Compare(a.UserID, b.UserID) })
+
+		picked, err := selector.SelectReviewers(ctx, teamName, tied, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(picked) == 0 {
+			// Nobody of them can take a review, try the owners covering fewer groups
+			for _, u := range tied {
+				skipped[u.UserID] = true
+			}
+			continue
+		}
+		reviewers = append(reviewers, picked[0])
+	}
+
+	return reviewers, nil
+}
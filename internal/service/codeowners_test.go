@@ -0,0 +1,148 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{
+			name:    "Unanchored Extension",
+			pattern: "*.go",
+			matches: []string{"main.go", "cmd/app/main.go"},
+			misses:  []string{"main.golang", "main.go.txt"},
+		},
+		{
+			name:    "Unanchored Name Matches At Any Depth",
+			pattern: "docs",
+			matches: []string{"docs", "docs/readme.md", "web/docs/index.html"},
+			misses:  []string{"mydocs/readme.md", "docs.md"},
+		},
+		{
+			name:    "Leading Slash Anchors To Root",
+			pattern: "/docs",
+			matches: []string{"docs", "docs/readme.md"},
+			misses:  []string{"web/docs/index.html"},
+		},
+		{
+			name:    "Inner Slash Anchors To Root",
+			pattern: "src/app",
+			matches: []string{"src/app", "src/app/main.go"},
+			misses:  []string{"lib/src/app/main.go", "src/application.go"},
+		},
+		{
+			name:    "Directory Star Matches Direct Children Only",
+			pattern: "dir/*",
+			matches: []string{"dir/a.go", "dir/.env"},
+			misses:  []string{"dir", "dir/sub/a.go", "other/dir/a.go"},
+		},
+		{
+			name:    "Trailing Slash Matches Everything In Directory",
+			pattern: "dir/",
+			matches: []string{"dir/a.go", "dir/sub/a.go", "other/dir/a.go"},
+			misses:  []string{"dir", "dirt/a.go"},
+		},
+		{
+			name:    "Anchored Trailing Slash",
+			pattern: "/dir/",
+			matches: []string{"dir/a.go", "dir/sub/a.go"},
+			misses:  []string{"other/dir/a.go"},
+		},
+		{
+			name:    "Double Star In The Middle",
+			pattern: "a/**/b",
+			matches: []string{"a/b", "a/x/b", "a/x/y/b", "a/x/b/c.go"},
+			misses:  []string{"a/xb", "c/a/x/b"},
+		},
+		{
+			name:    "Double Star At The End",
+			pattern: "logs/**",
+			matches: []string{"logs/app.log", "logs/2024/01/app.log"},
+			misses:  []string{"logs", "old/logs/app.log"},
+		},
+		{
+			name:    "Double Star At The Start",
+			pattern: "**/test",
+			matches: []string{"test", "test/a_test.go", "pkg/api/test/a_test.go"},
+			misses:  []string{"pkg/testing/a.go"},
+		},
+		{
+			name:    "Question Mark Matches One Character",
+			pattern: "?.txt",
+			matches: []string{"a.txt", "notes/b.txt"},
+			misses:  []string{"ab.txt", ".txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := compilePattern(tt.pattern)
+			require.NoError(t, err)
+
+			for _, path := range tt.matches {
+				assert.True(t, re.MatchString(path), "%q should match %q", tt.pattern, path)
+			}
+			for _, path := range tt.misses {
+				assert.False(t, re.MatchString(path), "%q should not match %q", tt.pattern, path)
+			}
+		})
+	}
+}
+
+func TestCompilePatternRejectsUnsupportedSyntax(t *testing.T) {
+	for _, pattern := range []string{"!*.go", "[ab].go", `a\*.go`, "/", "//"} {
+		_, err := compilePattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestOwnersOf(t *testing.T) {
+	rules, err := compileCodeOwners(`
+# Owners of everything not listed below
+*                 @org/all
+*.go              @gopher   # Go code
+/docs/            @writer
+docs/internal.go  @alice @bob
+`)
+	require.NoError(t, err)
+	require.Len(t, rules, 4)
+
+	tests := []struct {
+		name   string
+		path   string
+		owners []string
+		line   int
+	}{
+		{name: "Catch All", path: "README.md", owners: []string{"@org/all"}, line: 3},
+		{name: "Later Rule Overrides Catch All", path: "cmd/app/main.go", owners: []string{"@gopher"}, line: 4},
+		{name: "Leading Slash In Path", path: "/main.go", owners: []string{"@gopher"}, line: 4},
+		{name: "Directory Rule Overrides Extension", path: "docs/api.go", owners: []string{"@writer"}, line: 5},
+		{name: "Last Match Wins", path: "docs/internal.go", owners: []string{"@alice", "@bob"}, line: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := ownersOf(rules, tt.path)
+			require.True(t, ok)
+			assert.Equal(t, tt.owners, rule.Owners)
+			assert.Equal(t, tt.line, rule.Line)
+		})
+	}
+
+	t.Run("No Matching Rule", func(t *testing.T) {
+		rules, err := compileCodeOwners("/docs/ @writer")
+		require.NoError(t, err)
+
+		rule, ok := ownersOf(rules, "main.go")
+		assert.False(t, ok)
+		assert.Nil(t, rule)
+	})
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+// GitHub pull_request actions handled by the service
+const (
+	GitHubActionOpened         = "opened"
+	GitHubActionReadyForReview = "ready_for_review"
+	GitHubActionClosed         = "closed"
+	GitHubActionReopened       = "reopened"
+)
+
+// WebhookResult describes what happened to a webhook delivery
+type WebhookResult string
+
+// Possible webhook results
+const (
+	WebhookProcessed WebhookResult = "processed" // Event was applied to the PR
+	WebhookDuplicate WebhookResult = "duplicate" // Delivery was already received
+	WebhookIgnored   WebhookResult = "ignored"   // Event is not relevant for the service
+)
+
+// GitHubUser is a GitHub account referenced in webhook payloads
+type GitHubUser struct {
+	Login string `json:"login"`
+}
+
+// GitHubPullRequest is the part of a GitHub pull request used by the service
+type GitHubPullRequest struct {
+	Title  string     `json:"title"`
+	Draft  bool       `json:"draft"`
+	Merged bool       `json:"merged"`
+	User   GitHubUser `json:"user"`
+}
+
+// GitHubRepository is the repository a GitHub pull request belongs to
+type GitHubRepository struct {
+	FullName string `json:"full_name"`
+}
+
+// GitHubPullRequestEvent is the payload of a GitHub "pull_request" webhook
+type GitHubPullRequestEvent struct {
+	Action      string            `json:"action"`
+	Number      int               `json:"number"`
+	PullRequest GitHubPullRequest `json:"pull_request"`
+	Repository  GitHubRepository  `json:"repository"`
+	Sender      GitHubUser        `json:"sender"`
+}
+
+// PullRequestID returns the ID of the PR in the service, e.g. "octo-org/backend#42"
+func (e *GitHubPullRequestEvent) PullRequestID() string {
+	return fmt.Sprintf("%s#%d", e.Repository.FullName, e.Number)
+}
+
+// GitHubService maps GitHub webhook events onto pull request operations
+type GitHubService struct {
+	prService  *PullRequestService
+	userRepo   repository.UserRepository
+	deliveries repository.WebhookDeliveryRepository
+	secret     []byte
+}
+
+// NewGitHubService creates a new GitHubService.
+// With an empty webhookSecret every delivery is rejected.
+func NewGitHubService(
+	prService *PullRequestService,
+	userRepo repository.UserRepository,
+	deliveries repository.WebhookDeliveryRepository,
+	webhookSecret string,
+) *GitHubService {
+	return &GitHubService{
+		prService:  prService,
+		userRepo:   userRepo,
+		deliveries: deliveries,
+		secret:     []byte(webhookSecret),
+	}
+}
+
+// VerifySignature checks the X-Hub-Signature-256 header ("sha256=<hex HMAC of payload>")
+func (s *GitHubService) VerifySignature(payload []byte, signature string) error {
+	if len(s.secret) == 0 {
+		return domain.ErrUnauthorized
+	}
+
+	hexMAC, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return domain.ErrUnauthorized
+	}
+
+	received, err := hex.DecodeString(hexMAC)
+	if err != nil {
+		return domain.ErrUnauthorized
+	}
+
+	if !hmac.Equal(received, signPayload(s.secret, payload)) {
+		return domain.ErrUnauthorized
+	}
+
+	return nil
+}
+
+// HandlePullRequestEvent applies a "pull_request" event once per delivery ID.
+// A delivery that failed to apply is forgotten so that GitHub redelivery can retry it.
+func (s *GitHubService) HandlePullRequestEvent(
+	ctx context.Context,
+	deliveryID string,
+	event *GitHubPullRequestEvent,
+) (WebhookResult, error) {
+	switch event.Action {
+	case GitHubActionOpened, GitHubActionReadyForReview, GitHubActionClosed, GitHubActionReopened:
+	default:
+		return WebhookIgnored, nil
+	}
+
+	isNew, err := s.deliveries.Register(ctx, deliveryID, "pull_request")
+	if err != nil {
+		return "", err
+	}
+	if !isNew {
+		return WebhookDuplicate, nil
+	}
+
+	if err := s.applyPullRequestEvent(ctx, event); err != nil {
+		if forgetErr := s.deliveries.Forget(ctx, deliveryID); forgetErr != nil {
+			return "", errors.Join(err, forgetErr)
+		}
+		return "", err
+	}
+
+	return WebhookProcessed, nil
+}
+
+// applyPullRequestEvent calls the PR operation matching the event action
+func (s *GitHubService) applyPullRequestEvent(ctx context.Context, event *GitHubPullRequestEvent) error {
+	prID := event.PullRequestID()
+
+	actorID, err := s.resolveActor(ctx, event.Sender.Login)
+	if err != nil {
+		return err
+	}
+
+	switch event.Action {
+	case GitHubActionOpened:
+		author, err := s.userRepo.GetByGitHubLogin(ctx, event.PullRequest.User.Login)
+		if err != nil {
+			return err
+		}
+
+		// Reviewers come from the author's primary team; the webhook carries no changed files,
+		// so code owners are not taken into account
+		pr := &domain.PullRequest{
+			PullRequestID:   prID,
+			PullRequestName: event.PullRequest.Title,
+			AuthorID:        author.UserID,
+			Repository:      event.Repository.FullName,
+		}
+		_, err = s.prService.CreatePR(ctx, pr, event.PullRequest.Draft, actorID)
+		// The PR may have been created through the API before the webhook was set up
+		if err == domain.ErrPRExists {
+			return nil
+		}
+		return err
+	case GitHubActionReadyForReview:
+		_, err = s.prService.MarkReady(ctx, prID, actorID)
+	case GitHubActionReopened:
+		_, err = s.prService.ReopenPR(ctx, prID, actorID)
+	case GitHubActionClosed:
+		if event.PullRequest.Merged {
+			_, err = s.prService.MergePR(ctx, prID, actorID)
+		} else {
+			_, err = s.prService.ClosePR(ctx, prID, actorID)
+		}
+	}
+
+	return err
+}
+
+// resolveActor returns ID of the user linked to the GitHub login, or "" if nobody is linked
+func (s *GitHubService) resolveActor(ctx context.Context, login string) (string, error) {
+	if login == "" {
+		return "", nil
+	}
+
+	user, err := s.userRepo.GetByGitHubLogin(ctx, login)
+	if err == domain.ErrUserNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return user.UserID, nil
+}
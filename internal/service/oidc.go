@@ -0,0 +1,487 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+// oidcHTTPTimeout limits requests to the identity provider
+const oidcHTTPTimeout = 10 * time.Second
+
+// oidcSigningMethods lists ID token algorithms accepted from the provider; HMAC is excluded
+// because it would require the client secret as a verification key
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// OIDCSettings configures single sign-on through an OpenID Connect provider
+type OIDCSettings struct {
+	IssuerURL       string   // Provider issuer; discovery document is read from <issuer>/.well-known/openid-configuration
+	ClientID        string   // Client registered at the provider
+	ClientSecret    string   // Secret of the client
+	RedirectURL     string   // Callback URL registered at the provider
+	Scopes          []string // Requested scopes, "openid" is required
+	GroupsClaim     string   // ID token claim with the user's groups
+	GroupTeamPrefix string   // Only groups with this prefix name teams, the prefix is stripped
+	AutoProvision   bool     // Create unknown users in the team named by their groups
+	StateTTL        time.Duration
+}
+
+// oidcProvider holds the endpoints from the provider's discovery document
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCService implements the authorization code flow with PKCE and maps provider accounts to users
+type OIDCService struct {
+	identityRepo repository.IdentityRepository
+	userRepo     repository.UserRepository
+	teamRepo     repository.TeamRepository
+	authService  *AuthService
+	settings     OIDCSettings
+	client       *http.Client
+
+	mu       sync.Mutex
+	provider *oidcProvider
+	keys     map[string]crypto.PublicKey
+}
+
+// NewOIDCService creates a new OIDCService. The provider is discovered on the first login.
+func NewOIDCService(
+	identityRepo repository.IdentityRepository,
+	userRepo repository.UserRepository,
+	teamRepo repository.TeamRepository,
+	authService *AuthService,
+	settings OIDCSettings,
+) *OIDCService {
+	return &OIDCService{
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		teamRepo:     teamRepo,
+		authService:  authService,
+		settings:     settings,
+		client:       &http.Client{Timeout: oidcHTTPTimeout},
+	}
+}
+
+// AuthCodeURL starts a login and returns the provider URL the user should be redirected to
+func (s *OIDCService) AuthCodeURL(ctx context.Context) (string, error) {
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	loginState := &domain.OIDCLoginState{}
+	for _, value := range []*string{&loginState.State, &loginState.Nonce, &loginState.CodeVerifier} {
+		if *value, err = generateToken(""); err != nil {
+			return "", err
+		}
+	}
+
+	if err := s.identityRepo.SaveLoginState(ctx, loginState, s.settings.StateTTL); err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(loginState.CodeVerifier))
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", s.settings.ClientID)
+	query.Set("redirect_uri", s.settings.RedirectURL)
+	query.Set("scope", strings.Join(s.settings.Scopes, " "))
+	query.Set("state", loginState.State)
+	query.Set("nonce", loginState.Nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Callback completes a login: it exchanges the code for an ID token, maps the provider account
+// to a user and opens a session. Unknown or reused state is rejected with ErrInvalidToken.
+func (s *OIDCService) Callback(ctx context.Context, code, state string) (*TokenPair, error) {
+	loginState, err := s.identityRepo.ConsumeLoginState(ctx, state)
+	if err == domain.ErrNotFound {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := s.exchangeCode(ctx, provider, code, loginState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.verifyIDToken(ctx, provider, rawIDToken, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.StartSession(ctx, user)
+}
+
+// resolveUser finds the user linked to the provider account. An account that is not linked yet
+// is linked by verified email or, with auto-provisioning, created in the team named by its groups.
+func (s *OIDCService) resolveUser(ctx context.Context, identity *domain.OIDCIdentity) (*domain.User, error) {
+	user, err := s.identityRepo.GetUser(ctx, identity.Issuer, identity.Subject)
+	if err != domain.ErrUserNotFound {
+		return user, err
+	}
+
+	// Unverified emails are ignored, otherwise anyone could claim another user's email at the provider
+	if identity.Email != "" && identity.EmailVerified {
+		user, err := s.userRepo.GetByEmail(ctx, identity.Email)
+		if err == nil {
+			if err := s.identityRepo.Link(ctx, identity.Issuer, identity.Subject, user.UserID); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+		if err != domain.ErrUserNotFound {
+			return nil, err
+		}
+	}
+
+	if !s.settings.AutoProvision {
+		return nil, domain.ErrIdentityNotLinked
+	}
+
+	teamName, err := s.teamFromGroups(ctx, identity.Groups)
+	if err != nil {
+		return nil, err
+	}
+	if teamName == "" {
+		return nil, domain.ErrIdentityNotLinked
+	}
+
+	user = &domain.User{
+		UserID:   identity.Subject,
+		Username: identity.Name,
+		TeamName: teamName,
+		Teams:    []string{teamName},
+		IsActive: true,
+		Role:     domain.RoleMember,
+	}
+	if user.Username == "" {
+		user.Username = identity.Subject
+	}
+
+	email := ""
+	if identity.EmailVerified {
+		email = identity.Email
+	}
+
+	if err := s.identityRepo.Provision(ctx, user, email, identity.Issuer, identity.Subject); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// teamFromGroups returns the first existing team named by a group, or "" if there is none
+func (s *OIDCService) teamFromGroups(ctx context.Context, groups []string) (string, error) {
+	for _, group := range groups {
+		teamName, ok := strings.CutPrefix(group, s.settings.GroupTeamPrefix)
+		if !ok || teamName == "" {
+			continue
+		}
+
+		exists, err := s.teamRepo.Exists(ctx, teamName)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return teamName, nil
+		}
+	}
+	return "", nil
+}
+
+// discover reads and caches the provider's discovery document
+func (s *OIDCService) discover(ctx context.Context) (*oidcProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.provider != nil {
+		return s.provider, nil
+	}
+
+	var provider oidcProvider
+	discoveryURL := strings.TrimSuffix(s.settings.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := s.getJSON(ctx, discoveryURL, &provider); err != nil {
+		return nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+	}
+
+	if provider.Issuer != s.settings.IssuerURL {
+		return nil, fmt.Errorf("oidc provider issuer %q does not match %q", provider.Issuer, s.settings.IssuerURL)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery document is missing endpoints")
+	}
+
+	s.provider = &provider
+	return s.provider, nil
+}
+
+// exchangeCode redeems the authorization code at the token endpoint and returns the ID token
+func (s *OIDCService) exchangeCode(ctx context.Context, provider *oidcProvider, code, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.settings.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.settings.ClientID), url.QueryEscape(s.settings.ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange oidc code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// An expired, reused or forged code is the user's problem, not a server error
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		return "", domain.ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to decode oidc token response: %w", err)
+	}
+	if tokenResp.IDToken == "" {
+		return "", fmt.Errorf("oidc token response has no id_token")
+	}
+
+	return tokenResp.IDToken, nil
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience, expiry and nonce
+func (s *OIDCService) verifyIDToken(
+	ctx context.Context,
+	provider *oidcProvider,
+	rawIDToken, nonce string,
+) (*domain.OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return s.publicKey(ctx, provider, kid)
+		},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(s.settings.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, domain.ErrInvalidToken
+	}
+
+	identity := &domain.OIDCIdentity{Issuer: provider.Issuer}
+	identity.Subject, _ = claims["sub"].(string)
+	if identity.Subject == "" {
+		return nil, domain.ErrInvalidToken
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+
+	// Some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	switch groups := claims[s.settings.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	case string:
+		identity.Groups = []string{groups}
+	}
+
+	return identity, nil
+}
+
+// publicKey returns the provider key that signed an ID token. Keys are reloaded when the ID is unknown,
+// which picks up keys rotated at the provider.
+func (s *OIDCService) publicKey(ctx context.Context, provider *oidcProvider, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	var set domain.JWKS
+	if err := s.getJSON(ctx, provider.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch oidc keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// Keys for encryption and unsupported key types are skipped
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := parseJWK(jwk); err == nil {
+			keys[jwk.KeyID] = key
+		}
+	}
+	s.keys = keys
+
+	if key, ok := s.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown oidc signing key %q", kid)
+}
+
+// lookupKey finds a loaded key by ID; a token without ID matches the only key. Callers hold s.mu.
+func (s *OIDCService) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// getJSON fetches a JSON document from the provider
+func (s *OIDCService) getJSON(ctx context.Context, endpoint string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dst)
+}
+
+// parseJWK decodes an RSA, EC or Ed25519 public key
+func parseJWK(jwk domain.JWK) (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[jwk.Curve]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid ec point size")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+}
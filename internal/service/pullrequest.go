@@ -2,37 +2,47 @@ package service
 
 import (
 	"context"
+	"slices"
 
 	"github.com/aidar/avito-pr-project/internal/domain"
 	"github.com/aidar/avito-pr-project/internal/repository"
 )
 
-const maxReviewers = 2
-
 // PullRequestService handles business logic for pull requests
 type PullRequestService struct {
-	prRepo           repository.PullRequestRepository
-	userRepo         repository.UserRepository
-	reviewerSelector *ReviewerSelector
+	prRepo     repository.PullRequestRepository
+	userRepo   repository.UserRepository
+	selectors  *SelectorRegistry
+	codeOwners *CodeOwnersService
 }
 
 // NewPullRequestService creates a new PullRequestService
 func NewPullRequestService(
 	prRepo repository.PullRequestRepository,
 	userRepo repository.UserRepository,
-	reviewerSelector *ReviewerSelector,
+	selectors *SelectorRegistry,
+	codeOwners *CodeOwnersService,
 ) *PullRequestService {
 	return &PullRequestService{
-		prRepo:           prRepo,
-		userRepo:         userRepo,
-		reviewerSelector: reviewerSelector,
+		prRepo:     prRepo,
+		userRepo:   userRepo,
+		selectors:  selectors,
+		codeOwners: codeOwners,
 	}
 }
 
-// CreatePR creates a new PR and automatically assigns up to 2 reviewers from author's team
-func (s *PullRequestService) CreatePR(ctx context.Context, prID, prName, authorID string) (*domain.PullRequest, error) {
+// CreatePR creates a new PR reviewed by pr.TeamName, one of the author's teams (their primary team if empty).
+// The PR is created as a DRAFT and, unless draft is requested, marked ready for review right away.
+// Marking ready assigns up to max_reviewers of that team, code owners of the changed files first
+// and the team's fallback teams last.
+func (s *PullRequestService) CreatePR(
+	ctx context.Context,
+	pr *domain.PullRequest,
+	draft bool,
+	actorID string,
+) (*domain.PullRequest, error) {
 	// Check if PR already exists
-	exists, err := s.prRepo.Exists(ctx, prID)
+	exists, err := s.prRepo.Exists(ctx, pr.PullRequestID)
 	if err != nil {
 		return nil, err
 	}
@@ -41,53 +51,213 @@ func (s *PullRequestService) CreatePR(ctx context.Context, prID, prName, authorI
 	}
 
 	// Get author to find their team
-	author, err := s.userRepo.GetByID(ctx, authorID)
+	author, err := s.userRepo.GetByID(ctx, pr.AuthorID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get active team members excluding author
-	candidates, err := s.userRepo.GetActiveTeamMembers(ctx, author.TeamName, authorID)
+	if pr.TeamName == "" {
+		pr.TeamName = author.TeamName
+	} else if !slices.Contains(author.Teams, pr.TeamName) {
+		return nil, domain.ErrNotTeamMember
+	}
+
+	// Reviewers are assigned on the DRAFT -> OPEN transition done in the same transaction
+	pr.AssignedReviewers = []string{}
+	if !draft {
+		pr.AssignedReviewers, pr.FallbackReviewers, err = s.selectInitialReviewers(ctx, pr, pr.TeamName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.prRepo.Create(ctx, pr, !draft, actorID); err != nil {
+		return nil, err
+	}
+
+	// Return the created PR
+	return s.GetByID(ctx, pr.PullRequestID)
+}
+
+// selectInitialReviewers selects reviewers for a PR that becomes OPEN
+// using the strategy and limit configured for the PR's team: code owners
+// of the changed files first, then the rest of the team. Slots the team
+// can't fill are taken from its fallback teams in order; those reviewers
+// are returned separately as well.
+func (s *PullRequestService) selectInitialReviewers(
+	ctx context.Context,
+	pr *domain.PullRequest,
+	teamName string,
+) ([]string, []domain.FallbackReviewer, error) {
+	settings, err := s.selectors.Settings(ctx, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+	selector := s.selectors.Selector(settings)
+
+	reviewers, err := s.codeOwners.selectOwners(ctx, pr, teamName, selector, settings.MaxReviewers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The PR's team goes first, then the fallbacks
+	var fallbackReviewers []domain.FallbackReviewer
+	pools := append([]string{teamName}, settings.FallbackTeams...)
+	for i, pool := range pools {
+		if len(reviewers) >= settings.MaxReviewers {
+			break
+		}
+
+		// Get active members excluding author and the reviewers already picked
+		members, err := s.userRepo.GetActiveTeamMembers(ctx, pool, pr.AuthorID)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates := slices.DeleteFunc(members, func(u *domain.User) bool {
+			return slices.Contains(reviewers, u.UserID)
+		})
+
+		picked, err := selector.SelectReviewers(ctx, pool, candidates, settings.MaxReviewers-len(reviewers))
+		if err != nil {
+			return nil, nil, err
+		}
+		reviewers = append(reviewers, picked...)
+
+		if i > 0 {
+			for _, id := range picked {
+				fallbackReviewers = append(fallbackReviewers, domain.FallbackReviewer{UserID: id, TeamName: pool})
+			}
+		}
+	}
+
+	return reviewers, fallbackReviewers, nil
+}
+
+// reviewTeam returns the team that reviews the PR: the one chosen at creation
+// or, for PRs without a team, the author's primary team
+func (s *PullRequestService) reviewTeam(ctx context.Context, pr *domain.PullRequest) (string, error) {
+	if pr.TeamName != "" {
+		return pr.TeamName, nil
+	}
+
+	author, err := s.userRepo.GetByID(ctx, pr.AuthorID)
+	if err != nil {
+		return "", err
+	}
+	return author.TeamName, nil
+}
+
+// MarkReady moves a draft to OPEN and assigns reviewers (idempotent for open PRs)
+func (s *PullRequestService) MarkReady(ctx context.Context, prID, actorID string) (*domain.PullRequest, error) {
+	return s.openPR(ctx, prID, domain.StatusDraft, actorID)
+}
+
+// ReopenPR moves a closed PR back to OPEN and assigns fresh reviewers (idempotent for open PRs)
+func (s *PullRequestService) ReopenPR(ctx context.Context, prID, actorID string) (*domain.PullRequest, error) {
+	return s.openPR(ctx, prID, domain.StatusClosed, actorID)
+}
+
+// openPR moves a PR from the given status to OPEN with automatically selected reviewers
+func (s *PullRequestService) openPR(
+	ctx context.Context,
+	prID string,
+	from domain.PullRequestStatus,
+	actorID string,
+) (*domain.PullRequest, error) {
+	pr, err := s.prRepo.GetByID(ctx, prID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Select up to 2 reviewers
-	reviewers := s.reviewerSelector.SelectReviewers(candidates, maxReviewers)
+	switch pr.Status {
+	case domain.StatusOpen:
+		return s.GetByID(ctx, prID)
+	case domain.StatusMerged:
+		return nil, domain.ErrPRMerged
+	case from:
+	default:
+		return nil, domain.ErrInvalidStatus
+	}
 
-	// Create PR
-	pr := &domain.PullRequest{
-		PullRequestID:     prID,
-		PullRequestName:   prName,
-		AuthorID:          authorID,
-		Status:            domain.StatusOpen,
-		AssignedReviewers: reviewers,
+	teamName, err := s.reviewTeam(ctx, pr)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.prRepo.Create(ctx, pr); err != nil {
+	reviewers, fallbackReviewers, err := s.selectInitialReviewers(ctx, pr, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.prRepo.OpenWithReviewers(ctx, prID, from, reviewers, fallbackReviewers, actorID); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, prID)
+}
+
+// ClosePR closes an open PR or a draft without merging and frees its reviewers (idempotent)
+func (s *PullRequestService) ClosePR(ctx context.Context, prID, actorID string) (*domain.PullRequest, error) {
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch pr.Status {
+	case domain.StatusClosed:
+		return pr, nil
+	case domain.StatusMerged:
+		return nil, domain.ErrPRMerged
+	}
+
+	if err := s.prRepo.Close(ctx, prID, actorID); err != nil {
 		return nil, err
 	}
 
-	// Return the created PR
 	return s.prRepo.GetByID(ctx, prID)
 }
 
-// MergePR marks a PR as merged (idempotent operation)
-func (s *PullRequestService) MergePR(ctx context.Context, prID string) (*domain.PullRequest, error) {
-	return s.prRepo.Merge(ctx, prID)
+// MergePR marks a PR as merged (idempotent operation).
+// An open PR is merged only if it satisfies the merge rule of the PR's team.
+func (s *PullRequestService) MergePR(ctx context.Context, prID, actorID string) (*domain.PullRequest, error) {
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Already merged PRs are returned as is; drafts and closed PRs cannot be merged
+	if pr.IsMerged() {
+		return pr, nil
+	}
+	if err := pr.CheckOpen(); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.reviewSettings(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The repository re-checks the status and counts the verdicts under a lock of the PR row,
+	// so a PR closed or reviewed concurrently is not merged against a stale snapshot
+	return s.prRepo.Merge(ctx, prID, settings.RequiredApprovals, actorID)
 }
 
-// ReassignReviewer replaces old reviewer with a new one from the old reviewer's team
-func (s *PullRequestService) ReassignReviewer(ctx context.Context, prID, oldReviewerID string) (*domain.PullRequest, string, error) {
+// ReassignReviewer replaces old reviewer with newReviewerID or, if it is empty, with one selected
+// from the PR's team (the old reviewer's primary team for PRs without a team)
+func (s *PullRequestService) ReassignReviewer(
+	ctx context.Context,
+	prID, oldReviewerID, newReviewerID, actorID string,
+) (*domain.PullRequest, string, error) {
 	// Get PR
 	pr, err := s.prRepo.GetByID(ctx, prID)
 	if err != nil {
 		return nil, "", err
 	}
 
-	// Check if PR is merged
-	if pr.IsMerged() {
-		return nil, "", domain.ErrPRMerged
+	// Check if PR is open (not merged, closed or draft)
+	if err := pr.CheckOpen(); err != nil {
+		return nil, "", err
 	}
 
 	// Check if old reviewer is assigned
@@ -95,32 +265,58 @@ func (s *PullRequestService) ReassignReviewer(ctx context.Context, prID, oldRevi
 		return nil, "", domain.ErrNotAssigned
 	}
 
-	// Get old reviewer to find their team
-	oldReviewer, err := s.userRepo.GetByID(ctx, oldReviewerID)
+	if newReviewerID != "" {
+		if err := s.checkExplicitReviewer(ctx, pr, newReviewerID); err != nil {
+			return nil, "", err
+		}
+		return s.replaceReviewer(ctx, prID, oldReviewerID, newReviewerID, actorID)
+	}
+
+	teamName := pr.TeamName
+	if teamName == "" {
+		// Get old reviewer to find their team
+		oldReviewer, err := s.userRepo.GetByID(ctx, oldReviewerID)
+		if err != nil {
+			return nil, "", err
+		}
+		teamName = oldReviewer.TeamName
+	}
+
+	// Get active team members
+	// Don't exclude anyone initially - we'll filter in SelectReplacement
+	candidates, err := s.userRepo.GetActiveTeamMembers(ctx, teamName, "")
 	if err != nil {
 		return nil, "", err
 	}
 
-	// Get active team members from old reviewer's team
-	// Don't exclude anyone initially - we'll filter in SelectReplacement
-	candidates, err := s.userRepo.GetActiveTeamMembers(ctx, oldReviewer.TeamName, "")
+	// Select a replacement using the strategy of the team, excluding current reviewers
+	// and the author, who is always a member of the PR's team
+	selector, err := s.selectors.ForTeam(ctx, teamName)
 	if err != nil {
 		return nil, "", err
 	}
 
-	// Select a replacement (excluding current reviewers)
-	newReviewerID, err := s.reviewerSelector.SelectReplacement(candidates, pr.AssignedReviewers)
+	excluded := append([]string{pr.AuthorID}, pr.AssignedReviewers...)
+	newReviewerID, err = selector.SelectReplacement(ctx, teamName, candidates, excluded)
 	if err != nil {
 		return nil, "", err
 	}
 
+	return s.replaceReviewer(ctx, prID, oldReviewerID, newReviewerID, actorID)
+}
+
+// replaceReviewer stores the replacement and returns the updated PR
+func (s *PullRequestService) replaceReviewer(
+	ctx context.Context,
+	prID, oldReviewerID, newReviewerID, actorID string,
+) (*domain.PullRequest, string, error) {
 	// Update reviewers
-	if err := s.prRepo.UpdateReviewers(ctx, prID, oldReviewerID, newReviewerID); err != nil {
+	if err := s.prRepo.UpdateReviewers(ctx, prID, oldReviewerID, newReviewerID, actorID); err != nil {
 		return nil, "", err
 	}
 
 	// Return updated PR
-	updatedPR, errGet := s.prRepo.GetByID(ctx, prID)
+	updatedPR, errGet := s.GetByID(ctx, prID)
 	if errGet != nil {
 		return nil, "", errGet
 	}
@@ -128,12 +324,166 @@ func (s *PullRequestService) ReassignReviewer(ctx context.Context, prID, oldRevi
 	return updatedPR, newReviewerID, nil
 }
 
-// GetPRsByReviewer returns all PRs where user is assigned as reviewer
-func (s *PullRequestService) GetPRsByReviewer(ctx context.Context, userID string) ([]*domain.PullRequestShort, error) {
-	return s.prRepo.GetByReviewer(ctx, userID)
+// checkExplicitReviewer checks that a reviewer chosen by hand can review the PR: the user
+// must be active, not absent today, a member of some non-archived team, not the author and
+// not assigned yet. Membership in the PR's team and open review limits are not checked,
+// an explicit choice overrides them.
+func (s *PullRequestService) checkExplicitReviewer(ctx context.Context, pr *domain.PullRequest, reviewerID string) error {
+	if reviewerID == pr.AuthorID {
+		return domain.ErrAuthorReviewer
+	}
+	if pr.IsReviewerAssigned(reviewerID) {
+		return domain.ErrAlreadyAssigned
+	}
+
+	reviewer, err := s.userRepo.GetByID(ctx, reviewerID)
+	if err != nil {
+		return err
+	}
+	if !reviewer.IsActive {
+		return domain.ErrReviewerInactive
+	}
+
+	available, err := s.userRepo.IsAvailable(ctx, reviewerID)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return domain.ErrReviewerUnavailable
+	}
+
+	return nil
+}
+
+// AddReviewer assigns a reviewer chosen by hand to an open PR
+// as long as the PR stays within max_reviewers of its team. The limit is
+// checked by the repository under a lock of the PR row.
+func (s *PullRequestService) AddReviewer(ctx context.Context, prID, reviewerID, actorID string) (*domain.PullRequest, error) {
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pr.CheckOpen(); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkExplicitReviewer(ctx, pr, reviewerID); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.reviewSettings(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prRepo.AddReviewer(ctx, prID, reviewerID, settings.MaxReviewers, actorID); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, prID)
+}
+
+// RemoveReviewer unassigns a reviewer from an open PR without a replacement
+// as long as the PR keeps min_reviewers of its team. The limit is checked
+// by the repository under a lock of the PR row.
+func (s *PullRequestService) RemoveReviewer(ctx context.Context, prID, reviewerID, actorID string) (*domain.PullRequest, error) {
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pr.CheckOpen(); err != nil {
+		return nil, err
+	}
+
+	if !pr.IsReviewerAssigned(reviewerID) {
+		return nil, domain.ErrNotAssigned
+	}
+
+	settings, err := s.reviewSettings(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prRepo.RemoveReviewer(ctx, prID, reviewerID, settings.MinReviewers, actorID); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, prID)
+}
+
+// reviewSettings returns the reviewer settings of the team that reviews the PR
+func (s *PullRequestService) reviewSettings(ctx context.Context, pr *domain.PullRequest) (*domain.TeamSettings, error) {
+	teamName, err := s.reviewTeam(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	return s.selectors.Settings(ctx, teamName)
+}
+
+// GetPRsByReviewer returns all PRs where user is assigned as reviewer,
+// optionally skipping the ones the user has already approved
+func (s *PullRequestService) GetPRsByReviewer(
+	ctx context.Context,
+	userID string,
+	excludeApproved bool,
+) ([]*domain.PullRequestShort, error) {
+	return s.prRepo.GetByReviewer(ctx, userID, excludeApproved)
 }
 
-// GetByID retrieves a PR by ID
+// SubmitReview records a verdict of an assigned reviewer on an open PR
+func (s *PullRequestService) SubmitReview(
+	ctx context.Context,
+	prID, reviewerID string,
+	verdict domain.ReviewVerdict,
+) (*domain.PullRequest, error) {
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pr.CheckOpen(); err != nil {
+		return nil, err
+	}
+
+	// Only currently assigned reviewers may submit a verdict
+	if !pr.IsReviewerAssigned(reviewerID) {
+		return nil, domain.ErrNotAssigned
+	}
+
+	review := &domain.Review{
+		UserID:  reviewerID,
+		Verdict: verdict,
+	}
+	if err := s.prRepo.AddReview(ctx, prID, review); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, prID)
+}
+
+// GetTimeline returns the history of PR events in the order they happened
+func (s *PullRequestService) GetTimeline(ctx context.Context, prID string) ([]*domain.PREvent, error) {
+	return s.prRepo.GetTimeline(ctx, prID)
+}
+
+// GetByID retrieves a PR by ID and reports how many reviewers an open PR lacks
+// to reach the minimum of the PR's team
 func (s *PullRequestService) GetByID(ctx context.Context, prID string) (*domain.PullRequest, error) {
-	return s.prRepo.GetByID(ctx, prID)
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only open PRs are expected to have reviewers
+	if !pr.IsOpen() {
+		return pr, nil
+	}
+
+	settings, err := s.reviewSettings(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	pr.MissingReviewers = max(0, settings.MinReviewers-len(pr.AssignedReviewers))
+	return pr, nil
 }
@@ -1,40 +1,45 @@
 package service
 
 import (
+	"context"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/aidar/avito-pr-project/internal/domain"
 )
 
-// ReviewerSelector handles the logic of selecting reviewers
-type ReviewerSelector struct {
-	rng *rand.Rand
+// ReviewerSelector chooses reviewers from a list of candidates.
+// Implementations differ only in how candidates are ranked.
+type ReviewerSelector interface {
+	// SelectReviewers selects up to maxReviewers from candidates of the given team
+	SelectReviewers(ctx context.Context, teamName string, candidates []*domain.User, maxReviewers int) ([]string, error)
+
+	// SelectReplacement selects one candidate that is not in excluded.
+	// Returns domain.ErrNoCandidate if nobody is available.
+	SelectReplacement(ctx context.Context, teamName string, candidates []*domain.User, excluded []string) (string, error)
 }
 
-// NewReviewerSelector creates a new ReviewerSelector with its own random source
-func NewReviewerSelector() *ReviewerSelector {
-	return &ReviewerSelector{
-		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+// RandomSelector selects reviewers uniformly at random
+type RandomSelector struct {
+	rng *lockedRand
+}
+
+// NewRandomSelector creates a new RandomSelector with its own random source
+func NewRandomSelector() *RandomSelector {
+	return &RandomSelector{
+		rng: newLockedRand(),
 	}
 }
 
 // SelectReviewers randomly selects up to maxReviewers from candidates
 // Returns the selected reviewer IDs
-func (s *ReviewerSelector) SelectReviewers(candidates []*domain.User, maxReviewers int) []string {
-	if len(candidates) == 0 {
-		return []string{}
-	}
-
-	// If we have fewer candidates than needed, return all
-	if len(candidates) <= maxReviewers {
-		reviewers := make([]string, len(candidates))
-		for i, c := range candidates {
-			reviewers[i] = c.UserID
-		}
-		return reviewers
-	}
-
+func (s *RandomSelector) SelectReviewers(
+	_ context.Context,
+	_ string,
+	candidates []*domain.User,
+	maxReviewers int,
+) ([]string, error) {
 	// Randomly shuffle candidates and take first maxReviewers
 	shuffled := make([]*domain.User, len(candidates))
 	copy(shuffled, candidates)
@@ -42,36 +47,97 @@ func (s *ReviewerSelector) SelectReviewers(candidates []*domain.User, maxReviewe
 		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
 	})
 
-	reviewers := make([]string, maxReviewers)
-	for i := 0; i < maxReviewers; i++ {
-		reviewers[i] = shuffled[i].UserID
+	return takeIDs(shuffled, maxReviewers), nil
+}
+
+// SelectReplacement randomly selects one replacement from candidates, excluding current reviewers
+func (s *RandomSelector) SelectReplacement(
+	ctx context.Context,
+	teamName string,
+	candidates []*domain.User,
+	excluded []string,
+) (string, error) {
+	return selectReplacement(ctx, s, teamName, candidates, excluded)
+}
+
+// selectReplacement filters out excluded users and lets the selector pick one of the rest
+func selectReplacement(
+	ctx context.Context,
+	selector ReviewerSelector,
+	teamName string,
+	candidates []*domain.User,
+	excluded []string,
+) (string, error) {
+	available := excludeUsers(candidates, excluded)
+	if len(available) == 0 {
+		return "", domain.ErrNoCandidate
+	}
+
+	selected, err := selector.SelectReviewers(ctx, teamName, available, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(selected) == 0 {
+		return "", domain.ErrNoCandidate
 	}
 
-	return reviewers
+	return selected[0], nil
 }
 
-// SelectReplacement randomly selects one replacement from candidates, excluding current reviewers
-func (s *ReviewerSelector) SelectReplacement(candidates []*domain.User, currentReviewers []string) (string, error) {
-	// Filter out users who are already assigned as reviewers
-	available := make([]*domain.User, 0)
+// excludeUsers returns candidates whose IDs are not in excluded
+func excludeUsers(candidates []*domain.User, excluded []string) []*domain.User {
+	skip := make(map[string]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+
+	available := make([]*domain.User, 0, len(candidates))
 	for _, candidate := range candidates {
-		isAlreadyAssigned := false
-		for _, reviewerID := range currentReviewers {
-			if candidate.UserID == reviewerID {
-				isAlreadyAssigned = true
-				break
-			}
-		}
-		if !isAlreadyAssigned {
+		if !skip[candidate.UserID] {
 			available = append(available, candidate)
 		}
 	}
+	return available
+}
 
-	if len(available) == 0 {
-		return "", domain.ErrNoCandidate
+// takeIDs returns IDs of the first n users (or all of them if there are fewer)
+func takeIDs(users []*domain.User, n int) []string {
+	n = max(0, min(n, len(users)))
+
+	ids := make([]string, n)
+	for i := 0; i < n; i++ {
+		ids[i] = users[i].UserID
+	}
+	return ids
+}
+
+// userIDs returns IDs of all users
+func userIDs(users []*domain.User) []string {
+	return takeIDs(users, len(users))
+}
+
+// lockedRand is a random source safe for concurrent use by request handlers
+type lockedRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func newLockedRand() *lockedRand {
+	return &lockedRand{
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
+}
+
+// Shuffle pseudo-randomizes the order of n elements
+func (r *lockedRand) Shuffle(n int, swap func(i, j int)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rng.Shuffle(n, swap)
+}
 
-	// Randomly select one
-	selected := available[s.rng.Intn(len(available))]
-	return selected.UserID, nil
+// Intn returns a non-negative pseudo-random number in [0,n)
+func (r *lockedRand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Intn(n)
 }
@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+// RoundRobinSelector hands out reviews to team members in turn (ordered by user ID).
+// The rotation position is kept in memory per team, so it restarts with the process.
+type RoundRobinSelector struct {
+	mu         sync.Mutex
+	lastByTeam map[string]string // ID of the last selected reviewer per team
+}
+
+// NewRoundRobinSelector creates a new RoundRobinSelector
+func NewRoundRobinSelector() *RoundRobinSelector {
+	return &RoundRobinSelector{
+		lastByTeam: make(map[string]string),
+	}
+}
+
+// SelectReviewers selects up to maxReviewers candidates following the last selected one
+func (s *RoundRobinSelector) SelectReviewers(
+	_ context.Context,
+	teamName string,
+	candidates []*domain.User,
+	maxReviewers int,
+) ([]string, error) {
+	count := min(maxReviewers, len(candidates))
+	if count <= 0 {
+		return []string{}, nil
+	}
+
+	sorted := make([]*domain.User, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].UserID < sorted[j].UserID
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Continue right after the last selected reviewer; wrap around at the end
+	last := s.lastByTeam[teamName]
+	start := sort.Search(len(sorted), func(i int) bool {
+		return sorted[i].UserID > last
+	})
+
+	reviewers := make([]string, count)
+	for i := 0; i < count; i++ {
+		reviewers[i] = sorted[(start+i)%len(sorted)].UserID
+	}
+	s.lastByTeam[teamName] = reviewers[count-1]
+
+	return reviewers, nil
+}
+
+// SelectReplacement selects the next candidate in rotation, excluding current reviewers
+func (s *RoundRobinSelector) SelectReplacement(
+	ctx context.Context,
+	teamName string,
+	candidates []*domain.User,
+	excluded []string,
+) (string, error) {
+	return selectReplacement(ctx, s, teamName, candidates, excluded)
+}
+
+// LeastLoadedSelector prefers candidates with the fewest OPEN pull requests to review.
+// Ties are broken randomly.
+type LeastLoadedSelector struct {
+	prRepo repository.PullRequestRepository
+	rng    *lockedRand
+}
+
+// NewLeastLoadedSelector creates a new LeastLoadedSelector
+func NewLeastLoadedSelector(prRepo repository.PullRequestRepository) *LeastLoadedSelector {
+	return &LeastLoadedSelector{
+		prRepo: prRepo,
+		rng:    newLockedRand(),
+	}
+}
+
+// SelectReviewers selects up to maxReviewers least loaded candidates
+func (s *LeastLoadedSelector) SelectReviewers(
+	ctx context.Context,
+	_ string,
+	candidates []*domain.User,
+	maxReviewers int,
+) ([]string, error) {
+	if len(candidates) == 0 || maxReviewers <= 0 {
+		return []string{}, nil
+	}
+
+	load, err := s.loadOf(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shuffle first so that the stable sort leaves equally loaded candidates in random order
+	ranked := make([]*domain.User, len(candidates))
+	copy(ranked, candidates)
+	s.rng.Shuffle(len(ranked), func(i, j int) {
+		ranked[i], ranked[j] = ranked[j], ranked[i]
+	})
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return load[ranked[i].UserID] < load[ranked[j].UserID]
+	})
+
+	return takeIDs(ranked, maxReviewers), nil
+}
+
+// loadOf returns open review counts of the candidates
+func (s *LeastLoadedSelector) loadOf(ctx context.Context, candidates []*domain.User) (map[string]int, error) {
+	return openReviewCounts(ctx, s.prRepo, userIDs(candidates))
+}
+
+// openReviewCounts returns open review counts of the users. Inside a batch the counts
+// are read from the repository once and then reused.
+func openReviewCounts(ctx context.Context, prRepo repository.PullRequestRepository, ids []string) (map[string]int, error) {
+	batch := batchLoadFrom(ctx)
+	if batch == nil {
+		return prRepo.CountOpenReviews(ctx, ids)
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if _, ok := batch.counts[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		counts, err := prRepo.CountOpenReviews(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for id, count := range counts {
+			batch.counts[id] = count
+		}
+	}
+
+	return batch.counts, nil
+}
+
+// batchLoad holds review counts for a series of selections that are persisted
+// together (e.g. bulk deactivation), including picks made earlier in the series.
+// The picks are counted by capacitySelector, which wraps every strategy.
+type batchLoad struct {
+	counts map[string]int
+}
+
+type batchLoadKey struct{}
+
+// withBatchLoad marks ctx as a batch of selections persisted in one transaction
+func withBatchLoad(ctx context.Context) context.Context {
+	return context.WithValue(ctx, batchLoadKey{}, &batchLoad{counts: make(map[string]int)})
+}
+
+// batchLoadFrom returns the batch attached to ctx, or nil outside a batch
+func batchLoadFrom(ctx context.Context) *batchLoad {
+	batch, _ := ctx.Value(batchLoadKey{}).(*batchLoad)
+	return batch
+}
+
+// SelectReplacement selects the least loaded candidate, excluding current reviewers
+func (s *LeastLoadedSelector) SelectReplacement(
+	ctx context.Context,
+	teamName string,
+	candidates []*domain.User,
+	excluded []string,
+) (string, error) {
+	return selectReplacement(ctx, s, teamName, candidates, excluded)
+}
+
+// WeightedSelector selects candidates at random with probability proportional to
+// their review weight. Candidates with zero weight are never selected.
+type WeightedSelector struct {
+	userRepo repository.UserRepository
+	rng      *lockedRand
+}
+
+// NewWeightedSelector creates a new WeightedSelector
+func NewWeightedSelector(userRepo repository.UserRepository) *WeightedSelector {
+	return &WeightedSelector{
+		userRepo: userRepo,
+		rng:      newLockedRand(),
+	}
+}
+
+// SelectReviewers draws up to maxReviewers candidates without replacement
+func (s *WeightedSelector) SelectReviewers(
+	ctx context.Context,
+	_ string,
+	candidates []*domain.User,
+	maxReviewers int,
+) ([]string, error) {
+	if len(candidates) == 0 || maxReviewers <= 0 {
+		return []string{}, nil
+	}
+
+	weights, err := s.userRepo.GetReviewWeights(ctx, userIDs(candidates))
+	if err != nil {
+		return nil, err
+	}
+
+	pool := make([]*domain.User, 0, len(candidates))
+	total := 0
+	for _, c := range candidates {
+		if w := weights[c.UserID]; w > 0 {
+			pool = append(pool, c)
+			total += w
+		}
+	}
+
+	reviewers := make([]string, 0, min(maxReviewers, len(pool)))
+	for len(reviewers) < maxReviewers && len(pool) > 0 {
+		// Walk the cumulative weights until the random point is reached
+		point := s.rng.Intn(total)
+		idx := 0
+		for ; idx < len(pool)-1; idx++ {
+			point -= weights[pool[idx].UserID]
+			if point < 0 {
+				break
+			}
+		}
+
+		selected := pool[idx]
+		reviewers = append(reviewers, selected.UserID)
+		total -= weights[selected.UserID]
+		pool = append(pool[:idx], pool[idx+1:]...)
+	}
+
+	return reviewers, nil
+}
+
+// SelectReplacement draws one weighted candidate, excluding current reviewers
+func (s *WeightedSelector) SelectReplacement(
+	ctx context.Context,
+	teamName string,
+	candidates []*domain.User,
+	excluded []string,
+) (string, error) {
+	return selectReplacement(ctx, s, teamName, candidates, excluded)
+}
+
+// capacitySelector skips candidates who already have as many open reviews as their limit allows
+// (the user's own max_open_reviews or, without it, the team's) and lets the strategy choose among the rest
+type capacitySelector struct {
+	strategy     ReviewerSelector
+	userRepo     repository.UserRepository
+	prRepo       repository.PullRequestRepository
+	defaultLimit *int // Team's max_open_reviews; nil - unlimited
+}
+
+// SelectReviewers selects up to maxReviewers candidates who are below their limit
+func (s *capacitySelector) SelectReviewers(
+	ctx context.Context,
+	teamName string,
+	candidates []*domain.User,
+	maxReviewers int,
+) ([]string, error) {
+	available, err := s.withinCapacity(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pick(ctx, teamName, available, maxReviewers)
+}
+
+// SelectReplacement selects one candidate below their limit who is not in excluded.
+// Returns domain.ErrNoCandidate if nobody is active and domain.ErrCandidatesAtCapacity
+// if everyone active is at their limit.
+func (s *capacitySelector) SelectReplacement(
+	ctx context.Context,
+	teamName string,
+	candidates []*domain.User,
+	excluded []string,
+) (string, error) {
+	available := excludeUsers(candidates, excluded)
+	if len(available) == 0 {
+		return "", domain.ErrNoCandidate
+	}
+
+	available, err := s.withinCapacity(ctx, available)
+	if err != nil {
+		return "", err
+	}
+	if len(available) == 0 {
+		return "", domain.ErrCandidatesAtCapacity
+	}
+
+	selected, err := s.pick(ctx, teamName, available, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(selected) == 0 {
+		return "", domain.ErrNoCandidate
+	}
+
+	return selected[0], nil
+}
+
+// pick lets the strategy select reviewers and counts them within a batch
+func (s *capacitySelector) pick(
+	ctx context.Context,
+	teamName string,
+	candidates []*domain.User,
+	maxReviewers int,
+) ([]string, error) {
+	reviewers, err := s.strategy.SelectReviewers(ctx, teamName, candidates, maxReviewers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Within a batch the picks are not persisted yet, so count them here
+	if batch := batchLoadFrom(ctx); batch != nil {
+		for _, id := range reviewers {
+			batch.counts[id]++
+		}
+	}
+
+	return reviewers, nil
+}
+
+// withinCapacity returns candidates whose open reviews are below their limit
+func (s *capacitySelector) withinCapacity(ctx context.Context, candidates []*domain.User) ([]*domain.User, error) {
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	ids := userIDs(candidates)
+	limits, err := s.userRepo.GetMaxOpenReviews(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without limits the counts are only needed to track the picks of a batch
+	if s.defaultLimit == nil && len(limits) == 0 && batchLoadFrom(ctx) == nil {
+		return candidates, nil
+	}
+
+	counts, err := openReviewCounts(ctx, s.prRepo, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	available := make([]*domain.User, 0, len(candidates))
+	for _, c := range candidates {
+		limit, ok := limits[c.UserID]
+		if !ok {
+			if s.defaultLimit == nil {
+				available = append(available, c)
+				continue
+			}
+			limit = *s.defaultLimit
+		}
+		if counts[c.UserID] < limit {
+			available = append(available, c)
+		}
+	}
+
+	return available, nil
+}
+
+// SelectorRegistry resolves the reviewer selection strategy configured for a team
+type SelectorRegistry struct {
+	teamRepo        repository.TeamRepository
+	userRepo        repository.UserRepository
+	prRepo          repository.PullRequestRepository
+	selectors       map[domain.ReviewerStrategy]ReviewerSelector
+	defaultStrategy domain.ReviewerStrategy
+}
+
+// NewSelectorRegistry creates a registry of all supported strategies.
+// defaultStrategy is used for teams without their own setting (random if not valid).
+func NewSelectorRegistry(
+	teamRepo repository.TeamRepository,
+	userRepo repository.UserRepository,
+	prRepo repository.PullRequestRepository,
+	defaultStrategy domain.ReviewerStrategy,
+) *SelectorRegistry {
+	if !defaultStrategy.IsValid() {
+		defaultStrategy = domain.StrategyRandom
+	}
+
+	return &SelectorRegistry{
+		teamRepo: teamRepo,
+		userRepo: userRepo,
+		prRepo:   prRepo,
+		selectors: map[domain.ReviewerStrategy]ReviewerSelector{
+			domain.StrategyRandom:      NewRandomSelector(),
+			domain.StrategyRoundRobin:  NewRoundRobinSelector(),
+			domain.StrategyLeastLoaded: NewLeastLoadedSelector(prRepo),
+			domain.StrategyWeighted:    NewWeightedSelector(userRepo),
+		},
+		defaultStrategy: defaultStrategy,
+	}
+}
+
+// Settings returns team settings with the default strategy applied when unset
+func (r *SelectorRegistry) Settings(ctx context.Context, teamName string) (*domain.TeamSettings, error) {
+	// Users without a team get the defaults
+	if teamName == "" {
+		return &domain.TeamSettings{
+			ReviewerStrategy: r.defaultStrategy,
+			MinReviewers:     domain.DefaultMinReviewers,
+			MaxReviewers:     domain.DefaultMaxReviewers,
+		}, nil
+	}
+
+	settings, err := r.teamRepo.GetSettings(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !settings.ReviewerStrategy.IsValid() {
+		settings.ReviewerStrategy = r.defaultStrategy
+	}
+
+	return settings, nil
+}
+
+// Selector returns the selector for the strategy in settings that respects the reviewers' open review limits
+func (r *SelectorRegistry) Selector(settings *domain.TeamSettings) ReviewerSelector {
+	strategy, ok := r.selectors[settings.ReviewerStrategy]
+	if !ok {
+		strategy = r.selectors[r.defaultStrategy]
+	}
+
+	return &capacitySelector{
+		strategy:     strategy,
+		userRepo:     r.userRepo,
+		prRepo:       r.prRepo,
+		defaultLimit: settings.MaxOpenReviews,
+	}
+}
+
+// ForTeam returns the selector configured for the team
+func (r *SelectorRegistry) ForTeam(ctx context.Context, teamName string) (ReviewerSelector, error) {
+	settings, err := r.Settings(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Selector(settings), nil
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+// SCIMService provisions users and teams from an external directory (an IdP or HR system speaking SCIM 2.0).
+// Directory groups are teams; a user may belong to several of them.
+type SCIMService struct {
+	userRepo    repository.UserRepository
+	teamRepo    repository.TeamRepository
+	teamService *TeamService
+	defaultTeam string
+}
+
+// NewSCIMService creates a new SCIMService. Users created without a team and users removed from their
+// last team are placed in defaultTeam; with an empty defaultTeam such requests fail with ErrTeamRequired.
+func NewSCIMService(
+	userRepo repository.UserRepository,
+	teamRepo repository.TeamRepository,
+	teamService *TeamService,
+	defaultTeam string,
+) *SCIMService {
+	return &SCIMService{
+		userRepo:    userRepo,
+		teamRepo:    teamRepo,
+		teamService: teamService,
+		defaultTeam: defaultTeam,
+	}
+}
+
+// ListUsers returns a page of users matching the filter and the total number of matches
+func (s *SCIMService) ListUsers(ctx context.Context, filter domain.DirectoryFilter) ([]*domain.DirectoryUser, int, error) {
+	return s.userRepo.ListDirectory(ctx, filter)
+}
+
+// GetUser retrieves a user with directory attributes
+func (s *SCIMService) GetUser(ctx context.Context, userID string) (*domain.DirectoryUser, error) {
+	return s.userRepo.GetDirectoryUser(ctx, userID)
+}
+
+// CreateUser creates a member in the given team or, if none is given, in the default team
+func (s *SCIMService) CreateUser(ctx context.Context, user *domain.DirectoryUser) (*domain.DirectoryUser, error) {
+	if user.TeamName == "" {
+		user.TeamName = s.defaultTeam
+	}
+	if user.TeamName == "" {
+		return nil, domain.ErrTeamRequired
+	}
+	user.Role = domain.RoleMember
+
+	if err := s.userRepo.CreateDirectoryUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.GetDirectoryUser(ctx, user.UserID)
+}
+
+// ReplaceUser updates the user's attributes. Deactivating a user deprovisions them: their sessions
+// are revoked and their open reviews are reassigned. The attributes and the activity change are stored
+// in one transaction, so a failing request changes nothing. The team is the primary one: a new team
+// is added to the user's teams and becomes primary; an empty team keeps the current one.
+func (s *SCIMService) ReplaceUser(ctx context.Context, user *domain.DirectoryUser, actorID string) (*domain.DirectoryUser, error) {
+	current, err := s.userRepo.GetDirectoryUser(ctx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TeamName == "" {
+		user.TeamName = current.TeamName
+	}
+	if user.TeamName != current.TeamName {
+		exists, err := s.teamRepo.Exists(ctx, user.TeamName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, domain.ErrTeamNotFound
+		}
+	}
+
+	var reassignments []*domain.ReviewerReassignment
+	if current.IsActive && !user.IsActive {
+		reassignments, err = s.teamService.PlanHandover(ctx, user.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.userRepo.UpdateDirectoryUser(ctx, user, reassignments, actorID); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.GetDirectoryUser(ctx, user.UserID)
+}
+
+// DeprovisionUser deactivates the user and reassigns their open reviews to available members of the PRs' teams.
+// It works for users without a team too. Users are never deleted because pull requests keep referring to them.
+func (s *SCIMService) DeprovisionUser(ctx context.Context, userID, actorID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	reassignments, err := s.teamService.PlanHandover(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.userRepo.DeactivateWithReassignments(ctx, []string{userID}, reassignments, actorID)
+	return err
+}
+
+// ListGroups returns a page of teams with their members and the total number of matches.
+// A non-empty teamName selects that team only.
+func (s *SCIMService) ListGroups(ctx context.Context, teamName string, offset, limit int) ([]*domain.Team, int, error) {
+	names, total, err := s.teamRepo.ListNames(ctx, teamName, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	teams := make([]*domain.Team, 0, len(names))
+	for _, name := range names {
+		team, err := s.teamRepo.GetByName(ctx, name)
+		if err != nil {
+			return nil, 0, err
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, total, nil
+}
+
+// GetGroup retrieves a team with its members
+func (s *SCIMService) GetGroup(ctx context.Context, teamName string) (*domain.Team, error) {
+	return s.teamRepo.GetByName(ctx, teamName)
+}
+
+// CreateGroup creates a team and adds the given existing users to it
+func (s *SCIMService) CreateGroup(ctx context.Context, teamName string, memberIDs []string) (*domain.Team, error) {
+	// Members are checked up front so that an unknown member does not leave an empty team behind
+	for _, userID := range memberIDs {
+		if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.teamRepo.Create(ctx, teamName); err != nil {
+		return nil, err
+	}
+
+	if len(memberIDs) > 0 {
+		if _, err := s.userRepo.ChangeMembership(ctx, memberIDs, "", teamName, nil, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.teamRepo.GetByName(ctx, teamName)
+}
+
+// AddGroupMembers adds the given users to the team, keeping their other teams
+func (s *SCIMService) AddGroupMembers(ctx context.Context, teamName string, memberIDs []string) error {
+	exists, err := s.teamRepo.Exists(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrTeamNotFound
+	}
+
+	if len(memberIDs) == 0 {
+		return nil
+	}
+
+	_, err = s.userRepo.ChangeMembership(ctx, memberIDs, "", teamName, nil, "")
+	return err
+}
+
+// RemoveGroupMembers removes the given members from the team; those left without a team join the default team.
+// Users who are not in the team (for example, already removed from it) are skipped. The whole change is made
+// in one transaction, so a failing request changes nothing.
+func (s *SCIMService) RemoveGroupMembers(ctx context.Context, teamName string, memberIDs []string) error {
+	return s.userRepo.RemoveTeamMembers(ctx, teamName, memberIDs, s.defaultTeam)
+}
+
+// ReplaceGroupMembers makes the given users the team's only members; current members not listed
+// are removed from it, joining the default team if it was their only team. The whole change is made
+// in one transaction, so a failing request changes nothing.
+func (s *SCIMService) ReplaceGroupMembers(ctx context.Context, teamName string, memberIDs []string) error {
+	return s.userRepo.ReplaceTeamMembers(ctx, teamName, memberIDs, s.defaultTeam)
+}
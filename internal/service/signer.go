@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+// rsaKeyBits is the size of generated RS256 keys
+const rsaKeyBits = 2048
+
+const (
+	// keyReloadCooldown limits how often tokens with an unknown "kid" make the manager reload keys
+	keyReloadCooldown = 5 * time.Second
+
+	// keyReloadTimeout bounds the reload done while a token is being verified
+	keyReloadTimeout = 2 * time.Second
+)
+
+// TokenSigner signs access tokens and provides keys to verify them
+type TokenSigner interface {
+	// Sign returns the signed token for the claims
+	Sign(claims jwt.Claims) (string, error)
+
+	// Keyfunc returns the key that verifies the token
+	Keyfunc(token *jwt.Token) (interface{}, error)
+
+	// ValidMethods lists the accepted "alg" header values
+	ValidMethods() []string
+
+	// JWKS returns the public keys that verify tokens; empty for shared secrets
+	JWKS() domain.JWKS
+}
+
+// HMACSigner signs tokens with a shared secret (HS256)
+type HMACSigner struct {
+	secret []byte
+}
+
+// NewHMACSigner creates a new HMACSigner
+func NewHMACSigner(secret string) *HMACSigner {
+	return &HMACSigner{secret: []byte(secret)}
+}
+
+// Sign returns the token signed with the shared secret
+func (s *HMACSigner) Sign(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+// Keyfunc returns the shared secret
+func (s *HMACSigner) Keyfunc(*jwt.Token) (interface{}, error) {
+	return s.secret, nil
+}
+
+// ValidMethods accepts HS256 only
+func (s *HMACSigner) ValidMethods() []string {
+	return []string{jwt.SigningMethodHS256.Alg()}
+}
+
+// JWKS is empty because the shared secret must not be published
+func (s *HMACSigner) JWKS() domain.JWKS {
+	return domain.JWKS{Keys: []domain.JWK{}}
+}
+
+// KeyRotationPolicy defines how often signing keys are replaced
+type KeyRotationPolicy struct {
+	Interval time.Duration // Age after which a new signing key is generated
+	Overlap  time.Duration // How long a replaced key still verifies tokens; must cover the access token lifetime
+}
+
+// signingKey is a parsed key pair
+type signingKey struct {
+	kid       string
+	algorithm domain.SigningAlgorithm
+	private   crypto.Signer
+	public    crypto.PublicKey
+}
+
+// KeyManager signs tokens with asymmetric keys (RS256 or EdDSA) stored in the database.
+// The newest key signs, older keys keep verifying tokens until their overlap window ends.
+// Keys are shared by all instances of the service and rotated by whichever instance notices first.
+type KeyManager struct {
+	keyRepo      repository.SigningKeyRepository
+	algorithm    domain.SigningAlgorithm
+	rotation     KeyRotationPolicy
+	pollInterval time.Duration
+	logger       *slog.Logger
+
+	mu      sync.RWMutex
+	current *signingKey
+	keys    map[string]*signingKey
+	order   []string // Key IDs from the newest
+
+	reloadMu   sync.Mutex
+	lastReload time.Time // When an unknown "kid" last made the manager reload keys
+}
+
+// NewKeyManager creates a new KeyManager. Refresh must be called before it signs tokens.
+func NewKeyManager(
+	keyRepo repository.SigningKeyRepository,
+	algorithm domain.SigningAlgorithm,
+	rotation KeyRotationPolicy,
+	pollInterval time.Duration,
+	logger *slog.Logger,
+) *KeyManager {
+	return &KeyManager{
+		keyRepo:      keyRepo,
+		algorithm:    algorithm,
+		rotation:     rotation,
+		pollInterval: pollInterval,
+		logger:       logger,
+		keys:         map[string]*signingKey{},
+	}
+}
+
+// Run periodically rotates the signing key when it is due and picks up keys rotated by other instances
+func (m *KeyManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil && ctx.Err() == nil {
+				m.logger.Error("Failed to refresh signing keys", "error", err)
+			}
+		}
+	}
+}
+
+// Refresh generates a new signing key if the current one is due for rotation and reloads valid keys
+func (m *KeyManager) Refresh(ctx context.Context) error {
+	due, err := m.keyRepo.RotationDue(ctx, m.algorithm, m.rotation.Interval)
+	if err != nil {
+		return err
+	}
+
+	if due {
+		key, err := generateSigningKey(m.algorithm)
+		if err != nil {
+			return err
+		}
+
+		// Another instance may have rotated the key in the meantime, then the generated key is dropped
+		rotated, err := m.keyRepo.Rotate(ctx, key, m.rotation.Interval, m.rotation.Overlap)
+		if err != nil {
+			return err
+		}
+		if rotated {
+			m.logger.Info("Rotated JWT signing key", "kid", key.KeyID, "algorithm", key.Algorithm)
+		}
+	}
+
+	return m.load(ctx)
+}
+
+// load replaces the in-memory keys with the valid keys from the database
+func (m *KeyManager) load(ctx context.Context) error {
+	stored, err := m.keyRepo.ListValid(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(stored))
+	order := make([]string, 0, len(stored))
+	var current *signingKey
+	for _, record := range stored {
+		key, err := parseSigningKey(record)
+		if err != nil {
+			return err
+		}
+		keys[key.kid] = key
+		order = append(order, key.kid)
+
+		if current == nil && record.ExpiresAt == nil && key.algorithm == m.algorithm {
+			current = key
+		}
+	}
+	if current == nil {
+		return fmt.Errorf("no %s signing key available", m.algorithm)
+	}
+
+	m.mu.Lock()
+	m.current = current
+	m.keys = keys
+	m.order = order
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Sign returns the token signed with the current key, with its ID in the "kid" header
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	key := m.current
+	m.mu.RUnlock()
+
+	if key == nil {
+		return "", fmt.Errorf("no signing key loaded")
+	}
+
+	token := jwt.NewWithClaims(signingMethod(key.algorithm), claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Keyfunc returns the public key named by the token's "kid" header
+func (m *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := m.lookup(kid)
+	if !ok {
+		// Another instance may have rotated the key since the last poll
+		m.reloadUnknown(kid)
+		key, ok = m.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != string(key.algorithm) {
+		return nil, fmt.Errorf("signing key %q does not use %s", kid, token.Method.Alg())
+	}
+
+	return key.public, nil
+}
+
+// lookup returns the loaded key with the ID
+func (m *KeyManager) lookup(kid string) (*signingKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[kid]
+	return key, ok
+}
+
+// reloadUnknown reloads keys from the database at most once per keyReloadCooldown,
+// so tokens with made-up key IDs cannot flood the database
+func (m *KeyManager) reloadUnknown(kid string) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	// A concurrent reload may already have loaded the key
+	if _, ok := m.lookup(kid); ok || time.Since(m.lastReload) < keyReloadCooldown {
+		return
+	}
+	m.lastReload = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), keyReloadTimeout)
+	defer cancel()
+
+	if err := m.load(ctx); err != nil {
+		m.logger.Error("Failed to reload signing keys", "kid", kid, "error", err)
+	}
+}
+
+// ValidMethods accepts asymmetric algorithms only, so a public key can never be used as an HMAC secret
+func (m *KeyManager) ValidMethods() []string {
+	return []string{string(domain.SigningRS256), string(domain.SigningEdDSA)}
+}
+
+// JWKS returns public keys of the current and overlapping keys
+func (m *KeyManager) JWKS() domain.JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := domain.JWKS{Keys: make([]domain.JWK, 0, len(m.order))}
+	for _, kid := range m.order {
+		set.Keys = append(set.Keys, publicJWK(m.keys[kid]))
+	}
+	return set
+}
+
+// signingMethod returns the jwt signing method of the algorithm
+func signingMethod(algorithm domain.SigningAlgorithm) jwt.SigningMethod {
+	if algorithm == domain.SigningEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// generateSigningKey creates a key pair with a random key ID
+func generateSigningKey(algorithm domain.SigningAlgorithm) (*domain.SigningKey, error) {
+	var private crypto.Signer
+	switch algorithm {
+	case domain.SigningRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		private = key
+	case domain.SigningEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %q", algorithm)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	kid := make([]byte, 8)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, fmt.Errorf("failed to generate key id: %w", err)
+	}
+
+	return &domain.SigningKey{
+		KeyID:      hex.EncodeToString(kid),
+		Algorithm:  algorithm,
+		PrivateKey: privateDER,
+		PublicKey:  publicDER,
+	}, nil
+}
+
+// parseSigningKey decodes a stored key pair
+func parseSigningKey(stored *domain.SigningKey) (*signingKey, error) {
+	private, err := x509.ParsePKCS8PrivateKey(stored.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %q: %w", stored.KeyID, err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("signing key %q cannot sign", stored.KeyID)
+	}
+
+	return &signingKey{
+		kid:       stored.KeyID,
+		algorithm: stored.Algorithm,
+		private:   signer,
+		public:    signer.Public(),
+	}, nil
+}
+
+// publicJWK encodes the public key of the pair as a JWK
+func publicJWK(key *signingKey) domain.JWK {
+	jwk := domain.JWK{
+		KeyID:     key.kid,
+		Use:       "sig",
+		Algorithm: string(key.algorithm),
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
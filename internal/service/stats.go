@@ -20,6 +20,8 @@ type PRStats struct {
 	TotalPRs       int `json:"total_prs"`
 	OpenPRs        int `json:"open_prs"`
 	MergedPRs      int `json:"merged_prs"`
+	DraftPRs       int `json:"draft_prs"`
+	ClosedPRs      int `json:"closed_prs"`
 	TotalReviewers int `json:"total_reviewers"`
 }
 
@@ -84,6 +86,8 @@ func (s *StatsService) GetStats(ctx context.Context) (*Stats, error) {
 			COUNT(*) as total_prs,
 			COUNT(CASE WHEN status = 'OPEN' THEN 1 END) as open_prs,
 			COUNT(CASE WHEN status = 'MERGED' THEN 1 END) as merged_prs,
+			COUNT(CASE WHEN status = 'DRAFT' THEN 1 END) as draft_prs,
+			COUNT(CASE WHEN status = 'CLOSED' THEN 1 END) as closed_prs,
 			(SELECT COUNT(*) FROM pr_reviewers) as total_reviewers
 		FROM pull_requests
 	`
@@ -92,6 +96,8 @@ func (s *StatsService) GetStats(ctx context.Context) (*Stats, error) {
 		&stats.PRStats.TotalPRs,
 		&stats.PRStats.OpenPRs,
 		&stats.PRStats.MergedPRs,
+		&stats.PRStats.DraftPRs,
+		&stats.PRStats.ClosedPRs,
 		&stats.PRStats.TotalReviewers,
 	); err != nil {
 		return nil, err
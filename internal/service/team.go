@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"slices"
 
 	"github.com/aidar/avito-pr-project/internal/domain"
 	"github.com/aidar/avito-pr-project/internal/repository"
@@ -9,15 +11,24 @@ import (
 
 // TeamService handles business logic for teams
 type TeamService struct {
-	teamRepo repository.TeamRepository
-	userRepo repository.UserRepository
+	teamRepo  repository.TeamRepository
+	userRepo  repository.UserRepository
+	prRepo    repository.PullRequestRepository
+	selectors *SelectorRegistry
 }
 
 // NewTeamService creates a new TeamService
-func NewTeamService(teamRepo repository.TeamRepository, userRepo repository.UserRepository) *TeamService {
+func NewTeamService(
+	teamRepo repository.TeamRepository,
+	userRepo repository.UserRepository,
+	prRepo repository.PullRequestRepository,
+	selectors *SelectorRegistry,
+) *TeamService {
 	return &TeamService{
-		teamRepo: teamRepo,
-		userRepo: userRepo,
+		teamRepo:  teamRepo,
+		userRepo:  userRepo,
+		prRepo:    prRepo,
+		selectors: selectors,
 	}
 }
 
@@ -39,11 +50,17 @@ func (s *TeamService) AddTeam(ctx context.Context, team *domain.Team) (*domain.T
 
 	// Create or update members
 	for _, member := range team.Members {
+		role := member.Role
+		if role == "" {
+			role = domain.RoleMember
+		}
+
 		user := &domain.User{
 			UserID:   member.UserID,
 			Username: member.Username,
 			TeamName: team.TeamName,
 			IsActive: member.IsActive,
+			Role:     role,
 		}
 		if err := s.userRepo.CreateOrUpdate(ctx, user); err != nil {
 			return nil, err
@@ -58,3 +75,582 @@ func (s *TeamService) AddTeam(ctx context.Context, team *domain.Team) (*domain.T
 func (s *TeamService) GetTeam(ctx context.Context, teamName string) (*domain.Team, error) {
 	return s.teamRepo.GetByName(ctx, teamName)
 }
+
+// GetSettings returns team settings with defaults applied for unset values
+func (s *TeamService) GetSettings(ctx context.Context, teamName string) (*domain.TeamSettings, error) {
+	return s.selectors.Settings(ctx, teamName)
+}
+
+// SetReviewerStrategy changes the reviewer selection strategy of a team
+func (s *TeamService) SetReviewerStrategy(
+	ctx context.Context,
+	teamName string,
+	strategy domain.ReviewerStrategy,
+) (*domain.TeamSettings, error) {
+	if err := s.teamRepo.SetReviewerStrategy(ctx, teamName, strategy); err != nil {
+		return nil, err
+	}
+
+	return s.GetSettings(ctx, teamName)
+}
+
+// DeactivateUsers deactivates the given team members (the whole team if userIDs is empty)
+// and replaces them on open PRs with active members of each PR's team in a single transaction.
+// A reviewer with no available replacement is removed from the PR. Replacements that went stale
+// before the transaction are skipped; the applied ones are returned.
+func (s *TeamService) DeactivateUsers(
+	ctx context.Context,
+	teamName string,
+	userIDs []string,
+	actorID string,
+) ([]string, []*domain.ReviewerReassignment, error) {
+	exists, err := s.teamRepo.Exists(ctx, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, domain.ErrTeamNotFound
+	}
+
+	members, err := s.userRepo.GetTeamMembers(ctx, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Resolve the set of users to deactivate; all of them must belong to the team
+	memberByID := make(map[string]*domain.User, len(members))
+	for _, m := range members {
+		memberByID[m.UserID] = m
+	}
+
+	deactivating := make(map[string]bool)
+	var deactivated []string
+	if len(userIDs) == 0 {
+		for _, m := range members {
+			deactivating[m.UserID] = true
+			deactivated = append(deactivated, m.UserID)
+		}
+	} else {
+		for _, id := range userIDs {
+			if _, ok := memberByID[id]; !ok {
+				return nil, nil, domain.ErrUserNotFound
+			}
+			if !deactivating[id] {
+				deactivating[id] = true
+				deactivated = append(deactivated, id)
+			}
+		}
+	}
+
+	if len(deactivated) == 0 {
+		return []string{}, []*domain.ReviewerReassignment{}, nil
+	}
+
+	reassignments, err := s.planReassignments(ctx, teamName, deactivating, deactivated, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The plan is re-checked in the transaction: PRs merged or reviewers reassigned meanwhile are skipped
+	applied, err := s.userRepo.DeactivateWithReassignments(ctx, deactivated, reassignments, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return deactivated, applied, nil
+}
+
+// AddMembers adds members to an existing, not archived team: new users are created, existing users join it
+// with their other attributes and teams unchanged. The team becomes primary for users who had none.
+func (s *TeamService) AddMembers(ctx context.Context, teamName string, members []domain.TeamMember) (*domain.Team, error) {
+	if err := s.checkNotArchived(ctx, teamName); err != nil {
+		return nil, err
+	}
+
+	users := make([]*domain.User, 0, len(members))
+	for _, member := range members {
+		role := member.Role
+		if role == "" {
+			role = domain.RoleMember
+		}
+
+		users = append(users, &domain.User{
+			UserID:   member.UserID,
+			Username: member.Username,
+			TeamName: teamName,
+			IsActive: member.IsActive,
+			Role:     role,
+		})
+	}
+
+	if err := s.userRepo.AddTeamMembers(ctx, teamName, users); err != nil {
+		return nil, err
+	}
+
+	return s.teamRepo.GetByName(ctx, teamName)
+}
+
+// RemoveMembers removes the given members from the team; their other memberships are kept, and users whose
+// primary team it was get another of their teams as primary (or none). With ReviewPolicyReassign their open reviews
+// of the team's PRs are handed over to the active members who stay.
+func (s *TeamService) RemoveMembers(
+	ctx context.Context,
+	teamName string,
+	userIDs []string,
+	policy domain.ReviewPolicy,
+	actorID string,
+) ([]string, []*domain.ReviewerReassignment, error) {
+	exists, err := s.teamRepo.Exists(ctx, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, domain.ErrTeamNotFound
+	}
+
+	members, err := s.userRepo.GetTeamMembers(ctx, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// All of the users must belong to the team
+	leaving := make(map[string]bool)
+	var removed []string
+	for _, id := range userIDs {
+		if !slices.ContainsFunc(members, func(m *domain.User) bool { return m.UserID == id }) {
+			return nil, nil, domain.ErrUserNotFound
+		}
+		if !leaving[id] {
+			leaving[id] = true
+			removed = append(removed, id)
+		}
+	}
+
+	reassignments, err := s.leavingReassignments(ctx, teamName, leaving, removed, policy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied, err := s.userRepo.ChangeMembership(ctx, removed, teamName, "", reassignments, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return removed, applied, nil
+}
+
+// MoveMember moves the user from fromTeam (their primary team if empty) to another team that is not archived,
+// keeping their other memberships; if fromTeam was primary, the new team becomes primary. With ReviewPolicyReassign
+// their open reviews of fromTeam's PRs are handed over to its active members; moving a user to the team they
+// leave changes nothing. Returns the moved user and the team they left (empty if they had none).
+func (s *TeamService) MoveMember(
+	ctx context.Context,
+	userID, fromTeam, teamName string,
+	policy domain.ReviewPolicy,
+	actorID string,
+) (*domain.User, string, []*domain.ReviewerReassignment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if fromTeam == "" {
+		fromTeam = user.TeamName
+	} else if !slices.Contains(user.Teams, fromTeam) {
+		return nil, "", nil, domain.ErrNotTeamMember
+	}
+
+	if err := s.checkNotArchived(ctx, teamName); err != nil {
+		return nil, "", nil, err
+	}
+
+	reassignments := []*domain.ReviewerReassignment{}
+	if fromTeam == teamName {
+		return user, fromTeam, reassignments, nil
+	}
+
+	// Without an old team there is nobody to hand the reviews over to
+	if fromTeam != "" {
+		reassignments, err = s.leavingReassignments(
+			ctx, fromTeam, map[string]bool{userID: true}, []string{userID}, policy,
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	reassignments, err = s.userRepo.ChangeMembership(ctx, []string{userID}, fromTeam, teamName, reassignments, actorID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	user, err = s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return user, fromTeam, reassignments, nil
+}
+
+// PreviewRemoval reports what archiving or deleting the team would affect:
+// its members, open PRs authored by them and their assignments to open PRs
+func (s *TeamService) PreviewRemoval(ctx context.Context, teamName string) (*domain.TeamImpact, error) {
+	team, err := s.teamRepo.GetByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	impact := &domain.TeamImpact{
+		TeamName:         team.TeamName,
+		Archived:         team.ArchivedAt != nil,
+		Members:          team.Members,
+		OpenPullRequests: []*domain.PullRequestShort{},
+		OpenReviews:      []domain.ReviewAssignment{},
+	}
+	if impact.Members == nil {
+		impact.Members = []domain.TeamMember{}
+	}
+	if len(team.Members) == 0 {
+		return impact, nil
+	}
+
+	memberIDs := make([]string, 0, len(team.Members))
+	for _, m := range team.Members {
+		memberIDs = append(memberIDs, m.UserID)
+	}
+
+	impact.OpenPullRequests, err = s.prRepo.GetOpenByAuthors(ctx, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	prs, err := s.prRepo.GetOpenByReviewers(ctx, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range prs {
+		for _, reviewerID := range pr.AssignedReviewers {
+			if slices.Contains(memberIDs, reviewerID) {
+				impact.OpenReviews = append(impact.OpenReviews, domain.ReviewAssignment{
+					PullRequestID: pr.PullRequestID,
+					UserID:        reviewerID,
+				})
+			}
+		}
+	}
+
+	return impact, nil
+}
+
+// Archive handles the team's members according to action and archives the team in a single transaction:
+// its members are no longer picked as reviewers and it accepts no new members.
+// Returns the impact as it was before archiving.
+func (s *TeamService) Archive(
+	ctx context.Context,
+	teamName string,
+	action domain.MemberAction,
+	targetTeam, actorID string,
+) (*domain.TeamImpact, error) {
+	impact, err := s.PreviewRemoval(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	if impact.Archived {
+		return nil, domain.ErrTeamArchived
+	}
+
+	removal, err := s.planRemoval(ctx, impact, action, targetTeam, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.teamRepo.Archive(ctx, removal); err != nil {
+		return nil, err
+	}
+
+	return impact, nil
+}
+
+// Delete handles the team's members according to action and deletes the team with its settings
+// in a single transaction. Members who are still in the team lose its membership; those without other teams
+// are left without one. Returns the impact as it was before deletion.
+func (s *TeamService) Delete(
+	ctx context.Context,
+	teamName string,
+	action domain.MemberAction,
+	targetTeam, actorID string,
+) (*domain.TeamImpact, error) {
+	impact, err := s.PreviewRemoval(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	removal, err := s.planRemoval(ctx, impact, action, targetTeam, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.teamRepo.Delete(ctx, removal); err != nil {
+		return nil, err
+	}
+
+	return impact, nil
+}
+
+// planRemoval plans what happens to the members of a team being removed: they move to targetTeam keeping
+// their reviews, or are deactivated and replaced on their open PRs. Members of other teams are not deactivated,
+// they only leave the team and are replaced on its PRs. MemberActionKeep leaves them as they are.
+func (s *TeamService) planRemoval(
+	ctx context.Context,
+	impact *domain.TeamImpact,
+	action domain.MemberAction,
+	targetTeam, actorID string,
+) (*domain.TeamRemoval, error) {
+	removal := &domain.TeamRemoval{
+		TeamName:      impact.TeamName,
+		Reassignments: []*domain.ReviewerReassignment{},
+		ActorID:       actorID,
+	}
+	if len(impact.Members) == 0 {
+		return removal, nil
+	}
+
+	memberIDs := make([]string, 0, len(impact.Members))
+	for _, m := range impact.Members {
+		memberIDs = append(memberIDs, m.UserID)
+	}
+
+	switch action {
+	case domain.MemberActionMove:
+		if err := s.checkNotArchived(ctx, targetTeam); err != nil {
+			return nil, err
+		}
+		removal.MoveTo = targetTeam
+		removal.Moved = memberIDs
+	case domain.MemberActionDeactivate:
+		for _, userID := range memberIDs {
+			user, err := s.userRepo.GetByID(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			if len(user.Teams) > 1 {
+				removal.Detached = append(removal.Detached, userID)
+			} else {
+				removal.Deactivated = append(removal.Deactivated, userID)
+			}
+		}
+
+		// No member of the team is a replacement, even those who stay active in other teams
+		leaving := make(map[string]bool, len(memberIDs))
+		for _, userID := range memberIDs {
+			leaving[userID] = true
+		}
+
+		if len(removal.Deactivated) > 0 {
+			reassignments, err := s.planReassignments(ctx, impact.TeamName, leaving, removal.Deactivated, false)
+			if err != nil {
+				return nil, err
+			}
+			removal.Reassignments = append(removal.Reassignments, reassignments...)
+		}
+		if len(removal.Detached) > 0 {
+			reassignments, err := s.planReassignments(ctx, impact.TeamName, leaving, removal.Detached, true)
+			if err != nil {
+				return nil, err
+			}
+			removal.Reassignments = append(removal.Reassignments, reassignments...)
+		}
+	}
+
+	return removal, nil
+}
+
+// checkNotArchived returns ErrTeamNotFound or ErrTeamArchived unless the team exists and is not archived
+func (s *TeamService) checkNotArchived(ctx context.Context, teamName string) error {
+	team, err := s.teamRepo.GetByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	if team.ArchivedAt != nil {
+		return domain.ErrTeamArchived
+	}
+	return nil
+}
+
+// PlanHandover plans replacements for the user on all of their open PRs among the available members
+// of each PR's team; PRs without a team are treated as the user's primary team's
+func (s *TeamService) PlanHandover(ctx context.Context, userID string) ([]*domain.ReviewerReassignment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.planReassignments(ctx, user.TeamName, map[string]bool{userID: true}, []string{userID}, false)
+}
+
+// leavingReassignments plans reassignments on the team's PRs for the users leaving the team according to the policy
+func (s *TeamService) leavingReassignments(
+	ctx context.Context,
+	teamName string,
+	leaving map[string]bool,
+	leavingIDs []string,
+	policy domain.ReviewPolicy,
+) ([]*domain.ReviewerReassignment, error) {
+	if policy == domain.ReviewPolicyKeep || len(leavingIDs) == 0 {
+		return []*domain.ReviewerReassignment{}, nil
+	}
+	return s.planReassignments(ctx, teamName, leaving, leavingIDs, true)
+}
+
+// planReassignments picks replacements for the leaving users on their open PRs among the active members
+// of each PR's team who stay; PRs without a team are treated as teamName's. With teamOnly, only the PRs
+// of teamName are considered. A reviewer with no available replacement is planned for removal from the PR.
+func (s *TeamService) planReassignments(
+	ctx context.Context,
+	teamName string,
+	leaving map[string]bool,
+	leavingIDs []string,
+	teamOnly bool,
+) ([]*domain.ReviewerReassignment, error) {
+	reassignments := []*domain.ReviewerReassignment{}
+
+	prs, err := s.prRepo.GetOpenByReviewers(ctx, leavingIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Candidates and selectors are loaded once per team
+	candidatesByTeam := make(map[string][]*domain.User)
+	selectorByTeam := make(map[string]ReviewerSelector)
+
+	// All replacements are committed together, so load-aware strategies
+	// have to see the picks made for earlier PRs
+	batchCtx := withBatchLoad(ctx)
+
+	for _, pr := range prs {
+		reviewTeam := pr.TeamName
+		if reviewTeam == "" {
+			reviewTeam = teamName
+		}
+		if teamOnly && reviewTeam != teamName {
+			continue
+		}
+
+		candidates, ok := candidatesByTeam[reviewTeam]
+		if !ok {
+			members, err := s.userRepo.GetActiveTeamMembers(ctx, reviewTeam, "")
+			if err != nil {
+				return nil, err
+			}
+
+			// Replacement candidates are the team members who stay active
+			for _, m := range members {
+				if !leaving[m.UserID] {
+					candidates = append(candidates, m)
+				}
+			}
+			candidatesByTeam[reviewTeam] = candidates
+		}
+
+		selector, ok := selectorByTeam[reviewTeam]
+		if !ok {
+			selector, err = s.selectors.ForTeam(ctx, reviewTeam)
+			if err != nil {
+				return nil, err
+			}
+			selectorByTeam[reviewTeam] = selector
+		}
+
+		// Track reviewers as they change so two replacements on one PR never collide
+		current := make([]string, len(pr.AssignedReviewers))
+		copy(current, pr.AssignedReviewers)
+
+		for _, reviewerID := range pr.AssignedReviewers {
+			if !leaving[reviewerID] {
+				continue
+			}
+
+			excluded := append([]string{pr.AuthorID}, current...)
+			newReviewerID, err := selector.SelectReplacement(batchCtx, reviewTeam, candidates, excluded)
+			if err != nil && !errors.Is(err, domain.ErrNoCandidate) {
+				return nil, err
+			}
+
+			current = replaceReviewer(current, reviewerID, newReviewerID)
+			reassignments = append(reassignments, &domain.ReviewerReassignment{
+				PullRequestID: pr.PullRequestID,
+				OldUserID:     reviewerID,
+				ReplacedBy:    newReviewerID,
+			})
+		}
+	}
+
+	return reassignments, nil
+}
+
+// replaceReviewer swaps oldID for newID in reviewers, dropping oldID if newID is empty
+func replaceReviewer(reviewers []string, oldID, newID string) []string {
+	result := make([]string, 0, len(reviewers))
+	for _, id := range reviewers {
+		switch {
+		case id != oldID:
+			result = append(result, id)
+		case newID != "":
+			result = append(result, newID)
+		}
+	}
+	return result
+}
+
+// SetReviewerLimits changes how many reviewers PRs of the team get
+func (s *TeamService) SetReviewerLimits(
+	ctx context.Context,
+	teamName string,
+	minReviewers, maxReviewers int,
+) (*domain.TeamSettings, error) {
+	if err := s.teamRepo.SetReviewerLimits(ctx, teamName, minReviewers, maxReviewers); err != nil {
+		return nil, err
+	}
+
+	return s.GetSettings(ctx, teamName)
+}
+
+// SetMaxOpenReviews sets how many open reviews members of the team without their own limit may have
+// before they are skipped as reviewers (nil removes the limit)
+func (s *TeamService) SetMaxOpenReviews(
+	ctx context.Context,
+	teamName string,
+	limit *int,
+) (*domain.TeamSettings, error) {
+	if err := s.teamRepo.SetMaxOpenReviews(ctx, teamName, limit); err != nil {
+		return nil, err
+	}
+
+	return s.GetSettings(ctx, teamName)
+}
+
+// SetFallbackTeams replaces the ordered list of teams that supply reviewers
+// when the team can't fill max_reviewers itself
+func (s *TeamService) SetFallbackTeams(
+	ctx context.Context,
+	teamName string,
+	fallbackTeams []string,
+) (*domain.TeamSettings, error) {
+	if err := s.teamRepo.SetFallbackTeams(ctx, teamName, fallbackTeams); err != nil {
+		return nil, err
+	}
+
+	return s.GetSettings(ctx, teamName)
+}
+
+// SetMergeRule sets how many approvals PRs of the team need before merge (nil disables the rule)
+func (s *TeamService) SetMergeRule(
+	ctx context.Context,
+	teamName string,
+	requiredApprovals *int,
+) (*domain.TeamSettings, error) {
+	if err := s.teamRepo.SetRequiredApprovals(ctx, teamName, requiredApprovals); err != nil {
+		return nil, err
+	}
+
+	return s.GetSettings(ctx, teamName)
+}
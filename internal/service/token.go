@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+// APITokenPrefix marks API tokens so that they can be told apart from JWTs
+const APITokenPrefix = "prt_"
+
+// apiTokenDisplayLength is how many leading characters of a token are kept for display
+const apiTokenDisplayLength = 12
+
+// APITokenService issues, lists, revokes and authenticates scoped API tokens
+type APITokenService struct {
+	tokenRepo repository.APITokenRepository
+	userRepo  repository.UserRepository
+}
+
+// NewAPITokenService creates a new APITokenService
+func NewAPITokenService(tokenRepo repository.APITokenRepository, userRepo repository.UserRepository) *APITokenService {
+	return &APITokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// IsAPIToken reports whether the bearer token looks like an API token rather than a JWT
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// Create issues a new token and returns it together with the plaintext value, which is never stored.
+// Personal tokens belong to the principal; only admins may create service tokens.
+func (s *APITokenService) Create(
+	ctx context.Context,
+	p domain.Principal,
+	name string,
+	kind domain.APITokenKind,
+	scopes []domain.TokenScope,
+	ttl time.Duration,
+) (*domain.APIToken, string, error) {
+	token := &domain.APIToken{
+		Name:      name,
+		Kind:      kind,
+		Scopes:    scopes,
+		CreatedBy: p.UserID,
+	}
+
+	switch kind {
+	case domain.TokenPersonal:
+		token.UserID = p.UserID
+	case domain.TokenService:
+		if !p.IsAdmin() {
+			return nil, "", domain.ErrForbidden
+		}
+	}
+
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+
+	plaintext, err := generateToken(APITokenPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	token.Prefix = plaintext[:apiTokenDisplayLength]
+
+	if err := s.tokenRepo.Create(ctx, token, hashToken(plaintext)); err != nil {
+		return nil, "", err
+	}
+
+	return token, plaintext, nil
+}
+
+// List returns all tokens for admins and the principal's own tokens for everyone else
+func (s *APITokenService) List(ctx context.Context, p domain.Principal) ([]*domain.APIToken, error) {
+	if p.IsAdmin() {
+		return s.tokenRepo.List(ctx, "")
+	}
+	return s.tokenRepo.List(ctx, p.UserID)
+}
+
+// Revoke revokes a token. Users may revoke their own tokens, admins may revoke any token.
+func (s *APITokenService) Revoke(ctx context.Context, p domain.Principal, tokenID int64) (*domain.APIToken, error) {
+	token, err := s.tokenRepo.GetByID(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Other users' tokens are reported as missing so that their IDs are not disclosed
+	if !p.IsAdmin() && token.UserID != p.UserID {
+		return nil, domain.ErrTokenNotFound
+	}
+
+	if err := s.tokenRepo.Revoke(ctx, tokenID); err != nil {
+		return nil, err
+	}
+
+	return s.tokenRepo.GetByID(ctx, tokenID)
+}
+
+// Authenticate resolves an API token to the principal it acts as.
+// Personal tokens act as their owner with the owner's current role; service tokens act as an admin.
+func (s *APITokenService) Authenticate(ctx context.Context, plaintext string) (*domain.Principal, error) {
+	token, err := s.tokenRepo.GetActiveByHash(ctx, hashToken(plaintext))
+	if err == domain.ErrTokenNotFound {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &domain.Principal{
+		Role:    domain.RoleAdmin,
+		TokenID: token.TokenID,
+		Scopes:  token.Scopes,
+	}
+
+	if token.Kind == domain.TokenPersonal {
+		owner, err := s.userRepo.GetByID(ctx, token.UserID)
+		if err != nil {
+			return nil, err
+		}
+		// Deactivated users lose access through their tokens as well as their sessions
+		if !owner.IsActive {
+			return nil, domain.ErrInvalidToken
+		}
+		principal.UserID = owner.UserID
+		principal.TeamName = owner.TeamName
+		principal.Teams = owner.Teams
+		principal.LeadTeams = owner.LeadTeams
+		principal.Role = owner.Role
+	}
+
+	if err := s.tokenRepo.TouchLastUsed(ctx, token.TokenID); err != nil {
+		return nil, err
+	}
+
+	return principal, nil
+}
+
+// generateToken returns a random token with 256 bits of entropy and the given prefix
+func generateToken(prefix string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashToken returns the hex SHA-256 of a token from generateToken. Tokens carry 256 bits of entropy,
+// so a fast hash is enough and keeps lookup by hash possible.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -20,9 +20,9 @@ func NewUserService(userRepo repository.UserRepository) *UserService {
 }
 
 // SetIsActive updates user's active status
-func (s *UserService) SetIsActive(ctx context.Context, userID string, isActive bool) (*domain.User, error) {
+func (s *UserService) SetIsActive(ctx context.Context, userID string, isActive bool, actorID string) (*domain.User, error) {
 	// Update status
-	if err := s.userRepo.SetIsActive(ctx, userID, isActive); err != nil {
+	if err := s.userRepo.SetIsActive(ctx, userID, isActive, actorID); err != nil {
 		return nil, err
 	}
 
@@ -39,3 +39,42 @@ func (s *UserService) SetIsActive(ctx context.Context, userID string, isActive b
 func (s *UserService) GetByID(ctx context.Context, userID string) (*domain.User, error) {
 	return s.userRepo.GetByID(ctx, userID)
 }
+
+// SetReviewWeight updates user's weight for the weighted reviewer strategy
+func (s *UserService) SetReviewWeight(ctx context.Context, userID string, weight int) error {
+	return s.userRepo.SetReviewWeight(ctx, userID, weight)
+}
+
+// SetMaxOpenReviews sets how many open reviews the user may have before they are skipped as a reviewer
+// (nil falls back to the team's limit)
+func (s *UserService) SetMaxOpenReviews(ctx context.Context, userID string, limit *int) error {
+	return s.userRepo.SetMaxOpenReviews(ctx, userID, limit)
+}
+
+// SetGitHubLogin links a GitHub login to the user so that webhook events can be attributed to them
+func (s *UserService) SetGitHubLogin(ctx context.Context, userID, login string) error {
+	return s.userRepo.SetGitHubLogin(ctx, userID, login)
+}
+
+// SetEmail sets the user's email, which links the user to the identity provider account with the same verified email
+func (s *UserService) SetEmail(ctx context.Context, userID, email string) error {
+	return s.userRepo.SetEmail(ctx, userID, email)
+}
+
+// SetRole changes the user's role. The new role applies to tokens issued after the change.
+func (s *UserService) SetRole(ctx context.Context, userID string, role domain.Role) (*domain.User, error) {
+	if err := s.userRepo.SetRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.GetByID(ctx, userID)
+}
+
+// SetPrimaryTeam makes one of the user's teams their primary team, the default for their new PRs
+func (s *UserService) SetPrimaryTeam(ctx context.Context, userID, teamName string) (*domain.User, error) {
+	if err := s.userRepo.SetPrimaryTeam(ctx, userID, teamName); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.GetByID(ctx, userID)
+}
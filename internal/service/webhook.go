@@ -0,0 +1,231 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aidar/avito-pr-project/internal/domain"
+	"github.com/aidar/avito-pr-project/internal/repository"
+)
+
+// webhookBatchSize limits how many outbox events and deliveries are handled per poll
+const webhookBatchSize = 100
+
+// WebhookService manages subscriptions to outgoing webhooks
+type WebhookService struct {
+	webhookRepo repository.OutgoingWebhookRepository
+}
+
+// NewWebhookService creates a new WebhookService
+func NewWebhookService(webhookRepo repository.OutgoingWebhookRepository) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+	}
+}
+
+// Subscribe registers a subscriber for the given event types
+func (s *WebhookService) Subscribe(
+	ctx context.Context,
+	url, secret string,
+	eventTypes []domain.PREventType,
+) (*domain.WebhookSubscription, error) {
+	sub := &domain.WebhookSubscription{
+		URL:        url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+	}
+
+	if err := s.webhookRepo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// ListSubscriptions returns all subscribers
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	return s.webhookRepo.ListSubscriptions(ctx)
+}
+
+// Unsubscribe removes a subscriber; its pending deliveries are dropped
+func (s *WebhookService) Unsubscribe(ctx context.Context, subscriptionID int64) error {
+	return s.webhookRepo.DeleteSubscription(ctx, subscriptionID)
+}
+
+// GetDeliveries returns the latest deliveries of a subscriber
+func (s *WebhookService) GetDeliveries(
+	ctx context.Context,
+	subscriptionID int64,
+	limit int,
+) ([]*domain.WebhookDelivery, error) {
+	return s.webhookRepo.GetDeliveries(ctx, subscriptionID, limit)
+}
+
+// WebhookPayload is the JSON body POSTed to subscribers
+type WebhookPayload struct {
+	DeliveryID int64              `json:"delivery_id"`
+	Event      domain.PREventType `json:"event"`
+	Data       *domain.PREvent    `json:"data"`
+}
+
+// WebhookRetryPolicy defines exponential backoff for failed deliveries
+type WebhookRetryPolicy struct {
+	BaseDelay   time.Duration // Delay after the first failed attempt
+	MaxDelay    time.Duration // Upper bound of the delay
+	MaxAttempts int           // Attempts after which the delivery is marked FAILED
+}
+
+// Delay returns the delay before the next attempt after the given number of failed attempts
+func (p WebhookRetryPolicy) Delay(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// WebhookDispatcher delivers events from the outbox to subscribers in the background
+type WebhookDispatcher struct {
+	webhookRepo  repository.OutgoingWebhookRepository
+	client       *http.Client
+	retry        WebhookRetryPolicy
+	pollInterval time.Duration
+	logger       *slog.Logger
+}
+
+// NewWebhookDispatcher creates a new WebhookDispatcher
+func NewWebhookDispatcher(
+	webhookRepo repository.OutgoingWebhookRepository,
+	retry WebhookRetryPolicy,
+	pollInterval, timeout time.Duration,
+	logger *slog.Logger,
+) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		webhookRepo:  webhookRepo,
+		client:       &http.Client{Timeout: timeout},
+		retry:        retry,
+		pollInterval: pollInterval,
+		logger:       logger,
+	}
+}
+
+// Run polls the outbox and sends due deliveries until ctx is canceled
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.dispatch(ctx); err != nil && ctx.Err() == nil {
+				d.logger.Error("Failed to dispatch webhooks", "error", err)
+			}
+		}
+	}
+}
+
+// dispatch fans out new outbox events to subscribers and sends deliveries that are due
+func (d *WebhookDispatcher) dispatch(ctx context.Context) error {
+	if _, err := d.webhookRepo.FanOutOutbox(ctx, webhookBatchSize); err != nil {
+		return err
+	}
+
+	// Deliveries are claimed one at a time: a claimed delivery is hidden from other
+	// instances until its own request times out, not until the whole batch is sent
+	for range webhookBatchSize {
+		deliveries, err := d.webhookRepo.ClaimDueDeliveries(ctx, 1, 2*d.client.Timeout)
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		if err := d.deliver(ctx, deliveries[0]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deliver sends one delivery and records the attempt in the delivery log
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *domain.PendingDelivery) error {
+	statusCode, sendErr := d.send(ctx, delivery)
+	if sendErr == nil {
+		return d.webhookRepo.MarkDelivered(ctx, delivery.DeliveryID, statusCode)
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	attempts := delivery.Attempts + 1
+	var retryAfter *time.Duration
+	if attempts < d.retry.MaxAttempts {
+		delay := d.retry.Delay(attempts)
+		retryAfter = &delay
+	}
+
+	d.logger.Warn("Webhook delivery failed",
+		"delivery_id", delivery.DeliveryID, "attempt", attempts, "error", sendErr)
+
+	return d.webhookRepo.MarkFailed(ctx, delivery.DeliveryID, code, sendErr.Error(), retryAfter)
+}
+
+// send POSTs the signed payload and returns the response status code
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *domain.PendingDelivery) (int, error) {
+	payload, err := json.Marshal(WebhookPayload{
+		DeliveryID: delivery.DeliveryID,
+		Event:      delivery.Event.Type,
+		Data:       &delivery.Event,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", string(delivery.Event.Type))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.DeliveryID, 10))
+	req.Header.Set("X-Webhook-Signature-256", "sha256="+hex.EncodeToString(signPayload([]byte(delivery.Secret), payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// signPayload returns HMAC-SHA256 of payload, the scheme used by GitHub webhooks
+func signPayload(secret, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
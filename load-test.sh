@@ -4,6 +4,10 @@ set -e
 
 API_URL="http://localhost:8080"
 
+# Первый администратор создается приложением при старте (AUTH_BOOTSTRAP_ADMIN_ID/AUTH_BOOTSTRAP_ADMIN_PASSWORD)
+ADMIN_ID="${ADMIN_ID:-admin}"
+ADMIN_PASSWORD="${ADMIN_PASSWORD:-admin-password-change-in-production}"
+
 echo "PR Service Load Testing - $(date)"
 echo ""
 
@@ -16,12 +20,17 @@ echo "API работает"
 
 # Подготовка тестовых данных
 echo "Настройка тестовых данных..."
+ADMIN_TOKEN=$(curl -s -X POST "$API_URL/auth/login" \
+  -H "Content-Type: application/json" \
+  -d "{\"user_id\":\"$ADMIN_ID\",\"password\":\"$ADMIN_PASSWORD\"}" | grep -o '"token":"[^"]*' | cut -d'"' -f4)
+
 curl -s -X POST "$API_URL/team/add" \
   -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $ADMIN_TOKEN" \
   -d '{
     "team_name": "loadtest",
     "members": [
-      {"user_id": "lt1", "username": "User1", "is_active": true},
+      {"user_id": "lt1", "username": "User1", "is_active": true, "role": "admin"},
       {"user_id": "lt2", "username": "User2", "is_active": true},
       {"user_id": "lt3", "username": "User3", "is_active": true},
       {"user_id": "lt4", "username": "User4", "is_active": true},
@@ -29,9 +38,14 @@ curl -s -X POST "$API_URL/team/add" \
     ]
   }' > /dev/null 2>&1 || true
 
+curl -s -X POST "$API_URL/auth/setPassword" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $ADMIN_TOKEN" \
+  -d '{"user_id":"lt1","new_password":"loadtest-password"}' > /dev/null 2>&1 || true
+
 TOKEN=$(curl -s -X POST "$API_URL/auth/login" \
   -H "Content-Type: application/json" \
-  -d '{"user_id":"lt1"}' | grep -o '"token":"[^"]*' | cut -d'"' -f4)
+  -d '{"user_id":"lt1","password":"loadtest-password"}' | grep -o '"token":"[^"]*' | cut -d'"' -f4)
 
 echo ""
 echo "Запуск нагрузочных тестов (требования: 5 RPS, <300ms, >99.9% успеха)..."
@@ -46,7 +60,7 @@ echo ""
 echo "[2/7] Аутентификация (500 запросов, 5 RPS)"
 ~/go/bin/hey -n 500 -c 5 -q 5 -m POST \
     -H "Content-Type: application/json" \
-    -d '{"user_id":"lt1"}' \
+    -d '{"user_id":"lt1","password":"loadtest-password"}' \
     "$API_URL/auth/login"
 echo ""
 
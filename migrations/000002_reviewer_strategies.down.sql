@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS review_weight;
+
+DROP TABLE IF EXISTS team_settings;
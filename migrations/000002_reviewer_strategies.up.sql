@@ -0,0 +1,10 @@
+-- Создание таблицы настроек команд
+CREATE TABLE IF NOT EXISTS team_settings (
+    team_name VARCHAR(255) PRIMARY KEY REFERENCES teams(team_name) ON DELETE CASCADE,
+    reviewer_strategy VARCHAR(32) NOT NULL DEFAULT 'random'
+        CHECK (reviewer_strategy IN ('random', 'round_robin', 'least_loaded', 'weighted')),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Вес пользователя для стратегии weighted (0 - никогда не выбирается)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS review_weight INTEGER NOT NULL DEFAULT 1 CHECK (review_weight >= 0);
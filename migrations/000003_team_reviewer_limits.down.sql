@@ -0,0 +1,7 @@
+ALTER TABLE team_settings DROP CONSTRAINT IF EXISTS chk_team_settings_reviewer_limits;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS max_reviewers;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS min_reviewers;
+
+UPDATE team_settings SET reviewer_strategy = 'random' WHERE reviewer_strategy IS NULL;
+ALTER TABLE team_settings ALTER COLUMN reviewer_strategy SET DEFAULT 'random';
+ALTER TABLE team_settings ALTER COLUMN reviewer_strategy SET NOT NULL;
@@ -0,0 +1,10 @@
+-- NULL в настройках означает значение по умолчанию, чтобы настройка одного параметра
+-- не фиксировала остальные (например, стратегию из REVIEWER_STRATEGY)
+ALTER TABLE team_settings ALTER COLUMN reviewer_strategy DROP NOT NULL;
+ALTER TABLE team_settings ALTER COLUMN reviewer_strategy DROP DEFAULT;
+
+-- Лимиты числа ревьюверов на PR для команды
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS min_reviewers INTEGER CHECK (min_reviewers >= 0);
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS max_reviewers INTEGER CHECK (max_reviewers >= 1);
+ALTER TABLE team_settings ADD CONSTRAINT chk_team_settings_reviewer_limits
+    CHECK (min_reviewers IS NULL OR max_reviewers IS NULL OR min_reviewers <= max_reviewers);
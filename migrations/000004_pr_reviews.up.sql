@@ -0,0 +1,11 @@
+-- Создание таблицы решений ревьюверов (только добавление, последнее решение - актуальное)
+CREATE TABLE IF NOT EXISTS pr_reviews (
+    review_id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(255) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE RESTRICT,
+    verdict VARCHAR(32) NOT NULL CHECK (verdict IN ('APPROVED', 'CHANGES_REQUESTED', 'COMMENTED')),
+    submitted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Индекс для быстрого получения последнего решения ревьювера по PR
+CREATE INDEX IF NOT EXISTS idx_pr_reviews_pr_user ON pr_reviews(pull_request_id, user_id, review_id DESC);
@@ -0,0 +1,2 @@
+-- Правило merge для команды: NULL - правило выключено, N - нужно N одобрений и ни одного CHANGES_REQUESTED
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS required_approvals INTEGER CHECK (required_approvals >= 0);
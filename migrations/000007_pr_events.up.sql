@@ -0,0 +1,15 @@
+-- Создание истории событий PR (только добавление, записи не изменяются и не удаляются)
+CREATE TABLE IF NOT EXISTS pr_events (
+    event_id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(255) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    event_type VARCHAR(64) NOT NULL,
+    actor_id VARCHAR(255),
+    user_id VARCHAR(255),
+    old_user_id VARCHAR(255),
+    new_user_id VARCHAR(255),
+    reason VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Индекс для получения истории PR в порядке добавления
+CREATE INDEX IF NOT EXISTS idx_pr_events_pr ON pr_events(pull_request_id, event_id);
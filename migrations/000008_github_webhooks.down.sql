@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS idx_users_github_login;
+ALTER TABLE users DROP COLUMN IF EXISTS github_login;
@@ -0,0 +1,10 @@
+-- Привязка пользователей к логинам GitHub (логины GitHub не чувствительны к регистру)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS github_login VARCHAR(255);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_github_login ON users(LOWER(github_login));
+
+-- Полученные доставки вебхуков для отбрасывания повторных доставок
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id VARCHAR(255) PRIMARY KEY,
+    event VARCHAR(64) NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
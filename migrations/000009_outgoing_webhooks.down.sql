@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS outgoing_webhook_deliveries;
+DROP TABLE IF EXISTS webhook_outbox;
+DROP TABLE IF EXISTS webhook_subscriptions;
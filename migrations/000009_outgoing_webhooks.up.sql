@@ -0,0 +1,37 @@
+-- Подписчики исходящих вебхуков
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    subscription_id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    event_types TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Outbox: события для рассылки записываются в одной транзакции с изменением PR
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    outbox_id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL REFERENCES pr_events(event_id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_pending ON webhook_outbox(outbox_id) WHERE processed_at IS NULL;
+
+-- Журнал доставок: одна запись на событие и подписчика, обновляется при каждой попытке
+CREATE TABLE IF NOT EXISTS outgoing_webhook_deliveries (
+    delivery_id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL REFERENCES pr_events(event_id),
+    status VARCHAR(16) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'DELIVERED', 'FAILED')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_status_code INTEGER,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (subscription_id, event_id)
+);
+
+-- Индекс для выборки доставок, которые пора отправить
+CREATE INDEX IF NOT EXISTS idx_outgoing_webhook_deliveries_due
+    ON outgoing_webhook_deliveries(next_attempt_at) WHERE status = 'PENDING';
@@ -0,0 +1,8 @@
+-- Учетные данные пользователей: bcrypt-хеш пароля и счетчик неудачных входов для блокировки
+CREATE TABLE IF NOT EXISTS user_credentials (
+    user_id VARCHAR(255) PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    password_changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
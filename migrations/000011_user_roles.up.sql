@@ -0,0 +1,6 @@
+-- Роли пользователей для разграничения доступа
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'member'
+    CHECK (role IN ('admin', 'team_lead', 'member'));
+
+-- Частичный индекс для быстрой проверки наличия администратора
+CREATE INDEX IF NOT EXISTS idx_users_admins ON users(user_id) WHERE role = 'admin';
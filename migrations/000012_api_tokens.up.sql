@@ -0,0 +1,20 @@
+-- Долгоживущие API токены для CI и интеграций. Хранится только SHA-256 хеш токена.
+CREATE TABLE IF NOT EXISTS api_tokens (
+    token_id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('personal', 'service')),
+    user_id VARCHAR(255) REFERENCES users(user_id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    -- Персональный токен принадлежит пользователю, сервисный - нет
+    CHECK ((kind = 'personal') = (user_id IS NOT NULL))
+);
+
+-- Индекс для списка токенов пользователя
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
@@ -0,0 +1,18 @@
+-- Сессии входа с ротируемыми refresh токенами. Хранится только SHA-256 хеш токена.
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    session_id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    refresh_token_hash CHAR(64) NOT NULL UNIQUE,
+    -- Хеш предыдущего refresh токена: его повторное использование означает утечку и отзывает сессию
+    previous_token_hash CHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    refreshed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+-- Индекс для отзыва активных сессий пользователя
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions(user_id) WHERE revoked_at IS NULL;
+
+-- Индекс для обнаружения повторного использования refresh токена
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_previous ON auth_sessions(previous_token_hash);
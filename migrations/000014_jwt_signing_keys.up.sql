@@ -0,0 +1,10 @@
+-- Ключи асимметричной подписи JWT. Новый ключ подписывает токены, прежние остаются в JWKS
+-- до expires_at, чтобы выданные ими токены проверялись до истечения.
+CREATE TABLE IF NOT EXISTS jwt_signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL CHECK (algorithm IN ('RS256', 'EdDSA')),
+    private_key BYTEA NOT NULL, -- PKCS #8 DER
+    public_key BYTEA NOT NULL,  -- PKIX DER
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP -- NULL у текущего ключа подписи
+);
@@ -0,0 +1,23 @@
+-- Email пользователя для сопоставления с учетной записью у провайдера OIDC (без учета регистра)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(320);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(LOWER(email));
+
+-- Привязка учетных записей провайдера OIDC (issuer + subject) к пользователям
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer VARCHAR(512) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+-- Незавершенные входы через OIDC: state из redirect, nonce ID токена и PKCE code_verifier
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state VARCHAR(64) PRIMARY KEY,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,3 @@
+-- ID пользователя в каталоге SCIM клиента (externalId), по нему клиент сопоставляет свои записи
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_users_external_id ON users(external_id);
@@ -0,0 +1,2 @@
+-- Перед откатом пользователей без команды нужно добавить в команды
+ALTER TABLE users ALTER COLUMN team_name SET NOT NULL;
@@ -0,0 +1,2 @@
+-- Пользователь может не состоять ни в одной команде (например, после удаления из команды)
+ALTER TABLE users ALTER COLUMN team_name DROP NOT NULL;
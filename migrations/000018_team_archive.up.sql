@@ -0,0 +1,7 @@
+-- Время архивации команды; архивная команда не участвует в выборе ревьюверов
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+
+-- Удаление команды оставляет ее участников без команды: удалить пользователей нельзя, на них ссылаются PR
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_name_fkey;
+ALTER TABLE users ADD CONSTRAINT users_team_name_fkey
+    FOREIGN KEY (team_name) REFERENCES teams(team_name) ON DELETE SET NULL;
@@ -0,0 +1,23 @@
+-- Участие пользователей в командах: пользователь может состоять в нескольких командах.
+-- users.team_name остается основной командой пользователя и всегда одна из его команд
+CREATE TABLE IF NOT EXISTS team_members (
+    team_name VARCHAR(255) NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (team_name, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_members_user_id ON team_members(user_id);
+
+INSERT INTO team_members (team_name, user_id)
+SELECT team_name, user_id FROM users WHERE team_name IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- Команда, из которой PR получает ревьюверов; автор выбирает ее из своих команд при создании PR
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS team_name VARCHAR(255)
+    REFERENCES teams(team_name) ON DELETE SET NULL;
+
+UPDATE pull_requests pr
+SET team_name = u.team_name
+FROM users u
+WHERE u.user_id = pr.author_id AND pr.team_name IS NULL;
@@ -0,0 +1,20 @@
+-- Периоды отсутствия пользователей (отпуск, больничный). Даты включительные;
+-- в течение периода пользователь не выбирается ревьювером
+CREATE TABLE IF NOT EXISTS user_absences (
+    absence_id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- Когда открытые ревью пользователя были переназначены фоновой задачей (только для долгих отсутствий)
+    reviews_reassigned_at TIMESTAMP,
+    CONSTRAINT user_absences_dates_check CHECK (end_date >= start_date)
+);
+
+-- Индекс для проверки доступности пользователя и пересечения периодов
+CREATE INDEX IF NOT EXISTS idx_user_absences_user_dates ON user_absences(user_id, start_date, end_date);
+
+-- Индекс для поиска начавшихся долгих отсутствий, ревью которых еще не переназначены
+CREATE INDEX IF NOT EXISTS idx_user_absences_pending ON user_absences(start_date) WHERE reviews_reassigned_at IS NULL;
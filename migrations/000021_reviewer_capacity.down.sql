@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS max_open_reviews;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS max_open_reviews;
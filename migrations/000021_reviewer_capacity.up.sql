@@ -0,0 +1,5 @@
+-- Лимит открытых ревью команды по умолчанию: NULL - без ограничения
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS max_open_reviews INTEGER CHECK (max_open_reviews >= 1);
+
+-- Собственный лимит открытых ревью пользователя: NULL - лимит команды, 0 - новые ревью не назначаются
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_open_reviews INTEGER CHECK (max_open_reviews >= 0);
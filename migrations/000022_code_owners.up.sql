@@ -0,0 +1,24 @@
+-- Файлы CODEOWNERS: правила привязаны либо к команде, либо к репозиторию.
+-- Хранится исходный текст, правила разбираются при назначении ревьюверов
+CREATE TABLE IF NOT EXISTS code_owners (
+    code_owners_id BIGSERIAL PRIMARY KEY,
+    team_name VARCHAR(255) REFERENCES teams(team_name) ON DELETE CASCADE,
+    repository VARCHAR(255),
+    content TEXT NOT NULL,
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT code_owners_scope_check CHECK ((team_name IS NULL) <> (repository IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_code_owners_team ON code_owners(team_name) WHERE team_name IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_code_owners_repository ON code_owners(repository) WHERE repository IS NOT NULL;
+
+-- Репозиторий PR выбирает CODEOWNERS репозитория вместо CODEOWNERS команды
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS repository VARCHAR(255);
+
+-- Измененные файлы PR; по ним определяются владельцы кода при назначении ревьюверов
+CREATE TABLE IF NOT EXISTS pr_changed_files (
+    pull_request_id VARCHAR(255) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    PRIMARY KEY (pull_request_id, path)
+);
@@ -0,0 +1,11 @@
+-- Запасные команды: из них по порядку добираются ревьюверы, если команды PR не хватает до max_reviewers
+CREATE TABLE IF NOT EXISTS team_fallback_teams (
+    team_name VARCHAR(255) NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+    fallback_team VARCHAR(255) NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (team_name, fallback_team),
+    CONSTRAINT team_fallback_teams_self_check CHECK (team_name <> fallback_team)
+);
+
+-- Запасная команда, из которой назначен ревьювер; NULL - ревьювер из команды PR
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS fallback_team VARCHAR(255);
@@ -0,0 +1,8 @@
+-- Лидерство - атрибут членства: пользователь с ролью team_lead ведет только команды, где отмечен лидом
+ALTER TABLE team_members ADD COLUMN IF NOT EXISTS is_lead BOOLEAN NOT NULL DEFAULT false;
+
+-- Существующие лиды остаются лидами своей основной команды
+UPDATE team_members tm
+SET is_lead = true
+FROM users u
+WHERE u.user_id = tm.user_id AND u.role = 'team_lead' AND u.team_name = tm.team_name;
@@ -0,0 +1,2 @@
+ALTER TABLE user_absences DROP COLUMN IF EXISTS next_handover_at;
+ALTER TABLE user_absences DROP COLUMN IF EXISTS handover_attempts;
@@ -0,0 +1,4 @@
+-- Неудачные попытки передать ревью отсутствующего пользователя: период откладывается до next_handover_at,
+-- чтобы постоянно падающие периоды не занимали пачку фоновой задачи и не блокировали остальные
+ALTER TABLE user_absences ADD COLUMN IF NOT EXISTS handover_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE user_absences ADD COLUMN IF NOT EXISTS next_handover_at TIMESTAMP;
@@ -5,17 +5,31 @@ set -e
 API_URL="http://localhost:8080"
 TOKEN=""
 
+# Первый администратор создается приложением при старте (AUTH_BOOTSTRAP_ADMIN_ID/AUTH_BOOTSTRAP_ADMIN_PASSWORD)
+ADMIN_ID="${ADMIN_ID:-admin}"
+ADMIN_PASSWORD="${ADMIN_PASSWORD:-admin-password-change-in-production}"
+
 echo "Тестирование API PR Service"
 echo ""
 
 # 1. Создание команды
 echo "[1/10] Создание команды..."
+ADMIN_TOKEN=$(curl -s -X POST "$API_URL/auth/login" \
+  -H "Content-Type: application/json" \
+  -d "{\"user_id\": \"$ADMIN_ID\", \"password\": \"$ADMIN_PASSWORD\"}" | grep -o '"token":"[^"]*' | cut -d'"' -f4)
+
+if [ -z "$ADMIN_TOKEN" ]; then
+  echo "Не удалось войти администратором $ADMIN_ID"
+  exit 1
+fi
+
 curl -s -X POST "$API_URL/team/add" \
   -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $ADMIN_TOKEN" \
   -d '{
     "team_name": "backend",
     "members": [
-      {"user_id": "u1", "username": "Alice", "is_active": true},
+      {"user_id": "u1", "username": "Alice", "is_active": true, "role": "admin"},
       {"user_id": "u2", "username": "Bob", "is_active": true},
       {"user_id": "u3", "username": "Charlie", "is_active": true}
     ]
@@ -24,9 +38,14 @@ echo ""
 
 # 2. Получение JWT токена
 echo "[2/10] Получение JWT токена..."
+curl -s -X POST "$API_URL/auth/setPassword" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $ADMIN_TOKEN" \
+  -d '{"user_id": "u1", "new_password": "u1-password"}' > /dev/null
+
 TOKEN=$(curl -s -X POST "$API_URL/auth/login" \
   -H "Content-Type: application/json" \
-  -d '{"user_id": "u1"}' | grep -o '"token":"[^"]*' | cut -d'"' -f4)
+  -d '{"user_id": "u1", "password": "u1-password"}' | grep -o '"token":"[^"]*' | cut -d'"' -f4)
 
 if [ -z "$TOKEN" ]; then
   echo "Не удалось получить токен"
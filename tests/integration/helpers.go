@@ -1,13 +1,19 @@
 package integration
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 	"time"
 
@@ -22,6 +28,18 @@ import (
 	"github.com/aidar/avito-pr-project/internal/config"
 )
 
+// testGitHubWebhookSecret используется для подписи записанных вебхуков GitHub в тестах
+const testGitHubWebhookSecret = "test-github-webhook-secret"
+
+// testPassword задается пользователям в тестах перед входом
+const testPassword = "test-password"
+
+// Первый администратор, создаваемый приложением при старте из конфигурации
+const (
+	testAdminID       = "bootstrap-admin"
+	testAdminPassword = "bootstrap-admin-password"
+)
+
 // TestEnvironment содержит все ресурсы необходимые для интеграционных тестов
 type TestEnvironment struct {
 	PostgresContainer *postgres.PostgresContainer
@@ -31,8 +49,9 @@ type TestEnvironment struct {
 	ctx               context.Context
 }
 
-// SetupTestEnvironment создает и инициализирует полное тестовое окружение
-func SetupTestEnvironment(t *testing.T) *TestEnvironment {
+// SetupTestEnvironment создает и инициализирует полное тестовое окружение.
+// opts позволяют изменить конфигурацию приложения до его запуска
+func SetupTestEnvironment(t *testing.T, opts ...func(cfg *config.Config)) *TestEnvironment {
 	t.Helper()
 	ctx := context.Background()
 
@@ -83,9 +102,43 @@ func SetupTestEnvironment(t *testing.T) *TestEnvironment {
 			MinConns: 5,
 		},
 		JWT: config.JWTConfig{
-			Secret:          "test-jwt-secret-key-for-integration-tests",
-			ExpirationHours: 24,
+			SigningAlgorithm:    "HS256",
+			Secret:              "test-jwt-secret-key-for-integration-tests",
+			AccessTTL:           15 * time.Minute,
+			RefreshTTL:          24 * time.Hour,
+			KeyRotationInterval: 24 * time.Hour,
+			KeyOverlap:          time.Hour,
+			KeyPollInterval:     time.Minute,
+		},
+		Auth: config.AuthConfig{
+			MaxFailedAttempts:      3,
+			LockoutDuration:        time.Minute,
+			BootstrapAdminID:       testAdminID,
+			BootstrapAdminPassword: testAdminPassword,
+		},
+		Reviewer: config.ReviewerConfig{
+			DefaultStrategy: "random",
+		},
+		GitHub: config.GitHubConfig{
+			WebhookSecret: testGitHubWebhookSecret,
 		},
+		// Короткие интервалы, чтобы тесты не ждали повторных попыток доставки
+		Webhook: config.WebhookConfig{
+			PollInterval:   100 * time.Millisecond,
+			Timeout:        2 * time.Second,
+			RetryBaseDelay: 200 * time.Millisecond,
+			RetryMaxDelay:  time.Second,
+			MaxAttempts:    5,
+		},
+		// Короткий интервал, чтобы тесты не ждали передачи ревью отсутствующих
+		Absence: config.AbsenceConfig{
+			PollInterval:    100 * time.Millisecond,
+			ReassignMinDays: 3,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
 	}
 
 	// Создаем и инициализируем приложение
@@ -159,16 +212,21 @@ func applyMigrations(t *testing.T, connStr string) {
 	require.NoError(t, err, "Failed to open database connection")
 	defer db.Close()
 
-	// Читаем файл миграции
+	// Находим все up-миграции (имена с порядковым префиксом сортируются в порядке применения)
 	projectRoot := getProjectRoot(t)
-	migrationPath := filepath.Join(projectRoot, "migrations", "000001_init_schema.up.sql")
-
-	migrationSQL, err := os.ReadFile(migrationPath)
-	require.NoError(t, err, "Failed to read migration file")
-
-	// Выполняем миграцию
-	_, err = db.Exec(string(migrationSQL))
-	require.NoError(t, err, "Failed to apply migration")
+	migrationPaths, err := filepath.Glob(filepath.Join(projectRoot, "migrations", "*.up.sql"))
+	require.NoError(t, err, "Failed to list migration files")
+	require.NotEmpty(t, migrationPaths, "No migration files found")
+	sort.Strings(migrationPaths)
+
+	for _, migrationPath := range migrationPaths {
+		migrationSQL, err := os.ReadFile(migrationPath)
+		require.NoError(t, err, "Failed to read migration file %s", migrationPath)
+
+		// Выполняем миграцию
+		_, err = db.Exec(string(migrationSQL))
+		require.NoError(t, err, "Failed to apply migration %s", migrationPath)
+	}
 
 	t.Log("Migrations applied successfully")
 }
@@ -216,6 +274,88 @@ func (te *TestEnvironment) MakeRequest(t *testing.T, method, path string, body i
 	return resp
 }
 
+// Login задает пользователю пароль testPassword от имени администратора и возвращает JWT токен
+func (te *TestEnvironment) Login(t *testing.T, userID string) string {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]string{"user_id": userID, "new_password": testPassword})
+	require.NoError(t, err)
+	resp := te.MakeRequest(t, http.MethodPost, "/auth/setPassword", bytes.NewReader(body), te.AdminToken(t))
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Failed to set password")
+
+	return te.login(t, userID, testPassword)
+}
+
+// AdminToken возвращает JWT токен первого администратора, созданного из конфигурации
+func (te *TestEnvironment) AdminToken(t *testing.T) string {
+	t.Helper()
+
+	return te.login(t, testAdminID, testAdminPassword)
+}
+
+// login входит по паролю и возвращает JWT токен
+func (te *TestEnvironment) login(t *testing.T, userID, password string) string {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]string{"user_id": userID, "password": password})
+	require.NoError(t, err)
+	resp := te.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Failed to login")
+
+	var loginResp struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&loginResp))
+
+	return loginResp.Token
+}
+
+// ReplayGitHubWebhook отправляет записанный payload из testdata/github, подписанный тестовым секретом
+func (te *TestEnvironment) ReplayGitHubWebhook(t *testing.T, event, file, deliveryID string) *http.Response {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", "github", file))
+	require.NoError(t, err, "Failed to read recorded payload")
+
+	return te.SendGitHubWebhook(t, event, deliveryID, payload, SignGitHubPayload(payload, testGitHubWebhookSecret))
+}
+
+// SendGitHubWebhook отправляет вебхук GitHub с указанной подписью
+func (te *TestEnvironment) SendGitHubWebhook(
+	t *testing.T,
+	event, deliveryID string,
+	payload []byte,
+	signature string,
+) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, te.BaseURL+"/webhooks/github", bytes.NewReader(payload))
+	require.NoError(t, err, "Failed to create request")
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", deliveryID)
+	req.Header.Set("X-Hub-Signature-256", signature)
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	resp, err := client.Do(req)
+	require.NoError(t, err, "Failed to make request")
+
+	return resp
+}
+
+// SignGitHubPayload вычисляет значение заголовка X-Hub-Signature-256 так же, как GitHub
+func SignGitHubPayload(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
 // WaitForHealthCheck ждет пока приложение станет доступным
 func (te *TestEnvironment) WaitForHealthCheck(t *testing.T) {
 	t.Helper()
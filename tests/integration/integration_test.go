@@ -2,13 +2,27 @@ package integration
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/aidar/avito-pr-project/internal/config"
 )
 
 // Тестовые структуры данных соответствующие API
@@ -21,14 +35,18 @@ type Member struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	IsActive bool   `json:"is_active"`
+	Role     string `json:"role,omitempty"`
 }
 
 type LoginRequest struct {
-	UserID string `json:"user_id"`
+	UserID   string `json:"user_id"`
+	Password string `json:"password"`
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 type CreatePRRequest struct {
@@ -48,6 +66,7 @@ type PullRequestResponse struct {
 type ReassignRequest struct {
 	PullRequestID string `json:"pull_request_id"`
 	OldReviewerID string `json:"old_user_id"`
+	NewReviewerID string `json:"new_user_id,omitempty"`
 }
 
 type SetIsActiveRequest struct {
@@ -72,7 +91,7 @@ func TestE2E_CompleteWorkflow(t *testing.T) {
 		team := Team{
 			TeamName: "backend-team",
 			Members: []Member{
-				{UserID: "user1", Username: "Alice", IsActive: true},
+				{UserID: "user1", Username: "Alice", IsActive: true, Role: "admin"},
 				{UserID: "user2", Username: "Bob", IsActive: true},
 				{UserID: "user3", Username: "Charlie", IsActive: true},
 				{UserID: "user4", Username: "David", IsActive: true},
@@ -80,7 +99,7 @@ func TestE2E_CompleteWorkflow(t *testing.T) {
 		}
 
 		body, _ := json.Marshal(team)
-		resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), "")
+		resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
 		defer resp.Body.Close()
 
 		assert.Equal(t, http.StatusCreated, resp.StatusCode, "Team creation should succeed")
@@ -89,20 +108,8 @@ func TestE2E_CompleteWorkflow(t *testing.T) {
 	// Логин как user1 для получения токена
 	var token string
 	t.Run("Login as User", func(t *testing.T) {
-		loginReq := LoginRequest{UserID: "user1"}
-		body, _ := json.Marshal(loginReq)
-
-		resp := env.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
-		defer resp.Body.Close()
-
-		require.Equal(t, http.StatusOK, resp.StatusCode, "Login should succeed")
-
-		var loginResp LoginResponse
-		err := json.NewDecoder(resp.Body).Decode(&loginResp)
-		require.NoError(t, err)
-		require.NotEmpty(t, loginResp.Token)
-
-		token = loginResp.Token
+		token = env.Login(t, "user1")
+		require.NotEmpty(t, token)
 	})
 
 	// Создание Pull Request
@@ -148,18 +155,10 @@ func TestE2E_CompleteWorkflow(t *testing.T) {
 		// Логин как один из ревьюеров
 		reviewerID := pr.Reviewers[0]
 
-		loginReq := LoginRequest{UserID: reviewerID}
-		body, _ := json.Marshal(loginReq)
-
-		resp := env.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
-		defer resp.Body.Close()
-
-		var loginResp LoginResponse
-		_ = json.NewDecoder(resp.Body).Decode(&loginResp)
-		reviewerToken := loginResp.Token
+		reviewerToken := env.Login(t, reviewerID)
 
 		// Получение ревью
-		resp = env.MakeRequest(t, http.MethodGet, "/users/getReview?user_id="+reviewerID, nil, reviewerToken)
+		resp := env.MakeRequest(t, http.MethodGet, "/users/getReview?user_id="+reviewerID, nil, reviewerToken)
 		defer resp.Body.Close()
 
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
@@ -263,24 +262,18 @@ func TestE2E_UserActivation(t *testing.T) {
 	team := Team{
 		TeamName: "frontend-team",
 		Members: []Member{
-			{UserID: "fe-user1", Username: "Emma", IsActive: true},
+			{UserID: "fe-user1", Username: "Emma", IsActive: true, Role: "admin"},
 			{UserID: "fe-user2", Username: "Frank", IsActive: true},
 			{UserID: "fe-user3", Username: "Grace", IsActive: true},
 		},
 	}
 
 	body, _ := json.Marshal(team)
-	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), "")
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
 	resp.Body.Close()
 
 	// Логин как fe-user1
-	loginReq := LoginRequest{UserID: "fe-user1"}
-	body, _ = json.Marshal(loginReq)
-	resp = env.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
-	var loginResp LoginResponse
-	json.NewDecoder(resp.Body).Decode(&loginResp)
-	resp.Body.Close()
-	token := loginResp.Token
+	token := env.Login(t, "fe-user1")
 
 	t.Run("Deactivate User", func(t *testing.T) {
 		setActiveReq := SetIsActiveRequest{
@@ -349,23 +342,17 @@ func TestE2E_MergeIdempotency(t *testing.T) {
 	team := Team{
 		TeamName: "devops-team",
 		Members: []Member{
-			{UserID: "devops1", Username: "Henry", IsActive: true},
+			{UserID: "devops1", Username: "Henry", IsActive: true, Role: "admin"},
 			{UserID: "devops2", Username: "Iris", IsActive: true},
 		},
 	}
 
 	body, _ := json.Marshal(team)
-	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), "")
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
 	resp.Body.Close()
 
 	// Логин
-	loginReq := LoginRequest{UserID: "devops1"}
-	body, _ = json.Marshal(loginReq)
-	resp = env.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
-	var loginResp LoginResponse
-	json.NewDecoder(resp.Body).Decode(&loginResp)
-	resp.Body.Close()
-	token := loginResp.Token
+	token := env.Login(t, "devops1")
 
 	// Создание PR
 	createPR := CreatePRRequest{
@@ -426,23 +413,17 @@ func TestE2E_GetTeam(t *testing.T) {
 	team := Team{
 		TeamName: "qa-team",
 		Members: []Member{
-			{UserID: "qa1", Username: "Jack", IsActive: true},
+			{UserID: "qa1", Username: "Jack", IsActive: true, Role: "admin"},
 			{UserID: "qa2", Username: "Kate", IsActive: true},
 		},
 	}
 
 	body, _ := json.Marshal(team)
-	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), "")
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
 	resp.Body.Close()
 
 	// Логин
-	loginReq := LoginRequest{UserID: "qa1"}
-	body, _ = json.Marshal(loginReq)
-	resp = env.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
-	var loginResp LoginResponse
-	json.NewDecoder(resp.Body).Decode(&loginResp)
-	resp.Body.Close()
-	token := loginResp.Token
+	token := env.Login(t, "qa1")
 
 	t.Run("Get Team", func(t *testing.T) {
 		resp := env.MakeRequest(t, http.MethodGet, "/team/get?team_name=qa-team", nil, token)
@@ -474,24 +455,18 @@ func TestE2E_Stats(t *testing.T) {
 	team := Team{
 		TeamName: "data-team",
 		Members: []Member{
-			{UserID: "data1", Username: "Leo", IsActive: true},
+			{UserID: "data1", Username: "Leo", IsActive: true, Role: "admin"},
 			{UserID: "data2", Username: "Mia", IsActive: true},
 			{UserID: "data3", Username: "Noah", IsActive: true},
 		},
 	}
 
 	body, _ := json.Marshal(team)
-	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), "")
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
 	resp.Body.Close()
 
 	// Логин
-	loginReq := LoginRequest{UserID: "data1"}
-	body, _ = json.Marshal(loginReq)
-	resp = env.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
-	var loginResp LoginResponse
-	json.NewDecoder(resp.Body).Decode(&loginResp)
-	resp.Body.Close()
-	token := loginResp.Token
+	token := env.Login(t, "data1")
 
 	// Создание нескольких PR
 	for i := 1; i <= 3; i++ {
@@ -550,22 +525,16 @@ func TestE2E_SmallTeam(t *testing.T) {
 	team := Team{
 		TeamName: "solo-team",
 		Members: []Member{
-			{UserID: "solo1", Username: "Oliver", IsActive: true},
+			{UserID: "solo1", Username: "Oliver", IsActive: true, Role: "admin"},
 		},
 	}
 
 	body, _ := json.Marshal(team)
-	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), "")
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
 	resp.Body.Close()
 
 	// Логин
-	loginReq := LoginRequest{UserID: "solo1"}
-	body, _ = json.Marshal(loginReq)
-	resp = env.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
-	var loginResp LoginResponse
-	json.NewDecoder(resp.Body).Decode(&loginResp)
-	resp.Body.Close()
-	token := loginResp.Token
+	token := env.Login(t, "solo1")
 
 	t.Run("Create PR with No Available Reviewers", func(t *testing.T) {
 		createPR := CreatePRRequest{
@@ -592,3 +561,3832 @@ func TestE2E_SmallTeam(t *testing.T) {
 		assert.Len(t, pr.Reviewers, 0, "Should have no reviewers when team has only author")
 	})
 }
+
+// TestE2E_BulkDeactivation тестирует массовую деактивацию с переназначением открытых ревью
+func TestE2E_BulkDeactivation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	// Создание команды: автор, два ревьювера и один запасной участник
+	team := Team{
+		TeamName: "mobile-team",
+		Members: []Member{
+			{UserID: "mob1", Username: "Paul", IsActive: true, Role: "admin"},
+			{UserID: "mob2", Username: "Quinn", IsActive: true},
+			{UserID: "mob3", Username: "Rose", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	// Логин
+	token := env.Login(t, "mob1")
+
+	// Создание PR - оба остальных участника становятся ревьюверами
+	createPR := CreatePRRequest{
+		PullRequestID:   "pr-mob-1",
+		PullRequestName: "Mobile release",
+		AuthorID:        "mob1",
+	}
+	body, _ = json.Marshal(createPR)
+	resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), token)
+	resp.Body.Close()
+
+	// Добавляем нового участника, который станет заменой
+	_, err := env.DB.Exec(env.ctx,
+		`INSERT INTO users (user_id, username, team_name, is_active) VALUES ('mob4', 'Sam', 'mobile-team', true)`)
+	require.NoError(t, err)
+
+	t.Run("Deactivate Reviewers", func(t *testing.T) {
+		deactivateReq := map[string]interface{}{
+			"team_name": "mobile-team",
+			"user_ids":  []string{"mob2", "mob3"},
+		}
+
+		body, _ := json.Marshal(deactivateReq)
+		resp := env.MakeRequest(t, http.MethodPost, "/team/deactivateUsers", bytes.NewReader(body), token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var deactivateResp struct {
+			DeactivatedUsers []string `json:"deactivated_users"`
+			Reassignments    []struct {
+				PullRequestID string `json:"pull_request_id"`
+				OldUserID     string `json:"old_user_id"`
+				ReplacedBy    string `json:"replaced_by"`
+			} `json:"reassignments"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&deactivateResp)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"mob2", "mob3"}, deactivateResp.DeactivatedUsers)
+		require.Len(t, deactivateResp.Reassignments, 2)
+
+		// Единственный активный кандидат может заменить только одного ревьювера
+		replaced := 0
+		for _, ra := range deactivateResp.Reassignments {
+			assert.Equal(t, "pr-mob-1", ra.PullRequestID)
+			if ra.ReplacedBy != "" {
+				assert.Equal(t, "mob4", ra.ReplacedBy)
+				replaced++
+			}
+		}
+		assert.Equal(t, 1, replaced)
+	})
+
+	t.Run("Inactive Reviewers Removed From PR", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/users/getReview?user_id=mob4", nil, token)
+		defer resp.Body.Close()
+
+		var reviewResp struct {
+			PullRequests []PullRequestResponse `json:"pull_requests"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&reviewResp)
+		require.NoError(t, err)
+		assert.Len(t, reviewResp.PullRequests, 1)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/users/getReview?user_id=mob2", nil, token)
+		defer resp.Body.Close()
+
+		err = json.NewDecoder(resp.Body).Decode(&reviewResp)
+		require.NoError(t, err)
+		assert.Empty(t, reviewResp.PullRequests)
+	})
+}
+
+// TestE2E_ReviewerStrategy тестирует настройку стратегии выбора ревьюверов для команды
+func TestE2E_ReviewerStrategy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	// Создание команды: автор и три кандидата
+	team := Team{
+		TeamName: "platform-team",
+		Members: []Member{
+			{UserID: "plat0", Username: "Tom", IsActive: true, Role: "admin"},
+			{UserID: "plat1", Username: "Uma", IsActive: true},
+			{UserID: "plat2", Username: "Vic", IsActive: true},
+			{UserID: "plat3", Username: "Will", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	// Логин
+	token := env.Login(t, "plat0")
+
+	t.Run("Default Strategy", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/team/getSettings?team_name=platform-team", nil, token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var settings map[string]interface{}
+		err := json.NewDecoder(resp.Body).Decode(&settings)
+		require.NoError(t, err)
+		assert.Equal(t, "random", settings["reviewer_strategy"])
+	})
+
+	t.Run("Reject Unknown Strategy", func(t *testing.T) {
+		req := map[string]string{"team_name": "platform-team", "reviewer_strategy": "alphabetical"}
+		body, _ := json.Marshal(req)
+
+		resp := env.MakeRequest(t, http.MethodPost, "/team/setReviewerStrategy", bytes.NewReader(body), token)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Round Robin Rotates Reviewers", func(t *testing.T) {
+		req := map[string]string{"team_name": "platform-team", "reviewer_strategy": "round_robin"}
+		body, _ := json.Marshal(req)
+
+		resp := env.MakeRequest(t, http.MethodPost, "/team/setReviewerStrategy", bytes.NewReader(body), token)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		expected := [][]string{{"plat1", "plat2"}, {"plat3", "plat1"}}
+		for i, want := range expected {
+			createPR := CreatePRRequest{
+				PullRequestID:   "pr-plat-" + string(rune('1'+i)),
+				PullRequestName: "Platform change",
+				AuthorID:        "plat0",
+			}
+			body, _ := json.Marshal(createPR)
+
+			resp := env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), token)
+			var createResp struct {
+				PR PullRequestResponse `json:"pr"`
+			}
+			err := json.NewDecoder(resp.Body).Decode(&createResp)
+			resp.Body.Close()
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, want, createResp.PR.Reviewers)
+		}
+	})
+}
+
+// TestE2E_LeastLoadedBulkReassign тестирует равномерное распределение ревью при массовой деактивации
+func TestE2E_LeastLoadedBulkReassign(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "search-team",
+		Members: []Member{
+			{UserID: "search-author", Username: "Xena", IsActive: true, Role: "admin"},
+			{UserID: "search-leaving", Username: "Yuri", IsActive: true},
+			{UserID: "search-a", Username: "Zoe", IsActive: true},
+			{UserID: "search-b", Username: "Adam", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	token := env.Login(t, "search-author")
+
+	// Четыре открытых PR, на каждом единственный ревьювер - уходящий участник
+	for i := 1; i <= 4; i++ {
+		prID := "pr-search-" + string(rune('0'+i))
+		_, err := env.DB.Exec(env.ctx,
+			`INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status) VALUES ($1, 'Search tuning', 'search-author', 'OPEN')`,
+			prID)
+		require.NoError(t, err)
+		_, err = env.DB.Exec(env.ctx,
+			`INSERT INTO pr_reviewers (pull_request_id, user_id) VALUES ($1, 'search-leaving')`, prID)
+		require.NoError(t, err)
+	}
+
+	strategyReq := map[string]string{"team_name": "search-team", "reviewer_strategy": "least_loaded"}
+	body, _ = json.Marshal(strategyReq)
+	resp = env.MakeRequest(t, http.MethodPost, "/team/setReviewerStrategy", bytes.NewReader(body), token)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	t.Run("Replacements Are Spread Evenly", func(t *testing.T) {
+		deactivateReq := map[string]interface{}{
+			"team_name": "search-team",
+			"user_ids":  []string{"search-leaving"},
+		}
+		body, _ := json.Marshal(deactivateReq)
+
+		resp := env.MakeRequest(t, http.MethodPost, "/team/deactivateUsers", bytes.NewReader(body), token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var deactivateResp struct {
+			Reassignments []struct {
+				ReplacedBy string `json:"replaced_by"`
+			} `json:"reassignments"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&deactivateResp)
+		require.NoError(t, err)
+		require.Len(t, deactivateResp.Reassignments, 4)
+
+		perReviewer := map[string]int{}
+		for _, ra := range deactivateResp.Reassignments {
+			perReviewer[ra.ReplacedBy]++
+		}
+		assert.Equal(t, map[string]int{"search-a": 2, "search-b": 2}, perReviewer)
+	})
+}
+
+// TestE2E_ReviewerLimits тестирует лимиты числа ревьюверов команды
+func TestE2E_ReviewerLimits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "security-team",
+		Members: []Member{
+			{UserID: "sec1", Username: "Bella", IsActive: true, Role: "admin"},
+			{UserID: "sec2", Username: "Carl", IsActive: true},
+			{UserID: "sec3", Username: "Dana", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	token := env.Login(t, "sec1")
+
+	t.Run("Reject Invalid Limits", func(t *testing.T) {
+		req := map[string]interface{}{"team_name": "security-team", "min_reviewers": 3, "max_reviewers": 2}
+		body, _ := json.Marshal(req)
+
+		resp := env.MakeRequest(t, http.MethodPost, "/team/setReviewerLimits", bytes.NewReader(body), token)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Set Limits", func(t *testing.T) {
+		req := map[string]interface{}{"team_name": "security-team", "min_reviewers": 3, "max_reviewers": 3}
+		body, _ := json.Marshal(req)
+
+		resp := env.MakeRequest(t, http.MethodPost, "/team/setReviewerLimits", bytes.NewReader(body), token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var settings map[string]interface{}
+		err := json.NewDecoder(resp.Body).Decode(&settings)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, settings["min_reviewers"])
+		assert.EqualValues(t, 3, settings["max_reviewers"])
+		assert.Equal(t, "random", settings["reviewer_strategy"], "Setting limits should keep the default strategy")
+	})
+
+	t.Run("PR Reports Missing Reviewers", func(t *testing.T) {
+		createPR := CreatePRRequest{
+			PullRequestID:   "pr-sec-1",
+			PullRequestName: "Rotate secrets",
+			AuthorID:        "sec1",
+		}
+		body, _ := json.Marshal(createPR)
+
+		resp := env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), token)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/pullRequest/get?pull_request_id=pr-sec-1", nil, token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var getResp struct {
+			PR struct {
+				Reviewers        []string `json:"assigned_reviewers"`
+				MissingReviewers int      `json:"missing_reviewers"`
+			} `json:"pr"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&getResp)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"sec2", "sec3"}, getResp.PR.Reviewers)
+		assert.Equal(t, 1, getResp.PR.MissingReviewers)
+	})
+}
+
+// TestE2E_ReviewVerdicts тестирует решения ревьюверов
+func TestE2E_ReviewVerdicts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "billing-team",
+		Members: []Member{
+			{UserID: "bill1", Username: "Eve", IsActive: true, Role: "admin"},
+			{UserID: "bill2", Username: "Finn", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	token := env.Login(t, "bill1")
+	tokens := map[string]string{"bill1": token, "bill2": env.Login(t, "bill2")}
+
+	// Единственный кандидат bill2 становится ревьювером
+	createPR := CreatePRRequest{
+		PullRequestID:   "pr-bill-1",
+		PullRequestName: "Invoice rounding",
+		AuthorID:        "bill1",
+	}
+	body, _ = json.Marshal(createPR)
+	resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), token)
+	resp.Body.Close()
+
+	submit := func(t *testing.T, userID, verdict string) *http.Response {
+		t.Helper()
+		req := map[string]string{"pull_request_id": "pr-bill-1", "user_id": userID, "verdict": verdict}
+		body, _ := json.Marshal(req)
+		return env.MakeRequest(t, http.MethodPost, "/pullRequest/review", bytes.NewReader(body), tokens[userID])
+	}
+
+	t.Run("Latest Verdict Wins", func(t *testing.T) {
+		resp := submit(t, "bill2", "CHANGES_REQUESTED")
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = submit(t, "bill2", "APPROVED")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var reviewResp struct {
+			PR struct {
+				Reviews []struct {
+					UserID  string `json:"user_id"`
+					Verdict string `json:"verdict"`
+				} `json:"reviews"`
+			} `json:"pr"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&reviewResp)
+		require.NoError(t, err)
+
+		require.Len(t, reviewResp.PR.Reviews, 1)
+		assert.Equal(t, "bill2", reviewResp.PR.Reviews[0].UserID)
+		assert.Equal(t, "APPROVED", reviewResp.PR.Reviews[0].Verdict)
+	})
+
+	t.Run("Reject Verdict From Non-Reviewer", func(t *testing.T) {
+		resp := submit(t, "bill1", "APPROVED")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("Reject Verdict On Behalf Of Another User", func(t *testing.T) {
+		req := map[string]string{"pull_request_id": "pr-bill-1", "user_id": "bill2", "verdict": "APPROVED"}
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, "/pullRequest/review", bytes.NewReader(body), tokens["bill1"])
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Reject Unknown Verdict", func(t *testing.T) {
+		resp := submit(t, "bill2", "LGTM")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Exclude Approved From Review List", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/users/getReview?user_id=bill2&exclude_approved=true", nil, token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var reviewResp struct {
+			PullRequests []PullRequestResponse `json:"pull_requests"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&reviewResp)
+		require.NoError(t, err)
+		assert.Empty(t, reviewResp.PullRequests)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/users/getReview?user_id=bill2", nil, token)
+		defer resp.Body.Close()
+
+		err = json.NewDecoder(resp.Body).Decode(&reviewResp)
+		require.NoError(t, err)
+		assert.Len(t, reviewResp.PullRequests, 1)
+	})
+}
+
+// TestE2E_MergeRule тестирует запрет merge без необходимых одобрений
+func TestE2E_MergeRule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "payments-team",
+		Members: []Member{
+			{UserID: "pay1", Username: "Gina", IsActive: true, Role: "admin"},
+			{UserID: "pay2", Username: "Hugo", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	token := env.Login(t, "pay1")
+
+	// Решение отправляет сам ревьювер под своим токеном
+	reviewerToken := env.Login(t, "pay2")
+
+	ruleReq := map[string]interface{}{"team_name": "payments-team", "required_approvals": 1}
+	body, _ = json.Marshal(ruleReq)
+	resp = env.MakeRequest(t, http.MethodPost, "/team/setMergeRule", bytes.NewReader(body), token)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	createPR := CreatePRRequest{
+		PullRequestID:   "pr-pay-1",
+		PullRequestName: "Refund flow",
+		AuthorID:        "pay1",
+	}
+	body, _ = json.Marshal(createPR)
+	resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), token)
+	resp.Body.Close()
+
+	merge := func(t *testing.T) *http.Response {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"pull_request_id": "pr-pay-1"})
+		return env.MakeRequest(t, http.MethodPost, "/pullRequest/merge", bytes.NewReader(body), token)
+	}
+	review := func(t *testing.T, verdict string) {
+		t.Helper()
+		req := map[string]string{"pull_request_id": "pr-pay-1", "user_id": "pay2", "verdict": verdict}
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, "/pullRequest/review", bytes.NewReader(body), reviewerToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assertNotApproved := func(t *testing.T, resp *http.Response) {
+		t.Helper()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		var errResp struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&errResp)
+		require.NoError(t, err)
+		assert.Equal(t, "NOT_APPROVED", errResp.Error.Code)
+	}
+
+	t.Run("Reject Merge Without Approval", func(t *testing.T) {
+		assertNotApproved(t, merge(t))
+	})
+
+	t.Run("Reject Merge With Changes Requested", func(t *testing.T) {
+		review(t, "CHANGES_REQUESTED")
+		assertNotApproved(t, merge(t))
+	})
+
+	t.Run("Merge After Approval", func(t *testing.T) {
+		review(t, "APPROVED")
+
+		resp := merge(t)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Merged PR Stays Idempotent", func(t *testing.T) {
+		resp := merge(t)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var mergeResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		json.NewDecoder(resp.Body).Decode(&mergeResp)
+		assert.Equal(t, "MERGED", mergeResp.PR.Status)
+	})
+}
+
+// TestE2E_DraftAndClosed проверяет жизненный цикл черновиков и закрытых PR
+func TestE2E_DraftAndClosed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "docs-team",
+		Members: []Member{
+			{UserID: "doc1", Username: "Ivy", IsActive: true, Role: "admin"},
+			{UserID: "doc2", Username: "Jack", IsActive: true},
+			{UserID: "doc3", Username: "Kate", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	token := env.Login(t, "doc1")
+
+	changeStatus := func(t *testing.T, path string) (int, PullRequestResponse) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"pull_request_id": "pr-doc-1"})
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+		defer resp.Body.Close()
+
+		var prResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		json.NewDecoder(resp.Body).Decode(&prResp)
+		return resp.StatusCode, prResp.PR
+	}
+
+	t.Run("Create Draft Without Reviewers", func(t *testing.T) {
+		createPR := map[string]interface{}{
+			"pull_request_id":   "pr-doc-1",
+			"pull_request_name": "Rewrite guide",
+			"author_id":         "doc1",
+			"draft":             true,
+		}
+		body, _ := json.Marshal(createPR)
+		resp := env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var prResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		json.NewDecoder(resp.Body).Decode(&prResp)
+		assert.Equal(t, "DRAFT", prResp.PR.Status)
+		assert.Empty(t, prResp.PR.Reviewers)
+	})
+
+	t.Run("Draft Cannot Be Merged", func(t *testing.T) {
+		status, _ := changeStatus(t, "/pullRequest/merge")
+		assert.Equal(t, http.StatusConflict, status)
+	})
+
+	t.Run("Ready Assigns Reviewers", func(t *testing.T) {
+		status, pr := changeStatus(t, "/pullRequest/ready")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "OPEN", pr.Status)
+		assert.ElementsMatch(t, []string{"doc2", "doc3"}, pr.Reviewers)
+	})
+
+	t.Run("Close Frees Reviewers", func(t *testing.T) {
+		status, pr := changeStatus(t, "/pullRequest/close")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "CLOSED", pr.Status)
+		assert.Empty(t, pr.Reviewers)
+
+		resp := env.MakeRequest(t, http.MethodGet, "/users/getReview?user_id=doc2", nil, token)
+		defer resp.Body.Close()
+
+		var reviewResp struct {
+			PullRequests []PullRequestResponse `json:"pull_requests"`
+		}
+		json.NewDecoder(resp.Body).Decode(&reviewResp)
+		assert.Empty(t, reviewResp.PullRequests)
+	})
+
+	t.Run("Closed PR Cannot Be Merged Or Marked Ready", func(t *testing.T) {
+		status, _ := changeStatus(t, "/pullRequest/merge")
+		assert.Equal(t, http.StatusConflict, status)
+
+		status, _ = changeStatus(t, "/pullRequest/ready")
+		assert.Equal(t, http.StatusConflict, status)
+	})
+
+	t.Run("Reopen Assigns Reviewers Again", func(t *testing.T) {
+		status, pr := changeStatus(t, "/pullRequest/reopen")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "OPEN", pr.Status)
+		assert.ElementsMatch(t, []string{"doc2", "doc3"}, pr.Reviewers)
+	})
+}
+
+// TestE2E_Timeline проверяет историю событий PR
+func TestE2E_Timeline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "infra-team",
+		Members: []Member{
+			{UserID: "inf1", Username: "Liam", IsActive: true, Role: "admin"},
+			{UserID: "inf2", Username: "Mia", IsActive: true},
+			{UserID: "inf3", Username: "Noah", IsActive: true},
+			{UserID: "inf4", Username: "Olga", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	token := env.Login(t, "inf1")
+
+	createPR := CreatePRRequest{
+		PullRequestID:   "pr-inf-1",
+		PullRequestName: "Upgrade cluster",
+		AuthorID:        "inf1",
+	}
+	body, _ = json.Marshal(createPR)
+	resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), token)
+	var createResp struct {
+		PR PullRequestResponse `json:"pr"`
+	}
+	json.NewDecoder(resp.Body).Decode(&createResp)
+	resp.Body.Close()
+	require.Len(t, createResp.PR.Reviewers, 2)
+
+	replaced := createResp.PR.Reviewers[0]
+	deactivated := createResp.PR.Reviewers[1]
+
+	reassignReq := ReassignRequest{PullRequestID: "pr-inf-1", OldReviewerID: replaced}
+	body, _ = json.Marshal(reassignReq)
+	resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/reassign", bytes.NewReader(body), token)
+	var reassignResp struct {
+		ReplacedBy string `json:"replaced_by"`
+	}
+	json.NewDecoder(resp.Body).Decode(&reassignResp)
+	resp.Body.Close()
+	require.NotEmpty(t, reassignResp.ReplacedBy)
+
+	setActiveReq := SetIsActiveRequest{UserID: deactivated, IsActive: false}
+	body, _ = json.Marshal(setActiveReq)
+	resp = env.MakeRequest(t, http.MethodPost, "/users/setIsActive", bytes.NewReader(body), token)
+	resp.Body.Close()
+
+	body, _ = json.Marshal(map[string]string{"pull_request_id": "pr-inf-1"})
+	resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/merge", bytes.NewReader(body), token)
+	resp.Body.Close()
+
+	t.Run("Timeline Records All Changes", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/pullRequest/timeline?pull_request_id=pr-inf-1", nil, token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var timeline struct {
+			Events []struct {
+				Type      string `json:"type"`
+				ActorID   string `json:"actor_id"`
+				UserID    string `json:"user_id"`
+				OldUserID string `json:"old_user_id"`
+				NewUserID string `json:"new_user_id"`
+				Reason    string `json:"reason"`
+			} `json:"events"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&timeline)
+		require.NoError(t, err)
+
+		var types []string
+		for _, e := range timeline.Events {
+			types = append(types, e.Type)
+		}
+		require.Equal(t, []string{
+			"created",
+			"ready_for_review",
+			"reviewer_assigned",
+			"reviewer_assigned",
+			"reviewer_reassigned",
+			"reviewer_activity_changed",
+			"merged",
+		}, types)
+
+		reassigned := timeline.Events[4]
+		assert.Equal(t, replaced, reassigned.OldUserID)
+		assert.Equal(t, reassignResp.ReplacedBy, reassigned.NewUserID)
+		assert.Equal(t, "inf1", reassigned.ActorID)
+		assert.Equal(t, "manual", reassigned.Reason)
+
+		activity := timeline.Events[5]
+		assert.Equal(t, deactivated, activity.UserID)
+		assert.Equal(t, "user_deactivated", activity.Reason)
+	})
+
+	t.Run("Repeated Merge Is Not Recorded", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]string{"pull_request_id": "pr-inf-1"})
+		resp := env.MakeRequest(t, http.MethodPost, "/pullRequest/merge", bytes.NewReader(body), token)
+		resp.Body.Close()
+
+		resp = env.MakeRequest(t, http.MethodGet, "/pullRequest/timeline?pull_request_id=pr-inf-1", nil, token)
+		defer resp.Body.Close()
+
+		var timeline struct {
+			Events []struct {
+				Type string `json:"type"`
+			} `json:"events"`
+		}
+		json.NewDecoder(resp.Body).Decode(&timeline)
+		assert.Len(t, timeline.Events, 6)
+	})
+
+	t.Run("Unknown PR", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/pullRequest/timeline?pull_request_id=missing", nil, token)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+// TestE2E_GitHubWebhook проверяет создание и merge PR по записанным вебхукам GitHub
+func TestE2E_GitHubWebhook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "octo-team",
+		Members: []Member{
+			{UserID: "gh1", Username: "Alice", IsActive: true, Role: "admin"},
+			{UserID: "gh2", Username: "Bob", IsActive: true},
+			{UserID: "gh3", Username: "Carol", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	token := env.Login(t, "gh1")
+
+	for userID, login := range map[string]string{"gh1": "octo-alice", "gh2": "octo-bob"} {
+		body, _ := json.Marshal(map[string]string{"user_id": userID, "github_login": login})
+		resp := env.MakeRequest(t, http.MethodPost, "/users/setGithubLogin", bytes.NewReader(body), token)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	prPath := "/pullRequest/get?pull_request_id=" + url.QueryEscape("octo-org/backend#7")
+	webhookStatus := func(t *testing.T, resp *http.Response) string {
+		t.Helper()
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var webhookResp struct {
+			Status string `json:"status"`
+		}
+		json.NewDecoder(resp.Body).Decode(&webhookResp)
+		return webhookResp.Status
+	}
+	getPR := func(t *testing.T) PullRequestResponse {
+		t.Helper()
+		resp := env.MakeRequest(t, http.MethodGet, prPath, nil, token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var prResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		json.NewDecoder(resp.Body).Decode(&prResp)
+		return prResp.PR
+	}
+
+	t.Run("Reject Invalid Signature", func(t *testing.T) {
+		payload := []byte(`{"action":"opened"}`)
+		resp := env.SendGitHubWebhook(t, "pull_request", "delivery-forged", payload,
+			SignGitHubPayload(payload, "wrong-secret"))
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Opened Creates PR", func(t *testing.T) {
+		resp := env.ReplayGitHubWebhook(t, "pull_request", "pull_request_opened.json", "delivery-1")
+		assert.Equal(t, "processed", webhookStatus(t, resp))
+
+		pr := getPR(t)
+		assert.Equal(t, "Add retries to payment client", pr.PullRequestName)
+		assert.Equal(t, "gh1", pr.AuthorID)
+		assert.Equal(t, "OPEN", pr.Status)
+		assert.ElementsMatch(t, []string{"gh2", "gh3"}, pr.Reviewers)
+	})
+
+	t.Run("Redelivery Is Deduplicated", func(t *testing.T) {
+		resp := env.ReplayGitHubWebhook(t, "pull_request", "pull_request_opened.json", "delivery-1")
+		assert.Equal(t, "duplicate", webhookStatus(t, resp))
+	})
+
+	t.Run("Unrelated Action Is Ignored", func(t *testing.T) {
+		resp := env.ReplayGitHubWebhook(t, "pull_request", "pull_request_labeled.json", "delivery-2")
+		assert.Equal(t, "ignored", webhookStatus(t, resp))
+	})
+
+	t.Run("Closed And Merged Merges PR", func(t *testing.T) {
+		resp := env.ReplayGitHubWebhook(t, "pull_request", "pull_request_closed_merged.json", "delivery-3")
+		assert.Equal(t, "processed", webhookStatus(t, resp))
+
+		assert.Equal(t, "MERGED", getPR(t).Status)
+
+		var actorID string
+		err := env.DB.QueryRow(env.ctx,
+			`SELECT actor_id FROM pr_events WHERE pull_request_id = $1 AND event_type = 'merged'`,
+			"octo-org/backend#7",
+		).Scan(&actorID)
+		require.NoError(t, err)
+		assert.Equal(t, "gh2", actorID)
+	})
+}
+
+// TestE2E_OutgoingWebhooks проверяет доставку событий подписчику с подписью и повторными попытками
+func TestE2E_OutgoingWebhooks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	// Подписчик отвечает ошибкой на первый запрос, чтобы проверить повторную отправку
+	const secret = "subscriber-secret"
+	var (
+		mu       sync.Mutex
+		requests int
+		received []map[string]interface{}
+	)
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if r.Header.Get("X-Webhook-Signature-256") != SignGitHubPayload(payload, secret) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body map[string]interface{}
+		json.Unmarshal(payload, &body)
+		received = append(received, body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer subscriber.Close()
+
+	team := Team{
+		TeamName: "hooks-team",
+		Members: []Member{
+			{UserID: "hk1", Username: "Pam", IsActive: true, Role: "admin"},
+			{UserID: "hk2", Username: "Quinn", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	token := env.Login(t, "hk1")
+
+	var subscriptionID int64
+	t.Run("Subscribe", func(t *testing.T) {
+		subReq := map[string]interface{}{
+			"url":         subscriber.URL,
+			"secret":      secret,
+			"event_types": []string{"reviewer_assigned", "merged"},
+		}
+		body, _ := json.Marshal(subReq)
+		resp := env.MakeRequest(t, http.MethodPost, "/webhooks/subscriptions/add", bytes.NewReader(body), token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var subResp struct {
+			Subscription struct {
+				SubscriptionID int64 `json:"subscription_id"`
+			} `json:"subscription"`
+		}
+		json.NewDecoder(resp.Body).Decode(&subResp)
+		subscriptionID = subResp.Subscription.SubscriptionID
+		require.NotZero(t, subscriptionID)
+	})
+
+	t.Run("Reject Unsupported Event Type", func(t *testing.T) {
+		subReq := map[string]interface{}{
+			"url":         subscriber.URL,
+			"secret":      secret,
+			"event_types": []string{"created"},
+		}
+		body, _ := json.Marshal(subReq)
+		resp := env.MakeRequest(t, http.MethodPost, "/webhooks/subscriptions/add", bytes.NewReader(body), token)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	createPR := CreatePRRequest{
+		PullRequestID:   "pr-hk-1",
+		PullRequestName: "Notify subscribers",
+		AuthorID:        "hk1",
+	}
+	body, _ = json.Marshal(createPR)
+	resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), token)
+	resp.Body.Close()
+
+	body, _ = json.Marshal(map[string]string{"pull_request_id": "pr-hk-1"})
+	resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/merge", bytes.NewReader(body), token)
+	resp.Body.Close()
+
+	t.Run("Events Delivered After Retry", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(received) == 2
+		}, 10*time.Second, 100*time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		var events []string
+		for _, payload := range received {
+			events = append(events, payload["event"].(string))
+		}
+		assert.ElementsMatch(t, []string{"reviewer_assigned", "merged"}, events)
+	})
+
+	t.Run("Delivery Log", func(t *testing.T) {
+		path := fmt.Sprintf("/webhooks/subscriptions/deliveries?subscription_id=%d", subscriptionID)
+		resp := env.MakeRequest(t, http.MethodGet, path, nil, token)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var logResp struct {
+			Deliveries []struct {
+				Status   string `json:"status"`
+				Attempts int    `json:"attempts"`
+			} `json:"deliveries"`
+		}
+		json.NewDecoder(resp.Body).Decode(&logResp)
+		require.Len(t, logResp.Deliveries, 2)
+
+		totalAttempts := 0
+		for _, d := range logResp.Deliveries {
+			assert.Equal(t, "DELIVERED", d.Status)
+			totalAttempts += d.Attempts
+		}
+		assert.Equal(t, 3, totalAttempts)
+	})
+}
+
+// TestE2E_PasswordLogin проверяет вход по паролю, смену пароля и блокировку после неудачных попыток
+func TestE2E_PasswordLogin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "auth-team",
+		Members: []Member{
+			{UserID: "au1", Username: "Rita", IsActive: true},
+			{UserID: "au2", Username: "Sam", IsActive: true},
+			{UserID: "au3", Username: "Tara", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	login := func(t *testing.T, userID, password string) *http.Response {
+		body, _ := json.Marshal(LoginRequest{UserID: userID, Password: password})
+		return env.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
+	}
+
+	setPassword := func(t *testing.T, userID, current, newPassword, token string) *http.Response {
+		body, _ := json.Marshal(map[string]string{
+			"user_id":          userID,
+			"current_password": current,
+			"new_password":     newPassword,
+		})
+		return env.MakeRequest(t, http.MethodPost, "/auth/setPassword", bytes.NewReader(body), token)
+	}
+	adminToken := env.AdminToken(t)
+	var userToken string
+
+	t.Run("Login Without Password Rejected", func(t *testing.T) {
+		resp := login(t, "au1", "anything-goes")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Unknown User Rejected", func(t *testing.T) {
+		resp := login(t, "nobody", "anything-goes")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Reject Anonymous Initial Password", func(t *testing.T) {
+		resp := setPassword(t, "au1", "", "taken-over-password", "")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Set Initial Password", func(t *testing.T) {
+		resp := setPassword(t, "au1", "", "short", adminToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = setPassword(t, "au1", "", "first-password", adminToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = login(t, "au1", "first-password")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var loginResp LoginResponse
+		json.NewDecoder(resp.Body).Decode(&loginResp)
+		require.NotEmpty(t, loginResp.Token)
+		userToken = loginResp.Token
+
+		// Хранится только bcrypt-хеш
+		var hash string
+		err := env.DB.QueryRow(env.ctx, `SELECT password_hash FROM user_credentials WHERE user_id = 'au1'`).Scan(&hash)
+		require.NoError(t, err)
+		assert.NotEqual(t, "first-password", hash)
+		assert.Contains(t, hash, "$2a$")
+	})
+
+	t.Run("Member Cannot Set Password Of Others", func(t *testing.T) {
+		resp := setPassword(t, "au3", "", "taken-over-password", userToken)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Change Password Requires Current", func(t *testing.T) {
+		// Заданный пароль меняет только сам пользователь
+		resp := setPassword(t, "au1", "", "taken-over-password", adminToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = setPassword(t, "au1", "", "taken-over-password", userToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp = setPassword(t, "au1", "first-password", "second-password", userToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = login(t, "au1", "first-password")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp = login(t, "au1", "second-password")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Lockout After Failed Attempts", func(t *testing.T) {
+		// В тестовом окружении вход блокируется после 3 неудачных попыток подряд
+		for i := 0; i < 3; i++ {
+			resp := login(t, "au1", "wrong-password")
+			resp.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+
+		resp := login(t, "au1", "second-password")
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusLocked, resp.StatusCode)
+
+		var errResp struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		assert.Equal(t, "ACCOUNT_LOCKED", errResp.Error.Code)
+
+		// Блокировка касается только этого пользователя
+		token := env.Login(t, "au2")
+		assert.NotEmpty(t, token)
+	})
+}
+
+// TestE2E_RoleBasedAccess проверяет начальную настройку и разграничение доступа по ролям
+func TestE2E_RoleBasedAccess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	addTeam := func(t *testing.T, team Team, token string) *http.Response {
+		body, _ := json.Marshal(team)
+		return env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), token)
+	}
+	post := func(t *testing.T, path string, req interface{}, token string) *http.Response {
+		body, _ := json.Marshal(req)
+		return env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+	}
+
+	t.Run("Reject Anonymous Team Creation", func(t *testing.T) {
+		resp := addTeam(t, Team{
+			TeamName: "rogue",
+			Members:  []Member{{UserID: "rogue1", Username: "Rex", IsActive: true, Role: "admin"}},
+		}, "")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Bootstrap Admin From Config", func(t *testing.T) {
+		resp := addTeam(t, Team{
+			TeamName: "ops",
+			Members:  []Member{{UserID: "ops1", Username: "Olly", IsActive: true, Role: "admin"}},
+		}, env.AdminToken(t))
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	adminToken := env.Login(t, "ops1")
+
+	resp := addTeam(t, Team{
+		TeamName: "beta",
+		Members: []Member{
+			{UserID: "b-lead", Username: "Bea", IsActive: true, Role: "team_lead"},
+			{UserID: "b-dev1", Username: "Ben", IsActive: true},
+			{UserID: "b-dev2", Username: "Bob", IsActive: true},
+			{UserID: "b-dev3", Username: "Bill", IsActive: true},
+		},
+	}, adminToken)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = addTeam(t, Team{
+		TeamName: "gamma",
+		Members: []Member{
+			{UserID: "g-lead", Username: "Gil", IsActive: true, Role: "team_lead"},
+			{UserID: "g-dev", Username: "Gus", IsActive: true},
+		},
+	}, adminToken)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	leadToken := env.Login(t, "b-lead")
+	memberToken := env.Login(t, "b-dev1")
+	otherLeadToken := env.Login(t, "g-lead")
+
+	t.Run("Member Cannot Manage", func(t *testing.T) {
+		resp := addTeam(t, Team{TeamName: "delta", Members: []Member{}}, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = post(t, "/users/setIsActive", SetIsActiveRequest{UserID: "b-dev2", IsActive: false}, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = post(t, "/users/setRole", map[string]string{"user_id": "b-dev1", "role": "admin"}, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/webhooks/subscriptions/list", nil, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Team Lead Manages Own Team Only", func(t *testing.T) {
+		resp := post(t, "/users/setIsActive", SetIsActiveRequest{UserID: "b-dev3", IsActive: false}, leadToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = post(t, "/users/setIsActive", SetIsActiveRequest{UserID: "b-dev3", IsActive: true}, leadToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = post(t, "/users/setIsActive", SetIsActiveRequest{UserID: "g-dev", IsActive: false}, leadToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		strategy := map[string]string{"team_name": "beta", "reviewer_strategy": "round_robin"}
+		resp = post(t, "/team/setReviewerStrategy", strategy, leadToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = post(t, "/team/setReviewerStrategy", strategy, otherLeadToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Create PR As Self Or By Lead", func(t *testing.T) {
+		resp := post(t, "/pullRequest/create", CreatePRRequest{
+			PullRequestID: "pr-beta-1", PullRequestName: "Own change", AuthorID: "b-dev1",
+		}, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		resp = post(t, "/pullRequest/create", CreatePRRequest{
+			PullRequestID: "pr-beta-2", PullRequestName: "Someone else's change", AuthorID: "b-dev2",
+		}, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = post(t, "/pullRequest/create", CreatePRRequest{
+			PullRequestID: "pr-beta-2", PullRequestName: "Change on behalf", AuthorID: "b-dev2",
+		}, leadToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("Act On Own PR Only", func(t *testing.T) {
+		prReq := map[string]string{"pull_request_id": "pr-beta-2"}
+
+		resp := post(t, "/pullRequest/close", prReq, env.Login(t, "g-dev"))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = post(t, "/pullRequest/close", prReq, otherLeadToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = post(t, "/pullRequest/close", map[string]string{"pull_request_id": "pr-beta-1"}, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = post(t, "/pullRequest/close", prReq, adminToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Review Only As Self", func(t *testing.T) {
+		review := map[string]string{"pull_request_id": "pr-beta-1", "user_id": "b-dev2", "verdict": "APPROVED"}
+		resp := post(t, "/pullRequest/review", review, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Admin Changes Role", func(t *testing.T) {
+		resp := post(t, "/users/setRole", map[string]string{"user_id": "g-dev", "role": "team_lead"}, adminToken)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var roleResp struct {
+			User struct {
+				Role string `json:"role"`
+			} `json:"user"`
+		}
+		json.NewDecoder(resp.Body).Decode(&roleResp)
+		assert.Equal(t, "team_lead", roleResp.User.Role)
+
+		resp = post(t, "/users/setRole", map[string]string{"user_id": "g-dev", "role": "owner"}, adminToken)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+// TestE2E_APITokens проверяет выдачу, области действия и отзыв API токенов
+func TestE2E_APITokens(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "ci-team",
+		Members: []Member{
+			{UserID: "ci1", Username: "Cora", IsActive: true, Role: "admin"},
+			{UserID: "ci2", Username: "Dean", IsActive: true},
+			{UserID: "ci3", Username: "Elle", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "ci1")
+	memberToken := env.Login(t, "ci2")
+
+	type tokenResponse struct {
+		Token    string `json:"token"`
+		APIToken struct {
+			TokenID   int64    `json:"token_id"`
+			Kind      string   `json:"kind"`
+			UserID    string   `json:"user_id"`
+			Scopes    []string `json:"scopes"`
+			Prefix    string   `json:"prefix"`
+			RevokedAt *string  `json:"revokedAt"`
+		} `json:"api_token"`
+	}
+	createToken := func(t *testing.T, req map[string]interface{}, token string) (*http.Response, tokenResponse) {
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, "/tokens/create", bytes.NewReader(body), token)
+		var tokenResp tokenResponse
+		json.NewDecoder(resp.Body).Decode(&tokenResp)
+		resp.Body.Close()
+		return resp, tokenResp
+	}
+
+	var serviceToken tokenResponse
+	t.Run("Create Service Token", func(t *testing.T) {
+		resp, tokenResp := createToken(t, map[string]interface{}{
+			"name": "ci-pipeline", "kind": "service", "scopes": []string{"pr:write"},
+		}, adminToken)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		assert.True(t, strings.HasPrefix(tokenResp.Token, "prt_"))
+		assert.Equal(t, "service", tokenResp.APIToken.Kind)
+		assert.Empty(t, tokenResp.APIToken.UserID)
+		assert.True(t, strings.HasPrefix(tokenResp.Token, tokenResp.APIToken.Prefix))
+		serviceToken = tokenResp
+
+		// В БД хранится только хеш токена
+		var hash string
+		err := env.DB.QueryRow(env.ctx,
+			`SELECT token_hash FROM api_tokens WHERE token_id = $1`, tokenResp.APIToken.TokenID,
+		).Scan(&hash)
+		require.NoError(t, err)
+		assert.Len(t, hash, 64)
+		assert.NotContains(t, hash, tokenResp.Token)
+	})
+
+	t.Run("Reject Invalid Tokens Requests", func(t *testing.T) {
+		resp, _ := createToken(t, map[string]interface{}{
+			"name": "bad", "scopes": []string{"admin:all"},
+		}, adminToken)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = createToken(t, map[string]interface{}{
+			"name": "bot", "kind": "service", "scopes": []string{"pr:write"},
+		}, memberToken)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Service Token Limited To PR Scope", func(t *testing.T) {
+		createPR := CreatePRRequest{PullRequestID: "pr-ci-1", PullRequestName: "Bump deps", AuthorID: "ci2"}
+		body, _ := json.Marshal(createPR)
+		resp := env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), serviceToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		body, _ = json.Marshal(map[string]string{"pull_request_id": "pr-ci-1"})
+		resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/merge", bytes.NewReader(body), serviceToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/stats", nil, serviceToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/team/get?team_name=ci-team", nil, serviceToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		// Управление пользователями и токенами API токенам недоступно
+		body, _ = json.Marshal(SetIsActiveRequest{UserID: "ci3", IsActive: false})
+		resp = env.MakeRequest(t, http.MethodPost, "/users/setIsActive", bytes.NewReader(body), serviceToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/tokens/list", nil, serviceToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	var personalToken tokenResponse
+	t.Run("Personal Token Acts As Owner", func(t *testing.T) {
+		resp, tokenResp := createToken(t, map[string]interface{}{
+			"name": "dashboard", "scopes": []string{"team:read", "stats:read"}, "expires_in_days": 30,
+		}, memberToken)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "personal", tokenResp.APIToken.Kind)
+		assert.Equal(t, "ci2", tokenResp.APIToken.UserID)
+		personalToken = tokenResp
+
+		resp = env.MakeRequest(t, http.MethodGet, "/team/get?team_name=ci-team", nil, personalToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/stats/user?user_id=ci2", nil, personalToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		createPR := CreatePRRequest{PullRequestID: "pr-ci-2", PullRequestName: "Not allowed", AuthorID: "ci2"}
+		body, _ := json.Marshal(createPR)
+		resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), personalToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("List Tokens", func(t *testing.T) {
+		var listResp struct {
+			Tokens []struct {
+				TokenID int64 `json:"token_id"`
+			} `json:"tokens"`
+		}
+
+		resp := env.MakeRequest(t, http.MethodGet, "/tokens/list", nil, memberToken)
+		json.NewDecoder(resp.Body).Decode(&listResp)
+		resp.Body.Close()
+		require.Len(t, listResp.Tokens, 1)
+		assert.Equal(t, personalToken.APIToken.TokenID, listResp.Tokens[0].TokenID)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/tokens/list", nil, adminToken)
+		json.NewDecoder(resp.Body).Decode(&listResp)
+		resp.Body.Close()
+		assert.Len(t, listResp.Tokens, 2)
+	})
+
+	t.Run("Revoke Token", func(t *testing.T) {
+		revoke := func(t *testing.T, tokenID int64, token string) *http.Response {
+			body, _ := json.Marshal(map[string]int64{"token_id": tokenID})
+			return env.MakeRequest(t, http.MethodPost, "/tokens/revoke", bytes.NewReader(body), token)
+		}
+
+		// Чужой токен участнику не виден
+		resp := revoke(t, serviceToken.APIToken.TokenID, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = revoke(t, personalToken.APIToken.TokenID, memberToken)
+		var revokeResp tokenResponse
+		json.NewDecoder(resp.Body).Decode(&revokeResp)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotNil(t, revokeResp.APIToken.RevokedAt)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/team/get?team_name=ci-team", nil, personalToken.Token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+// TestE2E_Sessions проверяет обновление токенов, выход и отзыв сессий при деактивации
+func TestE2E_Sessions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "session-team",
+		Members: []Member{
+			{UserID: "ss1", Username: "Nora", IsActive: true, Role: "admin"},
+			{UserID: "ss2", Username: "Omar", IsActive: true},
+			{UserID: "ss3", Username: "Pia", IsActive: true},
+			{UserID: "ss4", Username: "Rune", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "ss1")
+	// Login задает пароль; сессии ниже открываются отдельными входами
+	env.Login(t, "ss2")
+	env.Login(t, "ss3")
+	env.Login(t, "ss4")
+
+	post := func(t *testing.T, path string, payload interface{}) (*http.Response, LoginResponse) {
+		body, _ := json.Marshal(payload)
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), "")
+		var loginResp LoginResponse
+		json.NewDecoder(resp.Body).Decode(&loginResp)
+		resp.Body.Close()
+		return resp, loginResp
+	}
+	login := func(t *testing.T, userID string) LoginResponse {
+		resp, loginResp := post(t, "/auth/login", LoginRequest{UserID: userID, Password: testPassword})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return loginResp
+	}
+	status := func(t *testing.T, userID, token string) int {
+		resp := env.MakeRequest(t, http.MethodGet, "/users/getReview?user_id="+userID, nil, token)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("Login Returns Token Pair", func(t *testing.T) {
+		pair := login(t, "ss2")
+
+		assert.NotEmpty(t, pair.Token)
+		assert.NotEmpty(t, pair.RefreshToken)
+		assert.Greater(t, pair.ExpiresIn, int64(0))
+		assert.LessOrEqual(t, pair.ExpiresIn, int64(15*60))
+		assert.Equal(t, http.StatusOK, status(t, "ss2", pair.Token))
+
+		// В БД хранится только хеш refresh токена
+		var stored int
+		err := env.DB.QueryRow(env.ctx,
+			`SELECT COUNT(*) FROM auth_sessions WHERE refresh_token_hash = $1`, pair.RefreshToken,
+		).Scan(&stored)
+		require.NoError(t, err)
+		assert.Zero(t, stored)
+	})
+
+	t.Run("Refresh Rotates Token", func(t *testing.T) {
+		first := login(t, "ss2")
+
+		resp, second := post(t, "/auth/refresh", map[string]string{"refresh_token": first.RefreshToken})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+		assert.Equal(t, http.StatusOK, status(t, "ss2", second.Token))
+
+		// Повторное использование замененного токена отзывает всю сессию
+		resp, _ = post(t, "/auth/refresh", map[string]string{"refresh_token": first.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		assert.Equal(t, http.StatusUnauthorized, status(t, "ss2", second.Token))
+		resp, _ = post(t, "/auth/refresh", map[string]string{"refresh_token": second.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		pair := login(t, "ss2")
+		other := login(t, "ss2")
+
+		resp, _ := post(t, "/auth/logout", map[string]interface{}{"refresh_token": pair.RefreshToken})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, http.StatusUnauthorized, status(t, "ss2", pair.Token))
+		assert.Equal(t, http.StatusOK, status(t, "ss2", other.Token), "Other sessions should stay active")
+
+		resp, _ = post(t, "/auth/logout", map[string]interface{}{"refresh_token": pair.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Logout All Sessions", func(t *testing.T) {
+		pair := login(t, "ss2")
+		other := login(t, "ss2")
+
+		resp, _ := post(t, "/auth/logout", map[string]interface{}{"refresh_token": pair.RefreshToken, "all": true})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, http.StatusUnauthorized, status(t, "ss2", other.Token))
+		resp, _ = post(t, "/auth/refresh", map[string]string{"refresh_token": other.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Deactivation Revokes Sessions", func(t *testing.T) {
+		pair := login(t, "ss3")
+		require.Equal(t, http.StatusOK, status(t, "ss3", pair.Token))
+
+		body, _ := json.Marshal(SetIsActiveRequest{UserID: "ss3", IsActive: false})
+		resp := env.MakeRequest(t, http.MethodPost, "/users/setIsActive", bytes.NewReader(body), adminToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, http.StatusUnauthorized, status(t, "ss3", pair.Token))
+		resp, _ = post(t, "/auth/refresh", map[string]string{"refresh_token": pair.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		// Неактивный пользователь не может войти заново
+		resp, _ = post(t, "/auth/login", LoginRequest{UserID: "ss3", Password: testPassword})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Refresh Rejects Deactivated User", func(t *testing.T) {
+		pair := login(t, "ss4")
+
+		// Деактивация в обход сервиса не отзывает сессии - их отзывает refresh
+		_, err := env.DB.Exec(env.ctx, `UPDATE users SET is_active = false WHERE user_id = 'ss4'`)
+		require.NoError(t, err)
+
+		resp, _ := post(t, "/auth/refresh", map[string]string{"refresh_token": pair.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		var active int
+		err = env.DB.QueryRow(env.ctx,
+			`SELECT COUNT(*) FROM auth_sessions WHERE user_id = 'ss4' AND revoked_at IS NULL`,
+		).Scan(&active)
+		require.NoError(t, err)
+		assert.Zero(t, active)
+		assert.Equal(t, http.StatusUnauthorized, status(t, "ss4", pair.Token))
+	})
+}
+
+// TestE2E_JWKS проверяет подпись EdDSA, публикацию ключей и их ротацию
+func TestE2E_JWKS(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Короткий интервал ротации, чтобы дождаться смены ключа в тесте
+	env := SetupTestEnvironment(t, func(cfg *config.Config) {
+		cfg.JWT.SigningAlgorithm = "EdDSA"
+		cfg.JWT.Secret = ""
+		cfg.JWT.KeyRotationInterval = 2 * time.Second
+		cfg.JWT.KeyPollInterval = 200 * time.Millisecond
+	})
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "jwks-team",
+		Members: []Member{
+			{UserID: "jw1", Username: "Quinn", IsActive: true, Role: "admin"},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	type jwk struct {
+		KeyType   string `json:"kty"`
+		KeyID     string `json:"kid"`
+		Algorithm string `json:"alg"`
+		Curve     string `json:"crv"`
+		X         string `json:"x"`
+	}
+	getJWKS := func(t *testing.T) []jwk {
+		resp := env.MakeRequest(t, http.MethodGet, "/.well-known/jwks.json", nil, "")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var set struct {
+			Keys []jwk `json:"keys"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&set))
+		return set.Keys
+	}
+	// verify проверяет подпись токена по опубликованным ключам, как это делал бы сторонний сервис
+	verify := func(t *testing.T, token string, keys []jwk) string {
+		parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+			for _, key := range keys {
+				if key.KeyID == token.Header["kid"] {
+					x, err := base64.RawURLEncoding.DecodeString(key.X)
+					if err != nil {
+						return nil, err
+					}
+					return ed25519.PublicKey(x), nil
+				}
+			}
+			return nil, fmt.Errorf("unknown kid %v", token.Header["kid"])
+		}, jwt.WithValidMethods([]string{"EdDSA"}))
+		require.NoError(t, err)
+		return parsed.Header["kid"].(string)
+	}
+
+	var firstToken, firstKID, firstPublicKey string
+	t.Run("Token Verifiable With JWKS", func(t *testing.T) {
+		keys := getJWKS(t)
+		require.Len(t, keys, 1)
+		assert.Equal(t, "OKP", keys[0].KeyType)
+		assert.Equal(t, "Ed25519", keys[0].Curve)
+		assert.Equal(t, "EdDSA", keys[0].Algorithm)
+
+		firstToken = env.Login(t, "jw1")
+		firstKID = verify(t, firstToken, keys)
+		assert.Equal(t, keys[0].KeyID, firstKID)
+		firstPublicKey = keys[0].X
+
+		resp := env.MakeRequest(t, http.MethodGet, "/team/get?team_name=jwks-team", nil, firstToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Key Rotation Keeps Old Tokens Valid", func(t *testing.T) {
+		var keys []jwk
+		require.Eventually(t, func() bool {
+			keys = getJWKS(t)
+			return len(keys) >= 2
+		}, 10*time.Second, 200*time.Millisecond, "Signing key should be rotated")
+
+		// Новый токен подписан новым ключом, старый ключ остается в наборе на время перекрытия
+		kids := make([]string, 0, len(keys))
+		for _, key := range keys {
+			kids = append(kids, key.KeyID)
+		}
+		assert.Contains(t, kids, firstKID)
+
+		body, _ := json.Marshal(LoginRequest{UserID: "jw1", Password: testPassword})
+		resp := env.MakeRequest(t, http.MethodPost, "/auth/login", bytes.NewReader(body), "")
+		var loginResp LoginResponse
+		json.NewDecoder(resp.Body).Decode(&loginResp)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEqual(t, firstKID, verify(t, loginResp.Token, keys))
+
+		resp = env.MakeRequest(t, http.MethodGet, "/team/get?team_name=jwks-team", nil, firstToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Reject HMAC Token", func(t *testing.T) {
+		// Подделка с публичным ключом в качестве секрета HMAC не принимается
+		publicKey, err := base64.RawURLEncoding.DecodeString(firstPublicKey)
+		require.NoError(t, err)
+
+		claims := jwt.MapClaims{"user_id": "jw1", "role": "admin", "sid": 1, "exp": time.Now().Add(time.Hour).Unix()}
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		forged.Header["kid"] = firstKID
+		forgedToken, err := forged.SignedString(publicKey)
+		require.NoError(t, err)
+
+		resp := env.MakeRequest(t, http.MethodGet, "/team/get?team_name=jwks-team", nil, forgedToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+// TestE2E_JWKSUnknownKID проверяет, что ключ, ротированный другим экземпляром, принимается до очередного опроса
+func TestE2E_JWKSUnknownKID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Опрос ключей реже, чем длится тест, поэтому новый ключ можно подхватить только по неизвестному kid
+	env := SetupTestEnvironment(t, func(cfg *config.Config) {
+		cfg.JWT.SigningAlgorithm = "EdDSA"
+		cfg.JWT.Secret = ""
+		cfg.JWT.KeyPollInterval = time.Hour
+	})
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "kid-team",
+		Members: []Member{
+			{UserID: "kd1", Username: "Rowan", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	token := env.Login(t, "kd1")
+
+	// Ротация другим экземпляром: новый ключ в БД, прежний остается на время перекрытия
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	_, err = env.DB.Exec(env.ctx, `UPDATE jwt_signing_keys SET expires_at = NOW() + INTERVAL '1 hour' WHERE expires_at IS NULL`)
+	require.NoError(t, err)
+	_, err = env.DB.Exec(env.ctx, `
+		INSERT INTO jwt_signing_keys (kid, algorithm, private_key, public_key)
+		VALUES ('rotated-elsewhere', 'EdDSA', $1, $2)
+	`, privateDER, publicDER)
+	require.NoError(t, err)
+
+	// Те же claims, что у выданного токена, но подписанные новым ключом
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	rotated := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	rotated.Header["kid"] = "rotated-elsewhere"
+	rotatedToken, err := rotated.SignedString(private)
+	require.NoError(t, err)
+
+	t.Run("Accept Token Signed With Unknown KID", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/team/get?team_name=kid-team", nil, rotatedToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// Перечитанный ключ сразу попадает в JWKS
+		resp = env.MakeRequest(t, http.MethodGet, "/.well-known/jwks.json", nil, "")
+		defer resp.Body.Close()
+		var set struct {
+			Keys []struct {
+				KeyID string `json:"kid"`
+			} `json:"keys"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&set))
+		kids := make([]string, 0, len(set.Keys))
+		for _, key := range set.Keys {
+			kids = append(kids, key.KeyID)
+		}
+		assert.Contains(t, kids, "rotated-elsewhere")
+	})
+
+	t.Run("Reject Token With Key Not In Database", func(t *testing.T) {
+		_, forgedKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		forged := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		forged.Header["kid"] = "never-stored"
+		forgedToken, err := forged.SignedString(forgedKey)
+		require.NoError(t, err)
+
+		resp := env.MakeRequest(t, http.MethodGet, "/team/get?team_name=kid-team", nil, forgedToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+// TestE2E_OIDCLogin проверяет вход через OIDC провайдера: привязку по email, по subject и автосоздание
+func TestE2E_OIDCLogin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	provider := NewMockOIDCProvider(t, "pr-service", "oidc-client-secret")
+
+	env := SetupTestEnvironment(t, func(cfg *config.Config) {
+		cfg.OIDC = config.OIDCConfig{
+			IssuerURL:       provider.Issuer(),
+			ClientID:        "pr-service",
+			ClientSecret:    "oidc-client-secret",
+			RedirectURL:     "http://127.0.0.1:18080/auth/oidc/callback",
+			Scopes:          []string{"openid", "email", "profile"},
+			GroupsClaim:     "groups",
+			GroupTeamPrefix: "team:",
+			AutoProvision:   true,
+			StateTTL:        time.Minute,
+		}
+	})
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "sso-team",
+		Members: []Member{
+			{UserID: "sso1", Username: "Rita", IsActive: true, Role: "admin"},
+			{UserID: "sso2", Username: "Sam", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "sso1")
+
+	type errorResponse struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	// callback завершает вход по адресу, на который провайдер перенаправил пользователя
+	callback := func(t *testing.T, callbackURL *url.URL) (int, LoginResponse, string) {
+		resp := env.MakeRequest(t, http.MethodGet, callbackURL.RequestURI(), nil, "")
+		defer resp.Body.Close()
+
+		raw, _ := io.ReadAll(resp.Body)
+		var loginResp LoginResponse
+		var errResp errorResponse
+		json.Unmarshal(raw, &loginResp)
+		json.Unmarshal(raw, &errResp)
+		return resp.StatusCode, loginResp, errResp.Error.Code
+	}
+	ssoLogin := func(t *testing.T, claims map[string]interface{}) (int, LoginResponse, string) {
+		provider.SetUser(claims)
+		return callback(t, env.OIDCAuthorize(t))
+	}
+	setEmail := func(t *testing.T, userID, email string) int {
+		body, _ := json.Marshal(map[string]string{"user_id": userID, "email": email})
+		resp := env.MakeRequest(t, http.MethodPost, "/users/setEmail", bytes.NewReader(body), adminToken)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	reviewStatus := func(t *testing.T, userID, token string) int {
+		resp := env.MakeRequest(t, http.MethodGet, "/users/getReview?user_id="+userID, nil, token)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("Unlinked User Rejected", func(t *testing.T) {
+		status, _, code := ssoLogin(t, map[string]interface{}{
+			"sub": "idp-sam", "email": "sam@example.com", "email_verified": true,
+		})
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "IDENTITY_NOT_LINKED", code)
+	})
+
+	t.Run("Link By Verified Email", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, setEmail(t, "sso2", "sam@example.com"))
+
+		// Email сравнивается без учета регистра
+		status, pair, _ := ssoLogin(t, map[string]interface{}{
+			"sub": "idp-sam", "email": "Sam@Example.com", "email_verified": true,
+		})
+		require.Equal(t, http.StatusOK, status)
+		assert.NotEmpty(t, pair.RefreshToken)
+		assert.Equal(t, http.StatusOK, reviewStatus(t, "sso2", pair.Token))
+
+		var linkedUser string
+		err := env.DB.QueryRow(env.ctx,
+			`SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2`, provider.Issuer(), "idp-sam",
+		).Scan(&linkedUser)
+		require.NoError(t, err)
+		assert.Equal(t, "sso2", linkedUser)
+	})
+
+	t.Run("Linked Identity Mapped By Subject", func(t *testing.T) {
+		// После привязки email у провайдера может измениться
+		status, pair, _ := ssoLogin(t, map[string]interface{}{
+			"sub": "idp-sam", "email": "samuel@example.com", "email_verified": true,
+		})
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, http.StatusOK, reviewStatus(t, "sso2", pair.Token))
+	})
+
+	t.Run("Email Must Be Unique", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, setEmail(t, "sso1", "SAM@example.com"))
+		assert.Equal(t, http.StatusBadRequest, setEmail(t, "sso1", "Rita <rita@example.com>"))
+	})
+
+	t.Run("Unverified Email Not Linked", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, setEmail(t, "sso1", "rita@example.com"))
+
+		status, _, code := ssoLogin(t, map[string]interface{}{
+			"sub": "idp-impostor", "email": "rita@example.com", "email_verified": false,
+		})
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "IDENTITY_NOT_LINKED", code)
+	})
+
+	t.Run("Auto Provision From Group", func(t *testing.T) {
+		status, pair, _ := ssoLogin(t, map[string]interface{}{
+			"sub":            "idp-tess",
+			"name":           "Tess",
+			"email":          "tess@example.com",
+			"email_verified": true,
+			"groups":         []string{"staff", "team:missing-team", "team:sso-team"},
+		})
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, http.StatusOK, reviewStatus(t, "idp-tess", pair.Token))
+
+		var username, teamName, role, email string
+		err := env.DB.QueryRow(env.ctx,
+			`SELECT username, team_name, role, email FROM users WHERE user_id = $1`, "idp-tess",
+		).Scan(&username, &teamName, &role, &email)
+		require.NoError(t, err)
+		assert.Equal(t, "Tess", username)
+		assert.Equal(t, "sso-team", teamName)
+		assert.Equal(t, "member", role)
+		assert.Equal(t, "tess@example.com", email)
+	})
+
+	t.Run("Unknown Group Rejected", func(t *testing.T) {
+		status, _, code := ssoLogin(t, map[string]interface{}{
+			"sub": "idp-uma", "groups": []string{"team:missing-team"},
+		})
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "IDENTITY_NOT_LINKED", code)
+	})
+
+	t.Run("Invalid Or Replayed State Rejected", func(t *testing.T) {
+		provider.SetUser(map[string]interface{}{"sub": "idp-sam"})
+		callbackURL := env.OIDCAuthorize(t)
+
+		forged := *callbackURL
+		query := forged.Query()
+		query.Set("state", "forged-state")
+		forged.RawQuery = query.Encode()
+		status, _, _ := callback(t, &forged)
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		status, _, _ = callback(t, callbackURL)
+		assert.Equal(t, http.StatusOK, status)
+
+		// state одноразовый
+		status, _, _ = callback(t, callbackURL)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("Provider Error Rejected", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/auth/oidc/callback?error=access_denied", nil, "")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/auth/oidc/callback?state=abc", nil, "")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Deactivated User Rejected", func(t *testing.T) {
+		body, _ := json.Marshal(SetIsActiveRequest{UserID: "sso2", IsActive: false})
+		resp := env.MakeRequest(t, http.MethodPost, "/users/setIsActive", bytes.NewReader(body), adminToken)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		status, _, _ := ssoLogin(t, map[string]interface{}{"sub": "idp-sam"})
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+}
+
+// TestE2E_SCIMProvisioning проверяет синхронизацию пользователей и команд по SCIM 2.0
+func TestE2E_SCIMProvisioning(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t, func(cfg *config.Config) {
+		cfg.SCIM.DefaultTeam = "scim-staging"
+	})
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "scim-staging",
+		Members: []Member{
+			{UserID: "scim-admin", Username: "Vera", IsActive: true, Role: "admin"},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "scim-admin")
+
+	createToken := func(t *testing.T, scope string) string {
+		body, _ := json.Marshal(map[string]interface{}{"name": "idp-" + scope, "kind": "service", "scopes": []string{scope}})
+		resp := env.MakeRequest(t, http.MethodPost, "/tokens/create", bytes.NewReader(body), adminToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var tokenResp struct {
+			Token string `json:"token"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokenResp))
+		return tokenResp.Token
+	}
+	scimToken := createToken(t, "scim:write")
+
+	type scimUser struct {
+		ID          string `json:"id"`
+		UserName    string `json:"userName"`
+		DisplayName string `json:"displayName"`
+		ExternalID  string `json:"externalId"`
+		Active      bool   `json:"active"`
+		Emails      []struct {
+			Value string `json:"value"`
+		} `json:"emails"`
+		Enterprise struct {
+			Department string `json:"department"`
+		} `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+		Groups []struct {
+			Value string `json:"value"`
+		} `json:"groups"`
+	}
+	type scimGroup struct {
+		ID      string `json:"id"`
+		Members []struct {
+			Value string `json:"value"`
+		} `json:"members"`
+	}
+	type scimError struct {
+		Status   string `json:"status"`
+		ScimType string `json:"scimType"`
+	}
+	// scim выполняет запрос с SCIM токеном и декодирует ответ в out
+	scim := func(t *testing.T, method, path string, payload interface{}, out interface{}) int {
+		var reqBody io.Reader
+		if payload != nil {
+			body, _ := json.Marshal(payload)
+			reqBody = bytes.NewReader(body)
+		}
+		resp := env.MakeRequest(t, method, "/scim/v2"+path, reqBody, scimToken)
+		defer resp.Body.Close()
+		if out != nil {
+			json.NewDecoder(resp.Body).Decode(out)
+		}
+		return resp.StatusCode
+	}
+	newUser := func(userName, name, team string) map[string]interface{} {
+		user := map[string]interface{}{
+			"schemas":     []string{"urn:ietf:params:scim:schemas:core:2.0:User"},
+			"userName":    userName,
+			"externalId":  "hr-" + name,
+			"displayName": name,
+			"active":      true,
+			"emails":      []map[string]interface{}{{"value": userName, "primary": true}},
+		}
+		if team != "" {
+			user["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"] = map[string]string{"department": team}
+		}
+		return user
+	}
+	// teamsOf возвращает основную команду пользователя и все его группы
+	teamsOf := func(t *testing.T, userID string) (string, []string) {
+		var user scimUser
+		require.Equal(t, http.StatusOK, scim(t, http.MethodGet, "/Users/"+url.PathEscape(userID), nil, &user))
+		groups := []string{}
+		for _, group := range user.Groups {
+			groups = append(groups, group.Value)
+		}
+		return user.Enterprise.Department, groups
+	}
+	patchOp := func(op, path string, value interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"schemas":    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+			"Operations": []map[string]interface{}{{"op": op, "path": path, "value": value}},
+		}
+	}
+
+	t.Run("Service Provider Config", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/scim/v2/ServiceProviderConfig", nil, scimToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/scim+json", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("Requires SCIM Scope", func(t *testing.T) {
+		prToken := createToken(t, "pr:write")
+		resp := env.MakeRequest(t, http.MethodGet, "/scim/v2/Users", nil, prToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Create Group", func(t *testing.T) {
+		group := map[string]interface{}{"displayName": "scim-platform", "members": []interface{}{}}
+		var created scimGroup
+		require.Equal(t, http.StatusCreated, scim(t, http.MethodPost, "/Groups", group, &created))
+		assert.Equal(t, "scim-platform", created.ID)
+
+		var errResp scimError
+		assert.Equal(t, http.StatusConflict, scim(t, http.MethodPost, "/Groups", group, &errResp))
+		assert.Equal(t, "uniqueness", errResp.ScimType)
+	})
+
+	t.Run("Create Users", func(t *testing.T) {
+		for _, name := range []string{"alice", "bob", "carol", "erin"} {
+			var created scimUser
+			status := scim(t, http.MethodPost, "/Users", newUser(name+"@example.com", name, "scim-platform"), &created)
+			require.Equal(t, http.StatusCreated, status)
+			assert.Equal(t, name+"@example.com", created.ID)
+			assert.Equal(t, name, created.DisplayName)
+			assert.True(t, created.Active)
+			assert.Equal(t, "scim-platform", created.Enterprise.Department)
+		}
+
+		// Без команды пользователь попадает в команду по умолчанию
+		var dave scimUser
+		require.Equal(t, http.StatusCreated, scim(t, http.MethodPost, "/Users", newUser("dave@example.com", "dave", ""), &dave))
+		assert.Equal(t, "scim-staging", dave.Enterprise.Department)
+
+		var errResp scimError
+		assert.Equal(t, http.StatusConflict, scim(t, http.MethodPost, "/Users", newUser("alice@example.com", "alice", ""), &errResp))
+		assert.Equal(t, "uniqueness", errResp.ScimType)
+		assert.Equal(t, http.StatusBadRequest,
+			scim(t, http.MethodPost, "/Users", newUser("frank@example.com", "frank", "missing-team"), nil))
+	})
+
+	t.Run("Filter Users", func(t *testing.T) {
+		var list struct {
+			TotalResults int        `json:"totalResults"`
+			Resources    []scimUser `json:"Resources"`
+		}
+		filter := url.QueryEscape(`userName eq "alice@example.com"`)
+		require.Equal(t, http.StatusOK, scim(t, http.MethodGet, "/Users?filter="+filter, nil, &list))
+		require.Equal(t, 1, list.TotalResults)
+		assert.Equal(t, "hr-alice", list.Resources[0].ExternalID)
+
+		filter = url.QueryEscape(`externalId eq "hr-nobody"`)
+		require.Equal(t, http.StatusOK, scim(t, http.MethodGet, "/Users?filter="+filter, nil, &list))
+		assert.Equal(t, 0, list.TotalResults)
+
+		filter = url.QueryEscape(`userName sw "a"`)
+		assert.Equal(t, http.StatusBadRequest, scim(t, http.MethodGet, "/Users?filter="+filter, nil, nil))
+	})
+
+	t.Run("Group Membership", func(t *testing.T) {
+		status := scim(t, http.MethodPatch, "/Groups/scim-platform",
+			patchOp("add", "members", []map[string]string{{"value": "dave@example.com"}}), nil)
+		require.Equal(t, http.StatusOK, status)
+
+		// Добавленный в группу пользователь остается и в прежней, основной команде
+		primary, groups := teamsOf(t, "dave@example.com")
+		assert.Equal(t, "scim-staging", primary)
+		assert.Equal(t, []string{"scim-platform", "scim-staging"}, groups)
+
+		status = scim(t, http.MethodPatch, "/Groups/scim-platform",
+			patchOp("remove", `members[value eq "dave@example.com"]`, nil), nil)
+		require.Equal(t, http.StatusOK, status)
+		primary, groups = teamsOf(t, "dave@example.com")
+		assert.Equal(t, "scim-staging", primary)
+		assert.Equal(t, []string{"scim-staging"}, groups)
+
+		// Исключенный из единственной команды переходит в команду по умолчанию
+		status = scim(t, http.MethodPatch, "/Groups/scim-platform",
+			patchOp("remove", `members[value eq "erin@example.com"]`, nil), nil)
+		require.Equal(t, http.StatusOK, status)
+		primary, groups = teamsOf(t, "erin@example.com")
+		assert.Equal(t, "scim-staging", primary)
+		assert.Equal(t, []string{"scim-staging"}, groups)
+
+		status = scim(t, http.MethodPatch, "/Groups/scim-platform",
+			patchOp("add", "members", []map[string]string{{"value": "erin@example.com"}}), nil)
+		require.Equal(t, http.StatusOK, status)
+
+		var group scimGroup
+		require.Equal(t, http.StatusOK, scim(t, http.MethodGet, "/Groups/scim-platform", nil, &group))
+		assert.Len(t, group.Members, 4)
+
+		assert.Equal(t, http.StatusBadRequest, scim(t, http.MethodPatch, "/Groups/scim-platform",
+			patchOp("add", "members", []map[string]string{{"value": "nobody@example.com"}}), nil))
+
+		// Неудачная замена состава ничего не меняет
+		replace := map[string]interface{}{
+			"schemas": []string{"urn:ietf:params:scim:schemas:core:2.0:Group"},
+			"members": []map[string]string{{"value": "erin@example.com"}, {"value": "nobody@example.com"}},
+		}
+		assert.Equal(t, http.StatusBadRequest, scim(t, http.MethodPut, "/Groups/scim-platform", replace, nil))
+
+		group = scimGroup{}
+		require.Equal(t, http.StatusOK, scim(t, http.MethodGet, "/Groups/scim-platform", nil, &group))
+		assert.Len(t, group.Members, 4)
+		primary, groups = teamsOf(t, "erin@example.com")
+		assert.Equal(t, "scim-staging", primary)
+		assert.Equal(t, []string{"scim-platform", "scim-staging"}, groups)
+	})
+
+	t.Run("Deprovision Reassigns Reviews", func(t *testing.T) {
+		body, _ := json.Marshal(CreatePRRequest{
+			PullRequestID: "pr-scim-1", PullRequestName: "Provisioned", AuthorID: "alice@example.com",
+		})
+		resp := env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), adminToken)
+		var createResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		json.NewDecoder(resp.Body).Decode(&createResp)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Len(t, createResp.PR.Reviewers, 2)
+
+		// Деактивация через PATCH со значением-строкой, как ее присылают некоторые IdP
+		deprovisioned := createResp.PR.Reviewers[0]
+		var user scimUser
+		status := scim(t, http.MethodPatch, "/Users/"+url.PathEscape(deprovisioned), patchOp("Replace", "active", "False"), &user)
+		require.Equal(t, http.StatusOK, status)
+		assert.False(t, user.Active)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/pullRequest/get?pull_request_id=pr-scim-1", nil, adminToken)
+		var getResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		json.NewDecoder(resp.Body).Decode(&getResp)
+		resp.Body.Close()
+		assert.NotContains(t, getResp.PR.Reviewers, deprovisioned)
+		assert.Len(t, getResp.PR.Reviewers, 2, "Deprovisioned reviewer should be replaced by the remaining teammate")
+	})
+
+	t.Run("Delete Deactivates User", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, scim(t, http.MethodDelete, "/Users/dave%40example.com", nil, nil))
+
+		var user scimUser
+		require.Equal(t, http.StatusOK, scim(t, http.MethodGet, "/Users/dave%40example.com", nil, &user))
+		assert.False(t, user.Active)
+
+		// Повторная активация через PUT
+		replacement := newUser("dave@example.com", "David", "")
+		require.Equal(t, http.StatusOK, scim(t, http.MethodPut, "/Users/dave%40example.com", replacement, &user))
+		assert.True(t, user.Active)
+		assert.Equal(t, "David", user.DisplayName)
+		assert.Equal(t, "scim-staging", user.Enterprise.Department)
+
+		assert.Equal(t, http.StatusNotFound, scim(t, http.MethodDelete, "/Users/nobody", nil, nil))
+	})
+
+	t.Run("Delete Deactivates User Without Team", func(t *testing.T) {
+		require.Equal(t, http.StatusCreated, scim(t, http.MethodPost, "/Users", newUser("erin@example.com", "Erin", ""), nil))
+
+		// Участник удаленной команды остается без команды
+		_, err := env.DB.Exec(env.ctx, `DELETE FROM team_members WHERE user_id = 'erin@example.com'`)
+		require.NoError(t, err)
+		_, err = env.DB.Exec(env.ctx, `UPDATE users SET team_name = NULL WHERE user_id = 'erin@example.com'`)
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusNoContent, scim(t, http.MethodDelete, "/Users/erin%40example.com", nil, nil))
+
+		var user scimUser
+		require.Equal(t, http.StatusOK, scim(t, http.MethodGet, "/Users/erin%40example.com", nil, &user))
+		assert.False(t, user.Active)
+	})
+}
+
+// TestE2E_TeamMembership тестирует добавление, удаление и перевод участников команд
+func TestE2E_TeamMembership(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "core-team",
+		Members: []Member{
+			{UserID: "core1", Username: "Alma", IsActive: true, Role: "admin"},
+			{UserID: "core2", Username: "Boris", IsActive: true},
+			{UserID: "core3", Username: "Clara", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "core1")
+
+	infra := Team{
+		TeamName: "infra-team",
+		Members: []Member{
+			{UserID: "infra1", Username: "Dmitry", IsActive: true, Role: "team_lead"},
+		},
+	}
+
+	body, _ = json.Marshal(infra)
+	resp = env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), adminToken)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	leadToken := env.Login(t, "infra1")
+
+	// PR автора core1 - ревьюверами становятся core2 и core3
+	createPR := CreatePRRequest{
+		PullRequestID:   "pr-core-1",
+		PullRequestName: "Core refactoring",
+		AuthorID:        "core1",
+	}
+	body, _ = json.Marshal(createPR)
+	resp = env.MakeRequest(t, http.MethodPost, "/pullRequest/create", bytes.NewReader(body), adminToken)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	post := func(t *testing.T, path string, req interface{}, token string, out interface{}) int {
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+		defer resp.Body.Close()
+		if out != nil && resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	getReviewers := func(t *testing.T) []string {
+		resp := env.MakeRequest(t, http.MethodGet, "/pullRequest/get?pull_request_id=pr-core-1", nil, adminToken)
+		defer resp.Body.Close()
+
+		var getResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&getResp))
+		return getResp.PR.Reviewers
+	}
+
+	type reassignment struct {
+		PullRequestID string `json:"pull_request_id"`
+		OldUserID     string `json:"old_user_id"`
+		ReplacedBy    string `json:"replaced_by"`
+	}
+
+	t.Run("Add Members", func(t *testing.T) {
+		req := map[string]interface{}{
+			"team_name": "core-team",
+			"members":   []Member{{UserID: "core4", Username: "Denis", IsActive: true}},
+		}
+
+		var addResp struct {
+			Team Team `json:"team"`
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/addMembers", req, adminToken, &addResp))
+		assert.Len(t, addResp.Team.Members, 4)
+
+		// Лид другой команды не может добавлять участников в чужую команду
+		assert.Equal(t, http.StatusForbidden, post(t, "/team/addMembers", req, leadToken, nil))
+
+		// Участник другой команды становится участником обеих, основная команда не меняется
+		req["members"] = []Member{{UserID: "infra1", Username: "Dmitry", IsActive: true}}
+		require.Equal(t, http.StatusOK, post(t, "/team/addMembers", req, adminToken, &addResp))
+		assert.Len(t, addResp.Team.Members, 5)
+
+		var primary string
+		var teams []string
+		err := env.DB.QueryRow(env.ctx, `
+			SELECT u.team_name, ARRAY(SELECT team_name FROM team_members WHERE user_id = u.user_id ORDER BY team_name)
+			FROM users u WHERE u.user_id = 'infra1'
+		`).Scan(&primary, &teams)
+		require.NoError(t, err)
+		assert.Equal(t, "infra-team", primary)
+		assert.Equal(t, []string{"core-team", "infra-team"}, teams)
+
+		// Исключение из одной команды не затрагивает другую
+		removeReq := map[string]interface{}{"team_name": "core-team", "user_ids": []string{"infra1"}}
+		require.Equal(t, http.StatusOK, post(t, "/team/removeMembers", removeReq, adminToken, nil))
+		err = env.DB.QueryRow(env.ctx, `SELECT team_name FROM users WHERE user_id = 'infra1'`).Scan(&primary)
+		require.NoError(t, err)
+		assert.Equal(t, "infra-team", primary)
+
+		// Лид не может выдать роль администратора
+		req = map[string]interface{}{
+			"team_name": "infra-team",
+			"members":   []Member{{UserID: "infra2", Username: "Egor", IsActive: true, Role: "admin"}},
+		}
+		assert.Equal(t, http.StatusForbidden, post(t, "/team/addMembers", req, leadToken, nil))
+	})
+
+	t.Run("Lead Cannot Pull Users From Other Teams", func(t *testing.T) {
+		// Участник и администратор чужой команды не добавляются лидом в его команду
+		for _, member := range []Member{
+			{UserID: "core2", Username: "Boris", IsActive: true},
+			{UserID: "core1", Username: "Alma", IsActive: true},
+		} {
+			req := map[string]interface{}{"team_name": "infra-team", "members": []Member{member}}
+			assert.Equal(t, http.StatusForbidden, post(t, "/team/addMembers", req, leadToken, nil), member.UserID)
+		}
+
+		// Лид может назначить другого лида только через администратора
+		req := map[string]interface{}{
+			"team_name": "infra-team",
+			"members":   []Member{{UserID: "infra3", Username: "Fedor", IsActive: true, Role: "team_lead"}},
+		}
+		assert.Equal(t, http.StatusForbidden, post(t, "/team/addMembers", req, leadToken, nil))
+	})
+
+	t.Run("Lead Only Of Marked Team", func(t *testing.T) {
+		// Лид infra-team, вступивший в core-team участником, не управляет ею
+		req := map[string]interface{}{
+			"team_name": "core-team",
+			"members":   []Member{{UserID: "infra1", Username: "Dmitry", IsActive: true}},
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/addMembers", req, adminToken, nil))
+		token := env.login(t, "infra1", testPassword)
+
+		strategy := map[string]string{"team_name": "core-team", "reviewer_strategy": "round_robin"}
+		assert.Equal(t, http.StatusForbidden, post(t, "/team/setReviewerStrategy", strategy, token, nil))
+		strategy["team_name"] = "infra-team"
+		assert.Equal(t, http.StatusOK, post(t, "/team/setReviewerStrategy", strategy, token, nil))
+
+		var team Team
+		resp := env.MakeRequest(t, http.MethodGet, "/team/get?team_name=core-team", nil, adminToken)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&team))
+		resp.Body.Close()
+		for _, member := range team.Members {
+			if member.UserID == "infra1" {
+				assert.Equal(t, "member", member.Role, "Role in a team the user does not lead")
+			}
+		}
+
+		removeReq := map[string]interface{}{"team_name": "core-team", "user_ids": []string{"infra1"}}
+		require.Equal(t, http.StatusOK, post(t, "/team/removeMembers", removeReq, adminToken, nil))
+	})
+
+	t.Run("Move Member With Reassign", func(t *testing.T) {
+		req := map[string]interface{}{"user_id": "core2", "team_name": "infra-team", "review_policy": "reassign"}
+
+		// Лид команды назначения не управляет командой пользователя
+		assert.Equal(t, http.StatusForbidden, post(t, "/team/moveMember", req, leadToken, nil))
+
+		var moveResp struct {
+			User struct {
+				TeamName string `json:"team_name"`
+			} `json:"user"`
+			FromTeam      string         `json:"from_team"`
+			Reassignments []reassignment `json:"reassignments"`
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/moveMember", req, adminToken, &moveResp))
+
+		assert.Equal(t, "infra-team", moveResp.User.TeamName)
+		assert.Equal(t, "core-team", moveResp.FromTeam)
+		require.Len(t, moveResp.Reassignments, 1)
+		assert.Equal(t, "core2", moveResp.Reassignments[0].OldUserID)
+		assert.Equal(t, "core4", moveResp.Reassignments[0].ReplacedBy, "Only core4 is free to take over the review")
+
+		assert.ElementsMatch(t, []string{"core3", "core4"}, getReviewers(t))
+	})
+
+	t.Run("Move Member Keeping Reviews", func(t *testing.T) {
+		req := map[string]interface{}{"user_id": "core3", "team_name": "infra-team", "review_policy": "keep"}
+
+		var moveResp struct {
+			Reassignments []reassignment `json:"reassignments"`
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/moveMember", req, adminToken, &moveResp))
+		assert.Empty(t, moveResp.Reassignments)
+		assert.Contains(t, getReviewers(t), "core3")
+
+		req["review_policy"] = "drop"
+		assert.Equal(t, http.StatusBadRequest, post(t, "/team/moveMember", req, adminToken, nil))
+
+		req = map[string]interface{}{"user_id": "core3", "team_name": "no-such-team"}
+		assert.Equal(t, http.StatusNotFound, post(t, "/team/moveMember", req, adminToken, nil))
+	})
+
+	t.Run("Remove Members", func(t *testing.T) {
+		req := map[string]interface{}{"team_name": "core-team", "user_ids": []string{"core4"}}
+
+		var removeResp struct {
+			RemovedUsers  []string       `json:"removed_users"`
+			Reassignments []reassignment `json:"reassignments"`
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/removeMembers", req, adminToken, &removeResp))
+
+		// По умолчанию ревью переназначаются; кроме автора в команде никого не осталось
+		assert.Equal(t, []string{"core4"}, removeResp.RemovedUsers)
+		require.Len(t, removeResp.Reassignments, 1)
+		assert.Empty(t, removeResp.Reassignments[0].ReplacedBy)
+		assert.Equal(t, []string{"core3"}, getReviewers(t))
+
+		var teamName *string
+		err := env.DB.QueryRow(env.ctx, `SELECT team_name FROM users WHERE user_id = 'core4'`).Scan(&teamName)
+		require.NoError(t, err)
+		assert.Nil(t, teamName, "Removed user should be left without a team")
+
+		// Пользователь не из команды
+		req["user_ids"] = []string{"infra1"}
+		assert.Equal(t, http.StatusNotFound, post(t, "/team/removeMembers", req, adminToken, nil))
+	})
+
+	t.Run("Removed User Joins Another Team", func(t *testing.T) {
+		req := map[string]interface{}{
+			"team_name": "infra-team",
+			"members":   []Member{{UserID: "core4", Username: "Denis", IsActive: true}},
+		}
+
+		// Пользователя без команды добавляет только администратор
+		assert.Equal(t, http.StatusForbidden, post(t, "/team/addMembers", req, leadToken, nil))
+
+		var addResp struct {
+			Team Team `json:"team"`
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/addMembers", req, adminToken, &addResp))
+		assert.Len(t, addResp.Team.Members, 4)
+	})
+
+	t.Run("Timeline Records Team Changes", func(t *testing.T) {
+		var count int
+		err := env.DB.QueryRow(env.ctx,
+			`SELECT COUNT(*) FROM pr_events WHERE pull_request_id = 'pr-core-1' AND reason = 'team_changed'`,
+		).Scan(&count)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+}
+
+// TestE2E_TeamArchiveAndDelete тестирует предпросмотр, архивацию и удаление команд
+func TestE2E_TeamArchiveAndDelete(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "legacy-team",
+		Members: []Member{
+			{UserID: "leg1", Username: "Fedor", IsActive: true, Role: "admin"},
+			{UserID: "leg2", Username: "Galina", IsActive: true},
+			{UserID: "leg3", Username: "Hugo", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "leg1")
+	memberToken := env.Login(t, "leg2")
+
+	post := func(t *testing.T, path string, req interface{}, token string, out interface{}) int {
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+		defer resp.Body.Close()
+		if out != nil && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated) {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	for _, other := range []Team{
+		{TeamName: "platform-team", Members: []Member{{UserID: "plat1", Username: "Irina", IsActive: true}}},
+		{TeamName: "sandbox-team", Members: []Member{
+			{UserID: "sb1", Username: "Jan", IsActive: true},
+			{UserID: "sb2", Username: "Kira", IsActive: true},
+		}},
+	} {
+		require.Equal(t, http.StatusCreated, post(t, "/team/add", other, adminToken, nil))
+	}
+
+	createPR := func(t *testing.T, prID, authorID string) []string {
+		req := CreatePRRequest{PullRequestID: prID, PullRequestName: "Change " + prID, AuthorID: authorID}
+
+		var createResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", req, adminToken, &createResp))
+		return createResp.PR.Reviewers
+	}
+
+	assert.ElementsMatch(t, []string{"leg2", "leg3"}, createPR(t, "pr-leg-1", "leg1"))
+	assert.Equal(t, []string{"sb2"}, createPR(t, "pr-sb-1", "sb1"))
+
+	type impact struct {
+		TeamName         string   `json:"team_name"`
+		Archived         bool     `json:"archived"`
+		Members          []Member `json:"members"`
+		OpenPullRequests []struct {
+			PullRequestID string `json:"pull_request_id"`
+		} `json:"open_pull_requests"`
+		OpenReviews []struct {
+			PullRequestID string `json:"pull_request_id"`
+			UserID        string `json:"user_id"`
+		} `json:"open_reviews"`
+	}
+
+	t.Run("Removal Preview", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/team/removalPreview?team_name=legacy-team", nil, adminToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var preview impact
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&preview))
+		assert.False(t, preview.Archived)
+		assert.Len(t, preview.Members, 3)
+		require.Len(t, preview.OpenPullRequests, 1)
+		assert.Equal(t, "pr-leg-1", preview.OpenPullRequests[0].PullRequestID)
+		assert.Len(t, preview.OpenReviews, 2)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/team/removalPreview?team_name=legacy-team", nil, memberToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Archive Team", func(t *testing.T) {
+		req := map[string]interface{}{"team_name": "legacy-team"}
+
+		assert.Equal(t, http.StatusForbidden, post(t, "/team/archive", req, memberToken, nil))
+
+		var archiveResp struct {
+			Members string `json:"members"`
+			Impact  impact `json:"impact"`
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/archive", req, adminToken, &archiveResp))
+		assert.Equal(t, "keep", archiveResp.Members)
+		assert.False(t, archiveResp.Impact.Archived, "Impact describes the team before archiving")
+
+		resp := env.MakeRequest(t, http.MethodGet, "/team/get?team_name=legacy-team", nil, adminToken)
+		defer resp.Body.Close()
+
+		var archived struct {
+			ArchivedAt *time.Time `json:"archived_at"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&archived))
+		assert.NotNil(t, archived.ArchivedAt)
+
+		// Повторная архивация и новые участники запрещены
+		assert.Equal(t, http.StatusConflict, post(t, "/team/archive", req, adminToken, nil))
+		addReq := map[string]interface{}{
+			"team_name": "legacy-team",
+			"members":   []Member{{UserID: "leg4", Username: "Lev", IsActive: true}},
+		}
+		assert.Equal(t, http.StatusConflict, post(t, "/team/addMembers", addReq, adminToken, nil))
+	})
+
+	t.Run("Archived Team Is Not Picked For Review", func(t *testing.T) {
+		assert.Empty(t, createPR(t, "pr-leg-2", "leg1"))
+	})
+
+	t.Run("Delete Team Moving Members", func(t *testing.T) {
+		req := map[string]interface{}{"team_name": "legacy-team", "members": "move"}
+		assert.Equal(t, http.StatusBadRequest, post(t, "/team/delete", req, adminToken, nil))
+
+		req["target_team"] = "platform-team"
+		var deleteResp struct {
+			TargetTeam string `json:"target_team"`
+			Impact     impact `json:"impact"`
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/delete", req, adminToken, &deleteResp))
+		assert.Equal(t, "platform-team", deleteResp.TargetTeam)
+		assert.True(t, deleteResp.Impact.Archived)
+
+		resp := env.MakeRequest(t, http.MethodGet, "/team/get?team_name=legacy-team", nil, adminToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = env.MakeRequest(t, http.MethodGet, "/team/get?team_name=platform-team", nil, adminToken)
+		defer resp.Body.Close()
+
+		var platform Team
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&platform))
+		assert.Len(t, platform.Members, 4)
+
+		// Переведенные участники сохранили свои ревью
+		resp = env.MakeRequest(t, http.MethodGet, "/pullRequest/get?pull_request_id=pr-leg-1", nil, adminToken)
+		defer resp.Body.Close()
+
+		var getResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&getResp))
+		assert.ElementsMatch(t, []string{"leg2", "leg3"}, getResp.PR.Reviewers)
+	})
+
+	t.Run("Delete Team Deactivating Members", func(t *testing.T) {
+		// plat1 состоит еще и в platform-team
+		addReq := map[string]interface{}{
+			"team_name": "sandbox-team",
+			"members":   []Member{{UserID: "plat1", Username: "Irina", IsActive: true}},
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/addMembers", addReq, adminToken, nil))
+
+		req := map[string]interface{}{"team_name": "sandbox-team", "members": "deactivate"}
+		require.Equal(t, http.StatusOK, post(t, "/team/delete", req, adminToken, nil))
+
+		var (
+			teamless int
+			active   int
+		)
+		err := env.DB.QueryRow(env.ctx, `
+			SELECT COUNT(*) FILTER (WHERE team_name IS NULL), COUNT(*) FILTER (WHERE is_active)
+			FROM users WHERE user_id IN ('sb1', 'sb2')
+		`).Scan(&teamless, &active)
+		require.NoError(t, err)
+		assert.Equal(t, 2, teamless, "Members of a deleted team are left without a team")
+		assert.Equal(t, 0, active)
+
+		// Участник нескольких команд не деактивируется, а только теряет членство в удаленной команде
+		var (
+			platActive bool
+			platTeam   string
+		)
+		err = env.DB.QueryRow(env.ctx, `SELECT is_active, team_name FROM users WHERE user_id = 'plat1'`).
+			Scan(&platActive, &platTeam)
+		require.NoError(t, err)
+		assert.True(t, platActive)
+		assert.Equal(t, "platform-team", platTeam)
+
+		// Деактивированный ревьювер снят с PR, сам PR и его автор сохранились
+		resp := env.MakeRequest(t, http.MethodGet, "/pullRequest/get?pull_request_id=pr-sb-1", nil, adminToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var getResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&getResp))
+		assert.Empty(t, getResp.PR.Reviewers)
+	})
+}
+
+// TestE2E_MultiTeamMembership тестирует участие пользователя в нескольких командах
+func TestE2E_MultiTeamMembership(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "web-team",
+		Members: []Member{
+			{UserID: "web1", Username: "Anna", IsActive: true, Role: "admin"},
+			{UserID: "web2", Username: "Bogdan", IsActive: true},
+			{UserID: "web3", Username: "Vlad", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "web1")
+
+	post := func(t *testing.T, path string, req interface{}, token string, out interface{}) int {
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	mobile := Team{
+		TeamName: "mobile-team",
+		Members: []Member{
+			{UserID: "mob1", Username: "Galina", IsActive: true},
+			{UserID: "mob2", Username: "Daniil", IsActive: true},
+		},
+	}
+	require.Equal(t, http.StatusCreated, post(t, "/team/add", mobile, adminToken, nil))
+
+	t.Run("Join Second Team", func(t *testing.T) {
+		req := map[string]interface{}{
+			"team_name": "mobile-team",
+			"members":   []Member{{UserID: "web2", Username: "Bogdan", IsActive: true}},
+		}
+
+		var addResp struct {
+			Team Team `json:"team"`
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/addMembers", req, adminToken, &addResp))
+		assert.Len(t, addResp.Team.Members, 3)
+
+		resp := env.MakeRequest(t, http.MethodGet, "/team/get?team_name=web-team", nil, adminToken)
+		defer resp.Body.Close()
+		var web Team
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&web))
+		assert.Len(t, web.Members, 3, "Joining another team keeps the user in the first one")
+	})
+
+	t.Run("JWT Carries All Teams", func(t *testing.T) {
+		token := env.Login(t, "web2")
+
+		var claims struct {
+			TeamName string   `json:"team_name"`
+			Teams    []string `json:"teams"`
+		}
+		parts := strings.Split(token, ".")
+		require.Len(t, parts, 3)
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(payload, &claims))
+
+		assert.Equal(t, "web-team", claims.TeamName)
+		assert.Equal(t, []string{"mobile-team", "web-team"}, claims.Teams)
+	})
+
+	type prResponse struct {
+		PR struct {
+			PullRequestResponse
+			TeamName string `json:"team_name"`
+		} `json:"pr"`
+	}
+
+	t.Run("Author Picks Reviewing Team", func(t *testing.T) {
+		req := map[string]interface{}{
+			"pull_request_id": "pr-multi-1", "pull_request_name": "Mobile API", "author_id": "web2", "team_name": "mobile-team",
+		}
+		var createResp prResponse
+		require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", req, adminToken, &createResp))
+		assert.Equal(t, "mobile-team", createResp.PR.TeamName)
+		assert.ElementsMatch(t, []string{"mob1", "mob2"}, createResp.PR.Reviewers)
+
+		// По умолчанию ревьюверы назначаются из основной команды
+		req = map[string]interface{}{"pull_request_id": "pr-multi-2", "pull_request_name": "Web page", "author_id": "web2"}
+		require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", req, adminToken, &createResp))
+		assert.Equal(t, "web-team", createResp.PR.TeamName)
+		assert.ElementsMatch(t, []string{"web1", "web3"}, createResp.PR.Reviewers)
+
+		// Автор может выбрать только свою команду
+		req = map[string]interface{}{
+			"pull_request_id": "pr-multi-3", "pull_request_name": "Foreign", "author_id": "web3", "team_name": "mobile-team",
+		}
+		var errResp struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		require.Equal(t, http.StatusConflict, post(t, "/pullRequest/create", req, adminToken, &errResp))
+		assert.Equal(t, "NOT_MEMBER", errResp.Error.Code)
+	})
+
+	t.Run("Reassign Within PR Team", func(t *testing.T) {
+		req := map[string]interface{}{
+			"team_name": "mobile-team",
+			"members":   []Member{{UserID: "mob3", Username: "Elena", IsActive: true}},
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/addMembers", req, adminToken, nil))
+
+		var reassignResp struct {
+			ReplacedBy string `json:"replaced_by"`
+		}
+		status := post(t, "/pullRequest/reassign", ReassignRequest{PullRequestID: "pr-multi-1", OldReviewerID: "mob1"},
+			adminToken, &reassignResp)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "mob3", reassignResp.ReplacedBy)
+	})
+
+	t.Run("Set Primary Team", func(t *testing.T) {
+		var setResp struct {
+			User struct {
+				TeamName string   `json:"team_name"`
+				Teams    []string `json:"teams"`
+			} `json:"user"`
+		}
+		req := map[string]string{"user_id": "web2", "team_name": "mobile-team"}
+		require.Equal(t, http.StatusOK, post(t, "/users/setPrimaryTeam", req, adminToken, &setResp))
+		assert.Equal(t, "mobile-team", setResp.User.TeamName)
+		assert.Equal(t, []string{"mobile-team", "web-team"}, setResp.User.Teams)
+
+		req = map[string]string{"user_id": "web3", "team_name": "mobile-team"}
+		assert.Equal(t, http.StatusConflict, post(t, "/users/setPrimaryTeam", req, adminToken, nil))
+	})
+
+	t.Run("Leave One Team", func(t *testing.T) {
+		// Выход из основной команды делает основной оставшуюся; ревью PR другой команды сохраняются
+		req := map[string]interface{}{"team_name": "mobile-team", "user_ids": []string{"web2"}}
+		require.Equal(t, http.StatusOK, post(t, "/team/removeMembers", req, adminToken, nil))
+
+		var primary string
+		err := env.DB.QueryRow(env.ctx, `SELECT team_name FROM users WHERE user_id = 'web2'`).Scan(&primary)
+		require.NoError(t, err)
+		assert.Equal(t, "web-team", primary)
+
+		var teamName string
+		err = env.DB.QueryRow(env.ctx,
+			`SELECT team_name FROM pull_requests WHERE pull_request_id = 'pr-multi-1'`,
+		).Scan(&teamName)
+		require.NoError(t, err)
+		assert.Equal(t, "mobile-team", teamName, "The PR keeps the team chosen at creation")
+	})
+}
+
+// TestE2E_UserAbsences проверяет периоды отсутствия: исключение из выбора ревьюверов и передачу ревью
+func TestE2E_UserAbsences(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "away-team",
+		Members: []Member{
+			{UserID: "aw1", Username: "Anton", IsActive: true, Role: "admin"},
+			{UserID: "aw2", Username: "Boris", IsActive: true},
+			{UserID: "aw3", Username: "Vera", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "aw1")
+	aw2Token := env.Login(t, "aw2")
+	aw3Token := env.Login(t, "aw3")
+
+	post := func(t *testing.T, path string, req interface{}, token string, out interface{}) int {
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	getReviewers := func(t *testing.T, prID string) []string {
+		resp := env.MakeRequest(t, http.MethodGet, "/pullRequest/get?pull_request_id="+prID, nil, adminToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var getResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&getResp))
+		return getResp.PR.Reviewers
+	}
+
+	type absenceResponse struct {
+		Absence struct {
+			AbsenceID           int64   `json:"absence_id"`
+			StartDate           string  `json:"start_date"`
+			EndDate             string  `json:"end_date"`
+			Days                int     `json:"days"`
+			ReviewsReassignedAt *string `json:"reviewsReassignedAt"`
+		} `json:"absence"`
+	}
+
+	// Даты сравниваются с CURRENT_DATE базы, которая работает в UTC
+	today := time.Now().UTC()
+	date := func(days int) string {
+		return today.AddDate(0, 0, days).Format(time.DateOnly)
+	}
+
+	// PR создан до отсутствий: ревьюверами назначены оба участника
+	createReq := CreatePRRequest{PullRequestID: "pr-away-1", PullRequestName: "Before vacation", AuthorID: "aw1"}
+	require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", createReq, adminToken, nil))
+	require.ElementsMatch(t, []string{"aw2", "aw3"}, getReviewers(t, "pr-away-1"))
+
+	var shortAbsenceID int64
+
+	t.Run("Validation And Access", func(t *testing.T) {
+		req := map[string]string{"user_id": "aw3", "start_date": date(1), "end_date": date(0)}
+		assert.Equal(t, http.StatusBadRequest, post(t, "/users/absences/add", req, aw3Token, nil))
+
+		req = map[string]string{"user_id": "aw3", "start_date": "01.01.2030", "end_date": date(0)}
+		assert.Equal(t, http.StatusBadRequest, post(t, "/users/absences/add", req, aw3Token, nil))
+
+		// Участник управляет только своими периодами
+		req = map[string]string{"user_id": "aw2", "start_date": date(0), "end_date": date(0)}
+		assert.Equal(t, http.StatusForbidden, post(t, "/users/absences/add", req, aw3Token, nil))
+	})
+
+	t.Run("Short Absence Excludes From Selection", func(t *testing.T) {
+		req := map[string]string{"user_id": "aw3", "start_date": date(0), "end_date": date(0), "reason": "doctor"}
+		var addResp absenceResponse
+		require.Equal(t, http.StatusCreated, post(t, "/users/absences/add", req, aw3Token, &addResp))
+		assert.Equal(t, date(0), addResp.Absence.StartDate)
+		assert.Equal(t, 1, addResp.Absence.Days)
+		shortAbsenceID = addResp.Absence.AbsenceID
+
+		createReq := CreatePRRequest{PullRequestID: "pr-away-2", PullRequestName: "During absence", AuthorID: "aw1"}
+		require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", createReq, adminToken, nil))
+		assert.Equal(t, []string{"aw2"}, getReviewers(t, "pr-away-2"), "An absent user is not picked as a reviewer")
+
+		// Ревью короткого отсутствия не передаются
+		time.Sleep(500 * time.Millisecond)
+		assert.ElementsMatch(t, []string{"aw2", "aw3"}, getReviewers(t, "pr-away-1"))
+
+		var active bool
+		err := env.DB.QueryRow(env.ctx, `SELECT is_active FROM users WHERE user_id = 'aw3'`).Scan(&active)
+		require.NoError(t, err)
+		assert.True(t, active, "An absence does not touch is_active")
+	})
+
+	t.Run("Overlapping Absence", func(t *testing.T) {
+		req := map[string]string{"user_id": "aw3", "start_date": date(-2), "end_date": date(2)}
+		var errResp struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		require.Equal(t, http.StatusConflict, post(t, "/users/absences/add", req, adminToken, &errResp))
+		assert.Equal(t, "ABSENCE_OVERLAP", errResp.Error.Code)
+	})
+
+	t.Run("Long Absence Hands Over Reviews", func(t *testing.T) {
+		req := map[string]interface{}{
+			"team_name": "away-team",
+			"members":   []Member{{UserID: "aw4", Username: "Gleb", IsActive: true}},
+		}
+		require.Equal(t, http.StatusOK, post(t, "/team/addMembers", req, adminToken, nil))
+
+		absenceReq := map[string]string{"user_id": "aw2", "start_date": date(0), "end_date": date(13), "reason": "vacation"}
+		var addResp absenceResponse
+		require.Equal(t, http.StatusCreated, post(t, "/users/absences/add", absenceReq, aw2Token, &addResp))
+		assert.Equal(t, 14, addResp.Absence.Days)
+
+		// aw3 тоже отсутствует, поэтому единственная замена - aw4
+		require.Eventually(t, func() bool {
+			return slices.Contains(getReviewers(t, "pr-away-2"), "aw4")
+		}, 10*time.Second, 100*time.Millisecond)
+
+		assert.Equal(t, []string{"aw4"}, getReviewers(t, "pr-away-2"))
+		assert.ElementsMatch(t, []string{"aw3", "aw4"}, getReviewers(t, "pr-away-1"))
+
+		var events int
+		err := env.DB.QueryRow(env.ctx,
+			`SELECT COUNT(*) FROM pr_events WHERE reason = 'user_absent' AND old_user_id = 'aw2' AND new_user_id = 'aw4'`,
+		).Scan(&events)
+		require.NoError(t, err)
+		assert.Equal(t, 2, events)
+
+		resp := env.MakeRequest(t, http.MethodGet, "/users/absences/list?user_id=aw2", nil, aw2Token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var listResp struct {
+			Absences []struct {
+				AbsenceID           int64   `json:"absence_id"`
+				Reason              string  `json:"reason"`
+				ReviewsReassignedAt *string `json:"reviewsReassignedAt"`
+			} `json:"absences"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&listResp))
+		require.Len(t, listResp.Absences, 1)
+		assert.Equal(t, "vacation", listResp.Absences[0].Reason)
+		assert.NotNil(t, listResp.Absences[0].ReviewsReassignedAt)
+	})
+
+	t.Run("Delete Absence", func(t *testing.T) {
+		req := map[string]int64{"absence_id": shortAbsenceID}
+		assert.Equal(t, http.StatusForbidden, post(t, "/users/absences/delete", req, aw2Token, nil))
+		require.Equal(t, http.StatusOK, post(t, "/users/absences/delete", req, aw3Token, nil))
+		assert.Equal(t, http.StatusNotFound, post(t, "/users/absences/delete", req, aw3Token, nil))
+
+		// После удаления периода пользователь снова выбирается ревьювером
+		createReq := CreatePRRequest{PullRequestID: "pr-away-3", PullRequestName: "After return", AuthorID: "aw1"}
+		require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", createReq, adminToken, nil))
+		assert.ElementsMatch(t, []string{"aw3", "aw4"}, getReviewers(t, "pr-away-3"))
+	})
+}
+
+// TestE2E_ReviewCapacity проверяет лимиты открытых ревью пользователя и команды
+func TestE2E_ReviewCapacity(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "cap-team",
+		Members: []Member{
+			{UserID: "cap1", Username: "Arina", IsActive: true, Role: "admin"},
+			{UserID: "cap2", Username: "Boris", IsActive: true},
+			{UserID: "cap3", Username: "Vasilisa", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "cap1")
+
+	post := func(t *testing.T, path string, req interface{}, token string, out interface{}) int {
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	createPR := func(t *testing.T, prID string) []string {
+		var createResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		req := CreatePRRequest{PullRequestID: prID, PullRequestName: "Capacity " + prID, AuthorID: "cap1"}
+		require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", req, adminToken, &createResp))
+		return createResp.PR.Reviewers
+	}
+
+	type errorResponse struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		req := map[string]interface{}{"user_id": "cap2", "max_open_reviews": -1}
+		assert.Equal(t, http.StatusBadRequest, post(t, "/users/setMaxOpenReviews", req, adminToken, nil))
+
+		req = map[string]interface{}{"team_name": "cap-team", "max_open_reviews": 0}
+		assert.Equal(t, http.StatusBadRequest, post(t, "/team/setMaxOpenReviews", req, adminToken, nil))
+	})
+
+	t.Run("User Limit", func(t *testing.T) {
+		req := map[string]interface{}{"user_id": "cap2", "max_open_reviews": 1}
+		require.Equal(t, http.StatusOK, post(t, "/users/setMaxOpenReviews", req, adminToken, nil))
+
+		assert.ElementsMatch(t, []string{"cap2", "cap3"}, createPR(t, "pr-cap-1"))
+		assert.Equal(t, []string{"cap3"}, createPR(t, "pr-cap-2"), "A user at their limit is skipped")
+	})
+
+	t.Run("Team Default Limit", func(t *testing.T) {
+		var settings struct {
+			MaxOpenReviews *int `json:"max_open_reviews"`
+		}
+		req := map[string]interface{}{"team_name": "cap-team", "max_open_reviews": 2}
+		require.Equal(t, http.StatusOK, post(t, "/team/setMaxOpenReviews", req, adminToken, &settings))
+		require.NotNil(t, settings.MaxOpenReviews)
+		assert.Equal(t, 2, *settings.MaxOpenReviews)
+
+		// У cap3 два открытых ревью (лимит команды), у cap2 - одно (собственный лимит)
+		assert.Empty(t, createPR(t, "pr-cap-3"))
+	})
+
+	t.Run("Reassign At Capacity", func(t *testing.T) {
+		var errResp errorResponse
+		status := post(t, "/pullRequest/reassign", ReassignRequest{PullRequestID: "pr-cap-2", OldReviewerID: "cap3"},
+			adminToken, &errResp)
+		require.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, "NO_CANDIDATE", errResp.Error.Code)
+		assert.Equal(t, "all active candidates are at review capacity", errResp.Error.Message)
+
+		// Без собственного лимита действует лимит команды, и у cap2 есть запас
+		req := map[string]interface{}{"user_id": "cap2", "max_open_reviews": nil}
+		require.Equal(t, http.StatusOK, post(t, "/users/setMaxOpenReviews", req, adminToken, nil))
+
+		var reassignResp struct {
+			ReplacedBy string `json:"replaced_by"`
+		}
+		status = post(t, "/pullRequest/reassign", ReassignRequest{PullRequestID: "pr-cap-2", OldReviewerID: "cap3"},
+			adminToken, &reassignResp)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "cap2", reassignResp.ReplacedBy)
+	})
+
+	t.Run("Reassign Without Active Candidates", func(t *testing.T) {
+		var errResp errorResponse
+		status := post(t, "/pullRequest/reassign", ReassignRequest{PullRequestID: "pr-cap-1", OldReviewerID: "cap2"},
+			adminToken, &errResp)
+		require.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, "NO_CANDIDATE", errResp.Error.Code)
+		assert.Equal(t, "no active replacement candidate in team", errResp.Error.Message)
+	})
+}
+
+// TestE2E_CodeOwners проверяет назначение владельцев кода измененных файлов по CODEOWNERS
+func TestE2E_CodeOwners(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "own-team",
+		Members: []Member{
+			{UserID: "own1", Username: "Alla", IsActive: true, Role: "admin"},
+			{UserID: "own2", Username: "Bogdan", IsActive: true},
+			{UserID: "own3", Username: "Valeria", IsActive: true},
+			{UserID: "own4", Username: "Gleb", IsActive: true},
+			{UserID: "own5", Username: "Daria", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "own1")
+
+	post := func(t *testing.T, path string, req interface{}, token string, out interface{}) int {
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	dbTeam := Team{
+		TeamName: "own-db",
+		Members: []Member{
+			{UserID: "db1", Username: "Evgenia", IsActive: true},
+			{UserID: "db2", Username: "Zakhar", IsActive: true},
+		},
+	}
+	require.Equal(t, http.StatusCreated, post(t, "/team/add", dbTeam, adminToken, nil))
+
+	createPR := func(t *testing.T, prID, repository string, files []string) []string {
+		var createResp struct {
+			PR PullRequestResponse `json:"pr"`
+		}
+		req := map[string]interface{}{
+			"pull_request_id":   prID,
+			"pull_request_name": "Owners " + prID,
+			"author_id":         "own1",
+			"repository":        repository,
+			"changed_files":     files,
+		}
+		require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", req, adminToken, &createResp))
+		return createResp.PR.Reviewers
+	}
+
+	teamOwners := "# Владельцы кода команды\n" +
+		"*              @own5\n" +
+		"/internal/db/  @org/own-db\n" +
+		"*.md           @own4 # документация\n" +
+		"/cmd/          @own1\n"
+
+	t.Run("Validation", func(t *testing.T) {
+		req := map[string]interface{}{"team_name": "own-team", "content": "*.go own2"}
+		assert.Equal(t, http.StatusBadRequest, post(t, "/codeowners/set", req, adminToken, nil))
+
+		req = map[string]interface{}{"team_name": "own-team", "repository": "org/app", "content": "* @own2"}
+		assert.Equal(t, http.StatusBadRequest, post(t, "/codeowners/set", req, adminToken, nil))
+
+		req = map[string]interface{}{"team_name": "own-team", "content": "* @own2"}
+		assert.Equal(t, http.StatusForbidden, post(t, "/codeowners/set", req, env.Login(t, "own2"), nil))
+
+		req = map[string]interface{}{"team_name": "missing-team", "content": "* @own2"}
+		assert.Equal(t, http.StatusNotFound, post(t, "/codeowners/set", req, adminToken, nil))
+	})
+
+	t.Run("Set And Get", func(t *testing.T) {
+		req := map[string]interface{}{"team_name": "own-team", "content": teamOwners}
+		require.Equal(t, http.StatusOK, post(t, "/codeowners/set", req, adminToken, nil))
+
+		resp := env.MakeRequest(t, http.MethodGet, "/codeowners/get?team_name=own-team", nil, adminToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var getResp struct {
+			CodeOwners struct {
+				TeamName string `json:"team_name"`
+				Content  string `json:"content"`
+			} `json:"code_owners"`
+			Rules []struct {
+				Line    int      `json:"line"`
+				Pattern string   `json:"pattern"`
+				Owners  []string `json:"owners"`
+			} `json:"rules"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&getResp))
+		assert.Equal(t, teamOwners, getResp.CodeOwners.Content)
+		require.Len(t, getResp.Rules, 4)
+		assert.Equal(t, 4, getResp.Rules[2].Line)
+		assert.Equal(t, "*.md", getResp.Rules[2].Pattern)
+		assert.Equal(t, []string{"@own4"}, getResp.Rules[2].Owners)
+	})
+
+	t.Run("Owners Are Assigned First", func(t *testing.T) {
+		reviewers := createPR(t, "pr-own-1", "", []string{"internal/db/query.go", "README.md"})
+		require.Len(t, reviewers, 2)
+		assert.Contains(t, reviewers, "own4")
+		assert.True(t, slices.Contains(reviewers, "db1") || slices.Contains(reviewers, "db2"),
+			"One of the owning team's members reviews internal/db")
+	})
+
+	t.Run("Remaining Slots Are Filled From Team", func(t *testing.T) {
+		// Автор - единственный владелец /cmd/, поэтому его файлы не требуют отдельного ревьювера
+		reviewers := createPR(t, "pr-own-2", "", []string{"cmd/main.go", "go.mod"})
+		require.Len(t, reviewers, 2)
+		assert.Contains(t, reviewers, "own5")
+		assert.NotContains(t, reviewers, "own1")
+		for _, id := range reviewers {
+			assert.Contains(t, []string{"own2", "own3", "own4", "own5"}, id)
+		}
+	})
+
+	t.Run("Repository Overrides Team", func(t *testing.T) {
+		req := map[string]interface{}{"repository": "org/app", "content": "* @own2 @own3"}
+		require.Equal(t, http.StatusOK, post(t, "/codeowners/set", req, adminToken, nil))
+
+		reviewers := createPR(t, "pr-own-3", "org/app", []string{"README.md"})
+		require.Len(t, reviewers, 2)
+		assert.True(t, slices.Contains(reviewers, "own2") || slices.Contains(reviewers, "own3"))
+
+		// Для репозитория без CODEOWNERS действует командный файл
+		reviewers = createPR(t, "pr-own-4", "org/other", []string{"README.md"})
+		assert.Contains(t, reviewers, "own4")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		req := map[string]interface{}{"repository": "org/app"}
+		require.Equal(t, http.StatusOK, post(t, "/codeowners/delete", req, adminToken, nil))
+		assert.Equal(t, http.StatusNotFound, post(t, "/codeowners/delete", req, adminToken, nil))
+
+		resp := env.MakeRequest(t, http.MethodGet, "/codeowners/get?repository=org/app", nil, adminToken)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+// TestE2E_FallbackTeams проверяет добор ревьюверов из запасных команд
+func TestE2E_FallbackTeams(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "fb-team",
+		Members: []Member{
+			{UserID: "fb1", Username: "Anfisa", IsActive: true, Role: "admin"},
+			{UserID: "fb2", Username: "Bronislav", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	adminToken := env.Login(t, "fb1")
+
+	post := func(t *testing.T, path string, req interface{}, token string, out interface{}) int {
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	for _, fallback := range []Team{
+		{TeamName: "fb-first", Members: []Member{{UserID: "fb3", Username: "Vlas", IsActive: true}}},
+		{TeamName: "fb-second", Members: []Member{
+			{UserID: "fb4", Username: "Glafira", IsActive: true},
+			{UserID: "fb5", Username: "Dobrynya", IsActive: true},
+		}},
+	} {
+		require.Equal(t, http.StatusCreated, post(t, "/team/add", fallback, adminToken, nil))
+	}
+
+	type fallbackReviewer struct {
+		UserID   string `json:"user_id"`
+		TeamName string `json:"team_name"`
+	}
+	type prResponse struct {
+		PR struct {
+			AssignedReviewers []string           `json:"assigned_reviewers"`
+			FallbackReviewers []fallbackReviewer `json:"fallback_reviewers"`
+		} `json:"pr"`
+	}
+
+	createPR := func(t *testing.T, prID string) prResponse {
+		var createResp prResponse
+		req := CreatePRRequest{PullRequestID: prID, PullRequestName: "Fallback " + prID, AuthorID: "fb1"}
+		require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", req, adminToken, &createResp))
+		return createResp
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		req := map[string]interface{}{"team_name": "fb-team", "fallback_teams": []string{"fb-team"}}
+		assert.Equal(t, http.StatusBadRequest, post(t, "/team/setFallbackTeams", req, adminToken, nil))
+
+		req = map[string]interface{}{"team_name": "fb-team", "fallback_teams": []string{"fb-first", "fb-first"}}
+		assert.Equal(t, http.StatusBadRequest, post(t, "/team/setFallbackTeams", req, adminToken, nil))
+
+		req = map[string]interface{}{"team_name": "fb-team", "fallback_teams": []string{"missing-team"}}
+		assert.Equal(t, http.StatusNotFound, post(t, "/team/setFallbackTeams", req, adminToken, nil))
+
+		req = map[string]interface{}{"team_name": "fb-team", "fallback_teams": []string{"fb-first"}}
+		assert.Equal(t, http.StatusForbidden, post(t, "/team/setFallbackTeams", req, env.Login(t, "fb2"), nil))
+	})
+
+	t.Run("Without Fallbacks", func(t *testing.T) {
+		pr := createPR(t, "pr-fb-0").PR
+		assert.Equal(t, []string{"fb2"}, pr.AssignedReviewers)
+		assert.Empty(t, pr.FallbackReviewers)
+	})
+
+	t.Run("Set Fallback Teams", func(t *testing.T) {
+		var settings struct {
+			FallbackTeams []string `json:"fallback_teams"`
+		}
+		req := map[string]interface{}{"team_name": "fb-team", "fallback_teams": []string{"fb-first", "fb-second"}}
+		require.Equal(t, http.StatusOK, post(t, "/team/setFallbackTeams", req, adminToken, &settings))
+		assert.Equal(t, []string{"fb-first", "fb-second"}, settings.FallbackTeams)
+	})
+
+	t.Run("First Fallback Fills The Gap", func(t *testing.T) {
+		pr := createPR(t, "pr-fb-1").PR
+		assert.ElementsMatch(t, []string{"fb2", "fb3"}, pr.AssignedReviewers)
+		assert.Equal(t, []fallbackReviewer{{UserID: "fb3", TeamName: "fb-first"}}, pr.FallbackReviewers)
+	})
+
+	t.Run("Fallbacks In Order", func(t *testing.T) {
+		req := map[string]interface{}{"team_name": "fb-team", "min_reviewers": 0, "max_reviewers": 3}
+		require.Equal(t, http.StatusOK, post(t, "/team/setReviewerLimits", req, adminToken, nil))
+
+		pr := createPR(t, "pr-fb-2").PR
+		require.Len(t, pr.AssignedReviewers, 3)
+		assert.Contains(t, pr.AssignedReviewers, "fb2")
+		require.Len(t, pr.FallbackReviewers, 2)
+		assert.Contains(t, pr.FallbackReviewers, fallbackReviewer{UserID: "fb3", TeamName: "fb-first"})
+		teams := []string{pr.FallbackReviewers[0].TeamName, pr.FallbackReviewers[1].TeamName}
+		assert.ElementsMatch(t, []string{"fb-first", "fb-second"}, teams)
+
+		// Источник ревьюверов сохраняется вместе с PR
+		resp := env.MakeRequest(t, http.MethodGet, "/pullRequest/get?pull_request_id=pr-fb-2", nil, adminToken)
+		defer resp.Body.Close()
+		var getResp prResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&getResp))
+		assert.ElementsMatch(t, pr.FallbackReviewers, getResp.PR.FallbackReviewers)
+	})
+}
+
+// TestE2E_ManualReviewers проверяет явный выбор ревьювера при переназначении и ручное назначение и снятие
+func TestE2E_ManualReviewers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	env.WaitForHealthCheck(t)
+
+	team := Team{
+		TeamName: "man-team",
+		Members: []Member{
+			{UserID: "man1", Username: "Avdotya", IsActive: true, Role: "admin"},
+			{UserID: "man2", Username: "Bogdana", IsActive: true},
+			{UserID: "man3", Username: "Vsevolod", IsActive: true},
+			{UserID: "man4", Username: "Gordey", IsActive: true},
+			{UserID: "man5", Username: "Dominika", IsActive: false},
+			{UserID: "man6", Username: "Elisey", IsActive: true},
+		},
+	}
+
+	body, _ := json.Marshal(team)
+	resp := env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	// Активный пользователь, чья единственная команда в архиве
+	archived := Team{
+		TeamName: "man-archived",
+		Members:  []Member{{UserID: "man7", Username: "Zlata", IsActive: true}},
+	}
+	body, _ = json.Marshal(archived)
+	resp = env.MakeRequest(t, http.MethodPost, "/team/add", bytes.NewReader(body), env.AdminToken(t))
+	resp.Body.Close()
+
+	_, err := env.DB.Exec(env.ctx, `UPDATE teams SET archived_at = NOW() WHERE team_name = 'man-archived'`)
+	require.NoError(t, err)
+
+	// man6 в отпуске: автоматически не назначается и не может быть выбран вручную
+	_, err = env.DB.Exec(env.ctx,
+		`INSERT INTO user_absences (user_id, start_date, end_date) VALUES ('man6', CURRENT_DATE, CURRENT_DATE + 3)`)
+	require.NoError(t, err)
+
+	adminToken := env.Login(t, "man1")
+
+	// Пароль задаётся при первом входе, повторный вход идёт по нему
+	tokens := map[string]string{}
+	token := func(t *testing.T, userID string) string {
+		if tokens[userID] == "" {
+			tokens[userID] = env.Login(t, userID)
+		}
+		return tokens[userID]
+	}
+
+	post := func(t *testing.T, path string, req interface{}, token string, out interface{}) int {
+		body, _ := json.Marshal(req)
+		resp := env.MakeRequest(t, http.MethodPost, path, bytes.NewReader(body), token)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	type errorResponse struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	type prResponse struct {
+		PR PullRequestResponse `json:"pr"`
+	}
+
+	// Лимиты команды: от 1 до 2 ревьюверов
+	req := map[string]interface{}{"team_name": "man-team", "min_reviewers": 1, "max_reviewers": 2}
+	require.Equal(t, http.StatusOK, post(t, "/team/setReviewerLimits", req, adminToken, nil))
+
+	var createResp prResponse
+	createReq := CreatePRRequest{PullRequestID: "pr-man-1", PullRequestName: "Manual reviewers", AuthorID: "man1"}
+	require.Equal(t, http.StatusCreated, post(t, "/pullRequest/create", createReq, adminToken, &createResp))
+	reviewers := createResp.PR.Reviewers
+	require.Len(t, reviewers, 2)
+
+	// Участник команды, не назначенный на PR
+	var free string
+	for _, id := range []string{"man2", "man3", "man4"} {
+		if !slices.Contains(reviewers, id) {
+			free = id
+		}
+	}
+
+	t.Run("Reassign To Invalid Reviewer", func(t *testing.T) {
+		cases := []struct {
+			newReviewer string
+			status      int
+			code        string
+		}{
+			{"man1", http.StatusConflict, "INVALID_REVIEWER"},
+			{"man5", http.StatusConflict, "INVALID_REVIEWER"},
+			{"man6", http.StatusConflict, "INVALID_REVIEWER"},
+			{"man7", http.StatusConflict, "INVALID_REVIEWER"},
+			{reviewers[1], http.StatusConflict, "ALREADY_ASSIGNED"},
+			{"missing-user", http.StatusNotFound, "NOT_FOUND"},
+		}
+		for _, tc := range cases {
+			var errResp errorResponse
+			reassign := ReassignRequest{PullRequestID: "pr-man-1", OldReviewerID: reviewers[0], NewReviewerID: tc.newReviewer}
+			assert.Equal(t, tc.status, post(t, "/pullRequest/reassign", reassign, adminToken, &errResp), tc.newReviewer)
+			assert.Equal(t, tc.code, errResp.Error.Code, tc.newReviewer)
+		}
+	})
+
+	t.Run("Only Lead Or Admin Chooses Reviewers", func(t *testing.T) {
+		reviewerToken := token(t, reviewers[0])
+
+		add := map[string]string{"pull_request_id": "pr-man-1", "user_id": free}
+		assert.Equal(t, http.StatusForbidden, post(t, "/pullRequest/addReviewer", add, reviewerToken, nil))
+
+		remove := map[string]string{"pull_request_id": "pr-man-1", "user_id": reviewers[1]}
+		assert.Equal(t, http.StatusForbidden, post(t, "/pullRequest/removeReviewer", remove, reviewerToken, nil))
+
+		reassign := ReassignRequest{PullRequestID: "pr-man-1", OldReviewerID: reviewers[0], NewReviewerID: free}
+		assert.Equal(t, http.StatusForbidden, post(t, "/pullRequest/reassign", reassign, reviewerToken, nil))
+	})
+
+	t.Run("Reassign To Chosen Reviewer", func(t *testing.T) {
+		var reassignResp struct {
+			PR         PullRequestResponse `json:"pr"`
+			ReplacedBy string              `json:"replaced_by"`
+		}
+		reassign := ReassignRequest{PullRequestID: "pr-man-1", OldReviewerID: reviewers[0], NewReviewerID: free}
+		require.Equal(t, http.StatusOK, post(t, "/pullRequest/reassign", reassign, adminToken, &reassignResp))
+		assert.Equal(t, free, reassignResp.ReplacedBy)
+		assert.ElementsMatch(t, []string{free, reviewers[1]}, reassignResp.PR.Reviewers)
+
+		free = reviewers[0]
+		reviewers = reassignResp.PR.Reviewers
+	})
+
+	t.Run("Add Reviewer Within Max", func(t *testing.T) {
+		var errResp errorResponse
+		add := map[string]string{"pull_request_id": "pr-man-1", "user_id": free}
+		require.Equal(t, http.StatusConflict, post(t, "/pullRequest/addReviewer", add, adminToken, &errResp))
+		assert.Equal(t, "REVIEWER_LIMIT", errResp.Error.Code)
+
+		req := map[string]interface{}{"team_name": "man-team", "min_reviewers": 1, "max_reviewers": 3}
+		require.Equal(t, http.StatusOK, post(t, "/team/setReviewerLimits", req, adminToken, nil))
+
+		var addResp prResponse
+		require.Equal(t, http.StatusOK, post(t, "/pullRequest/addReviewer", add, adminToken, &addResp))
+		assert.Len(t, addResp.PR.Reviewers, 3)
+		assert.Contains(t, addResp.PR.Reviewers, free)
+
+		errResp = errorResponse{}
+		require.Equal(t, http.StatusConflict, post(t, "/pullRequest/addReviewer", add, adminToken, &errResp))
+		assert.Equal(t, "ALREADY_ASSIGNED", errResp.Error.Code)
+	})
+
+	t.Run("Remove Reviewer Within Min", func(t *testing.T) {
+		var removeResp prResponse
+		for _, id := range []string{"man2", "man3"} {
+			remove := map[string]string{"pull_request_id": "pr-man-1", "user_id": id}
+			require.Equal(t, http.StatusOK, post(t, "/pullRequest/removeReviewer", remove, adminToken, &removeResp))
+		}
+		assert.Equal(t, []string{"man4"}, removeResp.PR.Reviewers)
+
+		var errResp errorResponse
+		remove := map[string]string{"pull_request_id": "pr-man-1", "user_id": "man4"}
+		require.Equal(t, http.StatusConflict, post(t, "/pullRequest/removeReviewer", remove, adminToken, &errResp))
+		assert.Equal(t, "REVIEWER_LIMIT", errResp.Error.Code)
+
+		errResp = errorResponse{}
+		remove = map[string]string{"pull_request_id": "pr-man-1", "user_id": "man2"}
+		require.Equal(t, http.StatusConflict, post(t, "/pullRequest/removeReviewer", remove, adminToken, &errResp))
+		assert.Equal(t, "NOT_ASSIGNED", errResp.Error.Code)
+	})
+
+	t.Run("Timeline Records Manual Changes", func(t *testing.T) {
+		resp := env.MakeRequest(t, http.MethodGet, "/pullRequest/timeline?pull_request_id=pr-man-1", nil, adminToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var timeline struct {
+			Events []struct {
+				Type   string `json:"type"`
+				UserID string `json:"user_id"`
+				Reason string `json:"reason"`
+			} `json:"events"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&timeline))
+
+		var manual []string
+		for _, e := range timeline.Events {
+			if e.Reason == "manual" && e.Type != "reviewer_reassigned" {
+				manual = append(manual, e.Type+":"+e.UserID)
+			}
+		}
+		assert.Equal(t, []string{
+			"reviewer_assigned:" + free,
+			"reviewer_unassigned:man2",
+			"reviewer_unassigned:man3",
+		}, manual)
+	})
+
+	t.Run("Reassigned Reviewer Starts Without Verdict", func(t *testing.T) {
+		add := map[string]string{"pull_request_id": "pr-man-1", "user_id": "man2"}
+		require.Equal(t, http.StatusOK, post(t, "/pullRequest/addReviewer", add, adminToken, nil))
+
+		review := map[string]string{"pull_request_id": "pr-man-1", "user_id": "man4", "verdict": "APPROVED"}
+		require.Equal(t, http.StatusOK, post(t, "/pullRequest/review", review, token(t, "man4"), nil))
+
+		remove := map[string]string{"pull_request_id": "pr-man-1", "user_id": "man4"}
+		require.Equal(t, http.StatusOK, post(t, "/pullRequest/removeReviewer", remove, adminToken, nil))
+
+		// Решение, отправленное до повторного назначения, не возвращается
+		var addResp struct {
+			PR struct {
+				Reviewers []string `json:"assigned_reviewers"`
+				Reviews   []struct {
+					UserID string `json:"user_id"`
+				} `json:"reviews"`
+			} `json:"pr"`
+		}
+		add = map[string]string{"pull_request_id": "pr-man-1", "user_id": "man4"}
+		require.Equal(t, http.StatusOK, post(t, "/pullRequest/addReviewer", add, adminToken, &addResp))
+		assert.Contains(t, addResp.PR.Reviewers, "man4")
+		assert.Empty(t, addResp.PR.Reviews)
+	})
+
+	t.Run("Only Open PRs", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, post(t, "/pullRequest/merge", map[string]string{"pull_request_id": "pr-man-1"}, adminToken, nil))
+
+		var errResp errorResponse
+		add := map[string]string{"pull_request_id": "pr-man-1", "user_id": "man2"}
+		require.Equal(t, http.StatusConflict, post(t, "/pullRequest/addReviewer", add, adminToken, &errResp))
+		assert.Equal(t, "PR_MERGED", errResp.Error.Code)
+	})
+}